@@ -22,9 +22,15 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/conseweb/stcwallet/chain"
+	"github.com/conseweb/stcwallet/internal/cfgutil"
+	"github.com/conseweb/stcwallet/signer"
+	"github.com/conseweb/stcwallet/wallet"
+	"github.com/conseweb/stcwallet/walletdb"
 )
 
 var (
@@ -67,14 +73,39 @@ func walletMain() error {
 		}()
 	}
 
-	// Load the wallet database.  It must have been created with the
-	// --create option already or this will return an appropriate error.
-	wallet, db, err := openWallet()
+	// Load the wallet database if it exists.  Otherwise, the server is
+	// started without a default wallet and one may be created with the
+	// createwallet RPC.
+	netDir := networkDir(cfg.DataDir, activeNet.Params)
+	dbExists, err := cfgutil.FileExists(filepath.Join(netDir, walletDbName))
 	if err != nil {
 		log.Errorf("%v", err)
 		return err
 	}
-	defer db.Close()
+	var w *wallet.Wallet
+	var db walletdb.DB
+	if dbExists {
+		w, db, err = openWallet()
+		if err != nil {
+			log.Errorf("%v", err)
+			return err
+		}
+	}
+
+	// Ask an external signer for the signatures of keys the wallets do
+	// not hold themselves.
+	var remote *signer.Remote
+	if cfg.Signer != "" {
+		remote, err = openSigner(cfg.Signer)
+		if err != nil {
+			log.Errorf("Cannot open external signer: %v", err)
+			if db != nil {
+				db.Close()
+			}
+			return err
+		}
+		defer remote.Close()
+	}
 
 	// Create and start HTTP server to serve wallet client connections.
 	// This will be updated with the wallet and chain server RPC client
@@ -83,68 +114,43 @@ func walletMain() error {
 		cfg.RPCMaxWebsockets)
 	if err != nil {
 		log.Errorf("Unable to create HTTP server: %v", err)
+		if db != nil {
+			db.Close()
+		}
 		return err
 	}
+	if remote != nil {
+		server.loader.SetSigner(remote)
+	}
 	server.Start()
-	server.SetWallet(wallet)
 
 	// Shutdown the server if an interrupt signal is received.
 	addInterruptHandler(server.Stop)
 
-	go func() {
-		for {
-			// Read CA certs and create the RPC client.
-			var certs []byte
-			if !cfg.DisableClientTLS {
-				certs, err = ioutil.ReadFile(cfg.CAFile)
-				if err != nil {
-					log.Warnf("Cannot open CA file: %v", err)
-					// If there's an error reading the CA file, continue
-					// with nil certs and without the client connection
-					certs = nil
-				}
-			} else {
-				log.Info("Client TLS is disabled")
-			}
-			rpcc, err := chain.NewClient(activeNet.Params, cfg.RPCConnect,
-				cfg.BtcdUsername, cfg.BtcdPassword, certs, cfg.DisableClientTLS)
-			if err != nil {
-				log.Errorf("Cannot create chain server RPC client: %v", err)
-				return
-			}
-			err = rpcc.Start()
-			if err != nil {
-				log.Warnf("Connection to Bitcoin RPC chain server " +
-					"unsuccessful -- available RPC methods will be limited")
-			}
-			// Even if Start errored, we still add the server disconnected.
-			// All client methods will then error, so it's obvious to a
-			// client that the there was a connection problem.
-			server.SetChainServer(rpcc)
-
-			// Start wallet goroutines and handle RPC client notifications
-			// if the server is not shutting down.
-			select {
-			case <-server.quit:
-				return
-			default:
-				wallet.Start(rpcc)
-			}
-
-			// Block goroutine until the client is finished.
-			rpcc.WaitForShutdown()
+	// An offline wallet is never attached to a chain server.  Only the
+	// requests needed to sign transactions are served until shutdown.
+	if cfg.Offline {
+		log.Info("Running offline -- only signing requests are served")
+		server.SetOffline()
+	}
 
-			wallet.SetChainSynced(false)
-			wallet.Stop()
+	if w != nil {
+		server.loadDefaultWallet(w, db)
+	} else {
+		log.Info("No wallet exists -- create one with the " +
+			"createwallet RPC or the --create option")
+	}
 
-			// Reconnect only if the server is not shutting down.
-			select {
-			case <-server.quit:
-				return
-			default:
-			}
+	// Load the named wallets, which are served in addition to the default
+	// wallet.
+	for _, name := range cfg.Wallets {
+		if _, err := server.loader.Load(name, nil); err != nil {
+			log.Errorf("Cannot load wallet %q: %v", name, err)
+			server.Stop()
+			server.WaitForShutdown()
+			return err
 		}
-	}()
+	}
 
 	// Wait for the server to shutdown either due to a stop RPC request
 	// or an interrupt.
@@ -152,3 +158,80 @@ func walletMain() error {
 	log.Info("Shutdown complete")
 	return nil
 }
+
+// openSigner connects to the external signer of the signer option, which was
+// validated when loading the config.
+func openSigner(option string) (*signer.Remote, error) {
+	kind, target, err := parseSigner(option)
+	if err != nil {
+		return nil, err
+	}
+	if kind == "exec" {
+		args := strings.Fields(target)
+		return signer.StartProcess(args[0], args[1:]...)
+	}
+	return signer.Dial(kind, target)
+}
+
+// syncWithChainServer connects a wallet to the chain server and keeps it
+// synced, reconnecting whenever the connection is lost, until quit is closed.
+// setChainSvr is called with each chain server RPC client before the wallet is
+// started with it.
+func syncWithChainServer(w *wallet.Wallet, setChainSvr func(*chain.Client),
+	quit <-chan struct{}) {
+
+	for {
+		// Read CA certs and create the RPC client.
+		var certs []byte
+		var err error
+		if !cfg.DisableClientTLS {
+			certs, err = ioutil.ReadFile(cfg.CAFile)
+			if err != nil {
+				log.Warnf("Cannot open CA file: %v", err)
+				// If there's an error reading the CA file, continue
+				// with nil certs and without the client connection
+				certs = nil
+			}
+		} else {
+			log.Info("Client TLS is disabled")
+		}
+		rpcc, err := chain.NewClient(activeNet.Params, cfg.RPCConnect,
+			cfg.BtcdUsername, cfg.BtcdPassword, certs, cfg.DisableClientTLS)
+		if err != nil {
+			log.Errorf("Cannot create chain server RPC client: %v", err)
+			return
+		}
+		err = rpcc.Start()
+		if err != nil {
+			log.Warnf("Connection to Bitcoin RPC chain server " +
+				"unsuccessful -- available RPC methods will be limited")
+		}
+		// Even if Start errored, we still add the server disconnected.
+		// All client methods will then error, so it's obvious to a
+		// client that the there was a connection problem.
+		setChainSvr(rpcc)
+
+		// Start wallet goroutines and handle RPC client notifications
+		// if the server is not shutting down.
+		select {
+		case <-quit:
+			rpcc.Stop()
+			return
+		default:
+			w.Start(rpcc)
+		}
+
+		// Block goroutine until the client is finished.
+		rpcc.WaitForShutdown()
+
+		w.SetChainSynced(false)
+		w.Stop()
+
+		// Reconnect only if the server is not shutting down.
+		select {
+		case <-quit:
+			return
+		default:
+		}
+	}
+}
@@ -17,6 +17,7 @@
 package chain
 
 import (
+	"encoding/json"
 	"errors"
 	"sync"
 	"time"
@@ -202,6 +203,40 @@ func (c *Client) BlockStamp() (*waddrmgr.BlockStamp, error) {
 	}
 }
 
+// ErrNoFeeEstimate is returned by EstimateFeeRate when the chain server does
+// not have enough data to estimate a fee rate.
+var ErrNoFeeEstimate = errors.New("no fee estimate available")
+
+// EstimateFeeRate returns the fee rate, in satoshis per byte, the chain server
+// estimates is needed for a transaction to begin confirming within numBlocks
+// blocks.  The estimate is rounded up to the next whole satoshi per byte.
+// ErrNoFeeEstimate is returned if the server has no estimate.
+func (c *Client) EstimateFeeRate(numBlocks uint32) (coinutil.Amount, error) {
+	param, err := json.Marshal(numBlocks)
+	if err != nil {
+		return 0, err
+	}
+	res, err := c.RawRequest("estimatefee", []json.RawMessage{param})
+	if err != nil {
+		return 0, err
+	}
+
+	// The estimate is reported in BTC per kilobyte, or as a negative
+	// number when no estimate is available.
+	var feePerKb float64
+	if err := json.Unmarshal(res, &feePerKb); err != nil {
+		return 0, err
+	}
+	if feePerKb <= 0 {
+		return 0, ErrNoFeeEstimate
+	}
+	amt, err := coinutil.NewAmount(feePerKb)
+	if err != nil {
+		return 0, err
+	}
+	return (amt + 999) / 1000, nil
+}
+
 // parseBlock parses a btcws definition of the block a tx is mined it to the
 // Block structure of the wtxmgr package, and the block index.  This is done
 // here since stcrpcclient doesn't parse this nicely for us.
@@ -23,12 +23,14 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/conseweb/coinutil"
 	flags "github.com/conseweb/go-flags"
 	"github.com/conseweb/stcwallet/internal/cfgutil"
 	"github.com/conseweb/stcwallet/internal/legacy/keystore"
 	"github.com/conseweb/stcwallet/netparams"
+	"github.com/conseweb/stcwallet/wallet"
 )
 
 const (
@@ -38,6 +40,8 @@ const (
 	defaultLogDirname       = "logs"
 	defaultLogFilename      = "stcwallet.log"
 	defaultDisallowFree     = false
+	defaultCoinSelection    = "largestfirst"
+	defaultFallbackFeeRate  = 1
 	defaultRPCMaxClients    = 10
 	defaultRPCMaxWebsockets = 25
 
@@ -67,35 +71,44 @@ var (
 )
 
 type config struct {
-	ShowVersion      bool     `short:"V" long:"version" description:"Display version information and exit"`
-	Create           bool     `long:"create" description:"Create the wallet if it does not exist"`
-	CreateTemp       bool     `long:"createtemp" description:"Create a temporary simulation wallet (pass=password) in the data directory indicated; must call with --datadir"`
-	CAFile           string   `long:"cafile" description:"File containing root certificates to authenticate a TLS connections with btcd"`
-	RPCConnect       string   `short:"c" long:"rpcconnect" description:"Hostname/IP and port of btcd RPC server to connect to (default localhost:18334, mainnet: localhost:8334, simnet: localhost:18556)"`
-	DebugLevel       string   `short:"d" long:"debuglevel" description:"Logging level {trace, debug, info, warn, error, critical}"`
-	ConfigFile       string   `short:"C" long:"configfile" description:"Path to configuration file"`
-	SvrListeners     []string `long:"rpclisten" description:"Listen for RPC/websocket connections on this interface/port (default port: 18332, mainnet: 8332, simnet: 18554)"`
-	DataDir          string   `short:"D" long:"datadir" description:"Directory to store wallets and transactions"`
-	LogDir           string   `long:"logdir" description:"Directory to log output."`
-	Username         string   `short:"u" long:"username" description:"Username for client and btcd authorization"`
-	Password         string   `short:"P" long:"password" default-mask:"-" description:"Password for client and btcd authorization"`
-	BtcdUsername     string   `long:"btcdusername" description:"Alternative username for btcd authorization"`
-	BtcdPassword     string   `long:"btcdpassword" default-mask:"-" description:"Alternative password for btcd authorization"`
-	WalletPass       string   `long:"walletpass" default-mask:"-" description:"The public wallet password -- Only required if the wallet was created with one"`
-	RPCCert          string   `long:"rpccert" description:"File containing the certificate file"`
-	RPCKey           string   `long:"rpckey" description:"File containing the certificate key"`
-	RPCMaxClients    int64    `long:"rpcmaxclients" description:"Max number of RPC clients for standard connections"`
-	RPCMaxWebsockets int64    `long:"rpcmaxwebsockets" description:"Max number of RPC websocket connections"`
-	DisableServerTLS bool     `long:"noservertls" description:"Disable TLS for the RPC server -- NOTE: This is only allowed if the RPC server is bound to localhost"`
-	DisableClientTLS bool     `long:"noclienttls" description:"Disable TLS for the RPC client -- NOTE: This is only allowed if the RPC client is connecting to localhost"`
-	MainNet          bool     `long:"mainnet" description:"Use the main Bitcoin network (default testnet3)"`
-	SimNet           bool     `long:"simnet" description:"Use the simulation test network (default testnet3)"`
-	KeypoolSize      uint     `short:"k" long:"keypoolsize" description:"DEPRECATED -- Maximum number of addresses in keypool"`
-	DisallowFree     bool     `long:"disallowfree" description:"Force transactions to always include a fee"`
-	Proxy            string   `long:"proxy" description:"Connect via SOCKS5 proxy (eg. 127.0.0.1:9050)"`
-	ProxyUser        string   `long:"proxyuser" description:"Username for proxy server"`
-	ProxyPass        string   `long:"proxypass" default-mask:"-" description:"Password for proxy server"`
-	Profile          string   `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
+	ShowVersion         bool          `short:"V" long:"version" description:"Display version information and exit"`
+	Create              bool          `long:"create" description:"Create the wallet if it does not exist"`
+	CreateTemp          bool          `long:"createtemp" description:"Create a temporary simulation wallet (pass=password) in the data directory indicated; must call with --datadir"`
+	CAFile              string        `long:"cafile" description:"File containing root certificates to authenticate a TLS connections with btcd"`
+	RPCConnect          string        `short:"c" long:"rpcconnect" description:"Hostname/IP and port of btcd RPC server to connect to (default localhost:18334, mainnet: localhost:8334, simnet: localhost:18556)"`
+	DebugLevel          string        `short:"d" long:"debuglevel" description:"Logging level {trace, debug, info, warn, error, critical}"`
+	ConfigFile          string        `short:"C" long:"configfile" description:"Path to configuration file"`
+	SvrListeners        []string      `long:"rpclisten" description:"Listen for RPC/websocket connections on this interface/port (default port: 18332, mainnet: 8332, simnet: 18554)"`
+	DataDir             string        `short:"D" long:"datadir" description:"Directory to store wallets and transactions"`
+	LogDir              string        `long:"logdir" description:"Directory to log output."`
+	Username            string        `short:"u" long:"username" description:"Username for client and btcd authorization"`
+	Password            string        `short:"P" long:"password" default-mask:"-" description:"Password for client and btcd authorization"`
+	BtcdUsername        string        `long:"btcdusername" description:"Alternative username for btcd authorization"`
+	BtcdPassword        string        `long:"btcdpassword" default-mask:"-" description:"Alternative password for btcd authorization"`
+	WalletPass          string        `long:"walletpass" default-mask:"-" description:"The public wallet password -- Only required if the wallet was created with one"`
+	RPCCert             string        `long:"rpccert" description:"File containing the certificate file"`
+	RPCKey              string        `long:"rpckey" description:"File containing the certificate key"`
+	RPCMaxClients       int64         `long:"rpcmaxclients" description:"Max number of RPC clients for standard connections"`
+	RPCMaxWebsockets    int64         `long:"rpcmaxwebsockets" description:"Max number of RPC websocket connections"`
+	DisableServerTLS    bool          `long:"noservertls" description:"Disable TLS for the RPC server -- NOTE: This is only allowed if the RPC server is bound to localhost"`
+	DisableClientTLS    bool          `long:"noclienttls" description:"Disable TLS for the RPC client -- NOTE: This is only allowed if the RPC client is connecting to localhost"`
+	MainNet             bool          `long:"mainnet" description:"Use the main Bitcoin network (default testnet3)"`
+	SimNet              bool          `long:"simnet" description:"Use the simulation test network (default testnet3)"`
+	KeypoolSize         uint          `short:"k" long:"keypoolsize" description:"DEPRECATED -- Maximum number of addresses in keypool"`
+	DisallowFree        bool          `long:"disallowfree" description:"Force transactions to always include a fee"`
+	CoinSelection       string        `long:"coinselection" description:"Input selection strategy for created transactions {largestfirst, smallestfirst, branchandbound, random}"`
+	FallbackFeeRate     int64         `long:"fallbackfeerate" description:"Fee rate in satoshis per byte used when the chain server has no fee estimate"`
+	SignalRBF           bool          `long:"signalrbf" description:"Signal that created transactions may be replaced by ones paying a higher fee (BIP0125), as required by bumpfee"`
+	UnminedExpiry       time.Duration `long:"unminedexpiry" description:"Abandon unmined transactions received longer than this duration ago (eg. 72h) -- 0 disables"`
+	UnminedExpiryBlocks int32         `long:"unminedexpiryblocks" description:"Abandon unmined transactions after this many blocks were mined without them -- 0 disables"`
+	GapLimit            uint32        `long:"gaplimit" description:"Maximum number of consecutive unused receiving addresses of an account, also used when discovering the accounts of a restored wallet -- 0 (default) disables the limit"`
+	Offline             bool          `long:"offline" description:"Run an air-gapped wallet which never connects to the chain server and only serves signing requests"`
+	Signer              string        `long:"signer" description:"External signer asked for signatures of keys the wallet does not hold {unix:<path>, tcp:<host:port>, exec:<command>}"`
+	Wallets             []string      `long:"wallet" description:"Named wallet to load in addition to the default wallet and serve to requests posted to /wallet/<name> (websocket clients are only served the default wallet), or to create with --create -- may be specified multiple times"`
+	Proxy               string        `long:"proxy" description:"Connect via SOCKS5 proxy (eg. 127.0.0.1:9050)"`
+	ProxyUser           string        `long:"proxyuser" description:"Username for proxy server"`
+	ProxyPass           string        `long:"proxypass" default-mask:"-" description:"Password for proxy server"`
+	Profile             string        `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
 }
 
 // cleanAndExpandPath expands environement variables and leading ~ in the
@@ -112,6 +125,23 @@ func cleanAndExpandPath(path string) string {
 	return filepath.Clean(os.ExpandEnv(path))
 }
 
+// parseSigner splits the signer option into the kind of external signer, one
+// of "unix", "tcp" or "exec", and the socket path, address or command of the
+// signer.
+func parseSigner(option string) (kind, target string, err error) {
+	parts := strings.SplitN(option, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		return "", "", fmt.Errorf("the signer option must be of the form "+
+			"<kind>:<target>, got %q", option)
+	}
+	switch parts[0] {
+	case "unix", "tcp", "exec":
+		return parts[0], parts[1], nil
+	}
+	return "", "", fmt.Errorf("unknown signer kind %q -- must be one of "+
+		"unix, tcp or exec", parts[0])
+}
+
 // validLogLevel returns whether or not logLevel is a valid debug log level.
 func validLogLevel(logLevel string) bool {
 	switch logLevel {
@@ -219,6 +249,8 @@ func loadConfig() (*config, []string, error) {
 		RPCKey:           defaultRPCKeyFile,
 		RPCCert:          defaultRPCCertFile,
 		DisallowFree:     defaultDisallowFree,
+		CoinSelection:    defaultCoinSelection,
+		FallbackFeeRate:  defaultFallbackFeeRate,
 		RPCMaxClients:    defaultRPCMaxClients,
 		RPCMaxWebsockets: defaultRPCMaxWebsockets,
 	}
@@ -339,6 +371,54 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Validate the coin selection strategy.
+	if _, err := wallet.CoinSelectorByName(cfg.CoinSelection); err != nil {
+		err := fmt.Errorf("%s: %v", "loadConfig", err)
+		fmt.Fprintln(os.Stderr, err)
+		parser.WriteHelp(os.Stderr)
+		return nil, nil, err
+	}
+
+	// Validate the external signer.
+	if cfg.Signer != "" {
+		if _, _, err := parseSigner(cfg.Signer); err != nil {
+			err := fmt.Errorf("%s: %v", "loadConfig", err)
+			fmt.Fprintln(os.Stderr, err)
+			parser.WriteHelp(os.Stderr)
+			return nil, nil, err
+		}
+	}
+
+	// Validate the names of the named wallets.
+	for _, name := range cfg.Wallets {
+		if err := checkWalletName(name); err != nil {
+			err := fmt.Errorf("%s: invalid wallet name %q: %v",
+				"loadConfig", name, err)
+			fmt.Fprintln(os.Stderr, err)
+			parser.WriteHelp(os.Stderr)
+			return nil, nil, err
+		}
+	}
+
+	// Validate the fallback fee rate.
+	if cfg.FallbackFeeRate <= 0 {
+		str := "%s: the fallbackfeerate option must be positive"
+		err := fmt.Errorf(str, "loadConfig")
+		fmt.Fprintln(os.Stderr, err)
+		parser.WriteHelp(os.Stderr)
+		return nil, nil, err
+	}
+
+	// Validate the unmined transaction expiry policies.
+	if cfg.UnminedExpiry < 0 || cfg.UnminedExpiryBlocks < 0 {
+		str := "%s: the unminedexpiry and unminedexpiryblocks options " +
+			"may not be negative"
+		err := fmt.Errorf(str, "loadConfig")
+		fmt.Fprintln(os.Stderr, err)
+		parser.WriteHelp(os.Stderr)
+		return nil, nil, err
+	}
+
 	// Exit if you try to use a simulation wallet with a standard
 	// data directory.
 	if cfg.DataDir == defaultDataDir && cfg.CreateTemp {
@@ -395,6 +475,18 @@ func loadConfig() (*config, []string, error) {
 				return nil, nil, err
 			}
 		}
+	} else if cfg.Create && len(cfg.Wallets) != 0 {
+		// Create the named wallets instead of the default wallet.
+		for _, name := range cfg.Wallets {
+			if err := createNamedWallet(&cfg, name); err != nil {
+				fmt.Fprintf(os.Stderr, "Unable to create wallet %q: %v\n",
+					name, err)
+				return nil, nil, err
+			}
+		}
+
+		// Created successfully, so exit now with success.
+		os.Exit(0)
 	} else if cfg.Create {
 		// Error if the create flag is set and the wallet already
 		// exists.
@@ -411,7 +503,7 @@ func loadConfig() (*config, []string, error) {
 		}
 
 		// Perform the initial wallet creation wizard.
-		if err := createWallet(&cfg); err != nil {
+		if err := createWallet(&cfg, netDir); err != nil {
 			fmt.Fprintln(os.Stderr, "Unable to create wallet:", err)
 			return nil, nil, err
 		}
@@ -419,21 +511,21 @@ func loadConfig() (*config, []string, error) {
 		// Created successfully, so exit now with success.
 		os.Exit(0)
 	} else if !dbFileExists {
+		// Without a wallet, the server is started with no default
+		// wallet, which may be created over RPC.  A legacy wallet must
+		// be imported with the --create option first.
 		keystorePath := filepath.Join(netDir, keystore.Filename)
 		keystoreExists, err := cfgutil.FileExists(keystorePath)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			return nil, nil, err
 		}
-		if !keystoreExists {
-			err = fmt.Errorf("The wallet does not exist.  Run with the " +
-				"--create option to initialize and create it.")
-		} else {
+		if keystoreExists {
 			err = fmt.Errorf("The wallet is in legacy format.  Run with the " +
 				"--create option to import it.")
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
 		}
-		fmt.Fprintln(os.Stderr, err)
-		return nil, nil, err
 	}
 
 	if cfg.RPCConnect == "" {
@@ -0,0 +1,195 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+// Package bip39 implements the mnemonic encoding of wallet seeds described by
+// BIP0039 using the English wordlist.  Entropy is encoded as a sentence of 12
+// to 24 words with a checksum, and the wallet seed is derived from the words
+// and an optional passphrase.
+package bip39
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/conseweb/golangcrypto/pbkdf2"
+)
+
+// Entropy lengths, in bits, of mnemonics with the minimum, recommended and
+// maximum number of words.
+const (
+	MinEntropyBits         = 128
+	RecommendedEntropyBits = 256
+	MaxEntropyBits         = 256
+)
+
+// seedIterations is the number of PBKDF2 iterations used to derive a seed.
+const seedIterations = 2048
+
+var (
+	// ErrEntropyLength describes an error where the entropy encoded by a
+	// mnemonic is not 128 to 256 bits in a multiple of 32 bits.
+	ErrEntropyLength = errors.New("entropy must be 128 to 256 bits in " +
+		"a multiple of 32 bits")
+
+	// ErrMnemonicLength describes an error where a mnemonic is not 12,
+	// 15, 18, 21 or 24 words long.
+	ErrMnemonicLength = errors.New("mnemonic must be 12, 15, 18, 21 " +
+		"or 24 words")
+
+	// ErrChecksum describes an error where the checksum of a mnemonic does
+	// not match the entropy it encodes, such as when a word was mistyped.
+	ErrChecksum = errors.New("invalid mnemonic checksum")
+
+	// ErrPassphraseNotASCII describes an error where a mnemonic passphrase
+	// contains characters outside of the ASCII range.  BIP0039 requires
+	// such passphrases to be Unicode normalized, which is not supported.
+	ErrPassphraseNotASCII = errors.New("mnemonic passphrase must only " +
+		"contain ASCII characters")
+)
+
+// UnknownWordError describes an error where a mnemonic contains a word which
+// is not in the wordlist.
+type UnknownWordError string
+
+// Error satisifies the error interface.
+func (e UnknownWordError) Error() string {
+	return fmt.Sprintf("unknown mnemonic word %q", string(e))
+}
+
+// words is the wordlist indexed by the 11-bit value each word encodes, and
+// wordIndexes maps each word back to its value.
+var (
+	words       = strings.Split(strings.TrimSpace(englishWordlist), "\n")
+	wordIndexes = make(map[string]int, len(words))
+)
+
+func init() {
+	if len(words) != 2048 {
+		panic("bip39: wordlist must contain 2048 words")
+	}
+	for i, word := range words {
+		wordIndexes[word] = i
+	}
+}
+
+// checkEntropyBits returns ErrEntropyLength if bits is not a valid entropy
+// length.
+func checkEntropyBits(bits int) error {
+	if bits < MinEntropyBits || bits > MaxEntropyBits || bits%32 != 0 {
+		return ErrEntropyLength
+	}
+	return nil
+}
+
+// NewEntropy returns bits of cryptographically random entropy, which must be
+// 128 to 256 bits in a multiple of 32 bits.
+func NewEntropy(bits int) ([]byte, error) {
+	if err := checkEntropyBits(bits); err != nil {
+		return nil, err
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+	return entropy, nil
+}
+
+// NewMnemonic encodes entropy as a mnemonic sentence of words separated by
+// single spaces.  Each 32 bits of entropy add three words.
+func NewMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if err := checkEntropyBits(bits); err != nil {
+		return "", err
+	}
+
+	// The entropy is followed by the first bits/32 bits of its hash, and
+	// each 11 bits of the result select a word.
+	hash := sha256.Sum256(entropy)
+	data := append(append([]byte{}, entropy...), hash[0])
+	bit := func(i int) int {
+		return int(data[i/8]>>(7-uint(i%8))) & 1
+	}
+	numWords := (bits + bits/32) / 11
+	sentence := make([]string, numWords)
+	for i := range sentence {
+		var index int
+		for j := 0; j < 11; j++ {
+			index = index<<1 | bit(i*11+j)
+		}
+		sentence[i] = words[index]
+	}
+	return strings.Join(sentence, " "), nil
+}
+
+// normalizeMnemonic returns the words of a mnemonic in lowercase separated by
+// single spaces.
+func normalizeMnemonic(mnemonic string) []string {
+	return strings.Fields(strings.ToLower(mnemonic))
+}
+
+// MnemonicEntropy decodes the entropy encoded by a mnemonic and validates its
+// checksum.  Words may be separated by any whitespace and are not case
+// sensitive.
+func MnemonicEntropy(mnemonic string) ([]byte, error) {
+	sentence := normalizeMnemonic(mnemonic)
+	if len(sentence) < 12 || len(sentence) > 24 || len(sentence)%3 != 0 {
+		return nil, ErrMnemonicLength
+	}
+
+	numBits := len(sentence) * 11
+	checksumBits := numBits / 33
+	data := make([]byte, (numBits+7)/8)
+	for i, word := range sentence {
+		index, ok := wordIndexes[word]
+		if !ok {
+			return nil, UnknownWordError(word)
+		}
+		for j := 0; j < 11; j++ {
+			if index&(1<<uint(10-j)) != 0 {
+				k := i*11 + j
+				data[k/8] |= 1 << (7 - uint(k%8))
+			}
+		}
+	}
+
+	entropy := data[:(numBits-checksumBits)/8]
+	hash := sha256.Sum256(entropy)
+	mask := byte(0xff) << uint(8-checksumBits)
+	if data[len(entropy)]&mask != hash[0]&mask {
+		return nil, ErrChecksum
+	}
+	return entropy, nil
+}
+
+// Seed returns the 64-byte wallet seed derived from a mnemonic and an optional
+// passphrase, after validating the mnemonic.  The passphrase must only contain
+// ASCII characters.
+func Seed(mnemonic, passphrase string) ([]byte, error) {
+	if _, err := MnemonicEntropy(mnemonic); err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(passphrase); i++ {
+		if passphrase[i] >= 0x80 {
+			return nil, ErrPassphraseNotASCII
+		}
+	}
+
+	sentence := strings.Join(normalizeMnemonic(mnemonic), " ")
+	return pbkdf2.Key([]byte(sentence), []byte("mnemonic"+passphrase),
+		seedIterations, 64, sha512.New), nil
+}
@@ -0,0 +1,151 @@
+package bip39
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Test vectors from the BIP0039 specification, which use the passphrase
+// "TREZOR".
+var vectors = []struct {
+	entropy  string
+	mnemonic string
+	seed     string
+}{
+	{
+		entropy:  "00000000000000000000000000000000",
+		mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+		seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+	{
+		entropy:  "ffffffffffffffffffffffffffffffff",
+		mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		seed:     "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+	},
+	{
+		entropy:  "9e885d952ad362caeb4efe34a8e91bd2",
+		mnemonic: "ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic",
+		seed:     "274ddc525802f7c828d8ef7ddbcdc5304e87ac3535913611fbbfa986d0c9e5476c91689f9c8a54fd55bd38606aa6a8595ad213d4c9c9f9aca3fb217069a41028",
+	},
+	{
+		entropy:  "0000000000000000000000000000000000000000000000000000000000000000",
+		mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+		seed:     "bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+	},
+	{
+		entropy:  "68a79eaca2324873eacc50cb9c6eca8cc68ea5d936f98787c60c7ebc74e6ce7c",
+		mnemonic: "hamster diagram private dutch cause delay private meat slide toddler razor book happy fancy gospel tennis maple dilemma loan word shrug inflict delay length",
+		seed:     "64c87cde7e12ecf6704ab95bb1408bef047c22db4cc7491c4271d170a1b213d20b385bc1588d9c7b38f1b39d415665b8a9030c9ec653d75e65f847d8fc1fc440",
+	},
+}
+
+func TestVectors(t *testing.T) {
+	for i, v := range vectors {
+		entropy, _ := hex.DecodeString(v.entropy)
+		mnemonic, err := NewMnemonic(entropy)
+		if err != nil {
+			t.Errorf("Vector %d: %v", i, err)
+			continue
+		}
+		if mnemonic != v.mnemonic {
+			t.Errorf("Vector %d: got mnemonic %q, want %q", i,
+				mnemonic, v.mnemonic)
+		}
+
+		decoded, err := MnemonicEntropy(v.mnemonic)
+		if err != nil {
+			t.Errorf("Vector %d: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(decoded, entropy) {
+			t.Errorf("Vector %d: got entropy %x, want %x", i,
+				decoded, entropy)
+		}
+
+		seed, err := Seed(v.mnemonic, "TREZOR")
+		if err != nil {
+			t.Errorf("Vector %d: %v", i, err)
+			continue
+		}
+		if hex.EncodeToString(seed) != v.seed {
+			t.Errorf("Vector %d: got seed %x, want %s", i, seed,
+				v.seed)
+		}
+	}
+}
+
+func TestMnemonicEntropyErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		mnemonic string
+		err      error
+	}{
+		{
+			name:     "too short",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			err:      ErrMnemonicLength,
+		},
+		{
+			name:     "bad checksum",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+			err:      ErrChecksum,
+		},
+		{
+			name:     "unknown word",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abut",
+			err:      UnknownWordError("abut"),
+		},
+	}
+	for _, test := range tests {
+		_, err := MnemonicEntropy(test.mnemonic)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err,
+				test.err)
+		}
+	}
+
+	// Whitespace and case are normalized.
+	_, err := MnemonicEntropy("  Legal winner THANK year wave sausage\n" +
+		"worth useful legal winner thank yellow ")
+	if err != nil {
+		t.Errorf("Unnormalized mnemonic: %v", err)
+	}
+
+	// Passphrases are not normalized, so only ASCII is accepted.
+	_, err = Seed(vectors[0].mnemonic, "caf\xc3\xa9")
+	if err != ErrPassphraseNotASCII {
+		t.Errorf("Got error %v for non-ASCII passphrase", err)
+	}
+}
+
+func TestNewEntropy(t *testing.T) {
+	for _, bits := range []int{0, 96, 130, 288} {
+		if _, err := NewEntropy(bits); err != ErrEntropyLength {
+			t.Errorf("NewEntropy(%d): got error %v", bits, err)
+		}
+	}
+	for _, bits := range []int{MinEntropyBits, RecommendedEntropyBits} {
+		entropy, err := NewEntropy(bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mnemonic, err := NewMnemonic(entropy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := MnemonicEntropy(mnemonic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decoded, entropy) {
+			t.Errorf("Mnemonic %q decoded to %x, want %x", mnemonic,
+				decoded, entropy)
+		}
+	}
+}
@@ -22,7 +22,6 @@ import (
 	"os"
 	"strings"
 
-	"github.com/conseweb/stcd/btcjson"
 	"github.com/conseweb/stcwallet/internal/rpchelp"
 )
 
@@ -51,7 +50,7 @@ func writeLocaleHelp(locale, goLocale string, descs map[string]string) {
 	writefln("return map[string]string{")
 	for i := range rpchelp.Methods {
 		m := &rpchelp.Methods[i]
-		helpText, err := btcjson.GenerateHelp(m.Method, descs, m.ResultTypes...)
+		helpText, err := rpchelp.GenerateHelp(m.Method, descs, m.ResultTypes...)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -73,7 +72,7 @@ func writeUsage() {
 	usageStrs := make([]string, len(rpchelp.Methods))
 	var err error
 	for i := range rpchelp.Methods {
-		usageStrs[i], err = btcjson.MethodUsageText(rpchelp.Methods[i].Method)
+		usageStrs[i], err = rpchelp.MethodUsageText(rpchelp.Methods[i].Method)
 		if err != nil {
 			log.Fatal(err)
 		}
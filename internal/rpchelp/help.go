@@ -0,0 +1,63 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+//+build !generate
+
+package rpchelp
+
+import (
+	"strings"
+
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/stcwallet/internal/walletjson"
+)
+
+// GenerateHelp generates the help text of a method like btcjson.GenerateHelp.
+// The help of btcjson commands extended by walletjson is generated for the
+// extended command, using the descriptions of the method.
+func GenerateHelp(method string, descs map[string]string, resultTypes ...interface{}) (string, error) {
+	extended, ok := walletjson.ExtendedMethod(method)
+	if !ok {
+		return btcjson.GenerateHelp(method, descs, resultTypes...)
+	}
+
+	extDescs := make(map[string]string, len(descs))
+	for k, v := range descs {
+		extDescs[k] = v
+		if strings.HasPrefix(k, method+"-") {
+			extDescs[extended+k[len(method):]] = v
+		}
+	}
+	help, err := btcjson.GenerateHelp(extended, extDescs, resultTypes...)
+	if err != nil {
+		return "", err
+	}
+	return method + strings.TrimPrefix(help, extended), nil
+}
+
+// MethodUsageText returns the single line usage of a method like
+// btcjson.MethodUsageText, including the parameters of btcjson commands
+// extended by walletjson.
+func MethodUsageText(method string) (string, error) {
+	extended, ok := walletjson.ExtendedMethod(method)
+	if !ok {
+		return btcjson.MethodUsageText(method)
+	}
+
+	usage, err := btcjson.MethodUsageText(extended)
+	if err != nil {
+		return "", err
+	}
+	return method + strings.TrimPrefix(usage, extended), nil
+}
@@ -17,6 +17,11 @@
 package rpchelp
 
 var helpDescsEnUS = map[string]string{
+	// AbandonTransactionCmd help.
+	"abandontransaction--synopsis": "Removes an unmined wallet transaction which is never expected to be mined, along with every unmined transaction spending its outputs, so the outputs spent by them can be spent again.\n" +
+		"The transactions are no longer resent to the chain server, but are recorded again if they are mined.",
+	"abandontransaction-txid": "Hash of the unmined transaction to abandon",
+
 	// AddMultisigAddressCmd help.
 	"addmultisigaddress--synopsis": "Generates and imports a multisig address and redeeming script to the 'imported' account.",
 	"addmultisigaddress-account":   "DEPRECATED -- Unused (all imported addresses belong to the imported account)",
@@ -24,6 +29,10 @@ var helpDescsEnUS = map[string]string{
 	"addmultisigaddress-nrequired": "The number of signatures required to redeem outputs paid to this address",
 	"addmultisigaddress--result0":  "The imported pay-to-script-hash address",
 
+	// BackupWalletCmd help.
+	"backupwallet--synopsis":   "Writes a consistent copy of the wallet database to a file while the wallet continues to run.",
+	"backupwallet-destination": "The file to write the backup to, or an existing directory to write a wallet.db backup file into",
+
 	// CreateMultisigCmd help.
 	"createmultisig--synopsis": "Generate a multisig address and redeem script.",
 	"createmultisig-keys":      "Pubkeys and/or pay-to-pubkey-hash addresses to partially control the multisig address",
@@ -38,6 +47,10 @@ var helpDescsEnUS = map[string]string{
 	"dumpprivkey-address":   "The address to return a private key for",
 	"dumpprivkey--result0":  "The WIF-encoded private key",
 
+	// DumpWalletCmd help.
+	"dumpwallet--synopsis": "Writes all private keys and scripts of the wallet, with the account names and derivation paths needed to restore them, to a new file in a format compatible with the reference implementation. The birthday written for every key is the birthday of the wallet, a lower bound of the height of the first block with transactions for the key.",
+	"dumpwallet-filename":  "The file to write the dump to, which must not already exist",
+
 	// GetAccountCmd help.
 	"getaccount--synopsis": "DEPRECATED -- Lookup the account name that some wallet address belongs to.",
 	"getaccount-address":   "The address to query the account for",
@@ -85,7 +98,7 @@ var helpDescsEnUS = map[string]string{
 	"infowalletresult-testnet":         "Whether or not server is using testnet",
 	"infowalletresult-relayfee":        "The minimum relay fee for non-free transactions in BTC/KB",
 	"infowalletresult-errors":          "Any current errors",
-	"infowalletresult-paytxfee":        "The increment used each time more fee is required for an authored transaction",
+	"infowalletresult-paytxfee":        "The fee per kilobyte set by settxfee, or zero when fee rates are estimated",
 	"infowalletresult-balance":         "The balance of all accounts calculated with one block confirmation",
 	"infowalletresult-walletversion":   "The version of the address manager database",
 	"infowalletresult-unlocked_until":  "Unset",
@@ -130,14 +143,16 @@ var helpDescsEnUS = map[string]string{
 	// GetTransactionResult help.
 	"gettransactionresult-amount":          "The total amount this transaction credits to the wallet, valued in bitcoin",
 	"gettransactionresult-fee":             "The total input value minus the total output value, or 0 if 'txid' is not a sent transaction",
-	"gettransactionresult-confirmations":   "The number of block confirmations of the transaction",
+	"gettransactionresult-confirmations":   "The number of block confirmations of the transaction, or -1 if it is conflicted by a double spend",
 	"gettransactionresult-blockhash":       "The hash of the block this transaction is mined in, or the empty string if unmined",
 	"gettransactionresult-blockindex":      "Unset",
 	"gettransactionresult-blocktime":       "The Unix time of the block header this transaction is mined in, or 0 if unmined",
 	"gettransactionresult-txid":            "The transaction hash",
-	"gettransactionresult-walletconflicts": "Unset",
+	"gettransactionresult-walletconflicts": "Hashes of the unmined transactions replaced by this transaction, such as by bumping its fee, followed by the hash of the transaction double spending this transaction if it is conflicted",
 	"gettransactionresult-time":            "The earliest Unix time this transaction was known to exist",
 	"gettransactionresult-timereceived":    "The earliest Unix time this transaction was known to exist",
+	"gettransactionresult-comment":         "The comment recorded for the transaction, omitted if none",
+	"gettransactionresult-to":              "The comment recorded for the recipient of the transaction, omitted if none",
 	"gettransactionresult-details":         "Additional details for each recorded wallet credit and debit",
 	"gettransactionresult-hex":             "The transaction encoded as a hexadecimal string",
 
@@ -150,12 +165,32 @@ var helpDescsEnUS = map[string]string{
 	"gettransactiondetailsresult-vout":              "The transaction output index",
 	"gettransactiondetailsresult-involveswatchonly": "Unset",
 
+	// GetWalletInfoCmd help.
+	"getwalletinfo--synopsis": "Returns a JSON object describing the state of the wallet.",
+
+	// GetWalletInfoResult help.
+	"getwalletinforesult-walletversion":       "The version of the address manager database",
+	"getwalletinforesult-balance":             "The balance of all accounts calculated with one block confirmation",
+	"getwalletinforesult-unconfirmed_balance": "The value of all unspent outputs of unmined transactions",
+	"getwalletinforesult-txcount":             "The number of transactions recorded by the wallet",
+	"getwalletinforesult-accountcount":        "The number of accounts, including the imported account",
+	"getwalletinforesult-addresscount":        "The number of addresses of all accounts",
+	"getwalletinforesult-locked":              "Whether the wallet is locked",
+	"getwalletinforesult-unlocked_until":      "The Unix time the wallet will be locked again, or 0 if the wallet is locked or was unlocked without a timeout",
+	"getwalletinforesult-syncedtohash":        "The hash of the block the wallet is synced to",
+	"getwalletinforesult-syncedtoheight":      "The height of the block the wallet is synced to",
+	"getwalletinforesult-rescanning":          "Whether a rescan is in progress",
+
 	// ImportPrivKeyCmd help.
 	"importprivkey--synopsis": "Imports a WIF-encoded private key to the 'imported' account.",
 	"importprivkey-privkey":   "The WIF-encoded private key",
 	"importprivkey-label":     "Unused (must be unset or 'imported')",
 	"importprivkey-rescan":    "Rescan the blockchain (since the genesis block) for outputs controlled by the imported key",
 
+	// ImportWalletCmd help.
+	"importwallet--synopsis": "Imports all private keys and scripts from a file written by dumpwallet and rescans the blockchain from the earliest key birthday. Keys derived from the seed of the wallet are restored to their accounts, creating missing accounts with their dumped names. All other keys and scripts are imported to the 'imported' account.",
+	"importwallet-filename":  "The wallet dump file to import",
+
 	// KeypoolRefillCmd help.
 	"keypoolrefill--synopsis": "DEPRECATED -- This request does nothing since no keypool is maintained.",
 	"keypoolrefill-newsize":   "Unused",
@@ -167,6 +202,16 @@ var helpDescsEnUS = map[string]string{
 	"listaccounts--result0--key":   "The account name",
 	"listaccounts--result0--value": "The account balance valued in bitcoin",
 
+	// ListAddressGroupingsCmd help.
+	"listaddressgroupings--synopsis": "Returns a JSON array of address groups, each a JSON array of objects describing wallet addresses which can be linked to each other on the block chain " +
+		"because they were spent together as transaction inputs or received change from a transaction spending another. " +
+		"Unlike the reference implementation, which describes each address with an array of its address, amount and account, each address is described by an object with named fields.",
+
+	// ListAddressGroupingsResult help.
+	"listaddressgroupingsresult-address": "The payment address",
+	"listaddressgroupingsresult-amount":  "The value of all unspent outputs paid to the address",
+	"listaddressgroupingsresult-account": "The account associated with the address",
+
 	// ListLockUnspentCmd help.
 	"listlockunspent--synopsis": "Returns a JSON array of outpoints marked as locked (with lockunspent) for this wallet session.",
 
@@ -219,18 +264,18 @@ var helpDescsEnUS = map[string]string{
 	"listtransactionsresult-category":          `The kind of transaction: "send" for sent transactions, "immature" for immature coinbase outputs, "generate" for mature coinbase outputs, or "recv" for all other received outputs.  Note: A single output may be included multiple times under different categories`,
 	"listtransactionsresult-amount":            "The value of the transaction output valued in bitcoin",
 	"listtransactionsresult-fee":               "The total input value minus the total output value for sent transactions",
-	"listtransactionsresult-confirmations":     "The number of block confirmations of the transaction",
+	"listtransactionsresult-confirmations":     "The number of block confirmations of the transaction, or -1 if it is conflicted by a double spend",
 	"listtransactionsresult-generated":         "Whether the transaction output is a coinbase output",
 	"listtransactionsresult-blockhash":         "The hash of the block this transaction is mined in, or the empty string if unmined",
 	"listtransactionsresult-blockindex":        "Unset",
 	"listtransactionsresult-blocktime":         "The Unix time of the block header this transaction is mined in, or 0 if unmined",
 	"listtransactionsresult-txid":              "The hash of the transaction",
 	"listtransactionsresult-vout":              "The transaction output index",
-	"listtransactionsresult-walletconflicts":   "Unset",
+	"listtransactionsresult-walletconflicts":   "Hashes of the unmined transactions replaced by this transaction, such as by bumping its fee, followed by the hash of the transaction double spending this transaction if it is conflicted",
 	"listtransactionsresult-time":              "The earliest Unix time this transaction was known to exist",
 	"listtransactionsresult-timereceived":      "The earliest Unix time this transaction was known to exist",
 	"listtransactionsresult-involveswatchonly": "Unset",
-	"listtransactionsresult-comment":           "Unset",
+	"listtransactionsresult-comment":           "The comment recorded for the transaction, omitted if none",
 	"listtransactionsresult-otheraccount":      "Unset",
 
 	// ListTransactionsCmd help.
@@ -273,8 +318,9 @@ var helpDescsEnUS = map[string]string{
 	"sendfrom-toaddress":   "Address to pay",
 	"sendfrom-amount":      "Amount to send to the payment address valued in bitcoin",
 	"sendfrom-minconf":     "Minimum number of block confirmations required before a transaction output is eligible to be spent",
-	"sendfrom-comment":     "Unused",
-	"sendfrom-commentto":   "Unused",
+	"sendfrom-comment":     "A comment to record with the transaction",
+	"sendfrom-commentto":   "A comment describing the recipient to record with the transaction",
+	"sendfrom-feerate":     "Fee rate in satoshis per byte, overriding the fee rate of the wallet",
 	"sendfrom--result0":    "The transaction hash of the sent transaction",
 
 	// SendManyCmd help.
@@ -286,7 +332,8 @@ var helpDescsEnUS = map[string]string{
 	"sendmany-amounts--key":   "Address to pay",
 	"sendmany-amounts--value": "Amount to send to the payment address valued in bitcoin",
 	"sendmany-minconf":        "Minimum number of block confirmations required before a transaction output is eligible to be spent",
-	"sendmany-comment":        "Unused",
+	"sendmany-comment":        "A comment to record with the transaction",
+	"sendmany-feerate":        "Fee rate in satoshis per byte, overriding the fee rate of the wallet",
 	"sendmany--result0":       "The transaction hash of the sent transaction",
 
 	// SendToAddressCmd help.
@@ -295,14 +342,16 @@ var helpDescsEnUS = map[string]string{
 		"A change output is automatically included to send extra output value back to the original account.",
 	"sendtoaddress-address":   "Address to pay",
 	"sendtoaddress-amount":    "Amount to send to the payment address valued in bitcoin",
-	"sendtoaddress-comment":   "Unused",
-	"sendtoaddress-commentto": "Unused",
+	"sendtoaddress-comment":   "A comment to record with the transaction",
+	"sendtoaddress-commentto": "A comment describing the recipient to record with the transaction",
+	"sendtoaddress-feerate":   "Fee rate in satoshis per byte, overriding the fee rate of the wallet",
 	"sendtoaddress--result0":  "The transaction hash of the sent transaction",
 
 	// SetTxFeeCmd help.
-	"settxfee--synopsis": "Modify the increment used each time more fee is required for an authored transaction.",
-	"settxfee-amount":    "The new fee increment valued in bitcoin",
-	"settxfee--result0":  "The boolean 'true'",
+	"settxfee--synopsis": "Sets the fee per kilobyte of authored transactions, overriding fee rate estimates.\n" +
+		"A zero amount returns to estimating the fee rate of each transaction.",
+	"settxfee-amount":   "The new fee per kilobyte valued in bitcoin",
+	"settxfee--result0": "The boolean 'true'",
 
 	// SignMessageCmd help.
 	"signmessage--synopsis": "Signs a message using the private key of a payment address.",
@@ -336,22 +385,26 @@ var helpDescsEnUS = map[string]string{
 		"The following fields are valid only when the address is controlled by this wallet (ismine=true): isscript, pubkey, iscompressed, account, addresses, hex, script, and sigsrequired.\n" +
 		"The following fields are only valid when address has an associated public key: pubkey, iscompressed.\n" +
 		"The following fields are only valid when address is a pay-to-script-hash address: addresses, hex, and script.\n" +
-		"If the address is a multisig address controlled by this wallet, the multisig fields will be left unset if the wallet is locked since the redeem script cannot be decrypted.",
+		"If the address is a multisig address controlled by this wallet, the multisig fields will be left unset if the wallet is locked since the redeem script cannot be decrypted.\n" +
+		"The following fields are only valid when the address is a chained address of this wallet: iswatchonly, hdkeypath, gaplimit, and exceedsgaplimit.",
 	"validateaddress-address": "Address to validate",
 
 	// ValidateAddressWalletResult help.
-	"validateaddresswalletresult-isvalid":      "Whether or not the address is valid",
-	"validateaddresswalletresult-address":      "The payment address (only when isvalid is true)",
-	"validateaddresswalletresult-ismine":       "Whether this address is controlled by the wallet (only when isvalid is true)",
-	"validateaddresswalletresult-iswatchonly":  "Unset",
-	"validateaddresswalletresult-isscript":     "Whether the payment address is a pay-to-script-hash address (only when isvalid is true)",
-	"validateaddresswalletresult-pubkey":       "The associated public key of the payment address, if any (only when isvalid is true)",
-	"validateaddresswalletresult-iscompressed": "Whether the address was created by hashing a compressed public key, if any (only when isvalid is true)",
-	"validateaddresswalletresult-account":      "The account this payment address belongs to (only when isvalid is true)",
-	"validateaddresswalletresult-addresses":    "All associated payment addresses of the script if address is a multisig address (only when isvalid is true)",
-	"validateaddresswalletresult-hex":          "The redeem script ",
-	"validateaddresswalletresult-script":       "The class of redeem script for a multisig address",
-	"validateaddresswalletresult-sigsrequired": "The number of required signatures to redeem outputs to the multisig address",
+	"validateaddresswalletresult-isvalid":         "Whether or not the address is valid",
+	"validateaddresswalletresult-address":         "The payment address (only when isvalid is true)",
+	"validateaddresswalletresult-ismine":          "Whether this address is controlled by the wallet (only when isvalid is true)",
+	"validateaddresswalletresult-iswatchonly":     "Whether the address belongs to a watching-only account imported from an extended public key",
+	"validateaddresswalletresult-isscript":        "Whether the payment address is a pay-to-script-hash address (only when isvalid is true)",
+	"validateaddresswalletresult-pubkey":          "The associated public key of the payment address, if any (only when isvalid is true)",
+	"validateaddresswalletresult-iscompressed":    "Whether the address was created by hashing a compressed public key, if any (only when isvalid is true)",
+	"validateaddresswalletresult-account":         "The account this payment address belongs to (only when isvalid is true)",
+	"validateaddresswalletresult-addresses":       "All associated payment addresses of the script if address is a multisig address (only when isvalid is true)",
+	"validateaddresswalletresult-hex":             "The redeem script ",
+	"validateaddresswalletresult-script":          "The class of redeem script for a multisig address",
+	"validateaddresswalletresult-sigsrequired":    "The number of required signatures to redeem outputs to the multisig address",
+	"validateaddresswalletresult-hdkeypath":       "The BIP0044 derivation path of the address, unset for watching-only and multisig accounts",
+	"validateaddresswalletresult-gaplimit":        "The maximum number of consecutive unused receiving addresses, unset when address generation is not limited",
+	"validateaddresswalletresult-exceedsgaplimit": "Whether the address follows at least gaplimit consecutive unused addresses, in which case it is not found when restoring the wallet from its seed",
 
 	// VerifyMessageCmd help.
 	"verifymessage--synopsis": "Verify a message was signed with the associated private key of some address.",
@@ -373,17 +426,98 @@ var helpDescsEnUS = map[string]string{
 	"walletpassphrasechange-oldpassphrase": "The old wallet passphrase",
 	"walletpassphrasechange-newpassphrase": "The new wallet passphrase",
 
+	// BumpFeeCmd help.
+	"bumpfee--synopsis": "Replaces an unmined wallet transaction with one paying a higher fee, lowering the change output or adding inputs of the account when needed.\n" +
+		"The replaced transaction is kept as a conflicted transaction and listed in the walletconflicts of the replacement.\n" +
+		"Transactions whose outputs are spent by other transactions can not be replaced.\n" +
+		"Only transactions signaling replaceability (BIP0125), as those created with the signalrbf option do, can be replaced.",
+	"bumpfee-txid":     "Hash of the unmined transaction to replace",
+	"bumpfee-feerate":  "Fee rate in satoshis per byte, overriding the fee rate of the wallet (must exceed the fee rate of the replaced transaction)",
+	"bumpfee--result0": "The transaction hash of the replacement transaction",
+
+	// CombinePSBTCmd help.
+	"combinepsbt--synopsis": "Merges several partially signed versions of the same transaction, such as those signed by each cosigner of a multisig account, into one holding every signature, script and key path of any of them.",
+	"combinepsbt-psbts":     "The base64 encoded partially signed transactions to combine",
+	"combinepsbt--result0":  "The combined partially signed transaction encoded as a base64 string",
+
+	// ConsolidateUnspentCmd help.
+	"consolidateunspent--synopsis": "Merges the spendable outputs of an account worth less than a threshold into outputs paying new internal addresses of the account.\n" +
+		"Only outputs with at least one confirmation which are worth more than the fee of spending them are merged, and locked outputs are skipped.\n" +
+		"As many transactions as are needed to keep each within the maximum size are created, each spending at least two outputs.",
+	"consolidateunspent-account":   "Account to merge the outputs of",
+	"consolidateunspent-threshold": "Outputs worth less than this amount valued in bitcoin are merged",
+	"consolidateunspent-maxtxsize": "Maximum size in bytes of each created transaction",
+	"consolidateunspent-feerate":   "Fee rate in satoshis per byte, overriding the fee rate of the wallet",
+	"consolidateunspent--result0":  "The transaction hashes of the created transactions",
+
 	// CreateNewAccountCmd help.
 	"createnewaccount--synopsis": "Creates a new account.\n" +
 		"The wallet must be unlocked for this request to succeed.",
 	"createnewaccount-account": "Name of the new account",
 
+	// CreateUnsignedTransactionCmd help.
+	"createunsignedtransaction--synopsis": "Authors a transaction spending unspent outputs of a watching-only or multisig account to many payment addresses without sending it.\n" +
+		"A change output is automatically included to send extra output value back to a new change address of the account.\n" +
+		"Transactions of watching-only accounts are left unsigned and transactions of multisig accounts are only signed with the key of this wallet.\n" +
+		"The transaction must be signed by the wallets holding the remaining private keys (for example with signrawtransaction) and sent with sendrawtransaction.",
+	"createunsignedtransaction-fromaccount":    "Watching-only or multisig account to spend the outputs of",
+	"createunsignedtransaction-amounts":        "Pairs of payment addresses and the output amount to pay each",
+	"createunsignedtransaction-amounts--desc":  "JSON object using payment addresses as keys and output amounts valued in bitcoin to send to each address",
+	"createunsignedtransaction-amounts--key":   "Address to pay",
+	"createunsignedtransaction-amounts--value": "Amount to send to the payment address valued in bitcoin",
+	"createunsignedtransaction-minconf":        "Minimum number of block confirmations required before a transaction output is eligible to be spent",
+	"createunsignedtransaction-feerate":        "Fee rate in satoshis per byte, overriding the fee rate of the wallet",
+	"createunsignedtransaction--result0":       "The unsigned or partially signed transaction encoded as a hexadecimal string",
+
+	// CreateWalletCmd help.
+	"createwallet--synopsis": "Creates a wallet and loads it.  The wallet is served at the /wallet/<name> path, or is the default wallet when the name is empty.\n" +
+		"The wallet is created from the passed seed or, when no seed is passed, from a generated BIP0039 mnemonic which is returned.\n" +
+		"The generated mnemonic is only returned once and is required to restore the wallet.",
+	"createwallet-walletname":         "The name of the wallet, or the empty string for the default wallet",
+	"createwallet-privpassphrase":     "The private passphrase protecting the private keys of the wallet",
+	"createwallet-pubpassphrase":      "The public passphrase protecting the public data of the wallet, which must be the walletpass option for the default wallet (default=the walletpass option)",
+	"createwallet-seed":               "An existing wallet generation seed, entered either as a hexadecimal value or as a BIP0039 mnemonic",
+	"createwallet-mnemonicpassphrase": "The passphrase protecting the passed or generated mnemonic",
+	"createwallet-scryptn":            "The scrypt CPU/memory cost parameter used to derive the passphrase keys, a power of 2 (default=262144)",
+	"createwallet-scryptr":            "The scrypt block size parameter used to derive the passphrase keys (default=8)",
+	"createwallet-scryptp":            "The scrypt parallelization parameter used to derive the passphrase keys (default=1)",
+
+	// CreateWalletResult help.
+	"createwalletresult-name":     "The name of the created wallet",
+	"createwalletresult-mnemonic": "The generated BIP0039 mnemonic of the wallet, omitted when a seed was passed",
+
+	// DiscoverAccountsCmd help.
+	"discoveraccounts--synopsis": "Recovers the accounts and addresses of a wallet restored from an existing seed.\n" +
+		"Addresses of each account are derived and the blockchain is rescanned for them (since the wallet's start block) until 'gaplimit' unused addresses follow the last used address of both the receiving and change branches.\n" +
+		"Accounts are created until one without any used address is found.\n" +
+		"Discovery runs in the background and its progress is reported by getdiscoverystatus.\n" +
+		"The wallet must be unlocked for this request to succeed, and is kept unlocked until discovery finished.",
+	"discoveraccounts-gaplimit": "Number of consecutive unused addresses to look for (default set by the gaplimit option)",
+
+	// ExportAccountKeyCmd help.
+	"exportaccountkey--synopsis": "Returns the BIP0044 account extended public key of an account, from which the addresses of the account can be derived without access to the wallet.\n" +
+		"The account extended private key is returned instead when 'private' is true, which requires the wallet to be unlocked.",
+	"exportaccountkey-account":  "The account to export the extended key of",
+	"exportaccountkey-private":  "Return the extended private key instead of the extended public key",
+	"exportaccountkey--result0": "The account extended key encoded as a base58 string (xpub/tpub or xprv/tprv)",
+
 	// ExportWatchingWalletCmd help.
 	"exportwatchingwallet--synopsis": "Creates and returns a duplicate of the wallet database without any private keys to be used as a watching-only wallet.",
 	"exportwatchingwallet-account":   "Unused (must be unset or \"*\")",
 	"exportwatchingwallet-download":  "Unused",
 	"exportwatchingwallet--result0":  "The watching-only database encoded as a base64 string",
 
+	// FinalizePSBTCmd help.
+	"finalizepsbt--synopsis": "Builds the signature script of every input of a partially signed transaction with enough signatures and checks it against the spent output.\n" +
+		"Once every input is finalized, the signed transaction is returned as a hexadecimal string to be sent with sendrawtransaction.",
+	"finalizepsbt-psbt":    "The base64 encoded partially signed transaction",
+	"finalizepsbt-extract": "Return the signed transaction instead of the partially signed transaction once every input is finalized",
+
+	// FinalizePSBTResult help.
+	"finalizepsbtresult-psbt":     "The partially signed transaction encoded as a base64 string (unset when the transaction is extracted)",
+	"finalizepsbtresult-hex":      "The signed transaction encoded as a hexadecimal string (only set when the transaction is extracted)",
+	"finalizepsbtresult-complete": "Whether every input is finalized",
+
 	// GetBestBlockCmd help.
 	"getbestblock--synopsis": "Returns the hash and height of the newest block in the best chain that wallet has finished syncing with.",
 
@@ -391,11 +525,28 @@ var helpDescsEnUS = map[string]string{
 	"getbestblockresult-hash":   "The hash of the block",
 	"getbestblockresult-height": "The blockchain height of the block",
 
+	// GetDiscoveryStatusCmd help.
+	"getdiscoverystatus--synopsis": "Returns the progress of the running account discovery started by discoveraccounts, or the result of the last discovery.",
+
+	// GetDiscoveryStatusResult help.
+	"getdiscoverystatusresult-running":   "Whether account discovery is in progress",
+	"getdiscoverystatusresult-account":   "The account being discovered, or the next account to use once discovery finished",
+	"getdiscoverystatusresult-rescanned": "The number of addresses rescanned so far",
+	"getdiscoverystatusresult-error":     "The error which stopped the last discovery (only set when discovery failed)",
+
 	// GetUnconfirmedBalanceCmd help.
 	"getunconfirmedbalance--synopsis": "Calculates the unspent output value of all unmined transaction outputs for an account.",
 	"getunconfirmedbalance-account":   "The account to query the unconfirmed balance for (default=\"default\")",
 	"getunconfirmedbalance--result0":  "Total amount of all unmined unspent outputs of the account valued in bitcoin.",
 
+	// ImportXpubCmd help.
+	"importxpub--synopsis": "Creates a watching-only account from the extended public key of an account whose private keys are kept elsewhere, such as in cold storage.\n" +
+		"Addresses of the account are derived from the key and its outputs are tracked, but the wallet can not sign for them.\n" +
+		"Transactions spending them are created with createunsignedtransaction.",
+	"importxpub-account": "Name of the new account",
+	"importxpub-xpub":    "The BIP0032 account extended public key (xpub or tpub)",
+	"importxpub-rescan":  "Derive addresses up to the gap limit past the last used address and rescan the blockchain (since the genesis block) for them",
+
 	// ListAddressTransactionsCmd help.
 	"listaddresstransactions--synopsis": "Returns a JSON array of objects containing verbose details for wallet transactions pertaining some addresses.",
 	"listaddresstransactions-addresses": "Addresses to filter transaction results by",
@@ -405,12 +556,104 @@ var helpDescsEnUS = map[string]string{
 	"listalltransactions--synopsis": "Returns a JSON array of objects in the same format as 'listtransactions' without limiting the number of returned objects.",
 	"listalltransactions-account":   "Unused (must be unset or \"*\")",
 
+	// ListWalletsCmd help.
+	"listwallets--synopsis": "Returns the names of the loaded wallets.  The default wallet is named by the empty string and every other wallet serves requests posted to the /wallet/<name> path.",
+	"listwallets--result0":  "The names of the loaded wallets",
+
+	// LoadWalletCmd help.
+	"loadwallet--synopsis":     "Loads an existing wallet which is not loaded yet.  The wallet is served at the /wallet/<name> path, or is the default wallet when the name is empty.",
+	"loadwallet-walletname":    "The name of the wallet, or the empty string for the default wallet",
+	"loadwallet-pubpassphrase": "The public passphrase of the wallet (default=the walletpass option)",
+
+	// MakeMultisigAccountCmd help.
+	"makemultisigaccount--synopsis": "Turns an account without any addresses into an HD multisig account.\n" +
+		"Each address of the account is the P2SH address of a multisig script of the keys derived at the same branch and index from the account extended public keys of this account and each cosigner, sorted as described by BIP0067.\n" +
+		"Cosigners exchange their account extended public keys with exportaccountkey.\n" +
+		"Transactions spending the outputs of the account are created with createunsignedtransaction and signed by the cosigners with signrawtransaction.",
+	"makemultisigaccount-account":   "The account to turn into a multisig account",
+	"makemultisigaccount-nrequired": "The number of signatures required to spend outputs of the account",
+	"makemultisigaccount-keys":      "The BIP0032 account extended public keys (xpub or tpub) of each cosigner, excluding this wallet",
+
+	// PayForParentCmd help.
+	"payforparent--synopsis": "Creates a child transaction spending the wallet outputs of an unmined transaction to a new internal address, paying a fee high enough that both transactions together pay the fee rate (child-pays-for-parent).\n" +
+		"The passed inputs, which must be unlocked P2PKH outputs of the same account, are spent by the child as well.\n" +
+		"Confirmed outputs of the same account are spent as well if these outputs do not cover the fee.\n" +
+		"The fee of the unmined transaction is assumed to be zero if its previous outputs can not be looked up.",
+	"payforparent-txid":     "Hash of the unmined transaction paying the wallet",
+	"payforparent-feerate":  "Fee rate in satoshis per byte of both transactions, overriding the fee rate of the wallet",
+	"payforparent-inputs":   "Further unspent outputs of the account to spend",
+	"payforparent--result0": "The transaction hash of the child transaction",
+
 	// RenameAccountCmd help.
 	"renameaccount--synopsis":  "Renames an account.",
 	"renameaccount-oldaccount": "The old account name to rename",
 	"renameaccount-newaccount": "The new name for the account",
 
+	// SendFromOutpointsCmd help.
+	"sendfromoutpoints--synopsis": "Authors, signs, and sends a transaction that spends exactly the passed unspent outputs and outputs to many payment addresses.\n" +
+		"Each spent output must be an unlocked P2PKH output of the account, and no other outputs are spent.\n" +
+		"A change output is automatically included to send extra output value back to the original account.",
+	"sendfromoutpoints-fromaccount":    "Account controlling the spent outputs",
+	"sendfromoutpoints-inputs":         "Unspent outputs to spend",
+	"sendfromoutpoints-amounts":        "Pairs of payment addresses and the output amount to pay each",
+	"sendfromoutpoints-amounts--desc":  "JSON object using payment addresses as keys and output amounts valued in bitcoin to send to each address",
+	"sendfromoutpoints-amounts--key":   "Address to pay",
+	"sendfromoutpoints-amounts--value": "Amount to send to the payment address valued in bitcoin",
+	"sendfromoutpoints-feerate":        "Fee rate in satoshis per byte, overriding the fee rate of the wallet",
+	"sendfromoutpoints-comment":        "A comment to record with the transaction",
+	"sendfromoutpoints--result0":       "The transaction hash of the sent transaction",
+
+	// SendPSBTCmd help.
+	"sendpsbt--synopsis": "Finalizes a partially signed transaction, such as one created by a watching-only wallet with walletcreatefundedpsbt and signed by an offline wallet with walletprocesspsbt, and sends the signed transaction.\n" +
+		"The transaction is recorded by the wallet once the chain server accepted it, so its spent outputs and change are tracked before it is mined.",
+	"sendpsbt-psbt":     "The base64 encoded partially signed transaction",
+	"sendpsbt--result0": "The transaction hash of the sent transaction",
+
+	// SetTxCommentCmd help.
+	"settxcomment--synopsis": "Replaces the comment recorded for a wallet transaction.  The comment recorded for the recipient of the transaction is only replaced if 'commentto' is set.  Empty comments are removed.",
+	"settxcomment-txid":      "Hash of the transaction",
+	"settxcomment-comment":   "The new comment for the transaction",
+	"settxcomment-commentto": "The new comment describing the recipient of the transaction",
+
+	// SweepPrivKeyCmd help.
+	"sweepprivkey--synopsis": "Moves every spendable output controlled by a WIF-encoded private key into the wallet without importing the key.\n" +
+		"The blockchain is rescanned (since the genesis block) for outputs paying to the key's address, which are spent to a new address of the account less the transaction fee.",
+	"sweepprivkey-privkey":  "The WIF-encoded private key to sweep",
+	"sweepprivkey-account":  "The account receiving the swept outputs (default=\"default\")",
+	"sweepprivkey-feerate":  "Fee rate in satoshis per byte, overriding the fee rate of the wallet",
+	"sweepprivkey--result0": "The transaction hash of the sweeping transaction",
+
+	// UnloadWalletCmd help.
+	"unloadwallet--synopsis":  "Stops a named wallet and closes its database.  Requests posted to the path of the wallet error until it is loaded again.  The default wallet can not be unloaded.",
+	"unloadwallet-walletname": "The name of the wallet to unload",
+
+	// WalletCreateFundedPSBTCmd help.
+	"walletcreatefundedpsbt--synopsis": "Authors a transaction spending unspent outputs of an account to many payment addresses and returns it as a partially signed transaction without signing it.\n" +
+		"A change output is automatically included to send extra output value back to a new change address of the account.\n" +
+		"The partially signed transaction carries the output spent by each input and the redeem scripts and HD key paths of the wallet's addresses.\n" +
+		"It is signed with walletprocesspsbt by each wallet holding the keys of the account, combined with combinepsbt and finalized with finalizepsbt.",
+	"walletcreatefundedpsbt-fromaccount":    "Account to spend the outputs of",
+	"walletcreatefundedpsbt-amounts":        "Pairs of payment addresses and the output amount to pay each",
+	"walletcreatefundedpsbt-amounts--desc":  "JSON object using payment addresses as keys and output amounts valued in bitcoin to send to each address",
+	"walletcreatefundedpsbt-amounts--key":   "Address to pay",
+	"walletcreatefundedpsbt-amounts--value": "Amount to send to the payment address valued in bitcoin",
+	"walletcreatefundedpsbt-minconf":        "Minimum number of block confirmations required before a transaction output is eligible to be spent",
+	"walletcreatefundedpsbt-feerate":        "Fee rate in satoshis per byte, overriding the fee rate of the wallet",
+	"walletcreatefundedpsbt--result0":       "The partially signed transaction encoded as a base64 string",
+
 	// WalletIsLockedCmd help.
 	"walletislocked--synopsis": "Returns whether or not the wallet is locked.",
 	"walletislocked--result0":  "Whether the wallet is locked",
+
+	// WalletProcessPSBTCmd help.
+	"walletprocesspsbt--synopsis": "Adds what the wallet knows about a partially signed transaction: the outputs spent by its inputs and the redeem scripts and HD key paths of the wallet's addresses.\n" +
+		"Unless 'sign' is false, the inputs are also signed with every key of the wallet which can redeem them, which requires the wallet to be unlocked.\n" +
+		"Keys of addresses the wallet has not derived, such as those handed out by a watching-only copy of the wallet, are found by the HD key paths of the inputs.\n" +
+		"Inputs with enough signatures are finalized.",
+	"walletprocesspsbt-psbt": "The base64 encoded partially signed transaction",
+	"walletprocesspsbt-sign": "Sign the inputs with the keys of the wallet",
+
+	// WalletProcessPSBTResult help.
+	"walletprocesspsbtresult-psbt":     "The partially signed transaction encoded as a base64 string",
+	"walletprocesspsbtresult-complete": "Whether every input is finalized",
 }
@@ -16,7 +16,10 @@
 
 package rpchelp
 
-import "github.com/conseweb/stcd/btcjson"
+import (
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/stcwallet/internal/walletjson"
+)
 
 // Common return types.
 var (
@@ -33,9 +36,12 @@ var Methods = []struct {
 	Method      string
 	ResultTypes []interface{}
 }{
+	{"abandontransaction", nil},
 	{"addmultisigaddress", returnsString},
+	{"backupwallet", nil},
 	{"createmultisig", []interface{}{(*btcjson.CreateMultiSigResult)(nil)}},
 	{"dumpprivkey", returnsString},
+	{"dumpwallet", nil},
 	{"getaccount", returnsString},
 	{"getaccountaddress", returnsString},
 	{"getaddressesbyaccount", returnsStringArray},
@@ -47,11 +53,14 @@ var Methods = []struct {
 	{"getrawchangeaddress", returnsString},
 	{"getreceivedbyaccount", returnsNumber},
 	{"getreceivedbyaddress", returnsNumber},
-	{"gettransaction", []interface{}{(*btcjson.GetTransactionResult)(nil)}},
+	{"gettransaction", []interface{}{(*walletjson.GetTransactionResult)(nil)}},
+	{"getwalletinfo", []interface{}{(*walletjson.GetWalletInfoResult)(nil)}},
 	{"help", append(returnsString, returnsString[0])},
 	{"importprivkey", nil},
+	{"importwallet", nil},
 	{"keypoolrefill", nil},
 	{"listaccounts", []interface{}{(*map[string]float64)(nil)}},
+	{"listaddressgroupings", []interface{}{(*[][]walletjson.ListAddressGroupingsResult)(nil)}},
 	{"listlockunspent", []interface{}{(*[]btcjson.TransactionInput)(nil)}},
 	{"listreceivedbyaccount", []interface{}{(*[]btcjson.ListReceivedByAccountResult)(nil)}},
 	{"listreceivedbyaddress", []interface{}{(*[]btcjson.ListReceivedByAddressResult)(nil)}},
@@ -65,19 +74,40 @@ var Methods = []struct {
 	{"settxfee", returnsBool},
 	{"signmessage", returnsString},
 	{"signrawtransaction", []interface{}{(*btcjson.SignRawTransactionResult)(nil)}},
-	{"validateaddress", []interface{}{(*btcjson.ValidateAddressWalletResult)(nil)}},
+	{"validateaddress", []interface{}{(*walletjson.ValidateAddressWalletResult)(nil)}},
 	{"verifymessage", returnsBool},
 	{"walletlock", nil},
 	{"walletpassphrase", nil},
 	{"walletpassphrasechange", nil},
+	{"bumpfee", returnsString},
+	{"combinepsbt", returnsString},
+	{"consolidateunspent", returnsStringArray},
 	{"createnewaccount", nil},
+	{"createunsignedtransaction", returnsString},
+	{"createwallet", []interface{}{(*walletjson.CreateWalletResult)(nil)}},
+	{"discoveraccounts", nil},
+	{"exportaccountkey", returnsString},
 	{"exportwatchingwallet", returnsString},
+	{"finalizepsbt", []interface{}{(*walletjson.FinalizePSBTResult)(nil)}},
 	{"getbestblock", []interface{}{(*btcjson.GetBestBlockResult)(nil)}},
+	{"getdiscoverystatus", []interface{}{(*walletjson.GetDiscoveryStatusResult)(nil)}},
 	{"getunconfirmedbalance", returnsNumber},
+	{"importxpub", nil},
 	{"listaddresstransactions", returnsLTRArray},
 	{"listalltransactions", returnsLTRArray},
+	{"listwallets", returnsStringArray},
+	{"loadwallet", nil},
+	{"makemultisigaccount", nil},
+	{"payforparent", returnsString},
 	{"renameaccount", nil},
+	{"sendfromoutpoints", returnsString},
+	{"sendpsbt", returnsString},
+	{"settxcomment", nil},
+	{"sweepprivkey", returnsString},
+	{"unloadwallet", nil},
+	{"walletcreatefundedpsbt", returnsString},
 	{"walletislocked", returnsBool},
+	{"walletprocesspsbt", []interface{}{(*walletjson.WalletProcessPSBTResult)(nil)}},
 }
 
 var HelpDescs = []struct {
@@ -0,0 +1,610 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+// Package walletjson defines the JSON-RPC commands handled by the wallet RPC
+// server, and the websocket notifications sent by it, which are not (yet)
+// provided by the btcjson package.  All commands are registered with btcjson
+// when this package is imported, so requests for them can be unmarshaled with
+// btcjson.UnmarshalCmd and help text can be generated for them the same way as
+// for the btcjson commands.
+package walletjson
+
+import "github.com/conseweb/stcd/btcjson"
+
+// AbandonTransactionCmd defines the abandontransaction JSON-RPC command.
+type AbandonTransactionCmd struct {
+	Txid string
+}
+
+// NewAbandonTransactionCmd returns a new instance which can be used to issue
+// an abandontransaction JSON-RPC command.
+func NewAbandonTransactionCmd(txid string) *AbandonTransactionCmd {
+	return &AbandonTransactionCmd{
+		Txid: txid,
+	}
+}
+
+// BackupWalletCmd defines the backupwallet JSON-RPC command.
+type BackupWalletCmd struct {
+	Destination string
+}
+
+// NewBackupWalletCmd returns a new instance which can be used to issue a
+// backupwallet JSON-RPC command.
+func NewBackupWalletCmd(destination string) *BackupWalletCmd {
+	return &BackupWalletCmd{
+		Destination: destination,
+	}
+}
+
+// BumpFeeCmd defines the bumpfee JSON-RPC command.
+type BumpFeeCmd struct {
+	Txid    string
+	FeeRate *int64
+}
+
+// NewBumpFeeCmd returns a new instance which can be used to issue a bumpfee
+// JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewBumpFeeCmd(txid string, feeRate *int64) *BumpFeeCmd {
+	return &BumpFeeCmd{
+		Txid:    txid,
+		FeeRate: feeRate,
+	}
+}
+
+// CombinePSBTCmd defines the combinepsbt JSON-RPC command.
+type CombinePSBTCmd struct {
+	PSBTs []string
+}
+
+// NewCombinePSBTCmd returns a new instance which can be used to issue a
+// combinepsbt JSON-RPC command.
+func NewCombinePSBTCmd(psbts []string) *CombinePSBTCmd {
+	return &CombinePSBTCmd{
+		PSBTs: psbts,
+	}
+}
+
+// ConsolidateUnspentCmd defines the consolidateunspent JSON-RPC command.
+type ConsolidateUnspentCmd struct {
+	Account   string
+	Threshold float64 // In BTC
+	MaxTxSize *int    `jsonrpcdefault:"100000"`
+	FeeRate   *int64
+}
+
+// NewConsolidateUnspentCmd returns a new instance which can be used to issue a
+// consolidateunspent JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewConsolidateUnspentCmd(account string, threshold float64, maxTxSize *int,
+	feeRate *int64) *ConsolidateUnspentCmd {
+
+	return &ConsolidateUnspentCmd{
+		Account:   account,
+		Threshold: threshold,
+		MaxTxSize: maxTxSize,
+		FeeRate:   feeRate,
+	}
+}
+
+// CreateUnsignedTransactionCmd defines the createunsignedtransaction JSON-RPC
+// command.
+type CreateUnsignedTransactionCmd struct {
+	FromAccount string
+	Amounts     map[string]float64 `jsonrpcusage:"{\"address\":amount,...}"` // In BTC
+	MinConf     *int               `jsonrpcdefault:"1"`
+	FeeRate     *int64
+}
+
+// NewCreateUnsignedTransactionCmd returns a new instance which can be used to
+// issue a createunsignedtransaction JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewCreateUnsignedTransactionCmd(fromAccount string, amounts map[string]float64,
+	minConf *int, feeRate *int64) *CreateUnsignedTransactionCmd {
+
+	return &CreateUnsignedTransactionCmd{
+		FromAccount: fromAccount,
+		Amounts:     amounts,
+		MinConf:     minConf,
+		FeeRate:     feeRate,
+	}
+}
+
+// CreateWalletCmd defines the createwallet JSON-RPC command.
+type CreateWalletCmd struct {
+	WalletName         string
+	PrivPassphrase     string
+	PubPassphrase      *string
+	Seed               *string
+	MnemonicPassphrase *string
+	ScryptN            *int
+	ScryptR            *int
+	ScryptP            *int
+}
+
+// NewCreateWalletCmd returns a new instance which can be used to issue a
+// createwallet JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewCreateWalletCmd(walletName, privPassphrase string, pubPassphrase,
+	seed, mnemonicPassphrase *string, scryptN, scryptR, scryptP *int) *CreateWalletCmd {
+
+	return &CreateWalletCmd{
+		WalletName:         walletName,
+		PrivPassphrase:     privPassphrase,
+		PubPassphrase:      pubPassphrase,
+		Seed:               seed,
+		MnemonicPassphrase: mnemonicPassphrase,
+		ScryptN:            scryptN,
+		ScryptR:            scryptR,
+		ScryptP:            scryptP,
+	}
+}
+
+// DiscoverAccountsCmd defines the discoveraccounts JSON-RPC command.
+type DiscoverAccountsCmd struct {
+	GapLimit *uint32
+}
+
+// NewDiscoverAccountsCmd returns a new instance which can be used to issue a
+// discoveraccounts JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewDiscoverAccountsCmd(gapLimit *uint32) *DiscoverAccountsCmd {
+	return &DiscoverAccountsCmd{
+		GapLimit: gapLimit,
+	}
+}
+
+// ExportAccountKeyCmd defines the exportaccountkey JSON-RPC command.
+type ExportAccountKeyCmd struct {
+	Account string
+	Private *bool `jsonrpcdefault:"false"`
+}
+
+// NewExportAccountKeyCmd returns a new instance which can be used to issue an
+// exportaccountkey JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewExportAccountKeyCmd(account string, private *bool) *ExportAccountKeyCmd {
+	return &ExportAccountKeyCmd{
+		Account: account,
+		Private: private,
+	}
+}
+
+// FinalizePSBTCmd defines the finalizepsbt JSON-RPC command.
+type FinalizePSBTCmd struct {
+	PSBT    string
+	Extract *bool `jsonrpcdefault:"true"`
+}
+
+// NewFinalizePSBTCmd returns a new instance which can be used to issue a
+// finalizepsbt JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewFinalizePSBTCmd(psbt string, extract *bool) *FinalizePSBTCmd {
+	return &FinalizePSBTCmd{
+		PSBT:    psbt,
+		Extract: extract,
+	}
+}
+
+// GetDiscoveryStatusCmd defines the getdiscoverystatus JSON-RPC command.
+type GetDiscoveryStatusCmd struct{}
+
+// NewGetDiscoveryStatusCmd returns a new instance which can be used to issue a
+// getdiscoverystatus JSON-RPC command.
+func NewGetDiscoveryStatusCmd() *GetDiscoveryStatusCmd {
+	return &GetDiscoveryStatusCmd{}
+}
+
+// GetWalletInfoCmd defines the getwalletinfo JSON-RPC command.
+type GetWalletInfoCmd struct{}
+
+// NewGetWalletInfoCmd returns a new instance which can be used to issue a
+// getwalletinfo JSON-RPC command.
+func NewGetWalletInfoCmd() *GetWalletInfoCmd {
+	return &GetWalletInfoCmd{}
+}
+
+// ImportXpubCmd defines the importxpub JSON-RPC command.
+type ImportXpubCmd struct {
+	Account string
+	Xpub    string
+	Rescan  *bool `jsonrpcdefault:"true"`
+}
+
+// NewImportXpubCmd returns a new instance which can be used to issue an
+// importxpub JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewImportXpubCmd(account, xpub string, rescan *bool) *ImportXpubCmd {
+	return &ImportXpubCmd{
+		Account: account,
+		Xpub:    xpub,
+		Rescan:  rescan,
+	}
+}
+
+// ListWalletsCmd defines the listwallets JSON-RPC command.
+type ListWalletsCmd struct{}
+
+// NewListWalletsCmd returns a new instance which can be used to issue a
+// listwallets JSON-RPC command.
+func NewListWalletsCmd() *ListWalletsCmd {
+	return &ListWalletsCmd{}
+}
+
+// LoadWalletCmd defines the loadwallet JSON-RPC command.
+type LoadWalletCmd struct {
+	WalletName    string
+	PubPassphrase *string
+}
+
+// NewLoadWalletCmd returns a new instance which can be used to issue a
+// loadwallet JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewLoadWalletCmd(walletName string, pubPassphrase *string) *LoadWalletCmd {
+	return &LoadWalletCmd{
+		WalletName:    walletName,
+		PubPassphrase: pubPassphrase,
+	}
+}
+
+// MakeMultisigAccountCmd defines the makemultisigaccount JSON-RPC command.
+type MakeMultisigAccountCmd struct {
+	Account   string
+	NRequired int
+	Keys      []string
+}
+
+// NewMakeMultisigAccountCmd returns a new instance which can be used to issue
+// a makemultisigaccount JSON-RPC command.
+func NewMakeMultisigAccountCmd(account string, nRequired int, keys []string) *MakeMultisigAccountCmd {
+	return &MakeMultisigAccountCmd{
+		Account:   account,
+		NRequired: nRequired,
+		Keys:      keys,
+	}
+}
+
+// PayForParentCmd defines the payforparent JSON-RPC command.
+type PayForParentCmd struct {
+	Txid    string
+	FeeRate *int64
+	Inputs  *[]btcjson.TransactionInput
+}
+
+// NewPayForParentCmd returns a new instance which can be used to issue a
+// payforparent JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewPayForParentCmd(txid string, feeRate *int64,
+	inputs *[]btcjson.TransactionInput) *PayForParentCmd {
+
+	return &PayForParentCmd{
+		Txid:    txid,
+		FeeRate: feeRate,
+		Inputs:  inputs,
+	}
+}
+
+// SendFromCmd defines the sendfrom JSON-RPC command.  It extends
+// btcjson.SendFromCmd with an optional fee rate, in satoshis per byte, which
+// overrides the fee rate of the wallet.
+type SendFromCmd struct {
+	FromAccount string
+	ToAddress   string
+	Amount      float64 // In BTC
+	MinConf     *int    `jsonrpcdefault:"1"`
+	Comment     *string
+	CommentTo   *string
+	FeeRate     *int64
+}
+
+// NewSendFromCmd returns a new instance which can be used to issue a sendfrom
+// JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSendFromCmd(fromAccount, toAddress string, amount float64, minConf *int,
+	comment, commentTo *string, feeRate *int64) *SendFromCmd {
+
+	return &SendFromCmd{
+		FromAccount: fromAccount,
+		ToAddress:   toAddress,
+		Amount:      amount,
+		MinConf:     minConf,
+		Comment:     comment,
+		CommentTo:   commentTo,
+		FeeRate:     feeRate,
+	}
+}
+
+// SendFromOutpointsCmd defines the sendfromoutpoints JSON-RPC command.
+type SendFromOutpointsCmd struct {
+	FromAccount string
+	Inputs      []btcjson.TransactionInput
+	Amounts     map[string]float64 `jsonrpcusage:"{\"address\":amount,...}"` // In BTC
+	FeeRate     *int64
+	Comment     *string
+}
+
+// NewSendFromOutpointsCmd returns a new instance which can be used to issue a
+// sendfromoutpoints JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSendFromOutpointsCmd(fromAccount string, inputs []btcjson.TransactionInput,
+	amounts map[string]float64, feeRate *int64, comment *string) *SendFromOutpointsCmd {
+
+	return &SendFromOutpointsCmd{
+		FromAccount: fromAccount,
+		Inputs:      inputs,
+		Amounts:     amounts,
+		FeeRate:     feeRate,
+		Comment:     comment,
+	}
+}
+
+// SendManyCmd defines the sendmany JSON-RPC command.  It extends
+// btcjson.SendManyCmd with an optional fee rate, in satoshis per byte, which
+// overrides the fee rate of the wallet.
+type SendManyCmd struct {
+	FromAccount string
+	Amounts     map[string]float64 `jsonrpcusage:"{\"address\":amount,...}"` // In BTC
+	MinConf     *int               `jsonrpcdefault:"1"`
+	Comment     *string
+	FeeRate     *int64
+}
+
+// NewSendManyCmd returns a new instance which can be used to issue a sendmany
+// JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSendManyCmd(fromAccount string, amounts map[string]float64,
+	minConf *int, comment *string, feeRate *int64) *SendManyCmd {
+
+	return &SendManyCmd{
+		FromAccount: fromAccount,
+		Amounts:     amounts,
+		MinConf:     minConf,
+		Comment:     comment,
+		FeeRate:     feeRate,
+	}
+}
+
+// SendPSBTCmd defines the sendpsbt JSON-RPC command.
+type SendPSBTCmd struct {
+	PSBT string
+}
+
+// NewSendPSBTCmd returns a new instance which can be used to issue a sendpsbt
+// JSON-RPC command.
+func NewSendPSBTCmd(psbt string) *SendPSBTCmd {
+	return &SendPSBTCmd{
+		PSBT: psbt,
+	}
+}
+
+// SendToAddressCmd defines the sendtoaddress JSON-RPC command.  It extends
+// btcjson.SendToAddressCmd with an optional fee rate, in satoshis per byte,
+// which overrides the fee rate of the wallet.
+type SendToAddressCmd struct {
+	Address   string
+	Amount    float64
+	Comment   *string
+	CommentTo *string
+	FeeRate   *int64
+}
+
+// NewSendToAddressCmd returns a new instance which can be used to issue a
+// sendtoaddress JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSendToAddressCmd(address string, amount float64, comment,
+	commentTo *string, feeRate *int64) *SendToAddressCmd {
+
+	return &SendToAddressCmd{
+		Address:   address,
+		Amount:    amount,
+		Comment:   comment,
+		CommentTo: commentTo,
+		FeeRate:   feeRate,
+	}
+}
+
+// SetTxCommentCmd defines the settxcomment JSON-RPC command.
+type SetTxCommentCmd struct {
+	Txid      string
+	Comment   string
+	CommentTo *string
+}
+
+// NewSetTxCommentCmd returns a new instance which can be used to issue a
+// settxcomment JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSetTxCommentCmd(txHash, comment string, commentTo *string) *SetTxCommentCmd {
+	return &SetTxCommentCmd{
+		Txid:      txHash,
+		Comment:   comment,
+		CommentTo: commentTo,
+	}
+}
+
+// SweepPrivKeyCmd defines the sweepprivkey JSON-RPC command.
+type SweepPrivKeyCmd struct {
+	PrivKey string
+	Account *string
+	FeeRate *int64
+}
+
+// NewSweepPrivKeyCmd returns a new instance which can be used to issue a
+// sweepprivkey JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSweepPrivKeyCmd(privKey string, account *string, feeRate *int64) *SweepPrivKeyCmd {
+	return &SweepPrivKeyCmd{
+		PrivKey: privKey,
+		Account: account,
+		FeeRate: feeRate,
+	}
+}
+
+// UnloadWalletCmd defines the unloadwallet JSON-RPC command.
+type UnloadWalletCmd struct {
+	WalletName string
+}
+
+// NewUnloadWalletCmd returns a new instance which can be used to issue an
+// unloadwallet JSON-RPC command.
+func NewUnloadWalletCmd(walletName string) *UnloadWalletCmd {
+	return &UnloadWalletCmd{
+		WalletName: walletName,
+	}
+}
+
+// WalletCreateFundedPSBTCmd defines the walletcreatefundedpsbt JSON-RPC
+// command.
+type WalletCreateFundedPSBTCmd struct {
+	FromAccount string
+	Amounts     map[string]float64 `jsonrpcusage:"{\"address\":amount,...}"` // In BTC
+	MinConf     *int               `jsonrpcdefault:"1"`
+	FeeRate     *int64
+}
+
+// NewWalletCreateFundedPSBTCmd returns a new instance which can be used to
+// issue a walletcreatefundedpsbt JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewWalletCreateFundedPSBTCmd(fromAccount string, amounts map[string]float64,
+	minConf *int, feeRate *int64) *WalletCreateFundedPSBTCmd {
+
+	return &WalletCreateFundedPSBTCmd{
+		FromAccount: fromAccount,
+		Amounts:     amounts,
+		MinConf:     minConf,
+		FeeRate:     feeRate,
+	}
+}
+
+// WalletProcessPSBTCmd defines the walletprocesspsbt JSON-RPC command.
+type WalletProcessPSBTCmd struct {
+	PSBT string
+	Sign *bool `jsonrpcdefault:"true"`
+}
+
+// NewWalletProcessPSBTCmd returns a new instance which can be used to issue a
+// walletprocesspsbt JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewWalletProcessPSBTCmd(psbt string, sign *bool) *WalletProcessPSBTCmd {
+	return &WalletProcessPSBTCmd{
+		PSBT: psbt,
+		Sign: sign,
+	}
+}
+
+// extendedMethodPrefix prefixes the methods the commands extending btcjson
+// commands with further parameters are registered with, since the methods of
+// the commands they extend are already registered by btcjson.
+const extendedMethodPrefix = "walletjson."
+
+// extendedMethods holds the methods of the btcjson commands extended by the
+// commands of this package.
+var extendedMethods = map[string]struct{}{
+	"sendfrom":      {},
+	"sendmany":      {},
+	"sendtoaddress": {},
+}
+
+// ExtendedMethod returns the method a command extending the btcjson command of
+// the passed method is registered with, and whether the command of the method
+// is extended by this package.
+func ExtendedMethod(method string) (string, bool) {
+	if _, ok := extendedMethods[method]; !ok {
+		return method, false
+	}
+	return extendedMethodPrefix + method, true
+}
+
+// UnmarshalCmd unmarshals a JSON-RPC request into a command like
+// btcjson.UnmarshalCmd, except that requests of the btcjson commands extended
+// by this package are unmarshaled into the extended commands.
+func UnmarshalCmd(r *btcjson.Request) (interface{}, error) {
+	if method, ok := ExtendedMethod(r.Method); ok {
+		req := *r
+		req.Method = method
+		r = &req
+	}
+	return btcjson.UnmarshalCmd(r)
+}
+
+func init() {
+	// The commands in this file are only usable with a wallet server.
+	flags := btcjson.UFWalletOnly
+
+	btcjson.MustRegisterCmd("abandontransaction", (*AbandonTransactionCmd)(nil), flags)
+	btcjson.MustRegisterCmd("backupwallet", (*BackupWalletCmd)(nil), flags)
+	btcjson.MustRegisterCmd("bumpfee", (*BumpFeeCmd)(nil), flags)
+	btcjson.MustRegisterCmd("combinepsbt", (*CombinePSBTCmd)(nil), flags)
+	btcjson.MustRegisterCmd("consolidateunspent", (*ConsolidateUnspentCmd)(nil), flags)
+	btcjson.MustRegisterCmd("createunsignedtransaction", (*CreateUnsignedTransactionCmd)(nil), flags)
+	btcjson.MustRegisterCmd("createwallet", (*CreateWalletCmd)(nil), flags)
+	btcjson.MustRegisterCmd("discoveraccounts", (*DiscoverAccountsCmd)(nil), flags)
+	btcjson.MustRegisterCmd("exportaccountkey", (*ExportAccountKeyCmd)(nil), flags)
+	btcjson.MustRegisterCmd("finalizepsbt", (*FinalizePSBTCmd)(nil), flags)
+	btcjson.MustRegisterCmd("getdiscoverystatus", (*GetDiscoveryStatusCmd)(nil), flags)
+	btcjson.MustRegisterCmd("getwalletinfo", (*GetWalletInfoCmd)(nil), flags)
+	btcjson.MustRegisterCmd("importxpub", (*ImportXpubCmd)(nil), flags)
+	btcjson.MustRegisterCmd("listwallets", (*ListWalletsCmd)(nil), flags)
+	btcjson.MustRegisterCmd("loadwallet", (*LoadWalletCmd)(nil), flags)
+	btcjson.MustRegisterCmd("makemultisigaccount", (*MakeMultisigAccountCmd)(nil), flags)
+	btcjson.MustRegisterCmd("payforparent", (*PayForParentCmd)(nil), flags)
+	btcjson.MustRegisterCmd("sendfromoutpoints", (*SendFromOutpointsCmd)(nil), flags)
+	btcjson.MustRegisterCmd("sendpsbt", (*SendPSBTCmd)(nil), flags)
+	btcjson.MustRegisterCmd("settxcomment", (*SetTxCommentCmd)(nil), flags)
+	btcjson.MustRegisterCmd("sweepprivkey", (*SweepPrivKeyCmd)(nil), flags)
+	btcjson.MustRegisterCmd("unloadwallet", (*UnloadWalletCmd)(nil), flags)
+	btcjson.MustRegisterCmd("walletcreatefundedpsbt", (*WalletCreateFundedPSBTCmd)(nil), flags)
+	btcjson.MustRegisterCmd("walletprocesspsbt", (*WalletProcessPSBTCmd)(nil), flags)
+
+	// Commands extending btcjson commands.
+	btcjson.MustRegisterCmd(extendedMethodPrefix+"sendfrom", (*SendFromCmd)(nil), flags)
+	btcjson.MustRegisterCmd(extendedMethodPrefix+"sendmany", (*SendManyCmd)(nil), flags)
+	btcjson.MustRegisterCmd(extendedMethodPrefix+"sendtoaddress", (*SendToAddressCmd)(nil), flags)
+}
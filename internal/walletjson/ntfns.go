@@ -0,0 +1,52 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package walletjson
+
+import "github.com/conseweb/stcd/btcjson"
+
+const (
+	// TxConflictedNtfnMethod is the method used for notifications from the
+	// wallet server that an unmined wallet transaction was double spent
+	// and removed as conflicted.
+	TxConflictedNtfnMethod = "txconflicted"
+)
+
+// TxConflictedNtfn defines the txconflicted JSON-RPC notification.
+type TxConflictedNtfn struct {
+	TxID         string
+	ConflictedBy *string
+}
+
+// NewTxConflictedNtfn returns a new instance which can be used to issue a
+// txconflicted JSON-RPC notification.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.  ConflictedBy is nil
+// when the double spending transaction is not known.
+func NewTxConflictedNtfn(txID string, conflictedBy *string) *TxConflictedNtfn {
+	return &TxConflictedNtfn{
+		TxID:         txID,
+		ConflictedBy: conflictedBy,
+	}
+}
+
+func init() {
+	// The notifications in this file are only usable with a wallet server
+	// over websockets and are notifications as opposed to commands.
+	flags := btcjson.UFWalletOnly | btcjson.UFWebsocketOnly |
+		btcjson.UFNotification
+
+	btcjson.MustRegisterCmd(TxConflictedNtfnMethod, (*TxConflictedNtfn)(nil), flags)
+}
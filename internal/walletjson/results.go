@@ -0,0 +1,117 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package walletjson
+
+import "github.com/conseweb/stcd/btcjson"
+
+// CreateWalletResult models the data returned from the createwallet command.
+// Mnemonic is only set when the seed of the wallet was generated, and is never
+// returned again.
+type CreateWalletResult struct {
+	Name     string `json:"name"`
+	Mnemonic string `json:"mnemonic,omitempty"`
+}
+
+// FinalizePSBTResult models the data returned from the finalizepsbt command.
+// Hex is only set when the transaction is complete and extracted, and PSBT
+// otherwise.
+type FinalizePSBTResult struct {
+	PSBT     string `json:"psbt,omitempty"`
+	Hex      string `json:"hex,omitempty"`
+	Complete bool   `json:"complete"`
+}
+
+// GetDiscoveryStatusResult models the data returned from the
+// getdiscoverystatus command.  Error is only set when the last discovery
+// failed.
+type GetDiscoveryStatusResult struct {
+	Running   bool   `json:"running"`
+	Account   uint32 `json:"account"`
+	Rescanned int    `json:"rescanned"`
+	Error     string `json:"error,omitempty"`
+}
+
+// GetWalletInfoResult models the data returned from the getwalletinfo
+// command.
+type GetWalletInfoResult struct {
+	WalletVersion      int32   `json:"walletversion"`
+	Balance            float64 `json:"balance"`
+	UnconfirmedBalance float64 `json:"unconfirmed_balance"`
+	TxCount            int     `json:"txcount"`
+	AccountCount       int     `json:"accountcount"`
+	AddressCount       int     `json:"addresscount"`
+	Locked             bool    `json:"locked"`
+	UnlockedUntil      int64   `json:"unlocked_until"`
+	SyncedToHash       string  `json:"syncedtohash"`
+	SyncedToHeight     int32   `json:"syncedtoheight"`
+	Rescanning         bool    `json:"rescanning"`
+}
+
+// GetTransactionResult models the data returned from the gettransaction
+// command.  It extends btcjson.GetTransactionResult with the comments recorded
+// for the transaction.
+type GetTransactionResult struct {
+	Amount          float64                               `json:"amount"`
+	Fee             float64                               `json:"fee,omitempty"`
+	Confirmations   int64                                 `json:"confirmations"`
+	BlockHash       string                                `json:"blockhash"`
+	BlockIndex      int64                                 `json:"blockindex"`
+	BlockTime       int64                                 `json:"blocktime"`
+	TxID            string                                `json:"txid"`
+	WalletConflicts []string                              `json:"walletconflicts"`
+	Time            int64                                 `json:"time"`
+	TimeReceived    int64                                 `json:"timereceived"`
+	Comment         string                                `json:"comment,omitempty"`
+	CommentTo       string                                `json:"to,omitempty"`
+	Details         []btcjson.GetTransactionDetailsResult `json:"details"`
+	Hex             string                                `json:"hex"`
+}
+
+// ListAddressGroupingsResult models an address of a group returned by the
+// listaddressgroupings command.
+type ListAddressGroupingsResult struct {
+	Address string  `json:"address"`
+	Amount  float64 `json:"amount"`
+	Account string  `json:"account"`
+}
+
+// ValidateAddressWalletResult models the data returned from the
+// validateaddress command.  It extends btcjson.ValidateAddressWalletResult with
+// the derivation path of chained addresses and whether they exceed the gap
+// limit of address generation.
+type ValidateAddressWalletResult struct {
+	IsValid         bool     `json:"isvalid"`
+	Address         string   `json:"address,omitempty"`
+	IsMine          bool     `json:"ismine,omitempty"`
+	IsWatchOnly     bool     `json:"iswatchonly,omitempty"`
+	IsScript        bool     `json:"isscript,omitempty"`
+	PubKey          string   `json:"pubkey,omitempty"`
+	IsCompressed    bool     `json:"iscompressed,omitempty"`
+	Account         string   `json:"account,omitempty"`
+	Addresses       []string `json:"addresses,omitempty"`
+	Hex             string   `json:"hex,omitempty"`
+	Script          string   `json:"script,omitempty"`
+	SigsRequired    int32    `json:"sigsrequired,omitempty"`
+	HDKeyPath       string   `json:"hdkeypath,omitempty"`
+	GapLimit        uint32   `json:"gaplimit,omitempty"`
+	ExceedsGapLimit bool     `json:"exceedsgaplimit,omitempty"`
+}
+
+// WalletProcessPSBTResult models the data returned from the walletprocesspsbt
+// command.
+type WalletProcessPSBTResult struct {
+	PSBT     string `json:"psbt"`
+	Complete bool   `json:"complete"`
+}
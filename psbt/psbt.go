@@ -0,0 +1,746 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+// Package psbt implements a container for partially signed transactions,
+// passed between the wallets and devices which each add what they know to a
+// transaction until it can be published.  The container carries the unsigned
+// transaction, the output spent by each input, redeem scripts, HD key paths
+// and the signatures collected so far.
+//
+// The binary encoding follows the key-value maps of BIP0174 with three
+// differences:
+//
+//   - Packets begin with a magic and version of their own rather than the
+//     BIP0174 magic, so they are never mistaken for BIP0174 packets.
+//   - The output spent by an input is always carried as a single transaction
+//     output (BIP0174 type 0x01) rather than the whole previous transaction,
+//     since the chain has no segregated witness.
+//   - HD key paths are relative to the account extended key identified by the
+//     fingerprint rather than to the master key, since the master key of a
+//     wallet is not kept.
+package psbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/btcec"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// magic is the prefix of every serialized packet, followed by the version of
+// the encoding.
+var magic = [4]byte{'s', 'p', 's', 't'}
+
+// packetVersion is the version of the encoding written by Serialize, and the
+// only version read by Deserialize.
+const packetVersion = 0
+
+// Key types of the global, input and output maps.
+const (
+	globalUnsignedTx = 0x00
+
+	inputPrevOut        = 0x01
+	inputPartialSig     = 0x02
+	inputRedeemScript   = 0x04
+	inputKeyPath        = 0x06
+	inputFinalScriptSig = 0x07
+
+	outputRedeemScript = 0x00
+	outputKeyPath      = 0x02
+)
+
+var (
+	// ErrMalformed describes an error where a serialized packet can not
+	// be decoded.
+	ErrMalformed = errors.New("malformed partially signed transaction")
+
+	// ErrUnknownVersion describes an error where a serialized packet is
+	// of an encoding version which is not supported.
+	ErrUnknownVersion = errors.New("unknown partially signed " +
+		"transaction version")
+
+	// ErrSignedTx describes an error where the transaction of a packet
+	// already has signature scripts.
+	ErrSignedTx = errors.New("transaction inputs must not have " +
+		"signature scripts")
+
+	// ErrTxMismatch describes an error where packets for different
+	// transactions are combined.
+	ErrTxMismatch = errors.New("partially signed transactions are " +
+		"for different transactions")
+
+	// ErrIncomplete describes an error where a transaction is extracted
+	// from a packet before every input is finalized.
+	ErrIncomplete = errors.New("partially signed transaction is not " +
+		"complete")
+)
+
+// PartialSig is the signature of a single public key for an input.  The
+// signature is DER encoded and followed by the hash type flag.
+type PartialSig struct {
+	PubKey    []byte
+	Signature []byte
+}
+
+// KeyPath describes how a public key was derived.  Fingerprint holds the
+// first four bytes of the hash160 of the serialized compressed public key of
+// the account extended key, as a big-endian integer, and Path the child
+// indexes (the branch and address index) from the account key to PubKey.
+type KeyPath struct {
+	PubKey      []byte
+	Fingerprint uint32
+	Path        []uint32
+}
+
+// Unknown is a key-value pair of a type not understood by this package.  They
+// are kept so packets pass through this package without losing data.
+type Unknown struct {
+	Key   []byte
+	Value []byte
+}
+
+// Input holds what is known about the signing of an input of the transaction.
+// All but FinalScriptSig and Unknowns are cleared once the input is
+// finalized.
+type Input struct {
+	PrevOut        *wire.TxOut
+	PartialSigs    []PartialSig
+	RedeemScript   []byte
+	KeyPaths       []KeyPath
+	FinalScriptSig []byte
+	Unknowns       []Unknown
+}
+
+// Output holds the scripts and key paths of an output of the transaction
+// paying the wallet, which let signers recognize change.
+type Output struct {
+	RedeemScript []byte
+	KeyPaths     []KeyPath
+	Unknowns     []Unknown
+}
+
+// Packet is a partially signed transaction.  Inputs and Outputs hold an item
+// for each input and output of UnsignedTx, in order.
+type Packet struct {
+	UnsignedTx *wire.MsgTx
+	Inputs     []Input
+	Outputs    []Output
+	Unknowns   []Unknown
+}
+
+// New returns a packet for a copy of tx with nothing known about any input or
+// output.  ErrSignedTx is returned if any input of tx has a signature script.
+func New(tx *wire.MsgTx) (*Packet, error) {
+	for _, txIn := range tx.TxIn {
+		if len(txIn.SignatureScript) != 0 {
+			return nil, ErrSignedTx
+		}
+	}
+	return &Packet{
+		UnsignedTx: tx.Copy(),
+		Inputs:     make([]Input, len(tx.TxIn)),
+		Outputs:    make([]Output, len(tx.TxOut)),
+	}, nil
+}
+
+// AddPartialSig adds the signature of pubKey for the input, replacing any
+// previous signature of the key.
+func (in *Input) AddPartialSig(pubKey, sig []byte) {
+	for i := range in.PartialSigs {
+		if bytes.Equal(in.PartialSigs[i].PubKey, pubKey) {
+			in.PartialSigs[i].Signature = sig
+			return
+		}
+	}
+	in.PartialSigs = append(in.PartialSigs, PartialSig{pubKey, sig})
+}
+
+// partialSig returns the signature of pubKey for the input, or nil if the key
+// has not signed it.
+func (in *Input) partialSig(pubKey []byte) []byte {
+	for i := range in.PartialSigs {
+		if bytes.Equal(in.PartialSigs[i].PubKey, pubKey) {
+			return in.PartialSigs[i].Signature
+		}
+	}
+	return nil
+}
+
+// addKeyPath returns paths with kp added, unless the path of its public key is
+// already known.
+func addKeyPath(paths []KeyPath, kp KeyPath) []KeyPath {
+	for i := range paths {
+		if bytes.Equal(paths[i].PubKey, kp.PubKey) {
+			return paths
+		}
+	}
+	return append(paths, kp)
+}
+
+// AddKeyPath records how a public key of the input was derived, unless it is
+// already known.
+func (in *Input) AddKeyPath(kp KeyPath) {
+	in.KeyPaths = addKeyPath(in.KeyPaths, kp)
+}
+
+// AddKeyPath records how a public key of the output was derived, unless it is
+// already known.
+func (out *Output) AddKeyPath(kp KeyPath) {
+	out.KeyPaths = addKeyPath(out.KeyPaths, kp)
+}
+
+// addUnknowns returns unknowns with each item of more added, unless its key is
+// already known.
+func addUnknowns(unknowns, more []Unknown) []Unknown {
+next:
+	for _, u := range more {
+		for i := range unknowns {
+			if bytes.Equal(unknowns[i].Key, u.Key) {
+				continue next
+			}
+		}
+		unknowns = append(unknowns, u)
+	}
+	return unknowns
+}
+
+// Combine returns a packet holding everything known by any of the passed
+// packets, which must all be for the same transaction.  The passed packets are
+// not modified.  ErrTxMismatch is returned if the transactions of the packets
+// differ.
+func Combine(packets ...*Packet) (*Packet, error) {
+	if len(packets) == 0 {
+		return nil, errors.New("no partially signed transactions to combine")
+	}
+
+	// Start with a copy of the first packet, made by round-tripping it
+	// through its serialization so no slices are shared.
+	var buf bytes.Buffer
+	if err := packets[0].Serialize(&buf); err != nil {
+		return nil, err
+	}
+	combined := new(Packet)
+	if err := combined.Deserialize(&buf); err != nil {
+		return nil, err
+	}
+
+	txHash := combined.UnsignedTx.TxSha()
+	for _, p := range packets[1:] {
+		if p.UnsignedTx.TxSha() != txHash {
+			return nil, ErrTxMismatch
+		}
+		for i := range p.Inputs {
+			in, other := &combined.Inputs[i], &p.Inputs[i]
+			if in.PrevOut == nil && other.PrevOut != nil {
+				in.PrevOut = wire.NewTxOut(other.PrevOut.Value,
+					copyBytes(other.PrevOut.PkScript))
+			}
+			if in.FinalScriptSig == nil && other.FinalScriptSig != nil {
+				in.FinalScriptSig = copyBytes(other.FinalScriptSig)
+				in.PartialSigs = nil
+				in.RedeemScript = nil
+				in.KeyPaths = nil
+			}
+			if in.FinalScriptSig == nil {
+				for _, sig := range other.PartialSigs {
+					if in.partialSig(sig.PubKey) == nil {
+						in.AddPartialSig(copyBytes(sig.PubKey),
+							copyBytes(sig.Signature))
+					}
+				}
+				if in.RedeemScript == nil {
+					in.RedeemScript = copyBytes(other.RedeemScript)
+				}
+				for _, kp := range other.KeyPaths {
+					in.AddKeyPath(kp)
+				}
+			}
+			in.Unknowns = addUnknowns(in.Unknowns, other.Unknowns)
+		}
+		for i := range p.Outputs {
+			out, other := &combined.Outputs[i], &p.Outputs[i]
+			if out.RedeemScript == nil {
+				out.RedeemScript = copyBytes(other.RedeemScript)
+			}
+			for _, kp := range other.KeyPaths {
+				out.AddKeyPath(kp)
+			}
+			out.Unknowns = addUnknowns(out.Unknowns, other.Unknowns)
+		}
+		combined.Unknowns = addUnknowns(combined.Unknowns, p.Unknowns)
+	}
+	return combined, nil
+}
+
+// copyBytes returns a copy of b, or nil if b is nil.
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
+
+// sigScript returns the signature script redeeming the output spent by the
+// input built from its partial signatures, or nil if the input does not have
+// enough signatures yet.  P2PKH, P2PK and P2SH multisig outputs are supported.
+func (in *Input) sigScript() ([]byte, error) {
+	// Scripts which can not be parsed can not be redeemed either.
+	pkScript := in.PrevOut.PkScript
+	pushes, err := txscript.PushedData(pkScript)
+	if err != nil {
+		return nil, nil
+	}
+
+	switch txscript.GetScriptClass(pkScript) {
+	case txscript.PubKeyHashTy:
+		for _, sig := range in.PartialSigs {
+			if bytes.Equal(coinutil.Hash160(sig.PubKey), pushes[0]) {
+				return txscript.NewScriptBuilder().AddData(sig.Signature).
+					AddData(sig.PubKey).Script()
+			}
+		}
+		return nil, nil
+
+	case txscript.PubKeyTy:
+		sig := in.partialSig(pushes[0])
+		if sig == nil {
+			return nil, nil
+		}
+		return txscript.NewScriptBuilder().AddData(sig).Script()
+
+	case txscript.ScriptHashTy:
+		if in.RedeemScript == nil {
+			return nil, nil
+		}
+		if !bytes.Equal(coinutil.Hash160(in.RedeemScript), pushes[0]) {
+			return nil, errors.New("redeem script does not match " +
+				"the script hash of the previous output")
+		}
+		if txscript.GetScriptClass(in.RedeemScript) != txscript.MultiSigTy {
+			return nil, nil
+		}
+		pubKeys, err := txscript.PushedData(in.RedeemScript)
+		if err != nil {
+			return nil, err
+		}
+		_, reqSigs, err := txscript.CalcMultiSigStats(in.RedeemScript)
+		if err != nil {
+			return nil, err
+		}
+
+		// Signatures must be in the order of the public keys of the
+		// redeem script.  OP_CHECKMULTISIG pops an extra item, which is
+		// pushed first.
+		b := txscript.NewScriptBuilder().AddOp(txscript.OP_FALSE)
+		numSigs := 0
+		for _, pubKey := range pubKeys {
+			if sig := in.partialSig(pubKey); sig != nil {
+				b.AddData(sig)
+				numSigs++
+				if numSigs == reqSigs {
+					return b.AddData(in.RedeemScript).Script()
+				}
+			}
+		}
+		return nil, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// Finalize builds the final signature script of each input for which enough
+// partial signatures have been collected, and checks it by executing it with
+// the script of the spent output.  Finalized inputs only keep their final
+// signature script.  Inputs lacking signatures or the spent output are left
+// as is.  An error is returned if a signature script does not execute
+// successfully, in which case no further inputs are finalized.
+func (p *Packet) Finalize() error {
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		if in.FinalScriptSig != nil || in.PrevOut == nil {
+			continue
+		}
+		sigScript, err := in.sigScript()
+		if err != nil {
+			return fmt.Errorf("input %d: %v", i, err)
+		}
+		if sigScript == nil {
+			continue
+		}
+
+		// Only the signature script of the input being executed is
+		// used, so the others may remain empty.
+		tx := p.UnsignedTx.Copy()
+		tx.TxIn[i].SignatureScript = sigScript
+		vm, err := txscript.NewEngine(in.PrevOut.PkScript, tx, i,
+			txscript.StandardVerifyFlags, nil)
+		if err != nil {
+			return fmt.Errorf("input %d: cannot create script engine: %v",
+				i, err)
+		}
+		if err := vm.Execute(); err != nil {
+			return fmt.Errorf("input %d: invalid signatures: %v", i, err)
+		}
+
+		in.FinalScriptSig = sigScript
+		in.PartialSigs = nil
+		in.RedeemScript = nil
+		in.KeyPaths = nil
+	}
+	return nil
+}
+
+// Complete returns whether every input of the packet is finalized.
+func (p *Packet) Complete() bool {
+	for i := range p.Inputs {
+		if p.Inputs[i].FinalScriptSig == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Extract returns the signed transaction of a complete packet.  ErrIncomplete
+// is returned if any input is not finalized.
+func (p *Packet) Extract() (*wire.MsgTx, error) {
+	if !p.Complete() {
+		return nil, ErrIncomplete
+	}
+	tx := p.UnsignedTx.Copy()
+	for i := range tx.TxIn {
+		tx.TxIn[i].SignatureScript = copyBytes(p.Inputs[i].FinalScriptSig)
+	}
+	return tx, nil
+}
+
+// writePair writes a single key-value pair of a map.
+func writePair(w io.Writer, keyType byte, keyData, value []byte) error {
+	key := append([]byte{keyType}, keyData...)
+	if err := wire.WriteVarBytes(w, 0, key); err != nil {
+		return err
+	}
+	return wire.WriteVarBytes(w, 0, value)
+}
+
+// writeKeyPaths writes a key-value pair for each key path.
+func writeKeyPaths(w io.Writer, keyType byte, paths []KeyPath) error {
+	for _, kp := range paths {
+		value := make([]byte, 4+4*len(kp.Path))
+		binary.BigEndian.PutUint32(value, kp.Fingerprint)
+		for i, index := range kp.Path {
+			binary.LittleEndian.PutUint32(value[4+4*i:], index)
+		}
+		if err := writePair(w, keyType, kp.PubKey, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeUnknowns writes each unknown key-value pair followed by the separator
+// ending a map.
+func writeUnknowns(w io.Writer, unknowns []Unknown) error {
+	for _, u := range unknowns {
+		if err := wire.WriteVarBytes(w, 0, u.Key); err != nil {
+			return err
+		}
+		if err := wire.WriteVarBytes(w, 0, u.Value); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{0})
+	return err
+}
+
+// Serialize writes the binary encoding of the packet to w.
+func (p *Packet) Serialize(w io.Writer) error {
+	if _, err := w.Write(append(magic[:], packetVersion)); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(p.UnsignedTx.SerializeSize())
+	if err := p.UnsignedTx.Serialize(&buf); err != nil {
+		return err
+	}
+	if err := writePair(w, globalUnsignedTx, nil, buf.Bytes()); err != nil {
+		return err
+	}
+	if err := writeUnknowns(w, p.Unknowns); err != nil {
+		return err
+	}
+
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		if in.PrevOut != nil {
+			buf.Reset()
+			err := binary.Write(&buf, binary.LittleEndian,
+				in.PrevOut.Value)
+			if err != nil {
+				return err
+			}
+			err = wire.WriteVarBytes(&buf, 0, in.PrevOut.PkScript)
+			if err != nil {
+				return err
+			}
+			err = writePair(w, inputPrevOut, nil, buf.Bytes())
+			if err != nil {
+				return err
+			}
+		}
+		for _, sig := range in.PartialSigs {
+			err := writePair(w, inputPartialSig, sig.PubKey,
+				sig.Signature)
+			if err != nil {
+				return err
+			}
+		}
+		if in.RedeemScript != nil {
+			err := writePair(w, inputRedeemScript, nil,
+				in.RedeemScript)
+			if err != nil {
+				return err
+			}
+		}
+		if err := writeKeyPaths(w, inputKeyPath, in.KeyPaths); err != nil {
+			return err
+		}
+		if in.FinalScriptSig != nil {
+			err := writePair(w, inputFinalScriptSig, nil,
+				in.FinalScriptSig)
+			if err != nil {
+				return err
+			}
+		}
+		if err := writeUnknowns(w, in.Unknowns); err != nil {
+			return err
+		}
+	}
+
+	for i := range p.Outputs {
+		out := &p.Outputs[i]
+		if out.RedeemScript != nil {
+			err := writePair(w, outputRedeemScript, nil,
+				out.RedeemScript)
+			if err != nil {
+				return err
+			}
+		}
+		if err := writeKeyPaths(w, outputKeyPath, out.KeyPaths); err != nil {
+			return err
+		}
+		if err := writeUnknowns(w, out.Unknowns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pair is a key-value pair read from a map.
+type pair struct {
+	keyType byte
+	keyData []byte
+	value   []byte
+}
+
+// readMap reads the key-value pairs of a map up to and including the
+// separator ending it.  Maps holding the same key twice are malformed.
+func readMap(r io.Reader) ([]pair, error) {
+	var pairs []pair
+	seen := make(map[string]struct{})
+	for {
+		key, err := wire.ReadVarBytes(r, 0, wire.MaxMessagePayload, "key")
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		if len(key) == 0 {
+			return pairs, nil
+		}
+		if _, ok := seen[string(key)]; ok {
+			return nil, ErrMalformed
+		}
+		seen[string(key)] = struct{}{}
+		value, err := wire.ReadVarBytes(r, 0, wire.MaxMessagePayload,
+			"value")
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		pairs = append(pairs, pair{key[0], key[1:], value})
+	}
+}
+
+// unknown returns the pair as an Unknown.
+func (kv *pair) unknown() Unknown {
+	return Unknown{append([]byte{kv.keyType}, kv.keyData...), kv.value}
+}
+
+// checkPubKey returns ErrMalformed unless pubKey is a valid serialized public
+// key.
+func checkPubKey(pubKey []byte) error {
+	if _, err := btcec.ParsePubKey(pubKey, btcec.S256()); err != nil {
+		return ErrMalformed
+	}
+	return nil
+}
+
+// readKeyPath decodes the key path of a key path pair.
+func readKeyPath(kv *pair) (KeyPath, error) {
+	if err := checkPubKey(kv.keyData); err != nil {
+		return KeyPath{}, err
+	}
+	if len(kv.value) < 4 || len(kv.value)%4 != 0 {
+		return KeyPath{}, ErrMalformed
+	}
+	kp := KeyPath{
+		PubKey:      kv.keyData,
+		Fingerprint: binary.BigEndian.Uint32(kv.value),
+		Path:        make([]uint32, len(kv.value)/4-1),
+	}
+	for i := range kp.Path {
+		kp.Path[i] = binary.LittleEndian.Uint32(kv.value[4+4*i:])
+	}
+	return kp, nil
+}
+
+// Deserialize decodes a packet from r into p.  ErrMalformed is returned if
+// the encoding is invalid, and ErrUnknownVersion if it is of another version.
+func (p *Packet) Deserialize(r io.Reader) error {
+	var m [len(magic)]byte
+	if _, err := io.ReadFull(r, m[:]); err != nil || m != magic {
+		return ErrMalformed
+	}
+	var version [1]byte
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return ErrMalformed
+	}
+	if version[0] != packetVersion {
+		return ErrUnknownVersion
+	}
+
+	pairs, err := readMap(r)
+	if err != nil {
+		return err
+	}
+	p.UnsignedTx = nil
+	p.Unknowns = nil
+	for i := range pairs {
+		kv := &pairs[i]
+		if kv.keyType != globalUnsignedTx {
+			p.Unknowns = append(p.Unknowns, kv.unknown())
+			continue
+		}
+		if len(kv.keyData) != 0 {
+			return ErrMalformed
+		}
+		tx := wire.NewMsgTx()
+		if err := tx.Deserialize(bytes.NewReader(kv.value)); err != nil {
+			return ErrMalformed
+		}
+		for _, txIn := range tx.TxIn {
+			if len(txIn.SignatureScript) != 0 {
+				return ErrSignedTx
+			}
+		}
+		p.UnsignedTx = tx
+	}
+	if p.UnsignedTx == nil {
+		return ErrMalformed
+	}
+
+	p.Inputs = make([]Input, len(p.UnsignedTx.TxIn))
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		pairs, err := readMap(r)
+		if err != nil {
+			return err
+		}
+		for j := range pairs {
+			kv := &pairs[j]
+			switch {
+			case kv.keyType == inputPrevOut && len(kv.keyData) == 0:
+				vr := bytes.NewReader(kv.value)
+				var value int64
+				err := binary.Read(vr, binary.LittleEndian, &value)
+				if err != nil {
+					return ErrMalformed
+				}
+				pkScript, err := wire.ReadVarBytes(vr, 0,
+					wire.MaxMessagePayload, "pkScript")
+				if err != nil || vr.Len() != 0 {
+					return ErrMalformed
+				}
+				in.PrevOut = wire.NewTxOut(value, pkScript)
+
+			case kv.keyType == inputPartialSig:
+				if err := checkPubKey(kv.keyData); err != nil {
+					return err
+				}
+				in.PartialSigs = append(in.PartialSigs,
+					PartialSig{kv.keyData, kv.value})
+
+			case kv.keyType == inputRedeemScript && len(kv.keyData) == 0:
+				in.RedeemScript = kv.value
+
+			case kv.keyType == inputKeyPath:
+				kp, err := readKeyPath(kv)
+				if err != nil {
+					return err
+				}
+				in.KeyPaths = append(in.KeyPaths, kp)
+
+			case kv.keyType == inputFinalScriptSig && len(kv.keyData) == 0:
+				in.FinalScriptSig = kv.value
+
+			default:
+				in.Unknowns = append(in.Unknowns, kv.unknown())
+			}
+		}
+	}
+
+	p.Outputs = make([]Output, len(p.UnsignedTx.TxOut))
+	for i := range p.Outputs {
+		out := &p.Outputs[i]
+		pairs, err := readMap(r)
+		if err != nil {
+			return err
+		}
+		for j := range pairs {
+			kv := &pairs[j]
+			switch {
+			case kv.keyType == outputRedeemScript && len(kv.keyData) == 0:
+				out.RedeemScript = kv.value
+
+			case kv.keyType == outputKeyPath:
+				kp, err := readKeyPath(kv)
+				if err != nil {
+					return err
+				}
+				out.KeyPaths = append(out.KeyPaths, kp)
+
+			default:
+				out.Unknowns = append(out.Unknowns, kv.unknown())
+			}
+		}
+	}
+	return nil
+}
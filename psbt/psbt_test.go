@@ -0,0 +1,236 @@
+package psbt
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/btcec"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+func privKey(b byte) *btcec.PrivateKey {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{b}, 32))
+	return key
+}
+
+// testPacket returns a packet spending a P2PKH output of key1 and a 2-of-2
+// P2SH multisig output of key1 and key2, along with the redeem script of the
+// multisig output.
+func testPacket(t *testing.T, key1, key2 *btcec.PrivateKey) (*Packet, []byte) {
+	params := &chaincfg.MainNetParams
+	pk1, err := coinutil.NewAddressPubKey(key1.PubKey().SerializeCompressed(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk2, err := coinutil.NewAddressPubKey(key2.PubKey().SerializeCompressed(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2pkh, err := txscript.PayToAddrScript(pk1.AddressPubKeyHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	redeemScript, err := txscript.MultiSigScript(
+		[]*coinutil.AddressPubKey{pk1, pk2}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptAddr, err := coinutil.NewAddressScriptHash(redeemScript, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2sh, err := txscript.PayToAddrScript(scriptAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := wire.NewMsgTx()
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 0}, nil))
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil))
+	tx.AddTxOut(wire.NewTxOut(2e6, p2pkh))
+	p, err := New(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Inputs[0].PrevOut = wire.NewTxOut(1e6, p2pkh)
+	p.Inputs[1].PrevOut = wire.NewTxOut(1.5e6, p2sh)
+	p.Inputs[1].RedeemScript = redeemScript
+	p.Inputs[1].AddKeyPath(KeyPath{
+		PubKey:      pk1.ScriptAddress(),
+		Fingerprint: 0x01020304,
+		Path:        []uint32{0, 7},
+	})
+	p.Outputs[0].AddKeyPath(KeyPath{
+		PubKey:      pk1.ScriptAddress(),
+		Fingerprint: 0x01020304,
+		Path:        []uint32{1, 2},
+	})
+	return p, redeemScript
+}
+
+func sign(t *testing.T, p *Packet, i int, script []byte, key *btcec.PrivateKey) {
+	sig, err := txscript.RawTxInSignature(p.UnsignedTx, i, script,
+		txscript.SigHashAll, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Inputs[i].AddPartialSig(key.PubKey().SerializeCompressed(), sig)
+}
+
+func roundTrip(t *testing.T, p *Packet) *Packet {
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(Packet)
+	if err := decoded.Deserialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestPacketSignCombineFinalize(t *testing.T) {
+	key1, key2 := privKey(1), privKey(2)
+	p, redeemScript := testPacket(t, key1, key2)
+	p.Unknowns = []Unknown{{Key: []byte{0xfc, 1}, Value: []byte{2}}}
+
+	// Serializing a decoded packet must produce the same encoding.
+	var buf, buf2 bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := roundTrip(t, p).Serialize(&buf2); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Fatalf("Packet changed by serialization: got %x, want %x",
+			buf2.Bytes(), buf.Bytes())
+	}
+	decoded := roundTrip(t, p)
+	if !reflect.DeepEqual(decoded.Inputs[1].KeyPaths, p.Inputs[1].KeyPaths) ||
+		!reflect.DeepEqual(decoded.Unknowns, p.Unknowns) {
+		t.Errorf("Key paths or unknowns changed by serialization")
+	}
+
+	// Each signer signs a copy of the packet with its own key.
+	p1, p2 := roundTrip(t, p), roundTrip(t, p)
+	sign(t, p1, 0, p.Inputs[0].PrevOut.PkScript, key1)
+	sign(t, p1, 1, redeemScript, key1)
+	sign(t, p2, 1, redeemScript, key2)
+
+	// Neither packet holds enough signatures for the multisig input.
+	if err := p1.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if p1.Inputs[0].FinalScriptSig == nil {
+		t.Error("P2PKH input was not finalized")
+	}
+	if p1.Inputs[1].FinalScriptSig != nil || p1.Complete() {
+		t.Error("Multisig input finalized with a single signature")
+	}
+	if _, err := p1.Extract(); err != ErrIncomplete {
+		t.Errorf("Extract of incomplete packet: got error %v, want %v",
+			err, ErrIncomplete)
+	}
+
+	combined, err := Combine(roundTrip(t, p1), p2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(combined.Inputs[1].PartialSigs) != 2 {
+		t.Fatalf("Combined packet has %d signatures for the multisig "+
+			"input, want 2", len(combined.Inputs[1].PartialSigs))
+	}
+	if len(p2.Inputs[1].PartialSigs) != 1 {
+		t.Error("Combine modified a passed packet")
+	}
+	if err := combined.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if !combined.Complete() {
+		t.Fatal("Combined packet is not complete")
+	}
+	in := &combined.Inputs[1]
+	if in.PartialSigs != nil || in.RedeemScript != nil || in.KeyPaths != nil {
+		t.Error("Finalized input kept its signing data")
+	}
+
+	tx, err := roundTrip(t, combined).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, txIn := range tx.TxIn {
+		vm, err := txscript.NewEngine(p.Inputs[i].PrevOut.PkScript, tx, i,
+			txscript.StandardVerifyFlags, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := vm.Execute(); err != nil {
+			t.Errorf("Input %d with script %x does not verify: %v", i,
+				txIn.SignatureScript, err)
+		}
+	}
+}
+
+func TestPacketErrors(t *testing.T) {
+	key1, key2 := privKey(1), privKey(2)
+	p, redeemScript := testPacket(t, key1, key2)
+
+	// A signature of another key for the P2PKH input is never used, and
+	// an invalid signature fails finalization.
+	sign(t, p, 0, p.Inputs[0].PrevOut.PkScript, key2)
+	if err := p.Finalize(); err != nil || p.Inputs[0].FinalScriptSig != nil {
+		t.Errorf("Finalized P2PKH input signed by another key (err %v)", err)
+	}
+	sign(t, p, 1, p.Inputs[0].PrevOut.PkScript, key1)
+	sign(t, p, 1, p.Inputs[0].PrevOut.PkScript, key2)
+	if err := p.Finalize(); err == nil {
+		t.Error("Finalized input with invalid signatures")
+	}
+	sign(t, p, 1, redeemScript, key1)
+	sign(t, p, 1, redeemScript, key2)
+	if err := p.Finalize(); err != nil || p.Inputs[1].FinalScriptSig == nil {
+		t.Errorf("Replaced signatures were not finalized (err %v)", err)
+	}
+
+	other, _ := testPacket(t, key2, key1)
+	if _, err := Combine(p, other); err != ErrTxMismatch {
+		t.Errorf("Combine of different transactions: got error %v, "+
+			"want %v", err, ErrTxMismatch)
+	}
+
+	signed := p.UnsignedTx.Copy()
+	signed.TxIn[0].SignatureScript = []byte{txscript.OP_TRUE}
+	if _, err := New(signed); err != ErrSignedTx {
+		t.Errorf("New with signed transaction: got error %v, want %v",
+			err, ErrSignedTx)
+	}
+
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	truncated := buf.Bytes()[:buf.Len()-1]
+	if err := new(Packet).Deserialize(bytes.NewReader(truncated)); err != ErrMalformed {
+		t.Errorf("Deserialize of truncated packet: got error %v, want %v",
+			err, ErrMalformed)
+	}
+
+	// Packets of other versions, and BIP0174 packets, are refused.
+	versioned := append([]byte(nil), buf.Bytes()...)
+	versioned[len(magic)]++
+	if err := new(Packet).Deserialize(bytes.NewReader(versioned)); err != ErrUnknownVersion {
+		t.Errorf("Deserialize of another version: got error %v, want %v",
+			err, ErrUnknownVersion)
+	}
+	bip174 := append([]byte{'p', 's', 'b', 't', 0xff},
+		buf.Bytes()[len(magic)+1:]...)
+	if err := new(Packet).Deserialize(bytes.NewReader(bip174)); err != ErrMalformed {
+		t.Errorf("Deserialize of BIP0174 packet: got error %v, want %v",
+			err, ErrMalformed)
+	}
+}
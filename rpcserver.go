@@ -39,6 +39,7 @@ import (
 	"time"
 
 	"github.com/conseweb/coinutil"
+	"github.com/conseweb/coinutil/hdkeychain"
 	"github.com/conseweb/stcd/btcec"
 	"github.com/conseweb/stcd/btcjson"
 	"github.com/conseweb/stcd/chaincfg"
@@ -46,8 +47,14 @@ import (
 	"github.com/conseweb/stcd/wire"
 	"github.com/conseweb/stcrpcclient"
 	"github.com/conseweb/stcwallet/chain"
+	"github.com/conseweb/stcwallet/internal/bip39"
+	"github.com/conseweb/stcwallet/internal/cfgutil"
+	"github.com/conseweb/stcwallet/internal/walletjson"
+	"github.com/conseweb/stcwallet/psbt"
+	"github.com/conseweb/stcwallet/signer"
 	"github.com/conseweb/stcwallet/waddrmgr"
 	"github.com/conseweb/stcwallet/wallet"
+	"github.com/conseweb/stcwallet/walletdb"
 	"github.com/conseweb/stcwallet/wtxmgr"
 	"github.com/conseweb/websocket"
 )
@@ -99,6 +106,11 @@ var (
 		Message: "Request requires a wallet but wallet has not loaded yet",
 	}
 
+	ErrWalletNotFound = btcjson.RPCError{
+		Code:    btcjson.ErrRPCWallet,
+		Message: "Requested wallet does not exist or is not loaded",
+	}
+
 	ErrWalletUnlockNeeded = btcjson.RPCError{
 		Code:    btcjson.ErrRPCWalletUnlockNeeded,
 		Message: "Enter the wallet passphrase with walletpassphrase first",
@@ -118,6 +130,18 @@ var (
 		Code:    btcjson.ErrRPCInvalidParameter,
 		Message: "Account name is reserved by RPC server",
 	}
+
+	ErrOfflineWallet = btcjson.RPCError{
+		Code: btcjson.ErrRPCWallet,
+		Message: "Request is not available to an offline wallet -- " +
+			"only signing requests are served",
+	}
+
+	ErrIncompleteTx = btcjson.RPCError{
+		Code: btcjson.ErrRPCWallet,
+		Message: "Transactions of watching-only and multisig accounts " +
+			"can not be fully signed -- use createunsignedtransaction",
+	}
 )
 
 // TODO(jrick): There are several error paths which 'replace' various errors
@@ -269,6 +293,15 @@ type rpcServer struct {
 	handlerLookup func(string) (requestHandler, bool)
 	handlerMu     sync.Mutex
 
+	// walletDB is the database of the default wallet, closed once the
+	// server shut down.
+	walletDB walletdb.DB
+
+	// loader holds the named wallets served in addition to the default
+	// wallet.  walletMtx serializes creating and loading wallets.
+	loader    *walletLoader
+	walletMtx sync.Mutex
+
 	listeners []net.Listener
 	authsha   [sha256.Size]byte
 	upgrader  websocket.Upgrader
@@ -286,6 +319,7 @@ type rpcServer struct {
 	connectedBlocks    <-chan wtxmgr.BlockMeta
 	disconnectedBlocks <-chan wtxmgr.BlockMeta
 	relevantTxs        <-chan chain.RelevantTx
+	conflictedTxs      <-chan wallet.ConflictedTx
 	managerLocked      <-chan bool
 	confirmedBalance   <-chan coinutil.Amount
 	unconfirmedBalance <-chan coinutil.Amount
@@ -314,6 +348,7 @@ func newRPCServer(listenAddrs []string, maxPost, maxWebsockets int64) (*rpcServe
 	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
 	s := rpcServer{
 		handlerLookup:       unloadedWalletHandlerFunc,
+		loader:              newWalletLoader(),
 		authsha:             sha256.Sum256([]byte(auth)),
 		maxPostClients:      maxPost,
 		maxWebsocketClients: maxWebsockets,
@@ -427,6 +462,14 @@ func (s *rpcServer) Start() {
 				http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
 				return
 			}
+			// Websocket clients, and the notifications they
+			// register for, are only served by the default wallet.
+			if isWebsocketUpgrade(r) {
+				http.Error(w, "400 Bad Request: websocket "+
+					"connections are only served at /ws, by "+
+					"the default wallet", http.StatusBadRequest)
+				return
+			}
 			s.wg.Add(1)
 			s.PostClientRPC(w, r)
 			s.wg.Done()
@@ -495,6 +538,9 @@ func (s *rpcServer) Stop() {
 	}
 	s.handlerMu.Unlock()
 
+	// Stop the named wallets and close their databases.
+	s.loader.UnloadAll()
+
 	// Stop all the listeners.
 	for _, listener := range s.listeners {
 		err := listener.Close()
@@ -521,6 +567,15 @@ func (s *rpcServer) WaitForShutdown() {
 	s.handlerMu.Unlock()
 
 	s.wg.Wait()
+
+	s.handlerMu.Lock()
+	if s.walletDB != nil {
+		if err := s.walletDB.Close(); err != nil {
+			log.Errorf("Cannot close wallet database: %v", err)
+		}
+		s.walletDB = nil
+	}
+	s.handlerMu.Unlock()
 }
 
 // SetWallet sets the wallet dependency component needed to run a fully
@@ -547,6 +602,26 @@ func (s *rpcServer) SetWallet(wallet *wallet.Wallet) {
 	}
 }
 
+// loadDefaultWallet serves an opened wallet as the default wallet and starts
+// it, either offline or synced with the chain server.  The wallet database is
+// closed once the server shut down.
+func (s *rpcServer) loadDefaultWallet(w *wallet.Wallet, db walletdb.DB) {
+	if remote := s.loader.Signer(); remote != nil {
+		w.Signer = signer.Fallback(w.Signer, remote)
+	}
+
+	s.handlerMu.Lock()
+	s.walletDB = db
+	s.handlerMu.Unlock()
+	s.SetWallet(w)
+
+	if s.loader.Offline() {
+		w.StartOffline()
+		return
+	}
+	go syncWithChainServer(w, s.SetChainServer, s.quit)
+}
+
 // SetChainServer sets the chain server client component needed to run a fully
 // functional bitcoin wallet RPC server.  This should be set even before the
 // client is connected, as any request handlers should return the error for
@@ -565,24 +640,86 @@ func (s *rpcServer) SetChainServer(chainSvr *chain.Client) {
 	}
 }
 
+// SetOffline restricts the server to the requests of an offline wallet, which
+// is never attached to a chain server and only signs transactions created
+// elsewhere.  Every other request, including those which would be passed
+// through to the chain server, errors with ErrOfflineWallet.
+func (s *rpcServer) SetOffline() {
+	defer s.handlerMu.Unlock()
+	s.handlerMu.Lock()
+
+	s.handlerLookup = offlineHandlerFunc
+	s.loader.SetOffline()
+}
+
 // HandlerClosure creates a closure function for handling requests of the given
-// method.  This may be a request that is handled directly by btcwallet, or
-// a chain server request that is handled by passing the request down to btcd.
+// method by the named wallet, or by the default wallet if walletName is empty.
+// This may be a request that is handled directly by btcwallet, or a chain
+// server request that is handled by passing the request down to btcd.
+// Requests managing the loaded wallets are handled by the server itself.
 //
 // NOTE: These handlers do not handle special cases, such as the authenticate
 // method.  Each of these must be checked beforehand (the method is already
 // known) and handled accordingly.
-func (s *rpcServer) HandlerClosure(method string) requestHandlerClosure {
+func (s *rpcServer) HandlerClosure(walletName, method string) requestHandlerClosure {
+	if handler := rpcHandlers[method].serverHandler; handler != nil {
+		return func(req *btcjson.Request) (interface{}, *btcjson.RPCError) {
+			cmd, err := walletjson.UnmarshalCmd(req)
+			if err != nil {
+				return nil, btcjson.ErrRPCInvalidRequest
+			}
+			res, err := handler(s, cmd)
+			if err != nil {
+				return nil, jsonError(err)
+			}
+			return res, nil
+		}
+	}
+
+	if walletName != "" {
+		lw := s.loader.Wallet(walletName)
+		if lw == nil {
+			return func(*btcjson.Request) (interface{}, *btcjson.RPCError) {
+				return nil, &ErrWalletNotFound
+			}
+		}
+		chainSvr := lw.ChainServer()
+		handlerLookup := lookupAnyHandler
+		switch {
+		case s.loader.Offline():
+			handlerLookup = offlineHandlerFunc
+		case chainSvr == nil:
+			handlerLookup = unloadedWalletHandlerFunc
+		}
+		closure := handlerClosure(handlerLookup, method, lw.wallet, chainSvr)
+
+		// The wallet is kept loaded until the request finished, and
+		// requests are refused once it is being unloaded.
+		return func(req *btcjson.Request) (interface{}, *btcjson.RPCError) {
+			if !lw.acquire() {
+				return nil, &ErrWalletNotFound
+			}
+			defer lw.release()
+			return closure(req)
+		}
+	}
+
 	defer s.handlerMu.Unlock()
 	s.handlerMu.Lock()
 
 	// With the lock held, make copies of these pointers for the closure.
-	wallet := s.wallet
-	chainSvr := s.chainSvr
+	return handlerClosure(s.handlerLookup, method, s.wallet, s.chainSvr)
+}
 
-	if handler, ok := s.handlerLookup(method); ok {
+// handlerClosure creates a closure function for handling requests of the given
+// method with the handler looked up by handlerLookup, or by passing the
+// request down to the chain server if no handler is found.
+func handlerClosure(handlerLookup func(string) (requestHandler, bool),
+	method string, wallet *wallet.Wallet, chainSvr *chain.Client) requestHandlerClosure {
+
+	if handler, ok := handlerLookup(method); ok {
 		return func(req *btcjson.Request) (interface{}, *btcjson.RPCError) {
-			cmd, err := btcjson.UnmarshalCmd(req)
+			cmd, err := walletjson.UnmarshalCmd(req)
 			if err != nil {
 				return nil, btcjson.ErrRPCInvalidRequest
 			}
@@ -633,6 +770,12 @@ func (s *rpcServer) checkAuthHeader(r *http.Request) error {
 	return nil
 }
 
+// isWebsocketUpgrade returns whether the HTTP request r asks to upgrade the
+// connection to a websocket.
+func isWebsocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
 // throttledFn wraps an http.HandlerFunc with throttling of concurrent active
 // clients by responding with an HTTP 429 when the threshold is crossed.
 func throttledFn(threshold int64, f http.HandlerFunc) http.Handler {
@@ -800,7 +943,7 @@ out:
 
 			default:
 				req := req // Copy for the closure
-				f := s.HandlerClosure(req.Method)
+				f := s.HandlerClosure("", req.Method)
 				wsc.wg.Add(1)
 				go func() {
 					resp, jsonErr := f(&req)
@@ -903,7 +1046,10 @@ func (s *rpcServer) WebsocketClientRPC(wsc *websocketClient) {
 // that may be read from a client.  This is currently limited to 4MB.
 const maxRequestSize = 1024 * 1024 * 4
 
-// PostClientRPC processes and replies to a JSON-RPC client request.
+// PostClientRPC processes and replies to a JSON-RPC client request.  Requests
+// posted to the /wallet/<name> path are handled by the named wallet, and all
+// other requests by the default wallet.  Named wallets are only served to
+// posted requests, never to websocket clients.
 func (s *rpcServer) PostClientRPC(w http.ResponseWriter, r *http.Request) {
 	body := http.MaxBytesReader(w, r.Body, maxRequestSize)
 	rpcRequest, err := ioutil.ReadAll(body)
@@ -948,7 +1094,8 @@ func (s *rpcServer) PostClientRPC(w http.ResponseWriter, r *http.Request) {
 		s.Stop()
 		res = "btcwallet stopping"
 	default:
-		res, jsonErr = s.HandlerClosure(req.Method)(&req)
+		res, jsonErr = s.HandlerClosure(walletNameFromPath(r.URL.Path),
+			req.Method)(&req)
 	}
 
 	// Marshal and send.
@@ -977,6 +1124,8 @@ type (
 
 	relevantTx chain.RelevantTx
 
+	conflictedTx wallet.ConflictedTx
+
 	managerLocked bool
 
 	confirmedBalance   coinutil.Amount
@@ -1021,6 +1170,16 @@ func (t relevantTx) notificationCmds(w *wallet.Wallet) []interface{} {
 	return ntfns
 }
 
+func (t conflictedTx) notificationCmds(w *wallet.Wallet) []interface{} {
+	var conflictedBy *string
+	if t.ConflictedBy != nil {
+		s := t.ConflictedBy.String()
+		conflictedBy = &s
+	}
+	n := walletjson.NewTxConflictedNtfn(t.Hash.String(), conflictedBy)
+	return []interface{}{n}
+}
+
 func (l managerLocked) notificationCmds(w *wallet.Wallet) []interface{} {
 	n := btcjson.NewWalletLockStateNtfn(bool(l))
 	return []interface{}{n}
@@ -1053,6 +1212,8 @@ out:
 			s.enqueueNotification <- blockDisconnected(n)
 		case n := <-s.relevantTxs:
 			s.enqueueNotification <- relevantTx(n)
+		case n := <-s.conflictedTxs:
+			s.enqueueNotification <- conflictedTx(n)
 		case n := <-s.managerLocked:
 			s.enqueueNotification <- managerLocked(n)
 		case n := <-s.confirmedBalance:
@@ -1083,6 +1244,12 @@ out:
 					"transaction notifications: %v", err)
 				continue
 			}
+			conflictedTxs, err := s.wallet.ListenConflictedTxs()
+			if err != nil {
+				log.Errorf("Could not register for conflicted "+
+					"transaction notifications: %v", err)
+				continue
+			}
 			managerLocked, err := s.wallet.ListenLockStatus()
 			if err != nil {
 				log.Errorf("Could not register for manager "+
@@ -1104,6 +1271,7 @@ out:
 			s.connectedBlocks = connectedBlocks
 			s.disconnectedBlocks = disconnectedBlocks
 			s.relevantTxs = relevantTxs
+			s.conflictedTxs = conflictedTxs
 			s.managerLocked = managerLocked
 			s.confirmedBalance = confirmedBalance
 			s.unconfirmedBalance = unconfirmedBalance
@@ -1123,6 +1291,7 @@ func (s *rpcServer) drainNotifications() {
 		case <-s.connectedBlocks:
 		case <-s.disconnectedBlocks:
 		case <-s.relevantTxs:
+		case <-s.conflictedTxs:
 		case <-s.managerLocked:
 		case <-s.confirmedBalance:
 		case <-s.unconfirmedBalance:
@@ -1229,9 +1398,17 @@ out:
 // catch-all error code, btcjson.ErrRPCWallet.
 type requestHandler func(*wallet.Wallet, *chain.Client, interface{}) (interface{}, error)
 
+// serverRequestHandler is a handler function for requests managing the wallets
+// served by the RPC server rather than a single wallet.
+type serverRequestHandler func(*rpcServer, interface{}) (interface{}, error)
+
 var rpcHandlers = map[string]struct {
 	handler requestHandler
 
+	// serverHandler is set instead of handler for the methods which are
+	// handled by the server itself, whichever wallet the request is for.
+	serverHandler serverRequestHandler
+
 	// Function variables cannot be compared against anything but nil, so
 	// use a boolean to record whether help generation is necessary.  This
 	// is used by the tests to ensure that help can be generated for every
@@ -1243,9 +1420,12 @@ var rpcHandlers = map[string]struct {
 	noHelp bool
 }{
 	// Reference implementation wallet methods (implemented)
+	"abandontransaction":     {handler: AbandonTransaction},
 	"addmultisigaddress":     {handler: AddMultiSigAddress},
+	"backupwallet":           {handler: BackupWallet},
 	"createmultisig":         {handler: CreateMultiSig},
 	"dumpprivkey":            {handler: DumpPrivKey},
+	"dumpwallet":             {handler: DumpWallet},
 	"getaccount":             {handler: GetAccount},
 	"getaccountaddress":      {handler: GetAccountAddress},
 	"getaddressesbyaccount":  {handler: GetAddressesByAccount},
@@ -1258,10 +1438,13 @@ var rpcHandlers = map[string]struct {
 	"getreceivedbyaccount":   {handler: GetReceivedByAccount},
 	"getreceivedbyaddress":   {handler: GetReceivedByAddress},
 	"gettransaction":         {handler: GetTransaction},
+	"getwalletinfo":          {handler: GetWalletInfo},
 	"help":                   {handler: Help},
 	"importprivkey":          {handler: ImportPrivKey},
+	"importwallet":           {handler: ImportWallet},
 	"keypoolrefill":          {handler: KeypoolRefill},
 	"listaccounts":           {handler: ListAccounts},
+	"listaddressgroupings":   {handler: ListAddressGroupings},
 	"listlockunspent":        {handler: ListLockUnspent},
 	"listreceivedbyaccount":  {handler: ListReceivedByAccount},
 	"listreceivedbyaddress":  {handler: ListReceivedByAddress},
@@ -1281,13 +1464,6 @@ var rpcHandlers = map[string]struct {
 	"walletpassphrase":       {handler: WalletPassphrase},
 	"walletpassphrasechange": {handler: WalletPassphraseChange},
 
-	// Reference implementation methods (still unimplemented)
-	"backupwallet":         {handler: Unimplemented, noHelp: true},
-	"dumpwallet":           {handler: Unimplemented, noHelp: true},
-	"getwalletinfo":        {handler: Unimplemented, noHelp: true},
-	"importwallet":         {handler: Unimplemented, noHelp: true},
-	"listaddressgroupings": {handler: Unimplemented, noHelp: true},
-
 	// Reference methods which can't be implemented by btcwallet due to
 	// design decision differences
 	"encryptwallet": {handler: Unsupported, noHelp: true},
@@ -1295,18 +1471,39 @@ var rpcHandlers = map[string]struct {
 	"setaccount":    {handler: Unsupported, noHelp: true},
 
 	// Extensions to the reference client JSON-RPC API
-	"createnewaccount":     {handler: CreateNewAccount},
-	"exportwatchingwallet": {handler: ExportWatchingWallet},
-	"getbestblock":         {handler: GetBestBlock},
+	"bumpfee":                   {handler: BumpFee},
+	"combinepsbt":               {handler: CombinePSBT},
+	"consolidateunspent":        {handler: ConsolidateUnspent},
+	"createnewaccount":          {handler: CreateNewAccount},
+	"createunsignedtransaction": {handler: CreateUnsignedTransaction},
+	"createwallet":              {serverHandler: (*rpcServer).CreateWallet},
+	"discoveraccounts":          {handler: DiscoverAccounts},
+	"exportaccountkey":          {handler: ExportAccountKey},
+	"exportwatchingwallet":      {handler: ExportWatchingWallet},
+	"finalizepsbt":              {handler: FinalizePSBT},
+	"getbestblock":              {handler: GetBestBlock},
+	"getdiscoverystatus":        {handler: GetDiscoveryStatus},
 	// This was an extension but the reference implementation added it as
 	// well, but with a different API (no account parameter).  It's listed
 	// here because it hasn't been update to use the reference
 	// implemenation's API.
 	"getunconfirmedbalance":   {handler: GetUnconfirmedBalance},
+	"importxpub":              {handler: ImportXpub},
 	"listaddresstransactions": {handler: ListAddressTransactions},
 	"listalltransactions":     {handler: ListAllTransactions},
+	"listwallets":             {serverHandler: (*rpcServer).ListWallets},
+	"loadwallet":              {serverHandler: (*rpcServer).LoadWallet},
+	"makemultisigaccount":     {handler: MakeMultisigAccount},
+	"payforparent":            {handler: PayForParent},
 	"renameaccount":           {handler: RenameAccount},
+	"sendfromoutpoints":       {handler: SendFromOutpoints},
+	"sendpsbt":                {handler: SendPSBT},
+	"settxcomment":            {handler: SetTxComment},
+	"sweepprivkey":            {handler: SweepPrivKey},
+	"unloadwallet":            {serverHandler: (*rpcServer).UnloadWallet},
+	"walletcreatefundedpsbt":  {handler: WalletCreateFundedPSBT},
 	"walletislocked":          {handler: WalletIsLocked},
+	"walletprocesspsbt":       {handler: WalletProcessPSBT},
 }
 
 // Unimplemented handles an unimplemented RPC request with the
@@ -1384,6 +1581,43 @@ func missingWalletHandlerFunc(method string) (f requestHandler, ok bool) {
 	return
 }
 
+// offlineHandlers are the methods served by an offline wallet.  They sign
+// transactions and messages given everything needed to sign, such as the
+// previous outputs spent by a transaction, without the chain server.
+var offlineHandlers = map[string]struct{}{
+	"combinepsbt":            {},
+	"exportaccountkey":       {},
+	"exportwatchingwallet":   {},
+	"finalizepsbt":           {},
+	"help":                   {},
+	"signmessage":            {},
+	"signrawtransaction":     {},
+	"validateaddress":        {},
+	"verifymessage":          {},
+	"walletislocked":         {},
+	"walletlock":             {},
+	"walletpassphrase":       {},
+	"walletpassphrasechange": {},
+	"walletprocesspsbt":      {},
+}
+
+// OfflineWallet is the handler func that is run for requests which are not
+// served by an offline wallet.
+func OfflineWallet(*wallet.Wallet, *chain.Client, interface{}) (interface{}, error) {
+	return nil, &ErrOfflineWallet
+}
+
+// offlineHandlerFunc looks up the request handler func for the passed method
+// if it is served by an offline wallet.  All other methods, including those
+// which would be passed through to the chain server, return a specialized
+// handler func erroring with ErrOfflineWallet, so ok is always true.
+func offlineHandlerFunc(method string) (f requestHandler, ok bool) {
+	if _, ok := offlineHandlers[method]; ok {
+		return rpcHandlers[method].handler, true
+	}
+	return OfflineWallet, true
+}
+
 // requestHandlerClosure is a closure over a requestHandler or passthrough
 // request with the RPC server's wallet and chain server variables as part
 // of the closure context.
@@ -1491,6 +1725,27 @@ func makeMultiSigScript(w *wallet.Wallet, keys []string, nRequired int) ([]byte,
 	return txscript.MultiSigScript(keysesPrecious, nRequired)
 }
 
+// AbandonTransaction handles an abandontransaction request by removing an
+// unmined transaction, and every unmined transaction spending it, from the
+// wallet.
+func AbandonTransaction(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.AbandonTransactionCmd)
+
+	txSha, err := wire.NewShaHashFromStr(cmd.Txid)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDecodeHexString,
+			Message: "Transaction hash string decode failed: " + err.Error(),
+		}
+	}
+
+	err = w.AbandonTx(txSha)
+	if serr, ok := err.(wtxmgr.Error); ok && serr.Code == wtxmgr.ErrInput {
+		return nil, InvalidParameterError{err}
+	}
+	return nil, err
+}
+
 // AddMultiSigAddress handles an addmultisigaddress request by adding a
 // multisig address to the given wallet.
 func AddMultiSigAddress(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
@@ -1520,6 +1775,36 @@ func AddMultiSigAddress(w *wallet.Wallet, chainSvr *chain.Client, icmd interface
 	return addr.Address().EncodeAddress(), nil
 }
 
+// BackupWallet handles a backupwallet request by writing a consistent copy of
+// the wallet database to the requested destination while the wallet continues
+// to run.  If the destination is an existing directory, the backup is written
+// to a file in that directory with the same name as the wallet database.
+func BackupWallet(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.BackupWalletCmd)
+
+	dest := cmd.Destination
+	destInfo, err := os.Stat(dest)
+	if err == nil && destInfo.IsDir() {
+		dest = filepath.Join(dest, walletDbName)
+		destInfo, err = os.Stat(dest)
+	}
+
+	// Replacing an open database file with a copy of itself would leave
+	// the running wallet writing to an unlinked file, so refuse to
+	// overwrite the database of any wallet.
+	if err == nil {
+		for _, dbPath := range walletDbPaths() {
+			dbInfo, err := os.Stat(dbPath)
+			if err == nil && os.SameFile(destInfo, dbInfo) {
+				e := errors.New("backup destination is a wallet database")
+				return nil, InvalidParameterError{e}
+			}
+		}
+	}
+
+	return nil, w.BackupWallet(dest)
+}
+
 // CreateMultiSig handles an createmultisig request by returning a
 // multisig address for the given inputs.
 func CreateMultiSig(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
@@ -1562,16 +1847,65 @@ func DumpPrivKey(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (in
 	return key, err
 }
 
-// DumpWallet handles a dumpwallet request by returning  all private
-// keys in a wallet, or an appropiate error if the wallet is locked.
-// TODO: finish this to match bitcoind by writing the dump to a file.
+// DumpWallet handles a dumpwallet request by writing all private keys and
+// scripts of the wallet, along with the information needed to restore them,
+// to a new file.  The file must not already exist.
 func DumpWallet(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
-	keys, err := w.DumpPrivKeys()
-	if waddrmgr.IsError(err, waddrmgr.ErrLocked) {
-		return nil, &ErrWalletUnlockNeeded
+	cmd := icmd.(*btcjson.DumpWalletCmd)
+
+	fi, err := os.OpenFile(cmd.Filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = w.DumpWallet(fi)
+	if closeErr := fi.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(cmd.Filename)
+		if waddrmgr.IsError(err, waddrmgr.ErrLocked) {
+			return nil, &ErrWalletUnlockNeeded
+		}
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// ExportAccountKey handles an exportaccountkey request by returning the BIP0044
+// account extended public key of an account, or its extended private key when
+// requested.  Exporting the private key requires the wallet to be unlocked.
+func ExportAccountKey(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.ExportAccountKeyCmd)
+
+	account, err := w.Manager.LookupAccount(cmd.Account)
+	if err != nil {
+		return nil, err
 	}
 
-	return keys, err
+	if !*cmd.Private {
+		acctKey, err := w.Manager.AccountPubKey(account)
+		if err != nil {
+			return nil, err
+		}
+		return acctKey.String(), nil
+	}
+
+	acctKey, err := w.Manager.AccountPrivKey(account)
+	switch {
+	case waddrmgr.IsError(err, waddrmgr.ErrLocked):
+		return nil, &ErrWalletUnlockNeeded
+	case waddrmgr.IsError(err, waddrmgr.ErrWatchingOnly):
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCWallet,
+			Message: "Account is watching-only and has no private key",
+		}
+	case err != nil:
+		return nil, err
+	}
+	xprv := acctKey.String()
+	acctKey.Zero()
+	return xprv, nil
 }
 
 // ExportWatchingWallet handles an exportwatchingwallet request by exporting the
@@ -1587,7 +1921,7 @@ func ExportWatchingWallet(w *wallet.Wallet, chainSvr *chain.Client, icmd interfa
 		}
 	}
 
-	return w.ExportWatchingWallet(cfg.WalletPass)
+	return w.ExportWatchingWallet()
 }
 
 // GetAddressesByAccount handles a getaddressesbyaccount request by returning
@@ -1679,11 +2013,14 @@ func GetInfo(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interf
 		return nil, err
 	}
 
-	// TODO(davec): This should probably have a database version as opposed
-	// to using the manager version.
-	info.WalletVersion = int32(waddrmgr.LatestMgrVersion)
+	version, err := w.Manager.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	info.WalletVersion = int32(version)
 	info.Balance = bal.ToBTC()
-	info.PaytxFee = w.FeeIncrement.ToBTC()
+	info.PaytxFee = (w.FeeRate * 1000).ToBTC()
 	// We don't set the following since they don't make much sense in the
 	// wallet architecture:
 	//  - unlocked_until
@@ -1692,6 +2029,65 @@ func GetInfo(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interf
 	return info, nil
 }
 
+// GetWalletInfo handles a getwalletinfo request by returning a summary of the
+// state of the wallet.
+func GetWalletInfo(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	confirmed, err := w.CalculateBalance(1)
+	if err != nil {
+		return nil, err
+	}
+	total, err := w.CalculateBalance(0)
+	if err != nil {
+		return nil, err
+	}
+	txCount, err := w.TxStore.TxCount()
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts, addrs int
+	err = w.Manager.ForEachAccount(func(uint32) error {
+		accounts++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = w.Manager.ForEachActiveAddress(func(coinutil.Address) error {
+		addrs++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	locked := w.Locked()
+	var unlockedUntil int64
+	if expiry := w.UnlockedUntil(); !locked && !expiry.IsZero() {
+		unlockedUntil = expiry.Unix()
+	}
+
+	version, err := w.Manager.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	syncedTo := w.Manager.SyncedTo()
+	return &walletjson.GetWalletInfoResult{
+		WalletVersion:      int32(version),
+		Balance:            confirmed.ToBTC(),
+		UnconfirmedBalance: (total - confirmed).ToBTC(),
+		TxCount:            txCount,
+		AccountCount:       accounts,
+		AddressCount:       addrs,
+		Locked:             locked,
+		UnlockedUntil:      unlockedUntil,
+		SyncedToHash:       syncedTo.Hash.String(),
+		SyncedToHeight:     syncedTo.Height,
+		Rescanning:         w.Rescanning(),
+	}, nil
+}
+
 func decodeAddress(s string, params *chaincfg.Params) (coinutil.Address, error) {
 	addr, err := coinutil.DecodeAddress(s, params)
 	if err != nil {
@@ -1820,17 +2216,11 @@ func ImportPrivKey(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (
 	return nil, err
 }
 
-// KeypoolRefill handles the keypoolrefill command. Since we handle the keypool
-// automatically this does nothing since refilling is never manually required.
-func KeypoolRefill(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
-	return nil, nil
-}
-
-// CreateNewAccount handles a createnewaccount request by creating and
-// returning a new account. If the last account has no transaction history
-// as per BIP 0044 a new account cannot be created so an error will be returned.
-func CreateNewAccount(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
-	cmd := icmd.(*btcjson.CreateNewAccountCmd)
+// ImportXpub handles an importxpub request by creating a watching-only account
+// from the extended public key of an account whose private keys are kept
+// elsewhere.
+func ImportXpub(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.ImportXpubCmd)
 
 	// The wildcard * is reserved by the rpc server with the special meaning
 	// of "all accounts", so disallow naming accounts to this string.
@@ -1838,75 +2228,547 @@ func CreateNewAccount(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}
 		return nil, &ErrReservedAccountName
 	}
 
-	// Check that we are within the maximum allowed non-empty accounts limit.
-	account, err := w.Manager.LastAccount()
+	acctKey, err := hdkeychain.NewKeyFromString(cmd.Xpub)
 	if err != nil {
-		return nil, err
-	}
-	if account > maxEmptyAccounts {
-		used, err := w.AccountUsed(account)
-		if err != nil {
-			return nil, err
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Extended key decode failed: " + err.Error(),
 		}
-		if !used {
-			return nil, errors.New("cannot create account: " +
-				"previous account has no transaction history")
+	}
+	if acctKey.IsPrivate() {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Extended key is private -- only public keys are imported",
 		}
 	}
-
-	_, err = w.Manager.NewAccount(cmd.Account)
-	if waddrmgr.IsError(err, waddrmgr.ErrLocked) {
+	if !acctKey.IsForNet(activeNet.Params) {
 		return nil, &btcjson.RPCError{
-			Code: btcjson.ErrRPCWalletUnlockNeeded,
-			Message: "Creating an account requires the wallet to be unlocked. " +
-				"Enter the wallet passphrase with walletpassphrase to unlock",
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Key is not intended for " + activeNet.Params.Name,
 		}
 	}
+
+	_, err = w.ImportAccount(cmd.Account, acctKey, *cmd.Rescan)
 	return nil, err
 }
 
-// RenameAccount handles a renameaccount request by renaming an account.
-// If the account does not exist an appropiate error will be returned.
-func RenameAccount(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
-	cmd := icmd.(*btcjson.RenameAccountCmd)
-
-	// The wildcard * is reserved by the rpc server with the special meaning
-	// of "all accounts", so disallow naming accounts to this string.
-	if cmd.NewAccount == "*" {
-		return nil, &ErrReservedAccountName
-	}
+// ImportWallet handles an importwallet request by importing every private
+// key and script of a file written by dumpwallet, and then rescanning the
+// blockchain for the imported addresses.
+func ImportWallet(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*btcjson.ImportWalletCmd)
 
-	// Check that given account exists
-	account, err := w.Manager.LookupAccount(cmd.OldAccount)
+	fi, err := os.Open(cmd.Filename)
 	if err != nil {
 		return nil, err
 	}
-	return nil, w.Manager.RenameAccount(account, cmd.NewAccount)
+	defer fi.Close()
+
+	err = w.ImportWallet(fi)
+	if waddrmgr.IsError(err, waddrmgr.ErrLocked) {
+		return nil, &ErrWalletUnlockNeeded
+	}
+	return nil, err
 }
 
-// GetNewAddress handles a getnewaddress request by returning a new
-// address for an account.  If the account does not exist an appropiate
-// error is returned.
-// TODO: Follow BIP 0044 and warn if number of unused addresses exceeds
-// the gap limit.
-func GetNewAddress(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
-	cmd := icmd.(*btcjson.GetNewAddressCmd)
+// KeypoolRefill handles the keypoolrefill command. Since we handle the keypool
+// automatically this does nothing since refilling is never manually required.
+func KeypoolRefill(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	return nil, nil
+}
 
-	acctName := "default"
-	if cmd.Account != nil {
-		acctName = *cmd.Account
-	}
-	account, err := w.Manager.LookupAccount(acctName)
+// MakeMultisigAccount handles a makemultisigaccount request by turning an
+// account without any addresses into an HD multisig account.  The addresses of
+// the account pay to redeem scripts requiring nrequired signatures of the keys
+// derived from the account and from the account extended public keys of the
+// other cosigners.
+func MakeMultisigAccount(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.MakeMultisigAccountCmd)
+
+	account, err := w.Manager.LookupAccount(cmd.Account)
 	if err != nil {
 		return nil, err
 	}
-	addr, err := w.NewAddress(account)
-	if err != nil {
-		return nil, err
+
+	cosignerKeys := make([]*hdkeychain.ExtendedKey, 0, len(cmd.Keys))
+	for _, s := range cmd.Keys {
+		key, err := hdkeychain.NewKeyFromString(s)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Extended key decode failed: " + err.Error(),
+			}
+		}
+		if key.IsPrivate() {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Extended key is private -- only the public keys of cosigners are used",
+			}
+		}
+		if !key.IsForNet(activeNet.Params) {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Key is not intended for " + activeNet.Params.Name,
+			}
+		}
+		cosignerKeys = append(cosignerKeys, key)
 	}
 
-	// Return the new payment address string.
-	return addr.EncodeAddress(), nil
+	err = w.Manager.MakeMultisigAccount(account, cmd.NRequired, cosignerKeys)
+	if waddrmgr.IsError(err, waddrmgr.ErrInvalidMultisig) {
+		return nil, InvalidParameterError{err}
+	}
+	return nil, err
+}
+
+// BumpFee handles a bumpfee request by replacing an unmined wallet
+// transaction with one paying a higher fee.  Upon success, the TxID of the
+// replacement transaction is returned.
+func BumpFee(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.BumpFeeCmd)
+
+	txSha, err := wire.NewShaHashFromStr(cmd.Txid)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDecodeHexString,
+			Message: "Transaction hash string decode failed: " + err.Error(),
+		}
+	}
+
+	feeRate, err := feeRateParam(cmd.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	replacement, err := w.BumpFee(txSha, feeRate)
+	if _, ok := err.(wallet.BumpFeeError); ok {
+		return nil, InvalidParameterError{err}
+	}
+	return sentTxResult(replacement, err)
+}
+
+// ConsolidateUnspent handles a consolidateunspent request by merging the
+// spendable outputs of an account worth less than a threshold into outputs
+// paying new internal addresses of the account.  Upon success, the TxIDs of
+// the created transactions are returned.
+func ConsolidateUnspent(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.ConsolidateUnspentCmd)
+
+	account, err := w.Manager.LookupAccount(cmd.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold, err := coinutil.NewAmount(cmd.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	if threshold <= 0 {
+		return nil, ErrNeedPositiveAmount
+	}
+
+	feeRate, err := feeRateParam(cmd.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes, err := w.ConsolidateUnspent(account, threshold, *cmd.MaxTxSize,
+		feeRate)
+	if err != nil {
+		if waddrmgr.IsError(err, waddrmgr.ErrLocked) {
+			return nil, &ErrWalletUnlockNeeded
+		}
+		return nil, err
+	}
+
+	txids := make([]string, len(hashes))
+	for i, hash := range hashes {
+		txids[i] = hash.String()
+	}
+	return txids, nil
+}
+
+// CreateNewAccount handles a createnewaccount request by creating and
+// returning a new account. If the last account has no transaction history
+// as per BIP 0044 a new account cannot be created so an error will be returned.
+func CreateNewAccount(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*btcjson.CreateNewAccountCmd)
+
+	// The wildcard * is reserved by the rpc server with the special meaning
+	// of "all accounts", so disallow naming accounts to this string.
+	if cmd.Account == "*" {
+		return nil, &ErrReservedAccountName
+	}
+
+	// Check that we are within the maximum allowed non-empty accounts limit.
+	account, err := w.Manager.LastAccount()
+	if err != nil {
+		return nil, err
+	}
+	if account > maxEmptyAccounts {
+		used, err := w.AccountUsed(account)
+		if err != nil {
+			return nil, err
+		}
+		if !used {
+			return nil, errors.New("cannot create account: " +
+				"previous account has no transaction history")
+		}
+	}
+
+	_, err = w.Manager.NewAccount(cmd.Account)
+	if waddrmgr.IsError(err, waddrmgr.ErrLocked) {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCWalletUnlockNeeded,
+			Message: "Creating an account requires the wallet to be unlocked. " +
+				"Enter the wallet passphrase with walletpassphrase to unlock",
+		}
+	}
+	return nil, err
+}
+
+// CreateUnsignedTransaction handles a createunsignedtransaction request by
+// creating a transaction spending unspent outputs of a watching-only or HD
+// multisig account to any number of payment addresses, without sending it.
+// Leftover inputs not sent to the payment addresses or a fee for the miner are
+// sent back to a new change address of the account.  Upon success, the
+// transaction is returned as a hexadecimal string.  Transactions of
+// watching-only accounts are unsigned and must be signed by the wallet holding
+// the account's private keys, while those of multisig accounts hold the
+// signatures of this wallet and must be signed by the other cosigners.
+func CreateUnsignedTransaction(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.CreateUnsignedTransactionCmd)
+
+	account, err := w.Manager.LookupAccount(cmd.FromAccount)
+	if err != nil {
+		return nil, err
+	}
+	watchingOnly, err := w.Manager.IsWatchingOnlyAccount(account)
+	if err != nil {
+		return nil, err
+	}
+	reqSigs, _, err := w.Manager.AccountMultisig(account)
+	if err != nil {
+		return nil, err
+	}
+	if !watchingOnly && reqSigs == 0 {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCWallet,
+			Message: "Account is neither watching-only nor multisig -- " +
+				"use sendmany to create and send a signed transaction",
+		}
+	}
+
+	minConf := int32(*cmd.MinConf)
+	if minConf < 0 {
+		return nil, ErrNeedPositiveMinconf
+	}
+
+	pairs := make(map[string]coinutil.Amount, len(cmd.Amounts))
+	for k, v := range cmd.Amounts {
+		amt, err := coinutil.NewAmount(v)
+		if err != nil {
+			return nil, err
+		}
+		pairs[k] = amt
+	}
+
+	feeRate, err := feeRateParam(cmd.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	createdTx, err := w.CreateSimpleTx(account, pairs, minConf, nil, feeRate)
+	if err != nil {
+		if err == wallet.ErrNonPositiveAmount {
+			return nil, ErrNeedPositiveAmount
+		}
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(createdTx.MsgTx.SerializeSize())
+	if err := createdTx.MsgTx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// CreateWallet handles a createwallet request by creating a wallet and loading
+// it.  The default wallet is created when the wallet name is empty.  The
+// wallet is created from the passed seed, entered either as a hexadecimal
+// value or as a BIP0039 mnemonic, or otherwise from a generated mnemonic which
+// is returned.  The generated mnemonic is never returned again.
+func (s *rpcServer) CreateWallet(icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.CreateWalletCmd)
+
+	if cmd.WalletName != "" {
+		if err := checkWalletName(cmd.WalletName); err != nil {
+			return nil, InvalidParameterError{err}
+		}
+	}
+	if cmd.PrivPassphrase == "" {
+		e := errors.New("private passphrase must not be empty")
+		return nil, InvalidParameterError{e}
+	}
+	pubPass := []byte(cfg.WalletPass)
+	if cmd.PubPassphrase != nil && *cmd.PubPassphrase != "" {
+		// The default wallet is always opened with the walletpass
+		// option at startup, so it could not be opened again with
+		// another public passphrase.
+		if cmd.WalletName == "" && *cmd.PubPassphrase != cfg.WalletPass {
+			e := errors.New("the public passphrase of the default " +
+				"wallet is set by the walletpass option")
+			return nil, InvalidParameterError{e}
+		}
+		pubPass = []byte(*cmd.PubPassphrase)
+	}
+	scryptOpts, err := scryptOptions(cmd.ScryptN, cmd.ScryptR, cmd.ScryptP)
+	if err != nil {
+		return nil, InvalidParameterError{err}
+	}
+
+	var mnemonicPass string
+	if cmd.MnemonicPassphrase != nil {
+		mnemonicPass = *cmd.MnemonicPassphrase
+	}
+	var seed []byte
+	var mnemonic string
+	if cmd.Seed != nil {
+		seed, err = decodeSeed(*cmd.Seed, mnemonicPass)
+		if err != nil {
+			return nil, InvalidParameterError{err}
+		}
+	} else {
+		entropy, err := bip39.NewEntropy(bip39.RecommendedEntropyBits)
+		if err != nil {
+			return nil, err
+		}
+		mnemonic, err = bip39.NewMnemonic(entropy)
+		if err != nil {
+			return nil, err
+		}
+		seed, err = bip39.Seed(mnemonic, mnemonicPass)
+		if err != nil {
+			return nil, InvalidParameterError{err}
+		}
+	}
+
+	s.walletMtx.Lock()
+	defer s.walletMtx.Unlock()
+
+	dir := networkDir(cfg.DataDir, activeNet.Params)
+	if cmd.WalletName != "" {
+		dir = namedWalletDir(cfg.DataDir, cmd.WalletName)
+	}
+	exists, err := cfgutil.FileExists(filepath.Join(dir, walletDbName))
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCWallet,
+			Message: "Wallet already exists",
+		}
+	}
+	err = createWalletDir(dir, seed, pubPass, []byte(cmd.PrivPassphrase),
+		scryptOpts)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Created wallet %q", cmd.WalletName)
+
+	if err := s.loadWallet(cmd.WalletName, pubPass); err != nil {
+		return nil, err
+	}
+	return walletjson.CreateWalletResult{
+		Name:     cmd.WalletName,
+		Mnemonic: mnemonic,
+	}, nil
+}
+
+// decodeSeed decodes a wallet generation seed entered either as a hexadecimal
+// value or as a BIP0039 mnemonic protected by the passed passphrase.
+func decodeSeed(seedStr, mnemonicPass string) ([]byte, error) {
+	seedStr = strings.TrimSpace(strings.ToLower(seedStr))
+
+	// Mnemonics are the only seeds containing spaces.
+	if strings.ContainsAny(seedStr, " \t") {
+		if _, err := bip39.MnemonicEntropy(seedStr); err != nil {
+			return nil, err
+		}
+		return bip39.Seed(seedStr, mnemonicPass)
+	}
+
+	seed, err := hex.DecodeString(seedStr)
+	if err != nil || len(seed) < hdkeychain.MinSeedBytes ||
+		len(seed) > hdkeychain.MaxSeedBytes {
+
+		return nil, fmt.Errorf("seed must be a mnemonic of 12 to 24 "+
+			"words, or a hexadecimal value of %d to %d bits",
+			hdkeychain.MinSeedBytes*8, hdkeychain.MaxSeedBytes*8)
+	}
+	return seed, nil
+}
+
+// scryptOptions returns the scrypt options used to derive the passphrase keys
+// of a created wallet.  Each unset parameter has its default value.
+func scryptOptions(n, r, p *int) (*waddrmgr.ScryptOptions, error) {
+	opts := waddrmgr.DefaultScryptOptions
+	if n != nil {
+		opts.N = *n
+	}
+	if r != nil {
+		opts.R = *r
+	}
+	if p != nil {
+		opts.P = *p
+	}
+	if opts.N <= 1 || opts.N&(opts.N-1) != 0 {
+		return nil, errors.New("scrypt N must be a power of 2 greater than 1")
+	}
+	if opts.R <= 0 || opts.P <= 0 {
+		return nil, errors.New("scrypt r and p must be positive")
+	}
+	return &opts, nil
+}
+
+// DiscoverAccounts handles a discoveraccounts request by recovering the
+// accounts and addresses of a wallet restored from an existing seed.  The
+// request returns once discovery started, and its progress is reported by
+// getdiscoverystatus.
+func DiscoverAccounts(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.DiscoverAccountsCmd)
+
+	gapLimit := w.Manager.GapLimit()
+	if gapLimit == 0 {
+		gapLimit = wallet.DefaultGapLimit
+	}
+	if cmd.GapLimit != nil {
+		if *cmd.GapLimit == 0 {
+			return nil, InvalidParameterError{
+				errors.New("gap limit must be positive"),
+			}
+		}
+		gapLimit = *cmd.GapLimit
+	}
+
+	err := w.DiscoverAccounts(gapLimit)
+	switch {
+	case waddrmgr.IsError(err, waddrmgr.ErrLocked):
+		return nil, &ErrWalletUnlockNeeded
+	case err == wallet.ErrDiscoveryRunning:
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCWallet,
+			Message: err.Error(),
+		}
+	}
+	return nil, err
+}
+
+// GetDiscoveryStatus handles a getdiscoverystatus request by returning the
+// progress of the running account discovery, or the result of the last one.
+func GetDiscoveryStatus(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	status := w.DiscoveryStatus()
+	result := &walletjson.GetDiscoveryStatusResult{
+		Running:   status.Running,
+		Account:   status.Account,
+		Rescanned: status.Rescanned,
+	}
+	if status.Err != nil {
+		result.Error = status.Err.Error()
+	}
+	return result, nil
+}
+
+// PayForParent handles a payforparent request by creating a child
+// transaction spending the wallet outputs of an unmined transaction, and any
+// passed inputs, with a fee high enough for both transactions to be mined.
+// Upon success, the TxID of the child transaction is returned.
+func PayForParent(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.PayForParentCmd)
+
+	txSha, err := wire.NewShaHashFromStr(cmd.Txid)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDecodeHexString,
+			Message: "Transaction hash string decode failed: " + err.Error(),
+		}
+	}
+
+	feeRate, err := feeRateParam(cmd.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []wire.OutPoint
+	if cmd.Inputs != nil {
+		inputs = make([]wire.OutPoint, len(*cmd.Inputs))
+		for i, input := range *cmd.Inputs {
+			hash, err := wire.NewShaHashFromStr(input.Txid)
+			if err != nil {
+				return nil, ParseError{err}
+			}
+			inputs[i] = wire.OutPoint{Hash: *hash, Index: input.Vout}
+		}
+	}
+
+	child, err := w.PayForParent(txSha, feeRate, inputs)
+	switch err.(type) {
+	case wallet.PayForParentError, wallet.OutpointError:
+		return nil, InvalidParameterError{err}
+	}
+	return sentTxResult(child, err)
+}
+
+// RenameAccount handles a renameaccount request by renaming an account.
+// If the account does not exist an appropiate error will be returned.
+func RenameAccount(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*btcjson.RenameAccountCmd)
+
+	// The wildcard * is reserved by the rpc server with the special meaning
+	// of "all accounts", so disallow naming accounts to this string.
+	if cmd.NewAccount == "*" {
+		return nil, &ErrReservedAccountName
+	}
+
+	// Check that given account exists
+	account, err := w.Manager.LookupAccount(cmd.OldAccount)
+	if err != nil {
+		return nil, err
+	}
+	return nil, w.Manager.RenameAccount(account, cmd.NewAccount)
+}
+
+// GetNewAddress handles a getnewaddress request by returning a new
+// address for an account.  If the account does not exist an appropiate
+// error is returned.
+func GetNewAddress(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*btcjson.GetNewAddressCmd)
+
+	acctName := "default"
+	if cmd.Account != nil {
+		acctName = *cmd.Account
+	}
+	account, err := w.Manager.LookupAccount(acctName)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := w.NewAddress(account)
+	if waddrmgr.IsError(err, waddrmgr.ErrGapLimit) {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCWalletKeypoolRanOut,
+			Message: "Creating an address would exceed the gap limit " +
+				"of consecutive unused addresses. Use a previously " +
+				"created address or raise the gaplimit option",
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the new payment address string.
+	return addr.EncodeAddress(), nil
 }
 
 // GetRawChangeAddress handles a getrawchangeaddress request by creating
@@ -1987,7 +2849,15 @@ func GetTransaction(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{})
 		return nil, err
 	}
 	if details == nil {
-		return nil, &ErrNoTransactionInfo
+		// Transactions removed as double spends are still reported,
+		// with the conflicting transaction in walletconflicts.
+		details, err = w.TxStore.ConflictedTxDetails(txSha)
+		if err != nil {
+			return nil, err
+		}
+		if details == nil {
+			return nil, &ErrNoTransactionInfo
+		}
 	}
 
 	syncBlock := w.Manager.SyncedTo()
@@ -2003,12 +2873,14 @@ func GetTransaction(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{})
 
 	// TODO: Add a "generated" field to this result type.  "generated":true
 	// is only added if the transaction is a coinbase.
-	ret := btcjson.GetTransactionResult{
+	ret := walletjson.GetTransactionResult{
 		TxID:            cmd.Txid,
 		Hex:             hex.EncodeToString(txBuf.Bytes()),
 		Time:            details.Received.Unix(),
 		TimeReceived:    details.Received.Unix(),
-		WalletConflicts: []string{}, // Not saved
+		Comment:         details.Metadata.Comment,
+		CommentTo:       details.Metadata.CommentTo,
+		WalletConflicts: wallet.WalletConflicts(details),
 		//Generated:     blockchain.IsCoinBaseTx(&details.MsgTx),
 	}
 
@@ -2017,6 +2889,9 @@ func GetTransaction(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{})
 		ret.BlockTime = details.Block.Time.Unix()
 		ret.Confirmations = int64(confirms(details.Block.Height, syncBlock.Height))
 	}
+	if details.Conflicted {
+		ret.Confirmations = -1
+	}
 
 	var (
 		debitTotal  coinutil.Amount
@@ -2248,6 +3123,41 @@ func ListAccounts(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (i
 	return accountBalances, nil
 }
 
+// ListAddressGroupings handles a listaddressgroupings request by returning
+// the wallet addresses grouped by common ownership as it may be inferred from
+// the wallet's transactions on the block chain.  Addresses are grouped when
+// they are spent together as transaction inputs or when one receives change
+// from a transaction spending another.
+func ListAddressGroupings(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	groupings, err := w.AddressGroupings()
+	if err != nil {
+		return nil, err
+	}
+
+	accountNames := make(map[uint32]string)
+	results := make([][]walletjson.ListAddressGroupingsResult, 0, len(groupings))
+	for _, grouping := range groupings {
+		group := make([]walletjson.ListAddressGroupingsResult, 0, len(grouping))
+		for _, ga := range grouping {
+			acctName, ok := accountNames[ga.Account]
+			if !ok {
+				acctName, err = w.Manager.AccountName(ga.Account)
+				if err != nil {
+					return nil, &ErrAccountNameNotFound
+				}
+				accountNames[ga.Account] = acctName
+			}
+			group = append(group, walletjson.ListAddressGroupingsResult{
+				Address: ga.Address.EncodeAddress(),
+				Amount:  ga.Balance.ToBTC(),
+				Account: acctName,
+			})
+		}
+		results = append(results, group)
+	}
+	return results, nil
+}
+
 // ListLockUnspent handles a listlockunspent request by returning an slice of
 // all locked outpoints.
 func ListLockUnspent(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
@@ -2527,6 +3437,86 @@ func ListUnspent(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (in
 	return w.ListUnspent(int32(*cmd.MinConf), int32(*cmd.MaxConf), addresses)
 }
 
+// ListWallets handles a listwallets request by returning the names of the
+// loaded wallets.  The default wallet, if loaded, is named by the empty
+// string.
+func (s *rpcServer) ListWallets(icmd interface{}) (interface{}, error) {
+	s.handlerMu.Lock()
+	defaultLoaded := s.wallet != nil
+	s.handlerMu.Unlock()
+
+	names := s.loader.Names()
+	if defaultLoaded {
+		names = append([]string{""}, names...)
+	}
+	return names, nil
+}
+
+// LoadWallet handles a loadwallet request by loading an existing wallet which
+// is not loaded yet.  The default wallet is loaded when the wallet name is
+// empty.
+func (s *rpcServer) LoadWallet(icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.LoadWalletCmd)
+
+	if cmd.WalletName != "" {
+		if err := checkWalletName(cmd.WalletName); err != nil {
+			return nil, InvalidParameterError{err}
+		}
+	}
+	var pubPass []byte
+	if cmd.PubPassphrase != nil {
+		pubPass = []byte(*cmd.PubPassphrase)
+	}
+
+	s.walletMtx.Lock()
+	defer s.walletMtx.Unlock()
+
+	err := s.loadWallet(cmd.WalletName, pubPass)
+	if err == errWalletNotExist {
+		return nil, &ErrWalletNotFound
+	}
+	return nil, err
+}
+
+// loadWallet loads the named wallet with the public passphrase, or the default
+// wallet if name is empty.  The public passphrase of the config is used if
+// pubPass is nil.  Callers must hold walletMtx.
+func (s *rpcServer) loadWallet(name string, pubPass []byte) error {
+	if name != "" {
+		_, err := s.loader.Load(name, pubPass)
+		return err
+	}
+
+	s.handlerMu.Lock()
+	loaded := s.wallet != nil
+	s.handlerMu.Unlock()
+	if loaded {
+		return errWalletLoaded
+	}
+
+	dir := networkDir(cfg.DataDir, activeNet.Params)
+	exists, err := cfgutil.FileExists(filepath.Join(dir, walletDbName))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errWalletNotExist
+	}
+	if pubPass == nil {
+		pubPass = []byte(cfg.WalletPass)
+	}
+	w, db, err := openWalletDir(dir, pubPass)
+	if err != nil {
+		if db != nil {
+			db.Close()
+		}
+		return err
+	}
+	s.loadDefaultWallet(w, db)
+	log.Info("Loaded the default wallet")
+	return nil
+}
+
 // LockUnspent handles the lockunspent command.
 func LockUnspent(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
 	cmd := icmd.(*btcjson.LockUnspentCmd)
@@ -2551,22 +3541,48 @@ func LockUnspent(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (in
 	return true, nil
 }
 
-// sendPairs creates and sends payment transactions.
+// feeRateParam returns the fee rate in satoshis per byte of an optional feerate
+// parameter, or zero to use the fee rate of the wallet when it is unset.
+func feeRateParam(feeRate *int64) (coinutil.Amount, error) {
+	if feeRate == nil {
+		return 0, nil
+	}
+	if *feeRate <= 0 {
+		e := errors.New("fee rate must be positive")
+		return 0, InvalidParameterError{e}
+	}
+	return coinutil.Amount(*feeRate), nil
+}
+
+// sendPairs creates and sends payment transactions.  A positive feeRate
+// overrides the fee rate of the wallet.
 // It returns the transaction hash in string format upon success
 // All errors are returned in btcjson.RPCError format
 func sendPairs(w *wallet.Wallet, amounts map[string]coinutil.Amount,
-	account uint32, minconf int32) (string, error) {
-	txSha, err := w.SendPairs(amounts, account, minconf)
+	account uint32, minconf int32, feeRate coinutil.Amount,
+	md *wtxmgr.TxMetadata) (string, error) {
+	txSha, err := w.SendPairs(amounts, account, minconf, nil, feeRate, md)
+	return sentTxResult(txSha, err)
+}
+
+// sentTxResult returns the result of a send request for the hash of the sent
+// transaction, or the error of a failed send in btcjson.RPCError format.
+func sentTxResult(txSha *wire.ShaHash, err error) (string, error) {
 	if err != nil {
 		if err == wallet.ErrNonPositiveAmount {
 			return "", ErrNeedPositiveAmount
 		}
+		if err == wallet.ErrIncompleteTx {
+			return "", &ErrIncompleteTx
+		}
 		if waddrmgr.IsError(err, waddrmgr.ErrLocked) {
 			return "", &ErrWalletUnlockNeeded
 		}
 		switch err.(type) {
 		case btcjson.RPCError:
 			return "", err
+		case wallet.OutpointError:
+			return "", InvalidParameterError{err}
 		}
 
 		return "", &btcjson.RPCError{
@@ -2580,22 +3596,29 @@ func sendPairs(w *wallet.Wallet, amounts map[string]coinutil.Amount,
 	return txShaStr, nil
 }
 
+// txMetadata returns the transaction metadata to record for the optional
+// comment parameters of a send request, or nil if neither is set.
+func txMetadata(comment, commentTo *string) *wtxmgr.TxMetadata {
+	if comment == nil && commentTo == nil {
+		return nil
+	}
+	md := new(wtxmgr.TxMetadata)
+	if comment != nil {
+		md.Comment = *comment
+	}
+	if commentTo != nil {
+		md.CommentTo = *commentTo
+	}
+	return md
+}
+
 // SendFrom handles a sendfrom RPC request by creating a new transaction
 // spending unspent transaction outputs for a wallet to another payment
 // address.  Leftover inputs not sent to the payment address or a fee for
 // the miner are sent back to a new address in the wallet.  Upon success,
 // the TxID for the created transaction is returned.
 func SendFrom(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
-	cmd := icmd.(*btcjson.SendFromCmd)
-
-	// Transaction comments are not yet supported.  Error instead of
-	// pretending to save them.
-	if cmd.Comment != nil || cmd.CommentTo != nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCUnimplemented,
-			Message: "Transaction comments are not yet supported",
-		}
-	}
+	cmd := icmd.(*walletjson.SendFromCmd)
 
 	account, err := w.Manager.LookupAccount(cmd.FromAccount)
 	if err != nil {
@@ -2619,7 +3642,58 @@ func SendFrom(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (inter
 		cmd.ToAddress: amt,
 	}
 
-	return sendPairs(w, pairs, account, minConf)
+	feeRate, err := feeRateParam(cmd.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	return sendPairs(w, pairs, account, minConf, feeRate,
+		txMetadata(cmd.Comment, cmd.CommentTo))
+}
+
+// SendFromOutpoints handles a sendfromoutpoints request by creating a new
+// transaction spending exactly the passed outpoints to any number of payment
+// addresses.  Leftover inputs not sent to the payment addresses or a fee for
+// the miner are sent back to a new address of the account.  Each outpoint must
+// be an unspent and unlocked P2PKH output of the account.  Upon success, the
+// TxID for the created transaction is returned.
+func SendFromOutpoints(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.SendFromOutpointsCmd)
+
+	account, err := w.Manager.LookupAccount(cmd.FromAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	outpoints := make([]wire.OutPoint, len(cmd.Inputs))
+	for i, input := range cmd.Inputs {
+		txSha, err := wire.NewShaHashFromStr(input.Txid)
+		if err != nil {
+			return nil, ParseError{err}
+		}
+		outpoints[i] = wire.OutPoint{Hash: *txSha, Index: input.Vout}
+	}
+	if len(outpoints) == 0 {
+		return nil, InvalidParameterError{errors.New("no outpoints to spend")}
+	}
+
+	pairs := make(map[string]coinutil.Amount, len(cmd.Amounts))
+	for k, v := range cmd.Amounts {
+		amt, err := coinutil.NewAmount(v)
+		if err != nil {
+			return nil, err
+		}
+		pairs[k] = amt
+	}
+
+	feeRate, err := feeRateParam(cmd.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	txSha, err := w.SendOutpoints(outpoints, pairs, account, feeRate,
+		txMetadata(cmd.Comment, nil))
+	return sentTxResult(txSha, err)
 }
 
 // SendMany handles a sendmany RPC request by creating a new transaction
@@ -2628,16 +3702,7 @@ func SendFrom(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (inter
 // or a fee for the miner are sent back to a new address in the wallet.
 // Upon success, the TxID for the created transaction is returned.
 func SendMany(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
-	cmd := icmd.(*btcjson.SendManyCmd)
-
-	// Transaction comments are not yet supported.  Error instead of
-	// pretending to save them.
-	if cmd.Comment != nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCUnimplemented,
-			Message: "Transaction comments are not yet supported",
-		}
-	}
+	cmd := icmd.(*walletjson.SendManyCmd)
 
 	account, err := w.Manager.LookupAccount(cmd.FromAccount)
 	if err != nil {
@@ -2660,7 +3725,13 @@ func SendMany(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (inter
 		pairs[k] = amt
 	}
 
-	return sendPairs(w, pairs, account, minConf)
+	feeRate, err := feeRateParam(cmd.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	return sendPairs(w, pairs, account, minConf, feeRate,
+		txMetadata(cmd.Comment, nil))
 }
 
 // SendToAddress handles a sendtoaddress RPC request by creating a new
@@ -2669,16 +3740,7 @@ func SendMany(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (inter
 // for the miner are sent back to a new address in the wallet.  Upon success,
 // the TxID for the created transaction is returned.
 func SendToAddress(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
-	cmd := icmd.(*btcjson.SendToAddressCmd)
-
-	// Transaction comments are not yet supported.  Error instead of
-	// pretending to save them.
-	if cmd.Comment != nil || cmd.CommentTo != nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCUnimplemented,
-			Message: "Transaction comments are not yet supported",
-		}
-	}
+	cmd := icmd.(*walletjson.SendToAddressCmd)
 
 	amt, err := coinutil.NewAmount(cmd.Amount)
 	if err != nil {
@@ -2695,11 +3757,51 @@ func SendToAddress(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (
 		cmd.Address: amt,
 	}
 
+	feeRate, err := feeRateParam(cmd.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+
 	// sendtoaddress always spends from the default account, this matches bitcoind
-	return sendPairs(w, pairs, waddrmgr.DefaultAccountNum, 1)
+	return sendPairs(w, pairs, waddrmgr.DefaultAccountNum, 1, feeRate,
+		txMetadata(cmd.Comment, cmd.CommentTo))
+}
+
+// SetTxComment handles a settxcomment request by replacing the comment
+// recorded for a wallet transaction.  The comment recorded for the recipient
+// of the transaction is only replaced when the commentto parameter is set.
+// Setting empty comments removes them.
+func SetTxComment(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.SetTxCommentCmd)
+
+	txSha, err := wire.NewShaHashFromStr(cmd.Txid)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDecodeHexString,
+			Message: "Transaction hash string decode failed: " + err.Error(),
+		}
+	}
+
+	details, err := w.TxStore.TxDetails(txSha)
+	if err != nil {
+		return nil, err
+	}
+	if details == nil {
+		return nil, &ErrNoTransactionInfo
+	}
+
+	md := details.Metadata
+	md.Comment = cmd.Comment
+	if cmd.CommentTo != nil {
+		md.CommentTo = *cmd.CommentTo
+	}
+	err = w.TxStore.SetTxMetadata(txSha, &md)
+	return nil, err
 }
 
-// SetTxFee sets the transaction fee per kilobyte added to transactions.
+// SetTxFee sets the transaction fee per kilobyte added to transactions.  The
+// fee is converted to a fee rate in satoshis per byte, rounding up.  Setting a
+// zero fee returns to estimating the fee rate of each transaction.
 func SetTxFee(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
 	cmd := icmd.(*btcjson.SetTxFeeCmd)
 
@@ -2708,11 +3810,11 @@ func SetTxFee(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (inter
 		return nil, ErrNeedPositiveAmount
 	}
 
-	incr, err := coinutil.NewAmount(cmd.Amount)
+	feePerKb, err := coinutil.NewAmount(cmd.Amount)
 	if err != nil {
 		return nil, err
 	}
-	w.FeeIncrement = incr
+	w.FeeRate = (feePerKb + 999) / 1000
 
 	// A boolean true result is returned upon success.
 	return true, nil
@@ -2732,7 +3834,7 @@ func SignMessage(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (in
 	if err != nil {
 		return nil, err
 	}
-	pka, ok := ainfo.(waddrmgr.ManagedPubKeyAddress)
+	_, ok := ainfo.(waddrmgr.ManagedPubKeyAddress)
 	if !ok {
 		msg := fmt.Sprintf("Address '%s' does not have an associated private key", addr)
 		return nil, &btcjson.RPCError{
@@ -2740,17 +3842,7 @@ func SignMessage(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (in
 			Message: msg,
 		}
 	}
-	privKey, err := pka.PrivKey()
-	if err != nil {
-		return nil, err
-	}
-
-	var buf bytes.Buffer
-	wire.WriteVarString(&buf, 0, "Bitcoin Signed Message:\n")
-	wire.WriteVarString(&buf, 0, cmd.Message)
-	messageHash := wire.DoubleSha256(buf.Bytes())
-	sigbytes, err := btcec.SignCompact(btcec.S256(), privKey,
-		messageHash, ainfo.Compressed())
+	sigbytes, err := w.SignMessage(addr, cmd.Message)
 	if err != nil {
 		return nil, err
 	}
@@ -2847,6 +3939,14 @@ func SignRawTransaction(w *wallet.Wallet, chainSvr *chain.Client, icmd interface
 			continue
 		}
 
+		// An offline wallet can not look up the previous output, so it
+		// must have been passed.
+		if chainSvr == nil {
+			e := fmt.Errorf("previous output %v was not passed",
+				txIn.PreviousOutPoint)
+			return nil, InvalidParameterError{e}
+		}
+
 		// Never heard of this one before, request it.
 		prevHash := &txIn.PreviousOutPoint.Hash
 		requested[txIn.PreviousOutPoint.Hash] = &pendingTx{
@@ -2941,59 +4041,23 @@ func SignRawTransaction(w *wallet.Wallet, chainSvr *chain.Client, icmd interface
 		}
 
 		// Set up our callbacks that we pass to txscript so it can
-		// look up the appropriate keys and scripts by address.
+		// look up the passed keys and scripts by address.
 		getKey := txscript.KeyClosure(func(addr coinutil.Address) (
 			*btcec.PrivateKey, bool, error) {
-			if len(keys) != 0 {
-				wif, ok := keys[addr.EncodeAddress()]
-				if !ok {
-					return nil, false,
-						errors.New("no key for address")
-				}
-				return wif.PrivKey, wif.CompressPubKey, nil
-			}
-			address, err := w.Manager.Address(addr)
-			if err != nil {
-				return nil, false, err
-			}
-
-			pka, ok := address.(waddrmgr.ManagedPubKeyAddress)
+			wif, ok := keys[addr.EncodeAddress()]
 			if !ok {
-				return nil, false, errors.New("address is not " +
-					"a pubkey address")
+				return nil, false, errors.New("no key for address")
 			}
-
-			key, err := pka.PrivKey()
-			if err != nil {
-				return nil, false, err
-			}
-
-			return key, pka.Compressed(), nil
+			return wif.PrivKey, wif.CompressPubKey, nil
 		})
 
 		getScript := txscript.ScriptClosure(func(
 			addr coinutil.Address) ([]byte, error) {
-			// If keys were provided then we can only use the
-			// scripts provided with our inputs, too.
-			if len(keys) != 0 {
-				script, ok := scripts[addr.EncodeAddress()]
-				if !ok {
-					return nil, errors.New("no script for " +
-						"address")
-				}
-				return script, nil
-			}
-			address, err := w.Manager.Address(addr)
-			if err != nil {
-				return nil, err
-			}
-			sa, ok := address.(waddrmgr.ManagedScriptAddress)
+			script, ok := scripts[addr.EncodeAddress()]
 			if !ok {
-				return nil, errors.New("address is not a script" +
-					" address")
+				return nil, errors.New("no script for address")
 			}
-
-			return sa.Script()
+			return script, nil
 		})
 
 		// SigHashSingle inputs can only be signed if there's a
@@ -3002,9 +4066,17 @@ func SignRawTransaction(w *wallet.Wallet, chainSvr *chain.Client, icmd interface
 		if (hashType&txscript.SigHashSingle) !=
 			txscript.SigHashSingle || i < len(msgTx.TxOut) {
 
-			script, err := txscript.SignTxOutput(activeNet.Params,
-				msgTx, i, input, hashType, getKey,
-				getScript, txIn.SignatureScript)
+			// Unless keys were passed, the wallet's Signer signs
+			// with the keys of the wallet's addresses.
+			var script []byte
+			if len(keys) != 0 {
+				script, err = txscript.SignTxOutput(activeNet.Params,
+					msgTx, i, input, hashType, getKey,
+					getScript, txIn.SignatureScript)
+			} else {
+				script, err = w.SignTxInput(msgTx, i, input,
+					hashType)
+			}
 			// Failure to sign isn't an error, it just means that
 			// the tx isn't complete.
 			if err != nil {
@@ -3056,11 +4128,242 @@ func SignRawTransaction(w *wallet.Wallet, chainSvr *chain.Client, icmd interface
 	}, nil
 }
 
+// WalletCreateFundedPSBT handles a walletcreatefundedpsbt request by creating
+// a partially signed transaction spending outputs of any account to many
+// payment addresses.  The transaction is not signed and the wallet does not
+// need to be unlocked.  Upon success, the base64 encoded partially signed
+// transaction is returned.
+func WalletCreateFundedPSBT(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.WalletCreateFundedPSBTCmd)
+
+	account, err := w.Manager.LookupAccount(cmd.FromAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	minConf := int32(*cmd.MinConf)
+	if minConf < 0 {
+		return nil, ErrNeedPositiveMinconf
+	}
+
+	pairs := make(map[string]coinutil.Amount, len(cmd.Amounts))
+	for k, v := range cmd.Amounts {
+		amt, err := coinutil.NewAmount(v)
+		if err != nil {
+			return nil, err
+		}
+		pairs[k] = amt
+	}
+
+	feeRate, err := feeRateParam(cmd.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := w.CreatePSBT(account, pairs, minConf, feeRate)
+	if err != nil {
+		if err == wallet.ErrNonPositiveAmount {
+			return nil, ErrNeedPositiveAmount
+		}
+		return nil, err
+	}
+	return encodePSBT(p)
+}
+
+// WalletProcessPSBT handles a walletprocesspsbt request by adding what the
+// wallet knows about a partially signed transaction, and the signatures of
+// the wallet's keys unless sign is false, then finalizing every input with
+// enough signatures.  Signing requires the wallet to be unlocked.
+func WalletProcessPSBT(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.WalletProcessPSBTCmd)
+
+	p, err := decodePSBT(cmd.PSBT)
+	if err != nil {
+		return nil, err
+	}
+	if *cmd.Sign {
+		err = w.SignPSBT(p)
+	} else {
+		err = w.UpdatePSBT(p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Finalize(); err != nil {
+		return nil, InvalidParameterError{err}
+	}
+
+	encoded, err := encodePSBT(p)
+	if err != nil {
+		return nil, err
+	}
+	return walletjson.WalletProcessPSBTResult{
+		PSBT:     encoded,
+		Complete: p.Complete(),
+	}, nil
+}
+
+// CombinePSBT handles a combinepsbt request by merging several partially
+// signed versions of the same transaction, such as those signed by each
+// cosigner of a multisig account, into one.
+func CombinePSBT(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.CombinePSBTCmd)
+
+	if len(cmd.PSBTs) == 0 {
+		return nil, InvalidParameterError{
+			errors.New("no partially signed transactions to combine"),
+		}
+	}
+	packets := make([]*psbt.Packet, 0, len(cmd.PSBTs))
+	for _, s := range cmd.PSBTs {
+		p, err := decodePSBT(s)
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, p)
+	}
+
+	combined, err := psbt.Combine(packets...)
+	if err != nil {
+		return nil, InvalidParameterError{err}
+	}
+	return encodePSBT(combined)
+}
+
+// FinalizePSBT handles a finalizepsbt request by building the signature
+// scripts of every input of a partially signed transaction with enough
+// signatures.  Once every input is finalized, the signed transaction is
+// returned hex encoded unless extract is false, ready to be sent with
+// sendrawtransaction.
+func FinalizePSBT(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.FinalizePSBTCmd)
+
+	p, err := decodePSBT(cmd.PSBT)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Finalize(); err != nil {
+		return nil, InvalidParameterError{err}
+	}
+
+	if *cmd.Extract && p.Complete() {
+		tx, err := p.Extract()
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		buf.Grow(tx.SerializeSize())
+		if err := tx.Serialize(&buf); err != nil {
+			return nil, err
+		}
+		return walletjson.FinalizePSBTResult{
+			Hex:      hex.EncodeToString(buf.Bytes()),
+			Complete: true,
+		}, nil
+	}
+
+	encoded, err := encodePSBT(p)
+	if err != nil {
+		return nil, err
+	}
+	return walletjson.FinalizePSBTResult{
+		PSBT:     encoded,
+		Complete: p.Complete(),
+	}, nil
+}
+
+// SendPSBT handles a sendpsbt request by finalizing a partially signed
+// transaction, such as one created by a watching-only wallet and signed by an
+// offline wallet, and publishing the signed transaction.  The transaction is
+// recorded by the wallet once the chain server accepted it, so its spends and
+// change are tracked before it is mined.  Upon success, the transaction hash
+// is returned.
+func SendPSBT(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.SendPSBTCmd)
+
+	p, err := decodePSBT(cmd.PSBT)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Finalize(); err != nil {
+		return nil, InvalidParameterError{err}
+	}
+	tx, err := p.Extract()
+	if err != nil {
+		return nil, InvalidParameterError{err}
+	}
+
+	txSha, err := w.PublishTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	txShaStr := txSha.String()
+	log.Infof("Successfully sent transaction %v", txShaStr)
+	return txShaStr, nil
+}
+
+// SweepPrivKey handles a sweepprivkey request by moving every spendable output
+// controlled by a WIF-encoded private key to a new address of an account,
+// without importing the key into the wallet.  Upon success, the TxID of the
+// sweeping transaction is returned.
+func SweepPrivKey(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.SweepPrivKeyCmd)
+
+	wif, err := coinutil.DecodeWIF(cmd.PrivKey)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "WIF decode failed: " + err.Error(),
+		}
+	}
+	if !wif.IsForNet(activeNet.Params) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Key is not intended for " + activeNet.Params.Name,
+		}
+	}
+
+	account := uint32(waddrmgr.DefaultAccountNum)
+	if cmd.Account != nil {
+		account, err = w.Manager.LookupAccount(*cmd.Account)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	feeRate, err := feeRateParam(cmd.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	txSha, err := w.SweepPrivKey(wif, account, feeRate)
+	if err == wallet.ErrNothingToSweep {
+		return nil, InvalidParameterError{err}
+	}
+	return sentTxResult(txSha, err)
+}
+
+// UnloadWallet handles an unloadwallet request by stopping a named wallet and
+// closing its database.  The default wallet can not be unloaded.
+func (s *rpcServer) UnloadWallet(icmd interface{}) (interface{}, error) {
+	cmd := icmd.(*walletjson.UnloadWalletCmd)
+
+	if cmd.WalletName == "" {
+		e := errors.New("the default wallet can not be unloaded")
+		return nil, InvalidParameterError{e}
+	}
+	err := s.loader.Unload(cmd.WalletName)
+	if err == errWalletNotLoaded {
+		return nil, &ErrWalletNotFound
+	}
+	return nil, err
+}
+
 // ValidateAddress handles the validateaddress command.
 func ValidateAddress(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{}) (interface{}, error) {
 	cmd := icmd.(*btcjson.ValidateAddressCmd)
 
-	result := btcjson.ValidateAddressWalletResult{}
+	result := walletjson.ValidateAddressWalletResult{}
 	addr, err := decodeAddress(cmd.Address, activeNet.Params)
 	if err != nil {
 		// Use result zero value (IsValid=false).
@@ -3133,6 +4436,38 @@ func ValidateAddress(w *wallet.Wallet, chainSvr *chain.Client, icmd interface{})
 		}
 	}
 
+	// Chained addresses also report their derivation path and whether
+	// they would be found when restoring the wallet from its seed.
+	details, err := w.Manager.AddrDetails(addr)
+	if err != nil {
+		return nil, err
+	}
+	if details.Chained {
+		// Accounts imported from an extended public key are not
+		// derived from the wallet seed, and the addresses of HD
+		// multisig accounts are not derived from a single key.
+		result.IsWatchOnly, err = w.Manager.IsWatchingOnlyAccount(
+			details.Account)
+		if err != nil {
+			return nil, err
+		}
+		var acctReqSigs int
+		acctReqSigs, _, err = w.Manager.AccountMultisig(details.Account)
+		if err != nil {
+			return nil, err
+		}
+		if !result.IsWatchOnly && acctReqSigs == 0 {
+			result.HDKeyPath = fmt.Sprintf("m/44'/%d'/%d'/%d/%d",
+				activeNet.Params.HDCoinType, details.Account,
+				details.Branch, details.Index)
+		}
+		result.GapLimit = w.Manager.GapLimit()
+		result.ExceedsGapLimit, err = w.Manager.ExceedsGapLimit(addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return result, nil
 }
 
@@ -3245,3 +4580,25 @@ func decodeHexStr(hexStr string) ([]byte, error) {
 	}
 	return decoded, nil
 }
+
+// decodePSBT decodes a base64 encoded partially signed transaction.
+func decodePSBT(s string) (*psbt.Packet, error) {
+	serialized, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, DeserializationError{err}
+	}
+	p := new(psbt.Packet)
+	if err := p.Deserialize(bytes.NewReader(serialized)); err != nil {
+		return nil, DeserializationError{err}
+	}
+	return p, nil
+}
+
+// encodePSBT returns the base64 encoding of a partially signed transaction.
+func encodePSBT(p *psbt.Packet) (string, error) {
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
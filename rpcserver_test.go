@@ -17,11 +17,22 @@
 package main
 
 import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/conseweb/stcd/btcjson"
+	"github.com/conseweb/stcwallet/internal/bip39"
+	"github.com/conseweb/stcwallet/internal/walletjson"
+	"github.com/conseweb/stcwallet/waddrmgr"
 )
 
 func TestThrottle(t *testing.T) {
@@ -54,3 +65,251 @@ func TestThrottle(t *testing.T) {
 		t.Fatalf("status codes: want: %v, got: %v", want, got)
 	}
 }
+
+func TestOfflineHandlers(t *testing.T) {
+	offline := reflect.ValueOf(OfflineWallet).Pointer()
+	for method := range offlineHandlers {
+		handlerData, ok := rpcHandlers[method]
+		if !ok {
+			t.Errorf("Offline method %q has no handler", method)
+			continue
+		}
+		f, ok := offlineHandlerFunc(method)
+		if !ok || reflect.ValueOf(f).Pointer() !=
+			reflect.ValueOf(handlerData.handler).Pointer() {
+			t.Errorf("Offline method %q is not handled", method)
+		}
+	}
+
+	// Wallet requests which need the chain server, and requests which
+	// would be passed through to it, must never be handled.
+	for _, method := range []string{"sendtoaddress", "sendpsbt", "getblock"} {
+		f, ok := offlineHandlerFunc(method)
+		if !ok || reflect.ValueOf(f).Pointer() != offline {
+			t.Errorf("Method %q is handled by an offline wallet", method)
+		}
+	}
+}
+
+func TestWalletNameFromPath(t *testing.T) {
+	tests := []struct {
+		path string
+		name string
+	}{
+		{"/", ""},
+		{"", ""},
+		{"/ws", ""},
+		{"/wallet/savings", "savings"},
+		{"/wallet/savings/", "savings"},
+		{"/wallets/savings", ""},
+	}
+	for _, test := range tests {
+		if name := walletNameFromPath(test.path); name != test.name {
+			t.Errorf("Path %q: got wallet name %q, want %q", test.path,
+				name, test.name)
+		}
+	}
+}
+
+func TestIsWebsocketUpgrade(t *testing.T) {
+	tests := []struct {
+		upgrade string
+		want    bool
+	}{
+		{"", false},
+		{"websocket", true},
+		{"WebSocket", true},
+		{"h2c", false},
+	}
+	for _, test := range tests {
+		r, err := http.NewRequest("GET", "/wallet/savings", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.upgrade != "" {
+			r.Header.Set("Upgrade", test.upgrade)
+		}
+		if got := isWebsocketUpgrade(r); got != test.want {
+			t.Errorf("Upgrade %q: got %v, want %v", test.upgrade, got,
+				test.want)
+		}
+	}
+}
+
+func TestCheckWalletName(t *testing.T) {
+	valid := []string{"savings", "cold-storage", "wallet_2", "a.b"}
+	for _, name := range valid {
+		if err := checkWalletName(name); err != nil {
+			t.Errorf("Wallet name %q: unexpected error: %v", name, err)
+		}
+	}
+	invalid := []string{"", ".hidden", "..", "a/b", "a b", "wallet%00",
+		strings.Repeat("a", maxWalletNameLen+1)}
+	for _, name := range invalid {
+		if err := checkWalletName(name); err == nil {
+			t.Errorf("Wallet name %q: expected error", name)
+		}
+	}
+}
+
+func TestUnknownWalletHandler(t *testing.T) {
+	s := &rpcServer{loader: newWalletLoader()}
+	req := btcjson.Request{Method: "getbalance"}
+	_, jsonErr := s.HandlerClosure("missing", req.Method)(&req)
+	if jsonErr == nil || *jsonErr != ErrWalletNotFound {
+		t.Errorf("Request of an unloaded wallet: got error %v, want %v",
+			jsonErr, &ErrWalletNotFound)
+	}
+}
+
+func TestDecodeSeed(t *testing.T) {
+	const mnemonic = "abandon abandon abandon abandon abandon abandon " +
+		"abandon abandon abandon abandon abandon about"
+	want, err := bip39.Seed(mnemonic, "TREZOR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, err := decodeSeed(" "+strings.ToUpper(mnemonic)+"\n", "TREZOR")
+	if err != nil {
+		t.Fatalf("Mnemonic: unexpected error: %v", err)
+	}
+	if !bytes.Equal(seed, want) {
+		t.Errorf("Mnemonic: got seed %x, want %x", seed, want)
+	}
+
+	hexSeed := strings.Repeat("ab", 32)
+	seed, err = decodeSeed(hexSeed, "")
+	if err != nil {
+		t.Fatalf("Hex seed: unexpected error: %v", err)
+	}
+	if hex.EncodeToString(seed) != hexSeed {
+		t.Errorf("Hex seed: got seed %x, want %s", seed, hexSeed)
+	}
+
+	invalid := []string{"", "abcd", "not hex", strings.Repeat("ab", 65),
+		"abandon abandon abandon abandon abandon abandon abandon " +
+			"abandon abandon abandon abandon abandon"}
+	for _, s := range invalid {
+		if _, err := decodeSeed(s, ""); err == nil {
+			t.Errorf("Seed %q: expected error", s)
+		}
+	}
+}
+
+func TestScryptOptions(t *testing.T) {
+	opts, err := scryptOptions(nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Default options: unexpected error: %v", err)
+	}
+	if *opts != waddrmgr.DefaultScryptOptions {
+		t.Errorf("Default options: got %+v, want %+v", *opts,
+			waddrmgr.DefaultScryptOptions)
+	}
+
+	n, r, p := 16, 1, 2
+	opts, err = scryptOptions(&n, &r, &p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := waddrmgr.ScryptOptions{N: n, R: r, P: p}
+	if *opts != want {
+		t.Errorf("Got options %+v, want %+v", *opts, want)
+	}
+
+	zero, notPow2 := 0, 1000
+	for _, params := range [][3]*int{
+		{&notPow2, nil, nil},
+		{&zero, nil, nil},
+		{nil, &zero, nil},
+		{nil, nil, &zero},
+	} {
+		if _, err := scryptOptions(params[0], params[1], params[2]); err == nil {
+			t.Errorf("Options %v: expected error", params)
+		}
+	}
+}
+
+func TestCreateDefaultWalletRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "createwallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldCfg := cfg
+	defer func() { cfg = oldCfg }()
+	cfg = &config{DataDir: dir, WalletPass: "public"}
+
+	s := &rpcServer{
+		loader:              newWalletLoader(),
+		registerWalletNtfns: make(chan struct{}, 1),
+		quit:                make(chan struct{}),
+	}
+	s.SetOffline()
+
+	// The default wallet may not be created with a public passphrase
+	// startup would not open it with.
+	n, r, p := 16, 1, 1
+	pubPass := "other"
+	cmd := walletjson.NewCreateWalletCmd("", "private", &pubPass, nil,
+		nil, &n, &r, &p)
+	_, err = s.CreateWallet(cmd)
+	if _, ok := err.(InvalidParameterError); !ok {
+		t.Fatalf("Custom public passphrase: got error %v, want an "+
+			"InvalidParameterError", err)
+	}
+	dbPath := filepath.Join(networkDir(dir, activeNet.Params), walletDbName)
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Fatalf("Custom public passphrase: wallet database was created")
+	}
+
+	cmd = walletjson.NewCreateWalletCmd("", "private", nil, nil, nil,
+		&n, &r, &p)
+	if _, err := s.CreateWallet(cmd); err != nil {
+		t.Fatalf("Unexpected error creating the wallet: %v", err)
+	}
+	s.wallet.Stop()
+	s.wallet.WaitForShutdown()
+	if err := s.walletDB.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restarting opens the created wallet like walletMain does.
+	w, db, err := openWallet()
+	if err != nil {
+		t.Fatalf("Unexpected error opening the created wallet: %v", err)
+	}
+	w.Stop()
+	w.WaitForShutdown()
+	db.Close()
+}
+
+func TestLoadedWalletRequests(t *testing.T) {
+	lw := &loadedWallet{name: "test"}
+	if !lw.acquire() {
+		t.Fatal("Request of a loaded wallet refused")
+	}
+
+	// Stopping the wallet waits for the running request.
+	stopped := make(chan struct{})
+	go func() {
+		lw.stopRequests()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("Wallet stopped while a request was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	lw.release()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Wallet not stopped after the request finished")
+	}
+
+	if lw.acquire() {
+		lw.release()
+		t.Error("Request of a stopped wallet accepted")
+	}
+}
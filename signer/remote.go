@@ -0,0 +1,401 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package signer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/conseweb/stcd/btcec"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// CodeUnknownKey is the error code an external signer responds with when it
+// does not hold the private key of a requested signature.
+const CodeUnknownKey = 1
+
+// DefaultTimeout is the default time an external signer is given to answer a
+// request, which may include waiting for the user to confirm it on a device.
+const DefaultTimeout = 2 * time.Minute
+
+var (
+	// ErrTimeout describes an error where an external signer did not
+	// answer a request in time.
+	ErrTimeout = errors.New("signer did not respond in time")
+
+	// ErrDisconnected describes an error where the connection to an
+	// external signer was reset after a failed request and can not be
+	// reestablished.
+	ErrDisconnected = errors.New("signer is disconnected")
+)
+
+// Request is a request sent to an external signer.  Requests and responses
+// are JSON objects, each written on a single line.  The methods are
+// "signtxinput", with SignTxInputParams, and "signmessage", with
+// SignMessageParams.  Requests are sent one at a time, and each must be
+// answered before the next is sent.
+type Request struct {
+	ID     uint64      `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+// KeyParams identifies the key of a request.  Byte strings are hex encoded.
+type KeyParams struct {
+	PubKey      string   `json:"pubkey"`
+	Fingerprint uint32   `json:"fingerprint"`
+	Path        []uint32 `json:"path"`
+}
+
+// SignTxInputParams are the parameters of a signtxinput request.  The result
+// is the hex encoded signature, with the hash type appended.
+type SignTxInputParams struct {
+	KeyParams
+	Tx       string `json:"tx"`
+	Index    int    `json:"index"`
+	Script   string `json:"script"`
+	HashType uint32 `json:"hashtype"`
+}
+
+// SignMessageParams are the parameters of a signmessage request.  The result
+// is the hex encoded compact signature of the message hash.
+type SignMessageParams struct {
+	KeyParams
+	Message string `json:"message"`
+}
+
+// Response is the response of an external signer to a request.  The error is
+// null unless the request failed.
+type Response struct {
+	ID     uint64         `json:"id"`
+	Result string         `json:"result"`
+	Error  *ResponseError `json:"error"`
+}
+
+// ResponseError describes why an external signer could not answer a request.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error satisifies the error interface.
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("signer error %d: %s", e.Code, e.Message)
+}
+
+// Remote is a Signer forwarding every request to an external signer, such as
+// a hardware device or HSM, so that its private keys are never present in the
+// wallet process.
+//
+// A request which fails to be sent or answered, or is not answered within
+// Timeout, resets the connection since later responses could no longer be
+// matched to their requests.  Signers started by Dial and StartProcess are
+// reconnected by the next request.
+type Remote struct {
+	// Timeout is the time the external signer is given to answer each
+	// request, or zero to wait indefinitely.  It must be set before the
+	// first request.
+	Timeout time.Duration
+
+	mtx    sync.Mutex
+	conn   io.ReadWriteCloser
+	r      *bufio.Reader
+	dial   func() (io.ReadWriteCloser, error)
+	closed bool
+	nextID uint64
+}
+
+// NewRemote returns a Remote sending requests over conn.  The Remote can not
+// reconnect once the connection is reset.
+func NewRemote(conn io.ReadWriteCloser) *Remote {
+	return &Remote{
+		Timeout: DefaultTimeout,
+		conn:    conn,
+		r:       bufio.NewReader(conn),
+	}
+}
+
+// newRedialingRemote returns a Remote connecting with dial, initially and
+// whenever the connection was reset.
+func newRedialingRemote(dial func() (io.ReadWriteCloser, error)) (*Remote, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	r := NewRemote(conn)
+	r.dial = dial
+	return r, nil
+}
+
+// Dial connects to an external signer listening on the named network, such as
+// "unix" or "tcp".
+func Dial(network, address string) (*Remote, error) {
+	return newRedialingRemote(func() (io.ReadWriteCloser, error) {
+		return net.Dial(network, address)
+	})
+}
+
+// processConn is the connection to an external signer subprocess over its
+// standard input and output.
+type processConn struct {
+	io.WriteCloser
+	io.Reader
+	cmd *exec.Cmd
+}
+
+// Close closes the standard input of the subprocess, which must then exit.
+func (c *processConn) Close() error {
+	err := c.WriteCloser.Close()
+	if werr := c.cmd.Wait(); err == nil {
+		err = werr
+	}
+	return err
+}
+
+// kill kills the subprocess, which may not exit by itself after failing to
+// answer a request.
+func (c *processConn) kill() {
+	c.cmd.Process.Kill()
+	c.Close()
+}
+
+// StartProcess starts an external signer subprocess, which reads requests from
+// its standard input and writes responses to its standard output.  A new
+// subprocess is started when the connection is reset.
+func StartProcess(name string, args ...string) (*Remote, error) {
+	return newRedialingRemote(func() (io.ReadWriteCloser, error) {
+		cmd := exec.Command(name, args...)
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return &processConn{stdin, stdout, cmd}, nil
+	})
+}
+
+// Close closes the connection to the external signer.
+func (r *Remote) Close() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.closed = true
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	return err
+}
+
+// reset closes the connection after a failed request, since any response
+// still to be read would be taken as the response of the next request.
+func (r *Remote) reset() {
+	if pc, ok := r.conn.(*processConn); ok {
+		pc.kill()
+	} else {
+		r.conn.Close()
+	}
+	r.conn = nil
+}
+
+// connect reconnects to the external signer if the connection was reset.
+func (r *Remote) connect() error {
+	if r.conn != nil {
+		return nil
+	}
+	if r.closed || r.dial == nil {
+		return ErrDisconnected
+	}
+	conn, err := r.dial()
+	if err != nil {
+		return err
+	}
+	r.conn = conn
+	r.r = bufio.NewReader(conn)
+	return nil
+}
+
+// keyParams returns the request parameters identifying key.
+func keyParams(key *Key) KeyParams {
+	return KeyParams{
+		PubKey:      hex.EncodeToString(key.PubKey),
+		Fingerprint: key.Fingerprint,
+		Path:        key.Path,
+	}
+}
+
+// exchange writes a request to the external signer and reads its response,
+// giving up after the timeout of the Remote.
+func (r *Remote) exchange(req []byte) ([]byte, error) {
+	type result struct {
+		line []byte
+		err  error
+	}
+	// The connection is only used by the goroutine until it is done, or
+	// reset when the request timed out.
+	done := make(chan result, 1)
+	conn, rd := r.conn, r.r
+	go func() {
+		if _, err := conn.Write(req); err != nil {
+			done <- result{nil, err}
+			return
+		}
+		line, err := rd.ReadBytes('\n')
+		done <- result{line, err}
+	}()
+
+	var timeout <-chan time.Time
+	if r.Timeout > 0 {
+		timer := time.NewTimer(r.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case res := <-done:
+		return res.line, res.err
+	case <-timeout:
+		return nil, ErrTimeout
+	}
+}
+
+// request sends a request to the external signer and returns the decoded
+// result of its response.  The connection is reset if the request fails
+// before a response is matched to it.
+func (r *Remote) request(method string, params interface{}) ([]byte, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if err := r.connect(); err != nil {
+		return nil, err
+	}
+
+	r.nextID++
+	req := Request{ID: r.nextID, Method: method, Params: params}
+	b, err := json.Marshal(&req)
+	if err != nil {
+		return nil, err
+	}
+	line, err := r.exchange(append(b, '\n'))
+	if err != nil {
+		r.reset()
+		return nil, err
+	}
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		r.reset()
+		return nil, err
+	}
+	if resp.ID != req.ID {
+		r.reset()
+		return nil, fmt.Errorf("signer responded to request %d, "+
+			"expected %d", resp.ID, req.ID)
+	}
+	if resp.Error != nil {
+		if resp.Error.Code == CodeUnknownKey {
+			return nil, ErrUnknownKey
+		}
+		return nil, resp.Error
+	}
+	return hex.DecodeString(resp.Result)
+}
+
+// SignTxInput asks the external signer to sign a transaction input.  The
+// returned signature is checked to be a valid signature of the input by the
+// public key of key, with the requested hash type.
+func (r *Remote) SignTxInput(tx *wire.MsgTx, idx int, subScript []byte,
+	hashType txscript.SigHashType, key *Key) ([]byte, error) {
+
+	var buf bytes.Buffer
+	buf.Grow(tx.SerializeSize())
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	sig, err := r.request("signtxinput", &SignTxInputParams{
+		KeyParams: keyParams(key),
+		Tx:        hex.EncodeToString(buf.Bytes()),
+		Index:     idx,
+		Script:    hex.EncodeToString(subScript),
+		HashType:  uint32(hashType),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sig) == 0 || txscript.SigHashType(sig[len(sig)-1]) != hashType {
+		return nil, errors.New("signer signed input with another " +
+			"hash type")
+	}
+	hash, err := signatureHash(tx, idx, subScript, hashType)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := btcec.ParsePubKey(key.PubKey, btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	signature, err := btcec.ParseSignature(sig[:len(sig)-1], btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	if !signature.Verify(hash, pubKey) {
+		return nil, errors.New("signer returned an invalid signature")
+	}
+	return sig, nil
+}
+
+// SignMessage asks the external signer to sign a message.  The returned
+// signature is checked to recover the public key of key.
+func (r *Remote) SignMessage(message string, key *Key) ([]byte, error) {
+	sig, err := r.request("signmessage", &SignMessageParams{
+		KeyParams: keyParams(key),
+		Message:   message,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, compressed, err := btcec.RecoverCompact(btcec.S256(), sig,
+		MessageHash(message))
+	if err != nil {
+		return nil, err
+	}
+	var serialized []byte
+	if compressed {
+		serialized = pubKey.SerializeCompressed()
+	} else {
+		serialized = pubKey.SerializeUncompressed()
+	}
+	if !bytes.Equal(serialized, key.PubKey) {
+		return nil, errors.New("signer signed message with another key")
+	}
+	return sig, nil
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package signer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// sigHashMask is the mask of the hash type of a signature without the
+// SigHashAnyOneCanPay flag.
+const sigHashMask = 0x1f
+
+// errMalformedScript describes an error where a script can not be parsed into
+// its opcodes.
+var errMalformedScript = errors.New("malformed script")
+
+// removeCodeSeparators returns script without its OP_CODESEPARATOR opcodes.
+func removeCodeSeparators(script []byte) ([]byte, error) {
+	out := make([]byte, 0, len(script))
+	for i := 0; i < len(script); {
+		op := script[i]
+		n := 1
+		switch {
+		case op >= txscript.OP_DATA_1 && op <= txscript.OP_DATA_75:
+			n += int(op)
+		case op == txscript.OP_PUSHDATA1:
+			if i+2 > len(script) {
+				return nil, errMalformedScript
+			}
+			n += 1 + int(script[i+1])
+		case op == txscript.OP_PUSHDATA2:
+			if i+3 > len(script) {
+				return nil, errMalformedScript
+			}
+			n += 2 + int(binary.LittleEndian.Uint16(script[i+1:]))
+		case op == txscript.OP_PUSHDATA4:
+			if i+5 > len(script) {
+				return nil, errMalformedScript
+			}
+			n += 4 + int(binary.LittleEndian.Uint32(script[i+1:]))
+		}
+		if n < 0 || i+n > len(script) {
+			return nil, errMalformedScript
+		}
+		if op != txscript.OP_CODESEPARATOR {
+			out = append(out, script[i:i+n]...)
+		}
+		i += n
+	}
+	return out, nil
+}
+
+// signatureHash returns the hash signed by a signature of input idx of tx for
+// the script subScript and the hash type, as computed by the script engine
+// when verifying the signature.
+func signatureHash(tx *wire.MsgTx, idx int, subScript []byte,
+	hashType txscript.SigHashType) ([]byte, error) {
+
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, fmt.Errorf("input index %d out of range", idx)
+	}
+
+	// Signing a single output for an input without a matching output
+	// signs the hash 1, which the engine keeps for compatibility.
+	if hashType&sigHashMask == txscript.SigHashSingle && idx >= len(tx.TxOut) {
+		var hash wire.ShaHash
+		hash[0] = 0x01
+		return hash[:], nil
+	}
+
+	script, err := removeCodeSeparators(subScript)
+	if err != nil {
+		return nil, err
+	}
+	txCopy := tx.Copy()
+	for i := range txCopy.TxIn {
+		if i == idx {
+			txCopy.TxIn[i].SignatureScript = script
+		} else {
+			txCopy.TxIn[i].SignatureScript = nil
+		}
+	}
+
+	switch hashType & sigHashMask {
+	case txscript.SigHashNone:
+		txCopy.TxOut = txCopy.TxOut[0:0]
+		for i := range txCopy.TxIn {
+			if i != idx {
+				txCopy.TxIn[i].Sequence = 0
+			}
+		}
+	case txscript.SigHashSingle:
+		txCopy.TxOut = txCopy.TxOut[:idx+1]
+		for i := 0; i < idx; i++ {
+			txCopy.TxOut[i].Value = -1
+			txCopy.TxOut[i].PkScript = nil
+		}
+		for i := range txCopy.TxIn {
+			if i != idx {
+				txCopy.TxIn[i].Sequence = 0
+			}
+		}
+	}
+	if hashType&txscript.SigHashAnyOneCanPay != 0 {
+		txCopy.TxIn = txCopy.TxIn[idx : idx+1]
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(txCopy.SerializeSize() + 4)
+	if err := txCopy.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(hashType))
+	buf.Write(b[:])
+	return wire.DoubleSha256(buf.Bytes()), nil
+}
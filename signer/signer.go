@@ -0,0 +1,125 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+// Package signer defines the interface through which transaction inputs and
+// messages are signed, so that private keys may be kept out of the wallet
+// process entirely.
+//
+// A Signer is asked for signatures by public key.  Keys derived from an
+// extended key are also identified by the fingerprint of that extended key and
+// the path of child indexes from it, so a hardware device or HSM holding only
+// the extended private key can derive the key to sign with.  The wallet
+// package implements a Signer over the keys of its address manager, and Remote
+// forwards requests to an external signer over a socket or the standard
+// input and output of a subprocess.
+package signer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/coinutil/hdkeychain"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// ErrUnknownKey describes an error where a signer does not hold the private
+// key of the public key a signature was requested of.
+var ErrUnknownKey = errors.New("signer does not hold the private key")
+
+// Key identifies the key a signature is requested of.
+type Key struct {
+	// PubKey is the serialized public key, compressed or uncompressed.
+	// Signatures of messages are made for the same encoding.
+	PubKey []byte
+
+	// Fingerprint identifies the extended key the key was derived from,
+	// and Path holds the child indexes of the derivation.  Path is nil
+	// for keys which were not derived from an extended key.
+	Fingerprint uint32
+	Path        []uint32
+}
+
+// Signer signs transaction inputs and messages with the private keys it holds.
+// Implementations return ErrUnknownKey when they do not hold the private key
+// of a requested signature.
+type Signer interface {
+	// SignTxInput returns the signature of input idx of tx, with the hash
+	// type appended, for the script subScript.  subScript is the output
+	// script spent by the input, or the redeem script of a pay-to-script
+	// hash output.
+	SignTxInput(tx *wire.MsgTx, idx int, subScript []byte,
+		hashType txscript.SigHashType, key *Key) ([]byte, error)
+
+	// SignMessage returns the compact signature of the hash of message
+	// returned by MessageHash.
+	SignMessage(message string, key *Key) ([]byte, error)
+}
+
+// MessageHash returns the hash of a message signed by SignMessage.  The
+// message is prefixed with the same magic bytes as messages signed by the
+// reference implementation.
+func MessageHash(message string) []byte {
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, "Bitcoin Signed Message:\n")
+	wire.WriteVarString(&buf, 0, message)
+	return wire.DoubleSha256(buf.Bytes())
+}
+
+// Fingerprint returns the fingerprint of an extended key, which is the first
+// four bytes of the hash160 of its compressed public key, read big-endian.
+func Fingerprint(key *hdkeychain.ExtendedKey) (uint32, error) {
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return 0, err
+	}
+	hash := coinutil.Hash160(pubKey.SerializeCompressed())
+	return binary.BigEndian.Uint32(hash[:4]), nil
+}
+
+// fallback is a Signer which asks several signers in turn.
+type fallback []Signer
+
+// Fallback returns a Signer asking each of the passed signers in order for a
+// signature until one holds the private key.  Errors other than ErrUnknownKey
+// are returned without asking the remaining signers.
+func Fallback(signers ...Signer) Signer {
+	return fallback(signers)
+}
+
+// SignTxInput asks each signer in turn to sign a transaction input.
+func (f fallback) SignTxInput(tx *wire.MsgTx, idx int, subScript []byte,
+	hashType txscript.SigHashType, key *Key) ([]byte, error) {
+
+	for _, s := range f {
+		sig, err := s.SignTxInput(tx, idx, subScript, hashType, key)
+		if err != ErrUnknownKey {
+			return sig, err
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// SignMessage asks each signer in turn to sign a message.
+func (f fallback) SignMessage(message string, key *Key) ([]byte, error) {
+	for _, s := range f {
+		sig, err := s.SignMessage(message, key)
+		if err != ErrUnknownKey {
+			return sig, err
+		}
+	}
+	return nil, ErrUnknownKey
+}
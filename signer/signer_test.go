@@ -0,0 +1,361 @@
+package signer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/conseweb/stcd/btcec"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+func privKey(b byte) *btcec.PrivateKey {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{b}, 32))
+	return key
+}
+
+// keySigner is a Signer holding a single private key.
+type keySigner struct {
+	key   *btcec.PrivateKey
+	calls int
+}
+
+func (s *keySigner) holds(key *Key) bool {
+	s.calls++
+	return bytes.Equal(key.PubKey, s.key.PubKey().SerializeCompressed())
+}
+
+func (s *keySigner) SignTxInput(tx *wire.MsgTx, idx int, subScript []byte,
+	hashType txscript.SigHashType, key *Key) ([]byte, error) {
+
+	if !s.holds(key) {
+		return nil, ErrUnknownKey
+	}
+	return txscript.RawTxInSignature(tx, idx, subScript, hashType, s.key)
+}
+
+func (s *keySigner) SignMessage(message string, key *Key) ([]byte, error) {
+	if !s.holds(key) {
+		return nil, ErrUnknownKey
+	}
+	return btcec.SignCompact(btcec.S256(), s.key, MessageHash(message), true)
+}
+
+// failSigner is a Signer failing every request.
+type failSigner struct{}
+
+var errFail = errors.New("signer failed")
+
+func (failSigner) SignTxInput(*wire.MsgTx, int, []byte, txscript.SigHashType,
+	*Key) ([]byte, error) {
+	return nil, errFail
+}
+
+func (failSigner) SignMessage(string, *Key) ([]byte, error) {
+	return nil, errFail
+}
+
+func TestFallback(t *testing.T) {
+	s1 := &keySigner{key: privKey(1)}
+	s2 := &keySigner{key: privKey(2)}
+	f := Fallback(s1, s2)
+
+	key2 := &Key{PubKey: privKey(2).PubKey().SerializeCompressed()}
+	if _, err := f.SignMessage("hello", key2); err != nil {
+		t.Fatalf("SignMessage: unexpected error: %v", err)
+	}
+	if s1.calls != 1 || s2.calls != 1 {
+		t.Errorf("Signers asked %d and %d times, want 1 and 1",
+			s1.calls, s2.calls)
+	}
+
+	key3 := &Key{PubKey: privKey(3).PubKey().SerializeCompressed()}
+	if _, err := f.SignMessage("hello", key3); err != ErrUnknownKey {
+		t.Errorf("SignMessage: got error %v, want ErrUnknownKey", err)
+	}
+
+	// Errors other than ErrUnknownKey are returned without asking the
+	// remaining signers.
+	s3 := &keySigner{key: privKey(3)}
+	f = Fallback(failSigner{}, s3)
+	if _, err := f.SignMessage("hello", key3); err != errFail {
+		t.Errorf("SignMessage: got error %v, want %v", err, errFail)
+	}
+	if s3.calls != 0 {
+		t.Errorf("Signer after the failed signer asked %d times",
+			s3.calls)
+	}
+}
+
+// serveSigner answers the requests read from conn with s until conn is
+// closed.
+func serveSigner(conn net.Conn, s Signer) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var req struct {
+			ID     uint64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(line, &req); err != nil {
+			return
+		}
+
+		var sig []byte
+		switch req.Method {
+		case "signmessage":
+			var params SignMessageParams
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				return
+			}
+			pubKey, _ := hex.DecodeString(params.PubKey)
+			key := &Key{pubKey, params.Fingerprint, params.Path}
+			sig, err = s.SignMessage(params.Message, key)
+		case "signtxinput":
+			var params SignTxInputParams
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				return
+			}
+			pubKey, _ := hex.DecodeString(params.PubKey)
+			key := &Key{pubKey, params.Fingerprint, params.Path}
+			serializedTx, _ := hex.DecodeString(params.Tx)
+			tx := wire.NewMsgTx()
+			if err := tx.Deserialize(bytes.NewReader(serializedTx)); err != nil {
+				return
+			}
+			script, _ := hex.DecodeString(params.Script)
+			sig, err = s.SignTxInput(tx, params.Index, script,
+				txscript.SigHashType(params.HashType), key)
+		default:
+			err = errors.New("unknown method")
+		}
+
+		resp := Response{ID: req.ID, Result: hex.EncodeToString(sig)}
+		switch {
+		case err == ErrUnknownKey:
+			resp.Error = &ResponseError{CodeUnknownKey, err.Error()}
+		case err != nil:
+			resp.Error = &ResponseError{2, err.Error()}
+		}
+		b, _ := json.Marshal(&resp)
+		if _, err := conn.Write(append(b, '\n')); err != nil {
+			return
+		}
+	}
+}
+
+// misbehavingSigner reads requests from conn until it is closed, answering
+// each with the response to another request when desync is set, or never
+// answering otherwise.
+func misbehavingSigner(conn net.Conn, desync bool) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		if _, err := r.ReadBytes('\n'); err != nil {
+			return
+		}
+		if !desync {
+			continue
+		}
+		b, _ := json.Marshal(&Response{ID: 1 << 32})
+		if _, err := conn.Write(append(b, '\n')); err != nil {
+			return
+		}
+	}
+}
+
+// otherKeySigner is a Signer signing every input with the same key,
+// regardless of the key requested.
+type otherKeySigner struct {
+	keySigner
+}
+
+func (s *otherKeySigner) SignTxInput(tx *wire.MsgTx, idx int, subScript []byte,
+	hashType txscript.SigHashType, key *Key) ([]byte, error) {
+
+	return txscript.RawTxInSignature(tx, idx, subScript, hashType, s.key)
+}
+
+func TestRemote(t *testing.T) {
+	client, server := net.Pipe()
+	go serveSigner(server, &keySigner{key: privKey(1)})
+	remote := NewRemote(client)
+	defer remote.Close()
+
+	pubKey := privKey(1).PubKey().SerializeCompressed()
+	key := &Key{PubKey: pubKey, Fingerprint: 0x01020304, Path: []uint32{0, 5}}
+	sig, err := remote.SignMessage("hello", key)
+	if err != nil {
+		t.Fatalf("SignMessage: unexpected error: %v", err)
+	}
+	recovered, _, err := btcec.RecoverCompact(btcec.S256(), sig,
+		MessageHash("hello"))
+	if err != nil {
+		t.Fatalf("RecoverCompact: unexpected error: %v", err)
+	}
+	if !bytes.Equal(recovered.SerializeCompressed(), pubKey) {
+		t.Errorf("Message signed by another key")
+	}
+
+	// Keys the external signer does not hold are reported by error code.
+	otherKey := &Key{PubKey: privKey(2).PubKey().SerializeCompressed()}
+	if _, err := remote.SignMessage("hello", otherKey); err != ErrUnknownKey {
+		t.Errorf("SignMessage: got error %v, want ErrUnknownKey", err)
+	}
+
+	tx := wire.NewMsgTx()
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil))
+	tx.AddTxOut(wire.NewTxOut(1e8, []byte{txscript.OP_TRUE}))
+	subScript := []byte{txscript.OP_TRUE}
+	sig, err = remote.SignTxInput(tx, 0, subScript, txscript.SigHashAll, key)
+	if err != nil {
+		t.Fatalf("SignTxInput: unexpected error: %v", err)
+	}
+	want, err := txscript.RawTxInSignature(tx, 0, subScript,
+		txscript.SigHashAll, privKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, want) {
+		t.Errorf("SignTxInput: got signature %x, want %x", sig, want)
+	}
+
+	// Signatures of other keys are refused.
+	client, server = net.Pipe()
+	go serveSigner(server, &otherKeySigner{keySigner{key: privKey(2)}})
+	other := NewRemote(client)
+	defer other.Close()
+	if _, err := other.SignTxInput(tx, 0, subScript, txscript.SigHashAll,
+		key); err == nil {
+		t.Error("SignTxInput: accepted signature of another key")
+	}
+}
+
+func TestRemoteReset(t *testing.T) {
+	// The first connection is never answered and the second answers with
+	// the response to another request.  Later connections are served.
+	dials := 0
+	remote, err := newRedialingRemote(func() (io.ReadWriteCloser, error) {
+		client, server := net.Pipe()
+		dials++
+		switch dials {
+		case 1:
+			go misbehavingSigner(server, false)
+		case 2:
+			go misbehavingSigner(server, true)
+		default:
+			go serveSigner(server, &keySigner{key: privKey(1)})
+		}
+		return client, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	remote.Timeout = 50 * time.Millisecond
+
+	key := &Key{PubKey: privKey(1).PubKey().SerializeCompressed()}
+	if _, err := remote.SignMessage("hello", key); err != ErrTimeout {
+		t.Errorf("SignMessage: got error %v, want %v", err, ErrTimeout)
+	}
+	if _, err := remote.SignMessage("hello", key); err == nil {
+		t.Error("SignMessage: accepted response to another request")
+	}
+	if _, err := remote.SignMessage("hello", key); err != nil {
+		t.Errorf("SignMessage after reconnecting: unexpected error: %v",
+			err)
+	}
+	if dials != 3 {
+		t.Errorf("Dialed %d times, want 3", dials)
+	}
+
+	// Remotes over a single connection can not reconnect.
+	client, server := net.Pipe()
+	go misbehavingSigner(server, false)
+	single := NewRemote(client)
+	defer single.Close()
+	single.Timeout = 50 * time.Millisecond
+	if _, err := single.SignMessage("hello", key); err != ErrTimeout {
+		t.Errorf("SignMessage: got error %v, want %v", err, ErrTimeout)
+	}
+	if _, err := single.SignMessage("hello", key); err != ErrDisconnected {
+		t.Errorf("SignMessage: got error %v, want %v", err,
+			ErrDisconnected)
+	}
+}
+
+func TestSignatureHash(t *testing.T) {
+	key := privKey(1)
+	pkScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_DUP).
+		AddOp(txscript.OP_HASH160).AddData(bytes.Repeat([]byte{1}, 20)).
+		AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	separated := append([]byte{txscript.OP_CODESEPARATOR}, pkScript...)
+
+	tx := wire.NewMsgTx()
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil))
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 2}, nil))
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 3}, nil))
+	tx.AddTxOut(wire.NewTxOut(1e8, pkScript))
+	tx.AddTxOut(wire.NewTxOut(2e8, pkScript))
+
+	// Signatures created by the script engine must verify against the
+	// hash, including the single output hash of an input without a
+	// matching output.
+	hashTypes := []txscript.SigHashType{
+		txscript.SigHashAll,
+		txscript.SigHashNone,
+		txscript.SigHashSingle,
+		txscript.SigHashAll | txscript.SigHashAnyOneCanPay,
+		txscript.SigHashNone | txscript.SigHashAnyOneCanPay,
+		txscript.SigHashSingle | txscript.SigHashAnyOneCanPay,
+	}
+	for _, hashType := range hashTypes {
+		for idx := range tx.TxIn {
+			for _, script := range [][]byte{pkScript, separated} {
+				sig, err := txscript.RawTxInSignature(tx, idx,
+					script, hashType, key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				hash, err := signatureHash(tx, idx, script,
+					hashType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				signature, err := btcec.ParseSignature(
+					sig[:len(sig)-1], btcec.S256())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !signature.Verify(hash, key.PubKey()) {
+					t.Errorf("Hash type %v, input %d: "+
+						"signature does not verify",
+						hashType, idx)
+				}
+			}
+		}
+	}
+
+	if _, err := signatureHash(tx, 3, pkScript, txscript.SigHashAll); err == nil {
+		t.Error("Expected an error for an input index out of range")
+	}
+	if _, err := signatureHash(tx, 0, []byte{txscript.OP_DATA_2, 1},
+		txscript.SigHashAll); err == nil {
+		t.Error("Expected an error for a malformed script")
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/conseweb/coinutil/hdkeychain"
 	"github.com/conseweb/stcd/txscript"
 	"github.com/conseweb/stcwallet/internal/zero"
+	"github.com/conseweb/stcwallet/signer"
 	"github.com/conseweb/stcwallet/waddrmgr"
 	"github.com/conseweb/stcwallet/walletdb"
 )
@@ -61,6 +62,7 @@ type Pool struct {
 	seriesLookup map[uint32]*SeriesData
 	manager      *waddrmgr.Manager
 	namespace    walletdb.Namespace
+	signer       signer.Signer
 }
 
 // PoolAddress represents a voting pool P2SH address, generated by
@@ -138,6 +140,15 @@ func newPool(namespace walletdb.Namespace, m *waddrmgr.Manager, poolID []byte) *
 	}
 }
 
+// SetSigner sets the Signer asked for the raw signatures of withdrawal
+// transactions by the series keys whose private keys are not loaded into the
+// pool, such as keys held by a hardware device or HSM.  The wallet daemon does
+// not load voting pools, so the signer is only set by applications using this
+// package directly.
+func (p *Pool) SetSigner(s signer.Signer) {
+	p.signer = s
+}
+
 // LoadAndGetDepositScript generates and returns a deposit script for the given seriesID,
 // branch and index of the Pool identified by poolID.
 func LoadAndGetDepositScript(namespace walletdb.Namespace, m *waddrmgr.Manager, poolID string, seriesID uint32, branch Branch, index Index) ([]byte, error) {
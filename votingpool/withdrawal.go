@@ -26,9 +26,11 @@ import (
 	"time"
 
 	"github.com/conseweb/coinutil"
+	"github.com/conseweb/coinutil/hdkeychain"
 	"github.com/conseweb/fastsha256"
 	"github.com/conseweb/stcd/txscript"
 	"github.com/conseweb/stcd/wire"
+	"github.com/conseweb/stcwallet/signer"
 	"github.com/conseweb/stcwallet/waddrmgr"
 	"github.com/conseweb/stcwallet/walletdb"
 	"github.com/conseweb/stcwallet/wtxmgr"
@@ -513,7 +515,7 @@ func (p *Pool) StartWithdrawal(roundID uint32, requests []OutputRequest,
 	if err := w.fulfillRequests(); err != nil {
 		return nil, err
 	}
-	w.status.sigs, err = getRawSigs(w.transactions)
+	w.status.sigs, err = getRawSigs(w.transactions, p.signer)
 	if err != nil {
 		return nil, err
 	}
@@ -858,9 +860,11 @@ func getWithdrawalStatus(p *Pool, roundID uint32, requests []OutputRequest,
 }
 
 // getRawSigs iterates over the inputs of each transaction given, constructing the
-// raw signatures for them using the private keys available to us.
+// raw signatures for them using the private keys available to us.  The raw
+// signatures of keys whose private keys are not available are asked of s, if
+// not nil.
 // It returns a map of ntxids to signature lists.
-func getRawSigs(transactions []*withdrawalTx) (map[Ntxid]TxSigs, error) {
+func getRawSigs(transactions []*withdrawalTx, s signer.Signer) (map[Ntxid]TxSigs, error) {
 	sigs := make(map[Ntxid]TxSigs)
 	for _, tx := range transactions {
 		txSigs := make(TxSigs, len(tx.inputs))
@@ -901,6 +905,12 @@ func getRawSigs(transactions []*withdrawalTx) (map[Ntxid]TxSigs, error) {
 					if err != nil {
 						return nil, newError(ErrRawSigning, "failed to generate raw signature", err)
 					}
+				} else if s != nil {
+					sig, err = signerRawSig(s, msgtx, inputIdx, redeemScript,
+						pubKey, creditAddr.Index())
+					if err != nil {
+						return nil, err
+					}
 				} else {
 					log.Debugf("Not generating raw sig for input %d of %s because private key "+
 						"for %s is not available: %v", inputIdx, ntxid, pubKey.String(), err)
@@ -915,6 +925,41 @@ func getRawSigs(transactions []*withdrawalTx) (map[Ntxid]TxSigs, error) {
 	return sigs, nil
 }
 
+// signerRawSig returns the raw signature s makes for the input with the given
+// index of msgtx, with the key derived from the series public key pubKey for
+// the given address index.  An empty signature is returned if s does not hold
+// the private key.
+func signerRawSig(s signer.Signer, msgtx *wire.MsgTx, inputIdx int, redeemScript []byte,
+	pubKey *hdkeychain.ExtendedKey, index Index) (RawSig, error) {
+	childKey, err := pubKey.Child(uint32(index))
+	if err != nil {
+		return nil, newError(ErrKeyChain, "failed to derive public key", err)
+	}
+	ecPubKey, err := childKey.ECPubKey()
+	if err != nil {
+		return nil, newError(ErrKeyChain, "failed to obtain ECPubKey", err)
+	}
+	fingerprint, err := signer.Fingerprint(pubKey)
+	if err != nil {
+		return nil, newError(ErrKeyChain, "failed to obtain fingerprint", err)
+	}
+	key := &signer.Key{
+		PubKey:      ecPubKey.SerializeCompressed(),
+		Fingerprint: fingerprint,
+		Path:        []uint32{uint32(index)},
+	}
+	sig, err := s.SignTxInput(msgtx, inputIdx, redeemScript, txscript.SigHashAll, key)
+	if err == signer.ErrUnknownKey {
+		log.Debugf("Not generating raw sig for input %d because the signer does "+
+			"not hold the private key for %s", inputIdx, pubKey.String())
+		return []byte{}, nil
+	}
+	if err != nil {
+		return nil, newError(ErrRawSigning, "failed to generate raw signature", err)
+	}
+	return sig, nil
+}
+
 // SignTx signs every input of the given MsgTx by looking up (on the addr
 // manager) the redeem script for each of them and constructing the signature
 // script using that and the given raw signatures.
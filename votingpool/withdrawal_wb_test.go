@@ -790,7 +790,7 @@ func TestSignMultiSigUTXO(t *testing.T) {
 	// Create a new tx with a single input that we're going to sign.
 	mgr := pool.Manager()
 	tx := createWithdrawalTx(t, pool, []int64{4e6}, []int64{4e6})
-	sigs, err := getRawSigs([]*withdrawalTx{tx})
+	sigs, err := getRawSigs([]*withdrawalTx{tx}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -862,7 +862,7 @@ func TestSignMultiSigUTXONotEnoughSigs(t *testing.T) {
 
 	mgr := pool.Manager()
 	tx := createWithdrawalTx(t, pool, []int64{4e6}, []int64{})
-	sigs, err := getRawSigs([]*withdrawalTx{tx})
+	sigs, err := getRawSigs([]*withdrawalTx{tx}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -905,7 +905,7 @@ func TestGetRawSigs(t *testing.T) {
 
 	tx := createWithdrawalTx(t, pool, []int64{5e6, 4e6}, []int64{})
 
-	sigs, err := getRawSigs([]*withdrawalTx{tx})
+	sigs, err := getRawSigs([]*withdrawalTx{tx}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -934,7 +934,7 @@ func TestGetRawSigsOnlyOnePrivKeyAvailable(t *testing.T) {
 		series.privateKeys[i] = nil
 	}
 
-	sigs, err := getRawSigs([]*withdrawalTx{tx})
+	sigs, err := getRawSigs([]*withdrawalTx{tx}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -956,7 +956,7 @@ func TestGetRawSigsUnparseableRedeemScript(t *testing.T) {
 	// getRawSigs().
 	tx.inputs[0].addr.script = []byte{0x01}
 
-	_, err := getRawSigs([]*withdrawalTx{tx})
+	_, err := getRawSigs([]*withdrawalTx{tx}, nil)
 
 	TstCheckError(t, "", err, ErrRawSigning)
 }
@@ -970,7 +970,7 @@ func TestGetRawSigsInvalidAddrBranch(t *testing.T) {
 	// an error in getRawSigs().
 	tx.inputs[0].addr.branch = Branch(999)
 
-	_, err := getRawSigs([]*withdrawalTx{tx})
+	_, err := getRawSigs([]*withdrawalTx{tx}, nil)
 
 	TstCheckError(t, "", err, ErrInvalidBranch)
 }
@@ -1035,7 +1035,7 @@ func TestTxSizeCalculation(t *testing.T) {
 	seriesID := tx.inputs[0].addr.SeriesID()
 	tx.addChange(TstNewChangeAddress(t, pool, seriesID, 0).addr.ScriptAddress())
 	msgtx := tx.toMsgTx()
-	sigs, err := getRawSigs([]*withdrawalTx{tx})
+	sigs, err := getRawSigs([]*withdrawalTx{tx}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
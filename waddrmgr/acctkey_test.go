@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2014 The btcsuite developers
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package waddrmgr_test
+
+import (
+	"testing"
+
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcwallet/waddrmgr"
+)
+
+// TestAccountKeys ensures the exported account extended keys derive the
+// addresses of the account and that the private key is only exported when the
+// manager is unlocked.
+func TestAccountKeys(t *testing.T) {
+	teardown, mgr := setupManager(t)
+	defer teardown()
+
+	acctKeyPub, err := mgr.AccountPubKey(0)
+	if err != nil {
+		t.Fatalf("AccountPubKey: unexpected error: %v", err)
+	}
+	if acctKeyPub.IsPrivate() {
+		t.Fatal("AccountPubKey: returned a private key")
+	}
+
+	addrs, err := mgr.NextExternalAddresses(0, 1)
+	if err != nil {
+		t.Fatalf("NextExternalAddresses: unexpected error: %v", err)
+	}
+	branchKey, err := acctKeyPub.Child(0)
+	if err != nil {
+		t.Fatalf("Child: unexpected error: %v", err)
+	}
+	key, err := branchKey.Child(0)
+	if err != nil {
+		t.Fatalf("Child: unexpected error: %v", err)
+	}
+	want, err := key.Address(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Address: unexpected error: %v", err)
+	}
+	if got := addrs[0].Address(); got.String() != want.String() {
+		t.Errorf("Derived address: got %v, want %v", got, want)
+	}
+
+	_, err = mgr.AccountPrivKey(0)
+	if !checkManagerError(t, "AccountPrivKey while locked", err,
+		waddrmgr.ErrLocked) {
+		return
+	}
+	_, err = mgr.AccountPubKey(waddrmgr.ImportedAddrAccount)
+	if !checkManagerError(t, "AccountPubKey of imported account", err,
+		waddrmgr.ErrInvalidAccount) {
+		return
+	}
+
+	if err := mgr.Unlock(privPassphrase); err != nil {
+		t.Fatalf("Unlock: unexpected error: %v", err)
+	}
+	acctKeyPriv, err := mgr.AccountPrivKey(0)
+	if err != nil {
+		t.Fatalf("AccountPrivKey: unexpected error: %v", err)
+	}
+	if !acctKeyPriv.IsPrivate() {
+		t.Fatal("AccountPrivKey: returned a public key")
+	}
+	neutered, err := acctKeyPriv.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+	if neutered.String() != acctKeyPub.String() {
+		t.Errorf("AccountPrivKey: public key mismatch: got %v, want %v",
+			neutered, acctKeyPub)
+	}
+
+	// The private key of a watching-only account is never available.
+	account, err := mgr.ImportAccount("cold", acctKeyPub)
+	if err != nil {
+		t.Fatalf("ImportAccount: unexpected error: %v", err)
+	}
+	_, err = mgr.AccountPrivKey(account)
+	if !checkManagerError(t, "AccountPrivKey of watching-only account",
+		err, waddrmgr.ErrWatchingOnly) {
+		return
+	}
+}
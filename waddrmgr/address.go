@@ -88,6 +88,27 @@ type ManagedScriptAddress interface {
 	Script() ([]byte, error)
 }
 
+// ManagedMultisigAddress extends ManagedScriptAddress for the chained
+// pay-to-script-hash addresses of HD multisig accounts.  The redeem script of
+// these addresses is derived from the account keys of every cosigner and is
+// available even when the address manager is locked.
+type ManagedMultisigAddress interface {
+	ManagedScriptAddress
+
+	// RequiredSigs returns the number of signatures required to redeem
+	// outputs paid to the address.
+	RequiredSigs() int
+
+	// OwnPubKey returns the public key of the redeem script which was
+	// derived from the account key of this address manager.
+	OwnPubKey() *btcec.PublicKey
+
+	// OwnPrivKey returns the private key for OwnPubKey.  It can fail if
+	// the address manager is watching-only or locked, or the account has
+	// no private keys.
+	OwnPrivKey() (*btcec.PrivateKey, error)
+}
+
 // managedAddress represents a public key address.  It also may or may not have
 // the private key associated with the public key.
 type managedAddress struct {
@@ -237,6 +258,13 @@ func (a *managedAddress) PrivKey() (*btcec.PrivateKey, error) {
 		return nil, managerError(ErrLocked, errLocked, nil)
 	}
 
+	// Addresses of accounts imported from an extended public key have no
+	// private key even when the manager is unlocked.
+	if len(a.privKeyEncrypted) == 0 {
+		str := fmt.Sprintf("account %d is watching-only", a.account)
+		return nil, managerError(ErrWatchingOnly, str, nil)
+	}
+
 	// Decrypt the key as needed.  Also, make sure it's a copy since the
 	// private key stored in memory can be cleared at any time.  Otherwise
 	// the returned private key could be invalidated from under the caller.
@@ -498,3 +526,140 @@ func newScriptAddress(m *Manager, account uint32, scriptHash, scriptEncrypted []
 		scriptEncrypted: scriptEncrypted,
 	}, nil
 }
+
+// multisigAddress represents a chained pay-to-script-hash address of an HD
+// multisig account.
+type multisigAddress struct {
+	manager   *Manager
+	account   uint32
+	address   *coinutil.AddressScriptHash
+	script    []byte
+	reqSigs   int
+	ownPubKey *btcec.PublicKey
+	branch    uint32
+	index     uint32
+	internal  bool
+}
+
+// Enforce multisigAddress satisfies the ManagedMultisigAddress interface.
+var _ ManagedMultisigAddress = (*multisigAddress)(nil)
+
+// Account returns the account the address is associated with.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) Account() uint32 {
+	return a.account
+}
+
+// Address returns the coinutil.Address which represents the managed address.
+// This will be a pay-to-script-hash address.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) Address() coinutil.Address {
+	return a.address
+}
+
+// AddrHash returns the script hash for the address.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) AddrHash() []byte {
+	return a.address.Hash160()[:]
+}
+
+// Imported always returns false since multisig addresses are part of the
+// address chains of their account.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) Imported() bool {
+	return false
+}
+
+// Internal returns true if the address was created for internal use such as a
+// change output of a transaction.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) Internal() bool {
+	return a.internal
+}
+
+// Compressed returns false since script addresses are never compressed.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) Compressed() bool {
+	return false
+}
+
+// Used returns true if the address has been used in a transaction.
+//
+// This is part of the ManagedAddress interface implementation.
+func (a *multisigAddress) Used() (bool, error) {
+	return a.manager.fetchUsed(a.AddrHash())
+}
+
+// Script returns the multisig redeem script of the address.  Unlike imported
+// script addresses, it does not require the address manager to be unlocked.
+//
+// This is part of the ManagedScriptAddress interface implementation.
+func (a *multisigAddress) Script() ([]byte, error) {
+	scriptCopy := make([]byte, len(a.script))
+	copy(scriptCopy, a.script)
+	return scriptCopy, nil
+}
+
+// RequiredSigs returns the number of signatures required to redeem outputs
+// paid to the address.
+//
+// This is part of the ManagedMultisigAddress interface implementation.
+func (a *multisigAddress) RequiredSigs() int {
+	return a.reqSigs
+}
+
+// OwnPubKey returns the public key of the redeem script which was derived from
+// the account key of this address manager.
+//
+// This is part of the ManagedMultisigAddress interface implementation.
+func (a *multisigAddress) OwnPubKey() *btcec.PublicKey {
+	return a.ownPubKey
+}
+
+// OwnPrivKey returns the private key for OwnPubKey.  It can fail if the address
+// manager is watching-only or locked, or the account has no private keys.
+//
+// This is part of the ManagedMultisigAddress interface implementation.
+func (a *multisigAddress) OwnPrivKey() (*btcec.PrivateKey, error) {
+	// No private keys are available for a watching-only address manager.
+	if a.manager.watchingOnly {
+		return nil, managerError(ErrWatchingOnly, errWatchingOnly, nil)
+	}
+
+	a.manager.mtx.Lock()
+	defer a.manager.mtx.Unlock()
+
+	// Account manager must be unlocked to derive the private key.
+	if a.manager.locked {
+		return nil, managerError(ErrLocked, errLocked, nil)
+	}
+
+	acctInfo, err := a.manager.loadAccountInfo(a.account)
+	if err != nil {
+		return nil, err
+	}
+	if acctInfo.watchingOnly() {
+		str := fmt.Sprintf("account %d is watching-only", a.account)
+		return nil, managerError(ErrWatchingOnly, str, nil)
+	}
+
+	key, err := a.manager.deriveKey(acctInfo, a.branch, a.index, true)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Zero()
+
+	privKey, err := key.ECPrivKey()
+	if err != nil {
+		str := fmt.Sprintf("failed to derive private key for %s",
+			a.address)
+		return nil, managerError(ErrKeyChain, str, err)
+	}
+	return privKey, nil
+}
@@ -31,7 +31,7 @@ import (
 
 const (
 	// LatestMgrVersion is the most recent manager version.
-	LatestMgrVersion = 4
+	LatestMgrVersion = 5
 )
 
 var (
@@ -107,6 +107,13 @@ type dbBIP0044AccountRow struct {
 	name              string
 }
 
+// dbMultisigAccountRow houses the information stored about an HD multisig
+// account in the database in addition to its BIP0044 account row.
+type dbMultisigAccountRow struct {
+	reqSigs               uint32
+	cosignerKeysEncrypted [][]byte
+}
+
 // dbAddressRow houses common information stored about an address in the
 // database.
 type dbAddressRow struct {
@@ -210,6 +217,12 @@ var (
 
 	// Used addresses (used bucket)
 	usedAddrBucketName = []byte("usedaddrs")
+
+	// multisigAcctBucketName is used to store the number of required
+	// signatures and the cosigner account keys of HD multisig accounts
+	// keyed by the account number.  Accounts without an entry are single
+	// key accounts.
+	multisigAcctBucketName = []byte("msigacct")
 )
 
 // uint32ToBytes converts a 32 bit unsigned integer into a 4-byte slice in
@@ -790,6 +803,104 @@ func putLastAccount(tx walletdb.Tx, account uint32) error {
 	return nil
 }
 
+// deserializeMultisigAccountRow deserializes the passed serialized multisig
+// account information.
+func deserializeMultisigAccountRow(accountID []byte, serializedRow []byte) (*dbMultisigAccountRow, error) {
+	// The serialized multisig account format is:
+	//   <reqsigs><numkeys>[<enckeylen><enckey>...]
+	//
+	// 4 bytes required signatures + 4 bytes number of cosigner keys +
+	// for each cosigner key 4 bytes encrypted key len + encrypted key
+
+	// Given the above, the length of the entry must be at a minimum
+	// the constant value sizes.
+	if len(serializedRow) < 8 {
+		str := fmt.Sprintf("malformed serialized multisig account for "+
+			"key %x", accountID)
+		return nil, managerError(ErrDatabase, str, nil)
+	}
+
+	row := dbMultisigAccountRow{
+		reqSigs: binary.LittleEndian.Uint32(serializedRow[0:4]),
+	}
+	numKeys := binary.LittleEndian.Uint32(serializedRow[4:8])
+	offset := uint32(8)
+	for i := uint32(0); i < numKeys; i++ {
+		if uint32(len(serializedRow)) < offset+4 {
+			str := fmt.Sprintf("malformed serialized multisig "+
+				"account for key %x", accountID)
+			return nil, managerError(ErrDatabase, str, nil)
+		}
+		keyLen := binary.LittleEndian.Uint32(serializedRow[offset : offset+4])
+		offset += 4
+		if uint32(len(serializedRow)) < offset+keyLen {
+			str := fmt.Sprintf("malformed serialized multisig "+
+				"account for key %x", accountID)
+			return nil, managerError(ErrDatabase, str, nil)
+		}
+		keyEncrypted := make([]byte, keyLen)
+		copy(keyEncrypted, serializedRow[offset:offset+keyLen])
+		offset += keyLen
+		row.cosignerKeysEncrypted = append(row.cosignerKeysEncrypted,
+			keyEncrypted)
+	}
+
+	return &row, nil
+}
+
+// serializeMultisigAccountRow returns the serialization of the passed multisig
+// account information.
+func serializeMultisigAccountRow(row *dbMultisigAccountRow) []byte {
+	// The serialized multisig account format is:
+	//   <reqsigs><numkeys>[<enckeylen><enckey>...]
+	//
+	// 4 bytes required signatures + 4 bytes number of cosigner keys +
+	// for each cosigner key 4 bytes encrypted key len + encrypted key
+	size := 8
+	for _, key := range row.cosignerKeysEncrypted {
+		size += 4 + len(key)
+	}
+	buf := make([]byte, size)
+	binary.LittleEndian.PutUint32(buf[0:4], row.reqSigs)
+	binary.LittleEndian.PutUint32(buf[4:8],
+		uint32(len(row.cosignerKeysEncrypted)))
+	offset := 8
+	for _, key := range row.cosignerKeysEncrypted {
+		binary.LittleEndian.PutUint32(buf[offset:offset+4],
+			uint32(len(key)))
+		offset += 4
+		copy(buf[offset:offset+len(key)], key)
+		offset += len(key)
+	}
+	return buf
+}
+
+// fetchMultisigAccount loads the multisig information of the provided account
+// from the database.  A nil row is returned for single key accounts.
+func fetchMultisigAccount(tx walletdb.Tx, account uint32) (*dbMultisigAccountRow, error) {
+	bucket := tx.RootBucket().Bucket(multisigAcctBucketName)
+
+	accountID := uint32ToBytes(account)
+	serializedRow := bucket.Get(accountID)
+	if serializedRow == nil {
+		return nil, nil
+	}
+	return deserializeMultisigAccountRow(accountID, serializedRow)
+}
+
+// putMultisigAccount stores the provided multisig information of an account to
+// the database.
+func putMultisigAccount(tx walletdb.Tx, account uint32, row *dbMultisigAccountRow) error {
+	bucket := tx.RootBucket().Bucket(multisigAcctBucketName)
+
+	err := bucket.Put(uint32ToBytes(account), serializeMultisigAccountRow(row))
+	if err != nil {
+		str := fmt.Sprintf("failed to store multisig account %d", account)
+		return managerError(ErrDatabase, str, err)
+	}
+	return nil
+}
+
 // fetchAddressRow loads address information for the provided address id from
 // the database.  This is used as a common base for the various address types
 // to load the common information.
@@ -1593,6 +1704,12 @@ func createManagerNS(namespace walletdb.Namespace) error {
 			return managerError(ErrDatabase, str, err)
 		}
 
+		_, err = rootBucket.CreateBucket(multisigAcctBucketName)
+		if err != nil {
+			str := "failed to create multisig accounts bucket"
+			return managerError(ErrDatabase, str, err)
+		}
+
 		if err := putLastAccount(tx, DefaultAccountNum); err != nil {
 			return err
 		}
@@ -1660,6 +1777,14 @@ func upgradeManager(namespace walletdb.Namespace, pubPassPhrase []byte, chainPar
 		return managerError(ErrDatabase, str, err)
 	}
 
+	// Databases written by newer versions may hold data this version can
+	// not handle.
+	if version > latestMgrVersion {
+		str := fmt.Sprintf("the manager version %d is newer than the "+
+			"latest supported version %d", version, latestMgrVersion)
+		return managerError(ErrUpgrade, str, nil)
+	}
+
 	// NOTE: There are currently no upgrades, but this is provided here as a
 	// template for how to properly do upgrades.  Each function to upgrade
 	// to the next version must include serializing the new version as a
@@ -1732,6 +1857,15 @@ func upgradeManager(namespace walletdb.Namespace, pubPassPhrase []byte, chainPar
 		version = 4
 	}
 
+	if version < 5 {
+		if err := upgradeToVersion5(namespace); err != nil {
+			return err
+		}
+
+		// The manager is now at version 5.
+		version = 5
+	}
+
 	// Ensure the manager is upraded to the latest version.  This check is
 	// to intentionally cause a failure if the manager version is updated
 	// without writing code to handle the upgrade.
@@ -1956,3 +2090,24 @@ func upgradeToVersion4(namespace walletdb.Namespace, pubPassPhrase []byte) error
 	}
 	return nil
 }
+
+// upgradeToVersion5 upgrades the database from version 4 to version 5.  The
+// multisigAcctBucketName bucket was introduced in version 5 to support HD
+// multisig accounts.  Watching-only accounts imported from an extended public
+// key, which have no encrypted account private key, were introduced in the
+// same version and need no further changes.
+func upgradeToVersion5(namespace walletdb.Namespace) error {
+	err := namespace.Update(func(tx walletdb.Tx) error {
+		_, err := tx.RootBucket().CreateBucket(multisigAcctBucketName)
+		if err != nil {
+			str := "failed to create multisig accounts bucket"
+			return managerError(ErrDatabase, str, err)
+		}
+
+		return putManagerVersion(tx, 5)
+	})
+	if err != nil {
+		return maybeConvertDbError(err)
+	}
+	return nil
+}
@@ -135,6 +135,14 @@ const (
 	// ErrCallBackBreak is used to break from a callback function passed
 	// down to the manager.
 	ErrCallBackBreak
+
+	// ErrGapLimit indicates that creating the requested addresses would
+	// exceed the gap limit of consecutive unused addresses.
+	ErrGapLimit
+
+	// ErrInvalidMultisig indicates that the number of required signatures
+	// or the cosigner keys of an HD multisig account are not valid.
+	ErrInvalidMultisig
 )
 
 // Map of ErrorCode values back to their constant names for pretty printing.
@@ -159,6 +167,8 @@ var errorCodeStrings = map[ErrorCode]string{
 	ErrWrongPassphrase:   "ErrWrongPassphrase",
 	ErrWrongNet:          "ErrWrongNet",
 	ErrCallBackBreak:     "ErrCallBackBreak",
+	ErrGapLimit:          "ErrGapLimit",
+	ErrInvalidMultisig:   "ErrInvalidMultisig",
 }
 
 // String returns the ErrorCode as a human-readable name.
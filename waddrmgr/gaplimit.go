@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2014 The btcsuite developers
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package waddrmgr
+
+import (
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/coinutil/hdkeychain"
+	"github.com/conseweb/stcwallet/walletdb"
+)
+
+// SetGapLimit sets the maximum number of consecutive unused addresses at the
+// end of the external branch of each account.  NextExternalAddresses returns
+// an error with the ErrGapLimit code instead of creating addresses exceeding
+// it, since account discovery does not find addresses following more unused
+// addresses than its gap limit when a wallet is restored from its seed.  The
+// internal branch is not limited since change addresses are used as soon as
+// they are created.  A gap limit of zero, the default, disables the limit.
+func (m *Manager) SetGapLimit(gapLimit uint32) {
+	m.mtx.Lock()
+	m.gapLimit = gapLimit
+	m.mtx.Unlock()
+}
+
+// GapLimit returns the gap limit set by SetGapLimit.
+func (m *Manager) GapLimit() uint32 {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return m.gapLimit
+}
+
+// chainedAddressUsed returns whether the address at the passed branch and
+// index of an account was used.  The hdkeychain.ErrInvalidChild error is
+// returned unchanged for indexes without an address.
+func (m *Manager) chainedAddressUsed(acctInfo *accountInfo, branch, index uint32) (bool, error) {
+	// Addresses of HD multisig accounts are stored by their script hash.
+	if acctInfo.multisig != nil {
+		script, _, err := m.multisigScript(acctInfo, branch, index)
+		if err != nil {
+			return false, err
+		}
+		return m.fetchUsed(coinutil.Hash160(script))
+	}
+
+	pubKey, err := deriveChildPubKey(acctInfo.acctKeyPub, branch, index)
+	if err != nil {
+		return false, err
+	}
+
+	// Chained addresses are always created from compressed public keys.
+	return m.fetchUsed(coinutil.Hash160(pubKey.SerializeCompressed()))
+}
+
+// unusedAddresses returns the number of consecutive unused addresses of a
+// branch preceding the address at index, counting no more than the gap limit.
+//
+// This function MUST be called with the manager lock held.
+func (m *Manager) unusedAddresses(acctInfo *accountInfo, branch, index uint32) (uint32, error) {
+	var unused uint32
+	for i := index; i > 0 && unused < m.gapLimit; i-- {
+		used, err := m.chainedAddressUsed(acctInfo, branch, i-1)
+		if err == hdkeychain.ErrInvalidChild {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if used {
+			break
+		}
+		unused++
+	}
+	return unused, nil
+}
+
+// ExceedsGapLimit returns whether a chained address follows at least the gap
+// limit of consecutive unused addresses of its branch, in which case account
+// discovery does not find it when the wallet is restored from its seed.  It
+// returns false for addresses which are not chained or when no gap limit is
+// set.  A ManagerError with an error code of ErrAddressNotFound is returned
+// if the address is not known.
+func (m *Manager) ExceedsGapLimit(address coinutil.Address) (bool, error) {
+	// Pay-to-pubkey addresses are stored by their pubkey hash.
+	if pka, ok := address.(*coinutil.AddressPubKey); ok {
+		address = pka.AddressPubKeyHash()
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.gapLimit == 0 {
+		return false, nil
+	}
+
+	var rowInterface interface{}
+	err := m.namespace.View(func(tx walletdb.Tx) error {
+		var err error
+		rowInterface, err = fetchAddress(tx, address.ScriptAddress())
+		return err
+	})
+	if err != nil {
+		return false, maybeConvertDbError(err)
+	}
+	row, ok := rowInterface.(*dbChainAddressRow)
+	if !ok {
+		return false, nil
+	}
+
+	acctInfo, err := m.loadAccountInfo(row.account)
+	if err != nil {
+		return false, err
+	}
+
+	var unused uint32
+	for i := uint32(0); i < row.index; i++ {
+		used, err := m.chainedAddressUsed(acctInfo, row.branch, i)
+		if err == hdkeychain.ErrInvalidChild {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if used {
+			unused = 0
+			continue
+		}
+		unused++
+		if unused >= m.gapLimit {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2014 The btcsuite developers
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package waddrmgr_test
+
+import (
+	"testing"
+
+	"github.com/conseweb/stcwallet/waddrmgr"
+)
+
+// TestGapLimit ensures external addresses are not created past the gap limit
+// and that addresses beyond it are reported.
+func TestGapLimit(t *testing.T) {
+	teardown, mgr := setupManager(t)
+	defer teardown()
+
+	mgr.SetGapLimit(3)
+	if limit := mgr.GapLimit(); limit != 3 {
+		t.Fatalf("GapLimit: got %d, want 3", limit)
+	}
+
+	addrs, err := mgr.NextExternalAddresses(0, 3)
+	if err != nil {
+		t.Fatalf("NextExternalAddresses: unexpected error: %v", err)
+	}
+	_, err = mgr.NextExternalAddresses(0, 1)
+	if !checkManagerError(t, "Address past gap limit", err,
+		waddrmgr.ErrGapLimit) {
+		return
+	}
+
+	// Change addresses are not limited.
+	if _, err := mgr.NextInternalAddresses(0, 5); err != nil {
+		t.Fatalf("NextInternalAddresses: unexpected error: %v", err)
+	}
+
+	// Using an address allows the gap limit of addresses following it.
+	if err := mgr.MarkUsed(addrs[1].Address()); err != nil {
+		t.Fatalf("MarkUsed: unexpected error: %v", err)
+	}
+	more, err := mgr.NextExternalAddresses(0, 2)
+	if err != nil {
+		t.Fatalf("NextExternalAddresses after use: unexpected error: %v",
+			err)
+	}
+	addrs = append(addrs, more...)
+	_, err = mgr.NextExternalAddresses(0, 1)
+	if !checkManagerError(t, "Address past gap limit after use", err,
+		waddrmgr.ErrGapLimit) {
+		return
+	}
+
+	// The last address follows two unused addresses, which only exceeds
+	// a gap limit of two.
+	last := addrs[len(addrs)-1].Address()
+	for _, test := range []struct {
+		gapLimit uint32
+		want     bool
+	}{
+		{3, false},
+		{2, true},
+		{0, false},
+	} {
+		mgr.SetGapLimit(test.gapLimit)
+		exceeds, err := mgr.ExceedsGapLimit(last)
+		if err != nil {
+			t.Fatalf("ExceedsGapLimit: unexpected error: %v", err)
+		}
+		if exceeds != test.want {
+			t.Errorf("ExceedsGapLimit with gap limit %d: got %v, "+
+				"want %v", test.gapLimit, exceeds, test.want)
+		}
+	}
+
+	// A gap limit of zero disables the limit.
+	if _, err := mgr.NextExternalAddresses(0, 1); err != nil {
+		t.Errorf("NextExternalAddresses without gap limit: unexpected "+
+			"error: %v", err)
+	}
+
+	// Restored addresses are derived past the gap limit, which is left
+	// unchanged.
+	mgr.SetGapLimit(2)
+	if _, err := mgr.RestoreExternalAddresses(0, 3); err != nil {
+		t.Fatalf("RestoreExternalAddresses: unexpected error: %v", err)
+	}
+	if limit := mgr.GapLimit(); limit != 2 {
+		t.Errorf("GapLimit after restoring addresses: got %d, want 2",
+			limit)
+	}
+	_, err = mgr.NextExternalAddresses(0, 1)
+	checkManagerError(t, "Address past gap limit after restoring", err,
+		waddrmgr.ErrGapLimit)
+}
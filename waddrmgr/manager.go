@@ -21,6 +21,7 @@ import (
 	"crypto/sha512"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/conseweb/coinutil"
 	"github.com/conseweb/coinutil/hdkeychain"
@@ -150,6 +151,8 @@ type accountInfo struct {
 	// The account key is used to derive the branches which in turn derive
 	// the internal and external addresses.
 	// The accountKeyPriv will be nil when the address manager is locked.
+	// Accounts imported from an extended public key have no encrypted
+	// private key and their accountKeyPriv is always nil.
 	acctKeyEncrypted []byte
 	acctKeyPriv      *hdkeychain.ExtendedKey
 	acctKeyPub       *hdkeychain.ExtendedKey
@@ -163,6 +166,16 @@ type accountInfo struct {
 	// intended for internal wallet use such as change addresses.
 	nextInternalIndex uint32
 	lastInternalAddr  ManagedAddress
+
+	// The multisig information is only set for HD multisig accounts, whose
+	// chained addresses are pay-to-script-hash multisig addresses.
+	multisig *multisigInfo
+}
+
+// watchingOnly returns whether the account was imported from an extended
+// public key and therefore has no private keys.
+func (a *accountInfo) watchingOnly() bool {
+	return len(a.acctKeyEncrypted) == 0
 }
 
 // unlockDeriveInfo houses the information needed to derive a private key for a
@@ -303,6 +316,11 @@ type Manager struct {
 	// manager is already unlocked.  The hash is zeroed each lock.
 	privPassphraseSalt   [saltSize]byte
 	hashedPrivPassphrase [sha512.Size]byte
+
+	// gapLimit is the maximum number of consecutive unused addresses at
+	// the end of the external branch of each account, or zero when
+	// external addresses may be created without limit.
+	gapLimit uint32
 }
 
 // lock performs a best try effort to remove and zero all secret keys associated
@@ -412,13 +430,14 @@ func (m *Manager) keyToManaged(derivedKey *hdkeychain.ExtendedKey, account, bran
 }
 
 // deriveKey returns either a public or private derived extended key based on
-// the private flag for the given an account info, branch, and index.
+// the private flag for the given an account info, branch, and index.  A public
+// key is always returned for watching-only accounts.
 func (m *Manager) deriveKey(acctInfo *accountInfo, branch, index uint32, private bool) (*hdkeychain.ExtendedKey, error) {
 	// Choose the public or private extended key based on whether or not
 	// the private flag was specified.  This, in turn, allows for public or
 	// private child derivation.
 	acctKey := acctInfo.acctKeyPub
-	if private {
+	if private && !acctInfo.watchingOnly() {
 		acctKey = acctInfo.acctKeyPriv
 	}
 
@@ -440,6 +459,35 @@ func (m *Manager) deriveKey(acctInfo *accountInfo, branch, index uint32, private
 	return addressKey, nil
 }
 
+// deriveChildPubKey returns the public key at the passed branch and index of
+// an account extended key.  The hdkeychain.ErrInvalidChild error is returned
+// unchanged for indexes without a key.
+func deriveChildPubKey(acctKey *hdkeychain.ExtendedKey, branch, index uint32) (*btcec.PublicKey, error) {
+	branchKey, err := acctKey.Child(branch)
+	if err != nil {
+		str := fmt.Sprintf("failed to derive extended key branch %d",
+			branch)
+		return nil, managerError(ErrKeyChain, str, err)
+	}
+	defer branchKey.Zero()
+
+	key, err := branchKey.Child(index)
+	if err == hdkeychain.ErrInvalidChild {
+		return nil, err
+	}
+	if err != nil {
+		str := fmt.Sprintf("failed to generate child %d", index)
+		return nil, managerError(ErrKeyChain, str, err)
+	}
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		str := fmt.Sprintf("failed to generate public key of child %d",
+			index)
+		return nil, managerError(ErrKeyChain, str, err)
+	}
+	return pubKey, nil
+}
+
 // loadAccountInfo attempts to load and cache information about the given
 // account from the database.   This includes what is necessary to derive new
 // keys for it and track the state of the internal and external branches.
@@ -493,7 +541,7 @@ func (m *Manager) loadAccountInfo(account uint32) (*accountInfo, error) {
 		nextInternalIndex: row.nextInternalIndex,
 	}
 
-	if !m.locked {
+	if !m.locked && !acctInfo.watchingOnly() {
 		// Use the crypto private key to decrypt the account private
 		// extended keys.
 		decrypted, err := m.cryptoKeyPriv.Decrypt(acctInfo.acctKeyEncrypted)
@@ -512,16 +560,18 @@ func (m *Manager) loadAccountInfo(account uint32) (*accountInfo, error) {
 		acctInfo.acctKeyPriv = acctKeyPriv
 	}
 
+	// Load the cosigner keys of HD multisig accounts.
+	acctInfo.multisig, err = m.loadMultisigInfo(account)
+	if err != nil {
+		return nil, err
+	}
+
 	// Derive and cache the managed address for the last external address.
 	branch, index := externalBranch, row.nextExternalIndex
 	if index > 0 {
 		index--
 	}
-	lastExtKey, err := m.deriveKey(acctInfo, branch, index, !m.locked)
-	if err != nil {
-		return nil, err
-	}
-	lastExtAddr, err := m.keyToManaged(lastExtKey, account, branch, index)
+	lastExtAddr, err := m.chainedAddress(acctInfo, account, branch, index)
 	if err != nil {
 		return nil, err
 	}
@@ -532,11 +582,7 @@ func (m *Manager) loadAccountInfo(account uint32) (*accountInfo, error) {
 	if index > 0 {
 		index--
 	}
-	lastIntKey, err := m.deriveKey(acctInfo, branch, index, !m.locked)
-	if err != nil {
-		return nil, err
-	}
-	lastIntAddr, err := m.keyToManaged(lastIntKey, account, branch, index)
+	lastIntAddr, err := m.chainedAddress(acctInfo, account, branch, index)
 	if err != nil {
 		return nil, err
 	}
@@ -547,18 +593,24 @@ func (m *Manager) loadAccountInfo(account uint32) (*accountInfo, error) {
 	return acctInfo, nil
 }
 
-// deriveKeyFromPath returns either a public or private derived extended key
-// based on the private flag for the given an account, branch, and index.
+// chainedAddress returns a new managed address for the passed branch and index
+// of an account.  Addresses of HD multisig accounts are multisig addresses.
 //
 // This function MUST be called with the manager lock held for writes.
-func (m *Manager) deriveKeyFromPath(account, branch, index uint32, private bool) (*hdkeychain.ExtendedKey, error) {
-	// Look up the account key information.
-	acctInfo, err := m.loadAccountInfo(account)
+func (m *Manager) chainedAddress(acctInfo *accountInfo, account, branch, index uint32) (ManagedAddress, error) {
+	if acctInfo.multisig != nil {
+		ma, err := m.newMultisigAddress(acctInfo, account, branch, index)
+		if err != nil {
+			return nil, err
+		}
+		return ma, nil
+	}
+
+	addressKey, err := m.deriveKey(acctInfo, branch, index, !m.locked)
 	if err != nil {
 		return nil, err
 	}
-
-	return m.deriveKey(acctInfo, branch, index, private)
+	return m.keyToManaged(addressKey, account, branch, index)
 }
 
 // chainAddressRowToManaged returns a new managed address based on chained
@@ -566,13 +618,12 @@ func (m *Manager) deriveKeyFromPath(account, branch, index uint32, private bool)
 //
 // This function MUST be called with the manager lock held for writes.
 func (m *Manager) chainAddressRowToManaged(row *dbChainAddressRow) (ManagedAddress, error) {
-	addressKey, err := m.deriveKeyFromPath(row.account, row.branch,
-		row.index, !m.locked)
+	acctInfo, err := m.loadAccountInfo(row.account)
 	if err != nil {
 		return nil, err
 	}
 
-	return m.keyToManaged(addressKey, row.account, row.branch, row.index)
+	return m.chainedAddress(acctInfo, row.account, row.branch, row.index)
 }
 
 // importedAddressRowToManaged returns a new managed address based on imported
@@ -719,6 +770,63 @@ func (m *Manager) AddrAccount(address coinutil.Address) (uint32, error) {
 	return account, nil
 }
 
+// AddressDetails describes when an address was added to the address manager
+// and, for chained addresses, the BIP0044 path it was derived from.
+type AddressDetails struct {
+	// Account is the account the address belongs to.
+	Account uint32
+
+	// AddTime is the time the address was added to the address manager.
+	AddTime time.Time
+
+	// Chained is true when the address was derived from the root extended
+	// key rather than imported.  Branch and Index are only set for chained
+	// addresses.
+	Chained bool
+	Branch  uint32
+	Index   uint32
+}
+
+// AddrDetails returns details about how and when the given address was added
+// to the address manager.  A ManagerError with an error code of
+// ErrAddressNotFound is returned if the address is not known.
+func (m *Manager) AddrDetails(address coinutil.Address) (*AddressDetails, error) {
+	// Pay-to-pubkey addresses are stored by their pubkey hash.
+	if pka, ok := address.(*coinutil.AddressPubKey); ok {
+		address = pka.AddressPubKeyHash()
+	}
+
+	var rowInterface interface{}
+	err := m.namespace.View(func(tx walletdb.Tx) error {
+		var err error
+		rowInterface, err = fetchAddress(tx, address.ScriptAddress())
+		return err
+	})
+	if err != nil {
+		return nil, maybeConvertDbError(err)
+	}
+
+	var details AddressDetails
+	var row *dbAddressRow
+	switch r := rowInterface.(type) {
+	case *dbChainAddressRow:
+		row = &r.dbAddressRow
+		details.Chained = true
+		details.Branch = r.branch
+		details.Index = r.index
+	case *dbImportedAddressRow:
+		row = &r.dbAddressRow
+	case *dbScriptAddressRow:
+		row = &r.dbAddressRow
+	default:
+		str := fmt.Sprintf("unsupported address type %T", rowInterface)
+		return nil, managerError(ErrDatabase, str, nil)
+	}
+	details.Account = row.account
+	details.AddTime = time.Unix(int64(row.addTime), 0)
+	return &details, nil
+}
+
 // ChangePassphrase changes either the public or private passphrase to the
 // provided value depending on the private flag.  In order to change the private
 // password, the address manager must not be watching-only.  The new passphrase
@@ -1323,6 +1431,9 @@ func (m *Manager) Unlock(passphrase []byte) error {
 	// Use the crypto private key to decrypt all of the account private
 	// extended keys.
 	for account, acctInfo := range m.acctInfo {
+		if acctInfo.watchingOnly() {
+			continue
+		}
 		decrypted, err := m.cryptoKeyPriv.Decrypt(acctInfo.acctKeyEncrypted)
 		if err != nil {
 			m.lock()
@@ -1345,8 +1456,22 @@ func (m *Manager) Unlock(passphrase []byte) error {
 	// Derive any private keys that are pending due to them being created
 	// while the address manager was locked.
 	for _, info := range m.deriveOnUnlock {
-		addressKey, err := m.deriveKeyFromPath(info.managedAddr.account,
-			info.branch, info.index, true)
+		acctInfo, err := m.loadAccountInfo(info.managedAddr.account)
+		if err != nil {
+			m.lock()
+			return err
+		}
+
+		// Addresses of watching-only accounts have no private keys to
+		// derive.
+		if acctInfo.watchingOnly() {
+			m.deriveOnUnlock[0] = nil
+			m.deriveOnUnlock = m.deriveOnUnlock[1:]
+			continue
+		}
+
+		addressKey, err := m.deriveKey(acctInfo, info.branch,
+			info.index, true)
 		if err != nil {
 			m.lock()
 			return err
@@ -1416,11 +1541,25 @@ func (m *Manager) ChainParams() *chaincfg.Params {
 	return m.chainParams
 }
 
+// Version returns the version of the manager as stored in its database.  It
+// is the latest version once the manager has been opened, since the database
+// is upgraded when opened.
+func (m *Manager) Version() (uint32, error) {
+	var version uint32
+	err := m.namespace.View(func(tx walletdb.Tx) error {
+		var err error
+		version, err = fetchManagerVersion(tx)
+		return err
+	})
+	return version, err
+}
+
 // nextAddresses returns the specified number of next chained address from the
-// branch indicated by the internal flag.
+// branch indicated by the internal flag.  External addresses exceeding the gap
+// limit are refused unless ignoreGapLimit is set.
 //
 // This function MUST be called with the manager lock held for writes.
-func (m *Manager) nextAddresses(account uint32, numAddresses uint32, internal bool) ([]ManagedAddress, error) {
+func (m *Manager) nextAddresses(account uint32, numAddresses uint32, internal, ignoreGapLimit bool) ([]ManagedAddress, error) {
 	// The next address can only be generated for accounts that have already
 	// been created.
 	acctInfo, err := m.loadAccountInfo(account)
@@ -1429,9 +1568,9 @@ func (m *Manager) nextAddresses(account uint32, numAddresses uint32, internal bo
 	}
 
 	// Choose the account key to used based on whether the address manager
-	// is locked.
+	// is locked and the account has private keys.
 	acctKey := acctInfo.acctKeyPub
-	if !m.locked {
+	if !m.locked && !acctInfo.watchingOnly() {
 		acctKey = acctInfo.acctKeyPriv
 	}
 
@@ -1453,6 +1592,29 @@ func (m *Manager) nextAddresses(account uint32, numAddresses uint32, internal bo
 		return nil, managerError(ErrTooManyAddresses, str, nil)
 	}
 
+	// Refuse to create external addresses which would follow more than the
+	// gap limit of consecutive unused addresses, since account discovery
+	// would not find them when restoring the wallet from its seed.
+	if !internal && !ignoreGapLimit && m.gapLimit != 0 {
+		unused, err := m.unusedAddresses(acctInfo, branchNum, nextIndex)
+		if err != nil {
+			return nil, err
+		}
+		if unused+numAddresses > m.gapLimit {
+			str := fmt.Sprintf("%d new addresses would exceed the gap "+
+				"limit of %d consecutive unused addresses",
+				numAddresses, m.gapLimit)
+			return nil, managerError(ErrGapLimit, str, nil)
+		}
+	}
+
+	// Addresses of HD multisig accounts are derived from the keys of every
+	// cosigner instead.
+	if acctInfo.multisig != nil {
+		return m.nextMultisigAddresses(acctInfo, account, branchNum,
+			nextIndex, numAddresses)
+	}
+
 	// Derive the appropriate branch key and ensure it is zeroed when done.
 	branchKey, err := acctKey.Child(branchNum)
 	if err != nil {
@@ -1540,7 +1702,7 @@ func (m *Manager) nextAddresses(account uint32, numAddresses uint32, internal bo
 		// Add the new managed address to the list of addresses that
 		// need their private keys derived when the address manager is
 		// next unlocked.
-		if m.locked && !m.watchingOnly {
+		if m.locked && !m.watchingOnly && !acctInfo.watchingOnly() {
 			m.deriveOnUnlock = append(m.deriveOnUnlock, info)
 		}
 
@@ -1572,7 +1734,25 @@ func (m *Manager) NextExternalAddresses(account uint32, numAddresses uint32) ([]
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
-	return m.nextAddresses(account, numAddresses, false)
+	return m.nextAddresses(account, numAddresses, false, false)
+}
+
+// RestoreExternalAddresses returns the specified number of next chained
+// addresses that are intended for external use, like NextExternalAddresses,
+// but without refusing addresses exceeding the gap limit.  It is meant for
+// restoring addresses which were already handed out by a previous wallet, and
+// which must be derived regardless of how many of them are unused.
+func (m *Manager) RestoreExternalAddresses(account uint32, numAddresses uint32) ([]ManagedAddress, error) {
+	// Enforce maximum account number.
+	if account > MaxAccountNum {
+		err := managerError(ErrAccountNumTooHigh, errAcctTooHigh, nil)
+		return nil, err
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return m.nextAddresses(account, numAddresses, false, true)
 }
 
 // NextInternalAddresses returns the specified number of next chained addresses
@@ -1587,7 +1767,7 @@ func (m *Manager) NextInternalAddresses(account uint32, numAddresses uint32) ([]
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
-	return m.nextAddresses(account, numAddresses, true)
+	return m.nextAddresses(account, numAddresses, true, false)
 }
 
 // LastExternalAddress returns the most recently requested chained external
@@ -1763,6 +1943,161 @@ func (m *Manager) NewAccount(name string) (uint32, error) {
 	return account, err
 }
 
+// ImportAccount creates a new watching-only account with the given name from
+// the extended public key of an account, such as one exported from a cold
+// storage wallet, and returns its account number.  Addresses on both branches
+// of the account are derived from the public key, but their private keys are
+// never available, so the PrivKey method of its addresses returns an error
+// with the ErrWatchingOnly code.  Since no private data is stored, importing
+// does not require the manager to be unlocked.  If an account with the same
+// name already exists, ErrDuplicateAccount will be returned.
+func (m *Manager) ImportAccount(name string, acctKeyPub *hdkeychain.ExtendedKey) (uint32, error) {
+	if acctKeyPub.IsPrivate() {
+		str := "account extended key to import must be public"
+		return 0, managerError(ErrKeyChain, str, nil)
+	}
+	if !acctKeyPub.IsForNet(m.chainParams) {
+		str := fmt.Sprintf("extended key is not for the same network the "+
+			"address manager is configured for (%s)",
+			m.chainParams.Name)
+		return 0, managerError(ErrWrongNet, str, nil)
+	}
+
+	// Ensure the branch keys can be derived for the account.
+	if err := checkBranchKeys(acctKeyPub); err != nil {
+		str := "failed to derive branch keys for account"
+		return 0, managerError(ErrKeyChain, str, err)
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	// Validate account name
+	if err := ValidateAccountName(name); err != nil {
+		return 0, err
+	}
+
+	// Check that account with the same name does not exist
+	_, err := m.lookupAccount(name)
+	if err == nil {
+		str := fmt.Sprintf("account with the same name already exists")
+		return 0, managerError(ErrDuplicateAccount, str, err)
+	}
+
+	acctPubEnc, err := m.cryptoKeyPub.Encrypt([]byte(acctKeyPub.String()))
+	if err != nil {
+		str := "failed to encrypt public key for account"
+		return 0, managerError(ErrCrypto, str, err)
+	}
+
+	var account uint32
+	err = m.namespace.Update(func(tx walletdb.Tx) error {
+		var err error
+		account, err = fetchLastAccount(tx)
+		if err != nil {
+			return err
+		}
+		account++
+		if account > MaxAccountNum {
+			return managerError(ErrAccountNumTooHigh, errAcctTooHigh,
+				nil)
+		}
+
+		// Watching-only accounts are saved without an encrypted
+		// private key.
+		err = putAccountInfo(tx, account, acctPubEnc, nil, 0, 0, name)
+		if err != nil {
+			return err
+		}
+		return putLastAccount(tx, account)
+	})
+	if err != nil {
+		return 0, maybeConvertDbError(err)
+	}
+	return account, nil
+}
+
+// IsWatchingOnlyAccount returns whether the account was imported from an
+// extended public key by ImportAccount and therefore has no private keys.
+func (m *Manager) IsWatchingOnlyAccount(account uint32) (bool, error) {
+	if account == ImportedAddrAccount {
+		return false, nil
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	acctInfo, err := m.loadAccountInfo(account)
+	if err != nil {
+		return false, err
+	}
+	return acctInfo.watchingOnly(), nil
+}
+
+// AccountPubKey returns the BIP0044 account extended public key of the
+// account.  It can be used to derive the addresses on both branches of the
+// account without access to the wallet, or imported as a watching-only account
+// by another wallet.
+func (m *Manager) AccountPubKey(account uint32) (*hdkeychain.ExtendedKey, error) {
+	if account == ImportedAddrAccount {
+		str := "imported account has no extended key"
+		return nil, managerError(ErrInvalidAccount, str, nil)
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	acctInfo, err := m.loadAccountInfo(account)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return a copy so the cached key can not be modified or zeroed by
+	// the caller.
+	return copyExtendedKey(acctInfo.acctKeyPub)
+}
+
+// AccountPrivKey returns the BIP0044 account extended private key of the
+// account, from which the private keys of all of its addresses can be derived.
+// It requires the manager to be unlocked, and returns an error with the
+// ErrWatchingOnly code for watching-only accounts and managers.
+func (m *Manager) AccountPrivKey(account uint32) (*hdkeychain.ExtendedKey, error) {
+	if m.watchingOnly {
+		return nil, managerError(ErrWatchingOnly, errWatchingOnly, nil)
+	}
+	if account == ImportedAddrAccount {
+		str := "imported account has no extended key"
+		return nil, managerError(ErrInvalidAccount, str, nil)
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.locked {
+		return nil, managerError(ErrLocked, errLocked, nil)
+	}
+
+	acctInfo, err := m.loadAccountInfo(account)
+	if err != nil {
+		return nil, err
+	}
+	if acctInfo.watchingOnly() {
+		str := fmt.Sprintf("account %d is watching-only", account)
+		return nil, managerError(ErrWatchingOnly, str, nil)
+	}
+	return copyExtendedKey(acctInfo.acctKeyPriv)
+}
+
+// copyExtendedKey returns a copy of an extended key.
+func copyExtendedKey(key *hdkeychain.ExtendedKey) (*hdkeychain.ExtendedKey, error) {
+	keyCopy, err := hdkeychain.NewKeyFromString(key.String())
+	if err != nil {
+		str := "failed to copy extended key"
+		return nil, managerError(ErrKeyChain, str, err)
+	}
+	return keyCopy, nil
+}
+
 // RenameAccount renames an account stored in the manager based on the
 // given account number with the given name.  If an account with the same name
 // already exists, ErrDuplicateAccount will be returned.
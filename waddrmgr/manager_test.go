@@ -533,6 +533,50 @@ func testInternalAddresses(tc *testContext) bool {
 	return true
 }
 
+// testAddrDetails ensures the details returned for the chained addresses of
+// the account under test report the expected BIP0044 branch and index.
+func testAddrDetails(tc *testContext) bool {
+	prefix := testNamePrefix(tc) + " testAddrDetails"
+	chainParams := tc.manager.ChainParams()
+
+	tests := []struct {
+		branch uint32
+		addrs  []expectedAddr
+	}{
+		{branch: 0, addrs: expectedExternalAddrs},
+		{branch: 1, addrs: expectedInternalAddrs},
+	}
+	for _, test := range tests {
+		for i, expected := range test.addrs {
+			prefix := fmt.Sprintf("%s branch %d #%d", prefix,
+				test.branch, i)
+			utilAddr, err := coinutil.NewAddressPubKeyHash(
+				expected.addressHash, chainParams)
+			if err != nil {
+				tc.t.Errorf("%s NewAddressPubKeyHash: unexpected "+
+					"error: %v", prefix, err)
+				return false
+			}
+			details, err := tc.manager.AddrDetails(utilAddr)
+			if err != nil {
+				tc.t.Errorf("%s: unexpected error: %v", prefix, err)
+				return false
+			}
+			if !details.Chained || details.Account != tc.account ||
+				details.Branch != test.branch ||
+				details.Index != uint32(i) {
+
+				tc.t.Errorf("%s: unexpected details - got %+v, "+
+					"want account %d branch %d index %d", prefix,
+					details, tc.account, test.branch, i)
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
 // testLocking tests the basic locking semantics of the address manager work
 // as expected.  Other tests ensure addresses behave as expected under locked
 // and unlocked conditions.
@@ -1350,6 +1394,7 @@ func testManagerAPI(tc *testContext) {
 	testLocking(tc)
 	testExternalAddresses(tc)
 	testInternalAddresses(tc)
+	testAddrDetails(tc)
 	testImportPrivateKey(tc)
 	testImportScript(tc)
 	testMarkUsed(tc)
@@ -1753,6 +1798,15 @@ func TestManager(t *testing.T) {
 	}
 	*waddrmgr.TstLatestMgrVersion--
 
+	// Ensure databases written by a newer version are refused.
+	*waddrmgr.TstLatestMgrVersion--
+	_, err = waddrmgr.Open(mgrNamespace, pubPassphrase,
+		&chaincfg.MainNetParams, nil)
+	if !checkManagerError(t, "Newer version", err, waddrmgr.ErrUpgrade) {
+		return
+	}
+	*waddrmgr.TstLatestMgrVersion++
+
 	// Open the manager and run all the tests again in open mode which
 	// avoids reinserting new addresses like the create mode tests do.
 	mgr, err = waddrmgr.Open(mgrNamespace, pubPassphrase,
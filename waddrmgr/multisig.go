@@ -0,0 +1,325 @@
+/*
+ * Copyright (c) 2014 The btcsuite developers
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package waddrmgr
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/coinutil/hdkeychain"
+	"github.com/conseweb/stcd/btcec"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcwallet/walletdb"
+)
+
+// MaxMultisigKeys is the maximum number of keys of an HD multisig account,
+// including the key of the account itself.  Redeem scripts with more
+// compressed public keys exceed the size allowed for standard
+// pay-to-script-hash scripts.
+const MaxMultisigKeys = 15
+
+// multisigInfo houses the number of required signatures and the account
+// extended public keys of the other cosigners of an HD multisig account.
+type multisigInfo struct {
+	reqSigs      int
+	cosignerKeys []*hdkeychain.ExtendedKey
+}
+
+// pubKeySlice implements sort.Interface to sort serialized public keys
+// lexicographically as described by BIP0067.
+type pubKeySlice [][]byte
+
+func (s pubKeySlice) Len() int           { return len(s) }
+func (s pubKeySlice) Less(i, j int) bool { return bytes.Compare(s[i], s[j]) < 0 }
+func (s pubKeySlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// multisigScript returns the redeem script of the address at the passed branch
+// and index of an HD multisig account along with the public key derived from
+// the account's own extended key.  The public keys of every cosigner are
+// sorted as described by BIP0067, so all cosigners derive the same script
+// regardless of the order of their keys.  The hdkeychain.ErrInvalidChild error
+// is returned unchanged if the index is invalid for any of the keys.
+func (m *Manager) multisigScript(acctInfo *accountInfo, branch, index uint32) ([]byte, *btcec.PublicKey, error) {
+	ownPubKey, err := deriveChildPubKey(acctInfo.acctKeyPub, branch, index)
+	if err != nil {
+		return nil, nil, err
+	}
+	pubKeys := make([][]byte, 0, len(acctInfo.multisig.cosignerKeys)+1)
+	pubKeys = append(pubKeys, ownPubKey.SerializeCompressed())
+	for _, cosignerKey := range acctInfo.multisig.cosignerKeys {
+		pubKey, err := deriveChildPubKey(cosignerKey, branch, index)
+		if err != nil {
+			return nil, nil, err
+		}
+		pubKeys = append(pubKeys, pubKey.SerializeCompressed())
+	}
+	sort.Sort(pubKeySlice(pubKeys))
+
+	addrs := make([]*coinutil.AddressPubKey, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		addrs[i], err = coinutil.NewAddressPubKey(pubKey, m.chainParams)
+		if err != nil {
+			str := "failed to create multisig public key address"
+			return nil, nil, managerError(ErrKeyChain, str, err)
+		}
+	}
+	script, err := txscript.MultiSigScript(addrs, acctInfo.multisig.reqSigs)
+	if err != nil {
+		str := "failed to create multisig redeem script"
+		return nil, nil, managerError(ErrInvalidMultisig, str, err)
+	}
+	return script, ownPubKey, nil
+}
+
+// newMultisigAddress returns the managed address at the passed branch and index
+// of an HD multisig account.  The hdkeychain.ErrInvalidChild error is returned
+// unchanged if the index is invalid for any of the cosigner keys.
+//
+// This function MUST be called with the manager lock held for writes.
+func (m *Manager) newMultisigAddress(acctInfo *accountInfo, account, branch, index uint32) (*multisigAddress, error) {
+	script, ownPubKey, err := m.multisigScript(acctInfo, branch, index)
+	if err != nil {
+		return nil, err
+	}
+	address, err := coinutil.NewAddressScriptHash(script, m.chainParams)
+	if err != nil {
+		str := "failed to create multisig address"
+		return nil, managerError(ErrKeyChain, str, err)
+	}
+
+	return &multisigAddress{
+		manager:   m,
+		account:   account,
+		address:   address,
+		script:    script,
+		reqSigs:   acctInfo.multisig.reqSigs,
+		ownPubKey: ownPubKey,
+		branch:    branch,
+		index:     index,
+		internal:  branch == internalBranch,
+	}, nil
+}
+
+// nextMultisigAddresses returns the specified number of next addresses of an
+// HD multisig account from the passed branch, starting at nextIndex.  Indexes
+// which are invalid for any of the cosigner keys are skipped.
+//
+// This function MUST be called with the manager lock held for writes.
+func (m *Manager) nextMultisigAddresses(acctInfo *accountInfo, account, branch, nextIndex, numAddresses uint32) ([]ManagedAddress, error) {
+	addrs := make([]*multisigAddress, 0, numAddresses)
+	for uint32(len(addrs)) < numAddresses {
+		ma, err := m.newMultisigAddress(acctInfo, account, branch,
+			nextIndex)
+		nextIndex++
+		if err == hdkeychain.ErrInvalidChild {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, ma)
+	}
+
+	// Now that all addresses have been successfully generated, update the
+	// database in a single transaction.
+	err := m.namespace.Update(func(tx walletdb.Tx) error {
+		for _, ma := range addrs {
+			err := putChainedAddress(tx, ma.AddrHash(), account,
+				ssFull, ma.branch, ma.index)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, maybeConvertDbError(err)
+	}
+
+	managedAddresses := make([]ManagedAddress, 0, len(addrs))
+	for _, ma := range addrs {
+		m.addrs[addrKey(ma.AddrHash())] = ma
+		managedAddresses = append(managedAddresses, ma)
+	}
+
+	// Set the last address and next address for tracking.
+	ma := addrs[len(addrs)-1]
+	if branch == internalBranch {
+		acctInfo.nextInternalIndex = nextIndex
+		acctInfo.lastInternalAddr = ma
+	} else {
+		acctInfo.nextExternalIndex = nextIndex
+		acctInfo.lastExternalAddr = ma
+	}
+
+	return managedAddresses, nil
+}
+
+// MakeMultisigAccount turns an account into an HD multisig account.  Each
+// address of a multisig account pays to a redeem script requiring reqSigs
+// signatures by the public keys derived at the address's branch and index of
+// the account's own extended key and each of the passed cosigner account
+// extended public keys.  Every cosigner shares the extended public key of its
+// own account, returned by AccountPubKey, and makes its account a multisig
+// account with the keys of the others, so all of them derive the same
+// addresses.
+//
+// Only accounts without any addresses can be made multisig accounts, and the
+// cosigner keys must be public keys for the network of the manager which are
+// distinct from each other and the account's own key.  A watching-only account
+// may be made a multisig account to track the funds of a multisig account kept
+// elsewhere.
+func (m *Manager) MakeMultisigAccount(account uint32, reqSigs int, cosignerKeys []*hdkeychain.ExtendedKey) error {
+	if account == ImportedAddrAccount {
+		str := "imported account can not be a multisig account"
+		return managerError(ErrInvalidAccount, str, nil)
+	}
+	numKeys := len(cosignerKeys) + 1
+	if numKeys < 2 || numKeys > MaxMultisigKeys {
+		str := fmt.Sprintf("multisig accounts require between 1 and %d "+
+			"cosigner keys", MaxMultisigKeys-1)
+		return managerError(ErrInvalidMultisig, str, nil)
+	}
+	if reqSigs < 1 || reqSigs > numKeys {
+		str := fmt.Sprintf("number of required signatures %d is not "+
+			"between 1 and the number of keys %d", reqSigs, numKeys)
+		return managerError(ErrInvalidMultisig, str, nil)
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	acctInfo, err := m.loadAccountInfo(account)
+	if err != nil {
+		return err
+	}
+	if acctInfo.multisig != nil {
+		str := fmt.Sprintf("account %d is already a multisig account",
+			account)
+		return managerError(ErrInvalidMultisig, str, nil)
+	}
+	if acctInfo.nextExternalIndex != 0 || acctInfo.nextInternalIndex != 0 {
+		str := fmt.Sprintf("account %d already has addresses", account)
+		return managerError(ErrInvalidMultisig, str, nil)
+	}
+
+	seen := map[string]struct{}{acctInfo.acctKeyPub.String(): {}}
+	row := dbMultisigAccountRow{reqSigs: uint32(reqSigs)}
+	for _, key := range cosignerKeys {
+		if key.IsPrivate() {
+			str := "cosigner extended keys must be public"
+			return managerError(ErrKeyChain, str, nil)
+		}
+		if !key.IsForNet(m.chainParams) {
+			str := fmt.Sprintf("cosigner extended key is not for the "+
+				"same network the address manager is configured "+
+				"for (%s)", m.chainParams.Name)
+			return managerError(ErrWrongNet, str, nil)
+		}
+		serializedKey := key.String()
+		if _, ok := seen[serializedKey]; ok {
+			str := "cosigner extended keys must be distinct"
+			return managerError(ErrInvalidMultisig, str, nil)
+		}
+		seen[serializedKey] = struct{}{}
+		if err := checkBranchKeys(key); err != nil {
+			str := "failed to derive branch keys for cosigner"
+			return managerError(ErrKeyChain, str, err)
+		}
+
+		keyEncrypted, err := m.cryptoKeyPub.Encrypt([]byte(serializedKey))
+		if err != nil {
+			str := "failed to encrypt cosigner extended key"
+			return managerError(ErrCrypto, str, err)
+		}
+		row.cosignerKeysEncrypted = append(row.cosignerKeysEncrypted,
+			keyEncrypted)
+	}
+
+	err = m.namespace.Update(func(tx walletdb.Tx) error {
+		return putMultisigAccount(tx, account, &row)
+	})
+	if err != nil {
+		return maybeConvertDbError(err)
+	}
+
+	// Remove the cached account info so it is loaded again with the
+	// multisig information and multisig last addresses.
+	delete(m.acctInfo, account)
+	return nil
+}
+
+// loadMultisigInfo loads the multisig information of an account from the
+// database.  A nil multisigInfo is returned for single key accounts.
+func (m *Manager) loadMultisigInfo(account uint32) (*multisigInfo, error) {
+	var row *dbMultisigAccountRow
+	err := m.namespace.View(func(tx walletdb.Tx) error {
+		var err error
+		row, err = fetchMultisigAccount(tx, account)
+		return err
+	})
+	if err != nil {
+		return nil, maybeConvertDbError(err)
+	}
+	if row == nil {
+		return nil, nil
+	}
+
+	info := &multisigInfo{
+		reqSigs:      int(row.reqSigs),
+		cosignerKeys: make([]*hdkeychain.ExtendedKey, 0, len(row.cosignerKeysEncrypted)),
+	}
+	for _, keyEncrypted := range row.cosignerKeysEncrypted {
+		serializedKey, err := m.cryptoKeyPub.Decrypt(keyEncrypted)
+		if err != nil {
+			str := fmt.Sprintf("failed to decrypt cosigner key for "+
+				"account %d", account)
+			return nil, managerError(ErrCrypto, str, err)
+		}
+		key, err := hdkeychain.NewKeyFromString(string(serializedKey))
+		if err != nil {
+			str := fmt.Sprintf("failed to create cosigner extended "+
+				"key for account %d", account)
+			return nil, managerError(ErrKeyChain, str, err)
+		}
+		info.cosignerKeys = append(info.cosignerKeys, key)
+	}
+	return info, nil
+}
+
+// AccountMultisig returns the number of signatures required to redeem outputs
+// paid to the addresses of an HD multisig account and the total number of keys
+// of each redeem script.  Both are zero for single key accounts.
+func (m *Manager) AccountMultisig(account uint32) (reqSigs, numKeys int, err error) {
+	if account == ImportedAddrAccount {
+		return 0, 0, nil
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	acctInfo, err := m.loadAccountInfo(account)
+	if err != nil {
+		return 0, 0, err
+	}
+	if acctInfo.multisig == nil {
+		return 0, 0, nil
+	}
+	return acctInfo.multisig.reqSigs, len(acctInfo.multisig.cosignerKeys) + 1, nil
+}
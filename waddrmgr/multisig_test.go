@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) 2014 The btcsuite developers
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package waddrmgr_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/coinutil/hdkeychain"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcwallet/waddrmgr"
+)
+
+// testXpub2 is the master extended public key of BIP0032 test vector 2.
+const testXpub2 = "xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUap" +
+	"SCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB"
+
+// TestMakeMultisigAccount ensures HD multisig accounts derive sorted multisig
+// redeem scripts from the keys of every cosigner.
+func TestMakeMultisigAccount(t *testing.T) {
+	teardown, mgr := setupManager(t)
+	defer teardown()
+
+	if err := mgr.Unlock(privPassphrase); err != nil {
+		t.Fatalf("Unlock: unexpected error: %v", err)
+	}
+	account, err := mgr.NewAccount("multisig")
+	if err != nil {
+		t.Fatalf("NewAccount: unexpected error: %v", err)
+	}
+
+	var cosignerKeys []*hdkeychain.ExtendedKey
+	for _, s := range []string{testXpub, testXpub2} {
+		key, err := hdkeychain.NewKeyFromString(s)
+		if err != nil {
+			t.Fatalf("NewKeyFromString: unexpected error: %v", err)
+		}
+		cosignerKeys = append(cosignerKeys, key)
+	}
+	xprv, err := hdkeychain.NewKeyFromString(testXprv)
+	if err != nil {
+		t.Fatalf("NewKeyFromString: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		reqSigs int
+		keys    []*hdkeychain.ExtendedKey
+		code    waddrmgr.ErrorCode
+	}{
+		{"no cosigners", 1, nil, waddrmgr.ErrInvalidMultisig},
+		{"no required signatures", 0, cosignerKeys, waddrmgr.ErrInvalidMultisig},
+		{"too many required signatures", 4, cosignerKeys, waddrmgr.ErrInvalidMultisig},
+		{"duplicate cosigner", 2, []*hdkeychain.ExtendedKey{cosignerKeys[0], cosignerKeys[0]}, waddrmgr.ErrInvalidMultisig},
+		{"private cosigner key", 2, []*hdkeychain.ExtendedKey{xprv}, waddrmgr.ErrKeyChain},
+	}
+	for _, test := range tests {
+		err := mgr.MakeMultisigAccount(account, test.reqSigs, test.keys)
+		if !checkManagerError(t, test.name, err, test.code) {
+			return
+		}
+	}
+
+	if err := mgr.MakeMultisigAccount(account, 2, cosignerKeys); err != nil {
+		t.Fatalf("MakeMultisigAccount: unexpected error: %v", err)
+	}
+	err = mgr.MakeMultisigAccount(account, 2, cosignerKeys)
+	if !checkManagerError(t, "Make multisig account twice", err,
+		waddrmgr.ErrInvalidMultisig) {
+		return
+	}
+	reqSigs, numKeys, err := mgr.AccountMultisig(account)
+	if err != nil {
+		t.Fatalf("AccountMultisig: unexpected error: %v", err)
+	}
+	if reqSigs != 2 || numKeys != 3 {
+		t.Fatalf("AccountMultisig: got %d of %d keys, want 2 of 3",
+			reqSigs, numKeys)
+	}
+
+	ownKey, err := mgr.AccountPubKey(account)
+	if err != nil {
+		t.Fatalf("AccountPubKey: unexpected error: %v", err)
+	}
+	addrs, err := mgr.NextExternalAddresses(account, 2)
+	if err != nil {
+		t.Fatalf("NextExternalAddresses: unexpected error: %v", err)
+	}
+	for i, addr := range addrs {
+		ma, ok := addr.(waddrmgr.ManagedMultisigAddress)
+		if !ok {
+			t.Fatalf("Address %d: got %T, want a multisig address",
+				i, addr)
+		}
+		if ma.RequiredSigs() != 2 {
+			t.Errorf("Address %d: got %d required signatures, want 2",
+				i, ma.RequiredSigs())
+		}
+		script, err := ma.Script()
+		if err != nil {
+			t.Fatalf("Script: unexpected error: %v", err)
+		}
+		want, err := coinutil.NewAddressScriptHash(script,
+			&chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatalf("NewAddressScriptHash: unexpected error: %v", err)
+		}
+		if ma.Address().String() != want.String() {
+			t.Errorf("Address %d: got %v, want %v", i, ma.Address(),
+				want)
+		}
+
+		// The redeem script must hold the sorted keys of every
+		// cosigner at the same branch and index.
+		class, scriptAddrs, nRequired, err := txscript.ExtractPkScriptAddrs(
+			script, &chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatalf("ExtractPkScriptAddrs: unexpected error: %v", err)
+		}
+		if class != txscript.MultiSigTy || nRequired != 2 ||
+			len(scriptAddrs) != 3 {
+			t.Fatalf("Address %d: unexpected script class %v with %d "+
+				"of %d keys", i, class, nRequired, len(scriptAddrs))
+		}
+		var pubKeys [][]byte
+		for j, key := range append(cosignerKeys, ownKey) {
+			branchKey, err := key.Child(0)
+			if err != nil {
+				t.Fatalf("Child: unexpected error: %v", err)
+			}
+			childKey, err := branchKey.Child(uint32(i))
+			if err != nil {
+				t.Fatalf("Child: unexpected error: %v", err)
+			}
+			pubKey, err := childKey.ECPubKey()
+			if err != nil {
+				t.Fatalf("ECPubKey: unexpected error: %v", err)
+			}
+			pubKeys = append(pubKeys, pubKey.SerializeCompressed())
+			if j == len(cosignerKeys) && !ma.OwnPubKey().IsEqual(pubKey) {
+				t.Errorf("Address %d: unexpected own public key", i)
+			}
+		}
+		for j := range scriptAddrs {
+			got := scriptAddrs[j].ScriptAddress()
+			if j > 0 && bytes.Compare(scriptAddrs[j-1].ScriptAddress(), got) >= 0 {
+				t.Errorf("Address %d: script keys are not sorted", i)
+			}
+			found := false
+			for _, pubKey := range pubKeys {
+				found = found || bytes.Equal(pubKey, got)
+			}
+			if !found {
+				t.Errorf("Address %d: unexpected script key %x", i, got)
+			}
+		}
+
+		privKey, err := ma.OwnPrivKey()
+		if err != nil {
+			t.Fatalf("OwnPrivKey: unexpected error: %v", err)
+		}
+		if !privKey.PubKey().IsEqual(ma.OwnPubKey()) {
+			t.Errorf("Address %d: private key does not match own "+
+				"public key", i)
+		}
+
+		// The address must be found by its script hash.
+		found, err := mgr.Address(ma.Address())
+		if err != nil {
+			t.Fatalf("Address: unexpected error: %v", err)
+		}
+		if found.Account() != account {
+			t.Errorf("Address %d: got account %d, want %d", i,
+				found.Account(), account)
+		}
+	}
+
+	// Accounts with addresses can not be made multisig accounts.
+	if _, err := mgr.NextExternalAddresses(0, 1); err != nil {
+		t.Fatalf("NextExternalAddresses: unexpected error: %v", err)
+	}
+	err = mgr.MakeMultisigAccount(0, 2, cosignerKeys)
+	if !checkManagerError(t, "Make used account multisig", err,
+		waddrmgr.ErrInvalidMultisig) {
+		return
+	}
+}
@@ -240,3 +240,15 @@ func (m *Manager) SyncedTo() BlockStamp {
 
 	return m.syncState.syncedTo
 }
+
+// StartBlock returns the block the address manager considers its birthday.
+// No transactions relevant to the addresses of the manager are expected to
+// appear in blocks before it, so it is the earliest block a rescan needs to
+// begin from.  The start block moves back in time when addresses with an
+// older birthday are imported.
+func (m *Manager) StartBlock() BlockStamp {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return m.syncState.startBlock
+}
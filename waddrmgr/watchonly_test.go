@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2014 The btcsuite developers
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package waddrmgr_test
+
+import (
+	"testing"
+
+	"github.com/conseweb/coinutil/hdkeychain"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcwallet/waddrmgr"
+)
+
+// The extended keys of BIP0032 test vector 1.
+const (
+	testXpub = "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybG" +
+		"hePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8"
+	testXprv = "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3j" +
+		"PPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"
+)
+
+// TestImportAccount ensures accounts imported from an extended public key
+// derive the expected addresses and never provide private keys.
+func TestImportAccount(t *testing.T) {
+	teardown, mgr := setupManager(t)
+	defer teardown()
+
+	xprv, err := hdkeychain.NewKeyFromString(testXprv)
+	if err != nil {
+		t.Fatalf("NewKeyFromString: unexpected error: %v", err)
+	}
+	_, err = mgr.ImportAccount("cold", xprv)
+	if !checkManagerError(t, "Import private key", err,
+		waddrmgr.ErrKeyChain) {
+		return
+	}
+
+	xpub, err := hdkeychain.NewKeyFromString(testXpub)
+	if err != nil {
+		t.Fatalf("NewKeyFromString: unexpected error: %v", err)
+	}
+	tpub, err := hdkeychain.NewKeyFromString(testXpub)
+	if err != nil {
+		t.Fatalf("NewKeyFromString: unexpected error: %v", err)
+	}
+	tpub.SetNet(&chaincfg.TestNet3Params)
+	_, err = mgr.ImportAccount("cold", tpub)
+	if !checkManagerError(t, "Import key for wrong network", err,
+		waddrmgr.ErrWrongNet) {
+		return
+	}
+
+	// Importing does not require the manager to be unlocked.
+	account, err := mgr.ImportAccount("cold", xpub)
+	if err != nil {
+		t.Fatalf("ImportAccount: unexpected error: %v", err)
+	}
+	if account != 1 {
+		t.Fatalf("ImportAccount: got account %d, want 1", account)
+	}
+	_, err = mgr.ImportAccount("cold", xpub)
+	if !checkManagerError(t, "Import duplicate account", err,
+		waddrmgr.ErrDuplicateAccount) {
+		return
+	}
+
+	watchingOnly, err := mgr.IsWatchingOnlyAccount(account)
+	if err != nil {
+		t.Fatalf("IsWatchingOnlyAccount: unexpected error: %v", err)
+	}
+	if !watchingOnly {
+		t.Fatal("Imported account is not watching-only")
+	}
+	watchingOnly, err = mgr.IsWatchingOnlyAccount(0)
+	if err != nil {
+		t.Fatalf("IsWatchingOnlyAccount: unexpected error: %v", err)
+	}
+	if watchingOnly {
+		t.Fatal("Default account is watching-only")
+	}
+
+	// Addresses created while locked must not prevent unlocking.
+	external, err := mgr.NextExternalAddresses(account, 1)
+	if err != nil {
+		t.Fatalf("NextExternalAddresses: unexpected error: %v", err)
+	}
+	if err := mgr.Unlock(privPassphrase); err != nil {
+		t.Fatalf("Unlock: unexpected error: %v", err)
+	}
+	internal, err := mgr.NextInternalAddresses(account, 1)
+	if err != nil {
+		t.Fatalf("NextInternalAddresses: unexpected error: %v", err)
+	}
+
+	for i, test := range []struct {
+		addr   waddrmgr.ManagedAddress
+		branch uint32
+	}{
+		{external[0], 0},
+		{internal[0], 1},
+	} {
+		branchKey, err := xpub.Child(test.branch)
+		if err != nil {
+			t.Fatalf("Child: unexpected error: %v", err)
+		}
+		key, err := branchKey.Child(0)
+		if err != nil {
+			t.Fatalf("Child: unexpected error: %v", err)
+		}
+		want, err := key.Address(&chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatalf("Address: unexpected error: %v", err)
+		}
+		if got := test.addr.Address(); got.String() != want.String() {
+			t.Errorf("Address %d: got %v, want %v", i, got, want)
+		}
+
+		pka := test.addr.(waddrmgr.ManagedPubKeyAddress)
+		_, err = pka.PrivKey()
+		if !checkManagerError(t, "PrivKey", err,
+			waddrmgr.ErrWatchingOnly) {
+			return
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package wallet
+
+import (
+	"time"
+
+	"github.com/conseweb/stcd/wire"
+	"github.com/conseweb/stcwallet/wtxmgr"
+)
+
+// AbandonTx removes an unmined wallet transaction which is never expected to
+// be mined, along with every unmined transaction spending its outputs, so the
+// wallet outputs spent by them can be spent again.  The transactions are no
+// longer resent to the chain server, but if one is still relayed and mined, it
+// is recorded again when the chain server notifies the wallet of it.
+func (w *Wallet) AbandonTx(txHash *wire.ShaHash) error {
+	if _, err := w.TxStore.AbandonTx(txHash); err != nil {
+		return err
+	}
+	w.notifyBalances(w.Manager.SyncedTo().Height)
+	return nil
+}
+
+// expiredTxs returns the hashes of the unmined transactions which were
+// received before cutoff.
+func expiredTxs(unmined []wtxmgr.TxDetails, cutoff time.Time) []wire.ShaHash {
+	var expired []wire.ShaHash
+	for i := range unmined {
+		if unmined[i].Received.Before(cutoff) {
+			expired = append(expired, unmined[i].Hash)
+		}
+	}
+	return expired
+}
+
+// unminedExpiryCutoff returns the time before which unmined transactions must
+// have been received to expire by the wallet's UnminedExpiry and
+// UnminedExpiryBlocks policies, after block b was connected.
+func (w *Wallet) unminedExpiryCutoff(b wtxmgr.BlockMeta) (time.Time, error) {
+	var cutoff time.Time
+	if w.UnminedExpiry > 0 {
+		cutoff = time.Now().Add(-w.UnminedExpiry)
+	}
+
+	// A transaction received before the block n-1 blocks below b was
+	// mined has remained unmined for the last n blocks.
+	n := w.UnminedExpiryBlocks
+	if n > 0 && b.Height >= n-1 {
+		hash, err := w.chainSvr.GetBlockHash(int64(b.Height - n + 1))
+		if err != nil {
+			return time.Time{}, err
+		}
+		block, err := w.chainSvr.GetBlockVerbose(hash, false)
+		if err != nil {
+			return time.Time{}, err
+		}
+		blockTime := time.Unix(block.Time, 0)
+		if blockTime.After(cutoff) {
+			cutoff = blockTime
+		}
+	}
+	return cutoff, nil
+}
+
+// expireUnminedTxs abandons every unmined transaction which has expired by
+// the wallet's expiry policies after block b was connected.
+func (w *Wallet) expireUnminedTxs(b wtxmgr.BlockMeta) {
+	if w.UnminedExpiry <= 0 && w.UnminedExpiryBlocks <= 0 {
+		return
+	}
+
+	cutoff, err := w.unminedExpiryCutoff(b)
+	if err != nil {
+		log.Errorf("Cannot determine expiry of unmined transactions: %v",
+			err)
+		return
+	}
+	var unmined []wtxmgr.TxDetails
+	err = w.TxStore.RangeTransactions(-1, -1, func(details []wtxmgr.TxDetails) (bool, error) {
+		unmined = append(unmined, details...)
+		return false, nil
+	})
+	if err != nil {
+		log.Errorf("Cannot load unmined transactions for expiry: %v", err)
+		return
+	}
+
+	// Spenders of an expired transaction are abandoned along with it, so
+	// they are skipped if they expired as well.
+	abandoned := make(map[wire.ShaHash]struct{})
+	for _, txHash := range expiredTxs(unmined, cutoff) {
+		if _, ok := abandoned[txHash]; ok {
+			continue
+		}
+		log.Infof("Unmined transaction %v has expired", txHash)
+		removed, err := w.TxStore.AbandonTx(&txHash)
+		if err != nil {
+			log.Errorf("Cannot abandon expired transaction %v: %v",
+				txHash, err)
+			continue
+		}
+		for _, h := range removed {
+			abandoned[h] = struct{}{}
+		}
+	}
+}
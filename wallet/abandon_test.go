@@ -0,0 +1,39 @@
+package wallet
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/conseweb/stcd/wire"
+	"github.com/conseweb/stcwallet/wtxmgr"
+)
+
+func TestExpiredTxs(t *testing.T) {
+	now := time.Now()
+	unmined := make([]wtxmgr.TxDetails, 3)
+	for i := range unmined {
+		unmined[i].Hash[0] = byte(i)
+		unmined[i].Received = now.Add(-time.Duration(i) * time.Hour)
+	}
+
+	tests := []struct {
+		name   string
+		cutoff time.Time
+		want   []wire.ShaHash
+	}{
+		{"no policy", time.Time{}, nil},
+		{"none expired", now.Add(-3 * time.Hour), nil},
+		{"older than cutoff", now.Add(-90 * time.Minute),
+			[]wire.ShaHash{unmined[2].Hash}},
+		{"all expired", now.Add(time.Minute),
+			[]wire.ShaHash{unmined[0].Hash, unmined[1].Hash,
+				unmined[2].Hash}},
+	}
+	for _, test := range tests {
+		got := expiredTxs(unmined, test.cutoff)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package wallet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+	"github.com/conseweb/stcwallet/wtxmgr"
+)
+
+// incrementalFeeRate is the fee rate, in satoshis per byte, by which the fee of
+// a replacement transaction must exceed the fee of the transaction it replaces
+// to be relayed (BIP0125).
+const incrementalFeeRate = 1
+
+// BumpFeeError describes an unmined transaction which can not be replaced by
+// BumpFee.
+type BumpFeeError struct {
+	Hash   wire.ShaHash
+	Reason string
+}
+
+// Error satisifies the builtin error interface.
+func (e BumpFeeError) Error() string {
+	return fmt.Sprintf("cannot bump fee of transaction %v: %s", e.Hash,
+		e.Reason)
+}
+
+// minReplacementFeeRate returns the lowest fee rate, in satoshis per byte, of a
+// transaction replacing one of size bytes paying fee.  This is the fee rate of
+// the replaced transaction, rounded up, plus the incremental relay fee rate.
+func minReplacementFeeRate(fee coinutil.Amount, size int) coinutil.Amount {
+	sz := coinutil.Amount(size)
+	return (fee+sz-1)/sz + incrementalFeeRate
+}
+
+// BumpFee replaces an unmined transaction created by the wallet with one
+// paying a higher fee, for transactions which are not being mined due to a
+// low fee.  The replacement spends the same inputs to the same outputs, paying
+// the fee by lowering the change output, or by adding inputs of the account
+// with at least one confirmation when the change does not cover it.  A
+// positive feeRate, in satoshis per byte, overrides the fee rate of the wallet,
+// and the fee rate is never lower than the rate required to replace the
+// transaction.
+//
+// The replacement is published before the replaced transaction is removed
+// from the transaction store, and the replacement records its hash, along with
+// its comments, in the metadata of the replacement.  Since the transaction
+// store removes every transaction spending its outputs, transactions whose
+// outputs are spent can not be replaced.  Chain servers only relay the
+// replacement if the replaced transaction signaled replaceability, which
+// transactions created by the wallet only do when SignalReplaceable is set, so
+// other transactions are refused.  The replacement always signals
+// replaceability so its fee can be bumped again.
+func (w *Wallet) BumpFee(txHash *wire.ShaHash, feeRate coinutil.Amount) (*wire.ShaHash, error) {
+	req := createTxRequest{
+		feeRate:  feeRate,
+		replaces: txHash,
+	}
+	createdTx, err := w.requestTx(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := wtxmgr.NewTxRecordFromMsgTx(createdTx.MsgTx, time.Now())
+	if err != nil {
+		log.Errorf("Cannot create record for replacement transaction: %v",
+			err)
+		return nil, err
+	}
+
+	// The replaced transaction is kept until the chain server accepts
+	// the replacement.
+	hash, err := w.chainSvr.SendRawTransaction(&rec.MsgTx, false)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Replaced transaction %v with %v", txHash, hash)
+
+	// The replacement was published, so failing to record it is only
+	// logged.  It is recorded again when the chain server notifies it.
+	err = w.TxStore.ReplaceTx(txHash, rec)
+	if err != nil {
+		log.Errorf("Error replacing tx history: %v", err)
+		return hash, nil
+	}
+	if createdTx.ChangeIndex >= 0 {
+		err = w.TxStore.AddCredit(rec, nil, uint32(createdTx.ChangeIndex), true)
+		if err != nil {
+			log.Errorf("Error adding change address for replacement "+
+				"tx: %v", err)
+		}
+	}
+	return hash, nil
+}
+
+// txBumpFee creates a transaction replacing the unmined wallet transaction
+// with hash txHash, as described by BumpFee.
+func (w *Wallet) txBumpFee(txHash *wire.ShaHash, feeRate coinutil.Amount) (*CreatedTx, error) {
+	heldUnlock, err := w.HoldUnlock()
+	if err != nil {
+		return nil, err
+	}
+	defer heldUnlock.Release()
+
+	bs, err := w.chainSvr.BlockStamp()
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := w.TxStore.TxDetails(txHash)
+	if err != nil {
+		return nil, err
+	}
+	if details == nil {
+		return nil, BumpFeeError{*txHash, "transaction is not recorded by the wallet"}
+	}
+	if details.Block.Height != -1 {
+		return nil, BumpFeeError{*txHash, "transaction is mined"}
+	}
+	if !signalsReplaceable(&details.MsgTx) {
+		return nil, BumpFeeError{*txHash, "transaction does not " +
+			"signal replaceability"}
+	}
+	if len(details.Debits) != len(details.MsgTx.TxIn) {
+		return nil, BumpFeeError{*txHash, "transaction spends outputs " +
+			"not controlled by the wallet"}
+	}
+	changeIdx := -1
+	for _, cred := range details.Credits {
+		if cred.Spent {
+			return nil, BumpFeeError{*txHash, "transaction outputs " +
+				"are spent by other transactions"}
+		}
+		if cred.Change {
+			changeIdx = int(cred.Index)
+		}
+	}
+
+	// Recreate the credits spent by the transaction so they can be signed
+	// again.  The replacement is created by the account of the first
+	// input.
+	debits := make(map[uint32]coinutil.Amount, len(details.Debits))
+	var totalIn coinutil.Amount
+	for _, deb := range details.Debits {
+		debits[deb.Index] = deb.Amount
+		totalIn += deb.Amount
+	}
+	var account uint32
+	required := make([]wtxmgr.Credit, len(details.MsgTx.TxIn))
+	for i, txIn := range details.MsgTx.TxIn {
+		prevOut := &txIn.PreviousOutPoint
+		prev, err := w.TxStore.TxDetails(&prevOut.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if prev == nil || int(prevOut.Index) >= len(prev.MsgTx.TxOut) {
+			return nil, BumpFeeError{*txHash, "previous transaction " +
+				"is not recorded by the wallet"}
+		}
+		pkScript := prev.MsgTx.TxOut[prevOut.Index].PkScript
+		class, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript,
+			w.chainParams)
+		if err != nil || class != txscript.PubKeyHashTy {
+			return nil, BumpFeeError{*txHash, "only P2PKH inputs can " +
+				"be signed"}
+		}
+		if i == 0 {
+			account, err = w.Manager.AddrAccount(addrs[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+		required[i] = wtxmgr.Credit{
+			OutPoint:     *prevOut,
+			BlockMeta:    prev.Block,
+			Amount:       debits[uint32(i)],
+			PkScript:     pkScript,
+			Received:     prev.Received,
+			FromCoinBase: blockchain.IsCoinBaseTx(&prev.MsgTx),
+		}
+	}
+
+	// Every output other than the change is paid again.  The change is
+	// paid to the same address, if it is still worth adding.
+	var totalOut coinutil.Amount
+	var changeAddr coinutil.Address
+	outputs := make(map[string]coinutil.Amount, len(details.MsgTx.TxOut))
+	for i, txOut := range details.MsgTx.TxOut {
+		totalOut += coinutil.Amount(txOut.Value)
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(txOut.PkScript,
+			w.chainParams)
+		if err != nil || len(addrs) != 1 {
+			return nil, BumpFeeError{*txHash, "only outputs paying " +
+				"a single address can be paid again"}
+		}
+		if i == changeIdx {
+			changeAddr = addrs[0]
+			continue
+		}
+		addrStr := addrs[0].EncodeAddress()
+		if _, ok := outputs[addrStr]; ok {
+			return nil, BumpFeeError{*txHash, "transaction pays an " +
+				"address more than once"}
+		}
+		outputs[addrStr] = coinutil.Amount(txOut.Value)
+	}
+	changeAddress := w.NewChangeAddress
+	if changeAddr != nil {
+		changeAddress = func(uint32) (coinutil.Address, error) {
+			return changeAddr, nil
+		}
+	}
+
+	fee := totalIn - totalOut
+	minFeeRate := minReplacementFeeRate(fee, details.MsgTx.SerializeSize())
+	if feeRate > 0 && feeRate < minFeeRate {
+		return nil, BumpFeeError{*txHash, fmt.Sprintf("fee rate must "+
+			"be at least %d satoshis per byte", minFeeRate)}
+	}
+	feeRate = w.txFeeRate(feeRate)
+	if feeRate < minFeeRate {
+		feeRate = minFeeRate
+	}
+
+	eligible, err := w.findEligibleOutputs(account, 1, bs)
+	if err != nil {
+		return nil, err
+	}
+	createdTx, err := createTx(&txParams{
+		eligible:      eligible,
+		required:      required,
+		selector:      w.CoinSelector,
+		outputs:       outputs,
+		bs:            bs,
+		feeRate:       feeRate,
+		disallowFree:  true,
+		mgr:           w.Manager,
+		account:       account,
+		changeAddress: changeAddress,
+		chainParams:   w.chainParams,
+		replaceable:   true,
+		signer:        w.Signer,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Removing the change output shrinks the replacement, so check that
+	// its fee still exceeds the replaced fee by the incremental relay fee.
+	added := make(map[wire.OutPoint]coinutil.Amount, len(eligible))
+	for i := range eligible {
+		added[eligible[i].OutPoint] = eligible[i].Amount
+	}
+	newFee := totalIn
+	for _, txIn := range createdTx.MsgTx.TxIn[len(required):] {
+		newFee += added[txIn.PreviousOutPoint]
+	}
+	for _, txOut := range createdTx.MsgTx.TxOut {
+		newFee -= coinutil.Amount(txOut.Value)
+	}
+	sz := createdTx.MsgTx.SerializeSize()
+	if newFee < fee+feeForSize(incrementalFeeRate, sz) {
+		return nil, BumpFeeError{*txHash, "replacement does not pay " +
+			"enough additional fee"}
+	}
+	return createdTx, nil
+}
+
+// WalletConflicts returns the hashes of the wallet transactions which were
+// replaced by the transaction described by details, oldest first.  For a
+// conflicted transaction, the hash of the transaction double spending it is
+// appended when known.  The result is never nil.
+func WalletConflicts(details *wtxmgr.TxDetails) []string {
+	conflicts := make([]string, 0, len(details.Metadata.Replaces)+1)
+	for i := range details.Metadata.Replaces {
+		conflicts = append(conflicts, details.Metadata.Replaces[i].String())
+	}
+	if details.Conflicted && details.ConflictedBy != nil {
+		conflicts = append(conflicts, details.ConflictedBy.String())
+	}
+	return conflicts
+}
@@ -0,0 +1,60 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/wire"
+)
+
+func TestMinReplacementFeeRate(t *testing.T) {
+	tests := []struct {
+		fee  coinutil.Amount
+		size int
+		want coinutil.Amount
+	}{
+		{fee: 226, size: 226, want: 2},
+		{fee: 227, size: 226, want: 3},
+		{fee: 0, size: 191, want: 1},
+		{fee: 2260, size: 226, want: 11},
+	}
+	for i, test := range tests {
+		got := minReplacementFeeRate(test.fee, test.size)
+		if got != test.want {
+			t.Errorf("Test %d: got fee rate %v, want %v", i, got,
+				test.want)
+		}
+	}
+}
+
+func TestCreateTxSignalsReplaceability(t *testing.T) {
+	tt := newCreateTxTest(t)
+
+	eligible := mockCredits(t, txInfo.hex, []uint32{1, 2, 3, 4})
+	required := mockCredits(t, txInfo.hex, []uint32{5})
+	outputs := map[string]coinutil.Amount{outAddr1: 2e7}
+	for _, replaceable := range []bool{false, true} {
+		params := tt.params(eligible, required, outputs)
+		params.replaceable = replaceable
+		tx, err := createTx(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := uint32(wire.MaxTxInSequenceNum)
+		if replaceable {
+			want = replaceableSequence
+		}
+		for i, txIn := range tx.MsgTx.TxIn {
+			if txIn.Sequence != want {
+				t.Errorf("Replaceable %v: input %d has sequence "+
+					"%d, want %d", replaceable, i, txIn.Sequence,
+					want)
+			}
+		}
+		if signalsReplaceable(tx.MsgTx) != replaceable {
+			t.Errorf("Replaceable %v: transaction signals "+
+				"replaceability %v", replaceable, !replaceable)
+		}
+	}
+}
@@ -45,7 +45,11 @@ func (w *Wallet) handleChainNotifications() {
 		case chain.BlockDisconnected:
 			err = w.disconnectBlock(wtxmgr.BlockMeta(n))
 		case chain.RelevantTx:
-			err = w.addRelevantTx(n.TxRecord, n.Block)
+			var swept bool
+			swept, err = w.sweepRelevantTx(n.TxRecord, n.Block)
+			if err == nil && !swept {
+				err = w.addRelevantTx(n.TxRecord, n.Block)
+			}
 
 		// The following are handled by the wallet's rescan
 		// goroutines, so just pass them there.
@@ -79,6 +83,7 @@ func (w *Wallet) connectBlock(b wtxmgr.BlockMeta) {
 	}
 	w.notifyConnectedBlock(b)
 
+	w.expireUnminedTxs(b)
 	w.notifyBalances(bs.Height)
 }
 
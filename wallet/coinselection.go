@@ -0,0 +1,218 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package wallet
+
+import (
+	"fmt"
+	badrand "math/rand"
+	"sort"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcwallet/wtxmgr"
+)
+
+// CoinSelector is the interface implemented by the input selection strategies
+// used when creating transactions.
+type CoinSelector interface {
+	// SelectCoins returns the eligible credits in the order they should be
+	// added as inputs to a transaction paying target to numOutputs
+	// outputs, where feeRate is the fee in satoshis per byte of the
+	// transaction.  Inputs are added in the returned order until the
+	// outputs and the fee are covered.  The eligible slice must not be
+	// modified.
+	SelectCoins(eligible []wtxmgr.Credit, target coinutil.Amount,
+		numOutputs int, feeRate coinutil.Amount) []wtxmgr.Credit
+}
+
+// coinSelectors maps the names accepted by CoinSelectorByName to each coin
+// selection strategy.
+var coinSelectors = map[string]CoinSelector{
+	"largestfirst":   LargestFirstSelector{},
+	"smallestfirst":  SmallestFirstSelector{},
+	"branchandbound": BranchAndBoundSelector{},
+	"random":         RandomSelector{},
+}
+
+// CoinSelectorByName returns the coin selection strategy with the passed name.
+// Valid names are "largestfirst", "smallestfirst", "branchandbound" and
+// "random".
+func CoinSelectorByName(name string) (CoinSelector, error) {
+	selector, ok := coinSelectors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown coin selection strategy %q", name)
+	}
+	return selector, nil
+}
+
+// changeCost returns the cost of adding a change output to a transaction,
+// which is the fee for the output itself plus the fee for later spending it.
+// Change worth less than this is better left to the miner.
+func changeCost(feeRate coinutil.Amount) coinutil.Amount {
+	return feeForSize(feeRate, txOutEstimate) +
+		feeForSize(feeRate, txInEstimate)
+}
+
+// dropsSmallChange returns whether change worth less than changeCost is added
+// to the fee of transactions spending the credits chosen by selector.  The
+// default largest-first strategy keeps all change, as the wallet did before
+// the other strategies were added.
+func dropsSmallChange(selector CoinSelector) bool {
+	_, largestFirst := selector.(LargestFirstSelector)
+	return !largestFirst
+}
+
+// sortedCredits returns a copy of credits sorted by amount, largest first when
+// reverse is true.
+func sortedCredits(credits []wtxmgr.Credit, reverse bool) []wtxmgr.Credit {
+	sorted := make([]wtxmgr.Credit, len(credits))
+	copy(sorted, credits)
+	if reverse {
+		sort.Sort(sort.Reverse(ByAmount(sorted)))
+	} else {
+		sort.Sort(ByAmount(sorted))
+	}
+	return sorted
+}
+
+// LargestFirstSelector is a CoinSelector which picks the credits with the
+// largest amounts first, reducing the number of inputs (and therefore the
+// fee) of each transaction.  This is the default strategy.
+type LargestFirstSelector struct{}
+
+// SelectCoins satisfies the CoinSelector interface.
+func (LargestFirstSelector) SelectCoins(eligible []wtxmgr.Credit,
+	target coinutil.Amount, numOutputs int,
+	feeRate coinutil.Amount) []wtxmgr.Credit {
+
+	return sortedCredits(eligible, true)
+}
+
+// SmallestFirstSelector is a CoinSelector which picks the credits with the
+// smallest amounts first.  This consolidates many small outputs into fewer
+// larger ones, at the cost of higher fees for the transactions doing so.
+type SmallestFirstSelector struct{}
+
+// SelectCoins satisfies the CoinSelector interface.
+func (SmallestFirstSelector) SelectCoins(eligible []wtxmgr.Credit,
+	target coinutil.Amount, numOutputs int,
+	feeRate coinutil.Amount) []wtxmgr.Credit {
+
+	return sortedCredits(eligible, false)
+}
+
+// RandomSelector is a CoinSelector which picks credits in a random order.
+// This makes it harder for observers to link wallet outputs together or to
+// fingerprint the wallet by its input selection.
+type RandomSelector struct{}
+
+// SelectCoins satisfies the CoinSelector interface.
+func (RandomSelector) SelectCoins(eligible []wtxmgr.Credit,
+	target coinutil.Amount, numOutputs int,
+	feeRate coinutil.Amount) []wtxmgr.Credit {
+
+	rng := badrand.New(badrand.NewSource(time.Now().UnixNano()))
+	shuffled := make([]wtxmgr.Credit, len(eligible))
+	for i, j := range rng.Perm(len(eligible)) {
+		shuffled[i] = eligible[j]
+	}
+	return shuffled
+}
+
+// bnbMaxTries is the maximum number of branches searched by the
+// BranchAndBoundSelector before giving up on finding an exact match.
+const bnbMaxTries = 100000
+
+// BranchAndBoundSelector is a CoinSelector which searches for a set of credits
+// that pays the target and fee exactly, or exceeds it by less than the cost
+// of a change output, so no change output is required.  Avoiding change saves
+// fees and does not reveal which output of the transaction pays the wallet.
+// If no such set is found, credits are picked largest first.
+type BranchAndBoundSelector struct{}
+
+// SelectCoins satisfies the CoinSelector interface.
+func (BranchAndBoundSelector) SelectCoins(eligible []wtxmgr.Credit,
+	target coinutil.Amount, numOutputs int,
+	feeRate coinutil.Amount) []wtxmgr.Credit {
+
+	sorted := sortedCredits(eligible, true)
+
+	// Credits worth no more than the fee of spending them can only
+	// increase the cost of the transaction, so they are not considered.
+	// Since sorted is ordered largest first, the candidates are a prefix.
+	n := 0
+	for n < len(sorted) && sorted[n].Amount > feeForSize(feeRate, txInEstimate) {
+		n++
+	}
+	candidates := sorted[:n]
+
+	// remaining[i] is the total amount of candidates i and later, used to
+	// prune branches which can never reach the target.
+	remaining := make([]coinutil.Amount, len(candidates)+1)
+	for i := len(candidates) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + candidates[i].Amount
+	}
+
+	targetFor := func(numInputs int) coinutil.Amount {
+		sz := estimateTxSize(numInputs, numOutputs)
+		return target + feeForSize(feeRate, sz)
+	}
+	maxExcess := changeCost(feeRate)
+
+	selected := make([]bool, len(candidates))
+	tries := 0
+	var search func(i, numInputs int, sum coinutil.Amount) bool
+	search = func(i, numInputs int, sum coinutil.Amount) bool {
+		tries++
+		if tries > bnbMaxTries {
+			return false
+		}
+		if numInputs > 0 && sum >= targetFor(numInputs) {
+			// Adding more inputs only increases the excess, so
+			// this branch ends here whether or not it matched.
+			return sum-targetFor(numInputs) < maxExcess
+		}
+		if i == len(candidates) || sum+remaining[i] < targetFor(numInputs) {
+			return false
+		}
+
+		// Search the branch including this candidate before the one
+		// omitting it.
+		selected[i] = true
+		if search(i+1, numInputs+1, sum+candidates[i].Amount) {
+			return true
+		}
+		selected[i] = false
+		return search(i+1, numInputs, sum)
+	}
+	if !search(0, 0, 0) {
+		return sorted
+	}
+
+	// Return the matching credits first, followed by every other credit
+	// largest first in case the actual fee exceeds the estimate.
+	ordered := make([]wtxmgr.Credit, 0, len(sorted))
+	for i := range candidates {
+		if selected[i] {
+			ordered = append(ordered, candidates[i])
+		}
+	}
+	for i := range sorted {
+		if i >= len(candidates) || !selected[i] {
+			ordered = append(ordered, sorted[i])
+		}
+	}
+	return ordered
+}
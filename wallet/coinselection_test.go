@@ -0,0 +1,143 @@
+package wallet
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcwallet/waddrmgr"
+	"github.com/conseweb/stcwallet/wtxmgr"
+)
+
+func creditAmounts(credits []wtxmgr.Credit) []coinutil.Amount {
+	amounts := make([]coinutil.Amount, len(credits))
+	for i := range credits {
+		amounts[i] = credits[i].Amount
+	}
+	return amounts
+}
+
+func TestCoinSelectors(t *testing.T) {
+	// Outputs 1-5 of txInfo are worth 3e6, 9e6, 1e7, 1.5e7 and 1e5.
+	eligible := mockCredits(t, txInfo.hex, []uint32{1, 2, 3, 4, 5})
+	original := creditAmounts(eligible)
+
+	tests := []struct {
+		name     string
+		selector CoinSelector
+		target   coinutil.Amount
+		want     []coinutil.Amount
+	}{
+		{
+			name:     "largest first",
+			selector: LargestFirstSelector{},
+			target:   18.9996e6,
+			want:     []coinutil.Amount{1.5e7, 1e7, 9e6, 3e6, 1e5},
+		},
+		{
+			name:     "smallest first",
+			selector: SmallestFirstSelector{},
+			target:   18.9996e6,
+			want:     []coinutil.Amount{1e5, 3e6, 9e6, 1e7, 1.5e7},
+		},
+		{
+			// 1e7 + 9e6 pays the target and the 336 satoshi fee
+			// for two inputs with 64 left over, which is not worth
+			// a change output.
+			name:     "branch and bound match",
+			selector: BranchAndBoundSelector{},
+			target:   18.9996e6,
+			want:     []coinutil.Amount{1e7, 9e6, 1.5e7, 3e6, 1e5},
+		},
+		{
+			name:     "branch and bound fallback",
+			selector: BranchAndBoundSelector{},
+			target:   1e6,
+			want:     []coinutil.Amount{1.5e7, 1e7, 9e6, 3e6, 1e5},
+		},
+	}
+	for _, test := range tests {
+		got := test.selector.SelectCoins(eligible, test.target, 1,
+			defaultFeeRate)
+		if amounts := creditAmounts(got); !reflect.DeepEqual(amounts, test.want) {
+			t.Errorf("%s: got credits %v, want %v", test.name, amounts,
+				test.want)
+		}
+		if amounts := creditAmounts(eligible); !reflect.DeepEqual(amounts, original) {
+			t.Fatalf("%s: eligible credits were modified", test.name)
+		}
+	}
+
+	// Random selection must return every credit exactly once.
+	got := creditAmounts(RandomSelector{}.SelectCoins(eligible, 1e6, 1,
+		defaultFeeRate))
+	want := creditAmounts(eligible)
+	sort.Sort(amountSlice(got))
+	sort.Sort(amountSlice(want))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("random: got credits %v, want %v", got, want)
+	}
+}
+
+type amountSlice []coinutil.Amount
+
+func (s amountSlice) Len() int           { return len(s) }
+func (s amountSlice) Less(i, j int) bool { return s[i] < s[j] }
+func (s amountSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func TestCreateTxBranchAndBoundNoChange(t *testing.T) {
+	bs := &waddrmgr.BlockStamp{Height: 11111}
+	mgr := newManager(t, txInfo.privKeys, bs)
+	account := uint32(0)
+	var tstChangeAddress = func(account uint32) (coinutil.Address, error) {
+		t.Fatal("Unexpected request for a change address")
+		return nil, nil
+	}
+
+	eligible := mockCredits(t, txInfo.hex, []uint32{1, 2, 3, 4, 5})
+	outputs := map[string]coinutil.Amount{outAddr1: 18.9996e6}
+	tx, err := createTx(&txParams{
+		eligible:      eligible,
+		selector:      BranchAndBoundSelector{},
+		outputs:       outputs,
+		bs:            bs,
+		feeRate:       defaultFeeRate,
+		mgr:           mgr,
+		account:       account,
+		changeAddress: tstChangeAddress,
+		chainParams:   &chaincfg.TestNet3Params,
+		signer:        newManagerSigner(mgr),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tx.ChangeIndex != -1 {
+		t.Fatalf("Unexpected change output at index %d", tx.ChangeIndex)
+	}
+	if len(tx.MsgTx.TxIn) != 2 {
+		t.Fatalf("Unexpected number of inputs; got %d, want 2",
+			len(tx.MsgTx.TxIn))
+	}
+	checkOutputsMatch(t, tx.MsgTx, outputs)
+}
+
+func TestDropsSmallChange(t *testing.T) {
+	tests := []struct {
+		selector CoinSelector
+		want     bool
+	}{
+		{LargestFirstSelector{}, false},
+		{SmallestFirstSelector{}, true},
+		{BranchAndBoundSelector{}, true},
+		{RandomSelector{}, true},
+	}
+	for _, test := range tests {
+		if got := dropsSmallChange(test.selector); got != test.want {
+			t.Errorf("%T: got %v, want %v", test.selector, got,
+				test.want)
+		}
+	}
+}
@@ -20,7 +20,6 @@ import (
 	"errors"
 	"fmt"
 	badrand "math/rand"
-	"sort"
 	"time"
 
 	"github.com/conseweb/coinutil"
@@ -28,6 +27,7 @@ import (
 	"github.com/conseweb/stcd/chaincfg"
 	"github.com/conseweb/stcd/txscript"
 	"github.com/conseweb/stcd/wire"
+	"github.com/conseweb/stcwallet/signer"
 	"github.com/conseweb/stcwallet/waddrmgr"
 	"github.com/conseweb/stcwallet/wtxmgr"
 )
@@ -66,8 +66,52 @@ func estimateTxSize(numInputs, numOutputs int) int {
 	return txOverheadEstimate + txInEstimate*numInputs + txOutEstimate*numOutputs
 }
 
-func feeForSize(incr coinutil.Amount, sz int) coinutil.Amount {
-	return coinutil.Amount(1+sz/1000) * incr
+// feeForSize returns the fee of a transaction of sz bytes paying feeRate
+// satoshis per byte.
+func feeForSize(feeRate coinutil.Amount, sz int) coinutil.Amount {
+	return coinutil.Amount(sz) * feeRate
+}
+
+// feeEstimateBlocks is the number of blocks within which created transactions
+// are targeted to begin confirming when estimating their fee rate.
+const feeEstimateBlocks = 6
+
+// FeeEstimator is the interface implemented by sources of fee rate estimates
+// for created transactions.  It is implemented by the chain server client.
+type FeeEstimator interface {
+	// EstimateFeeRate returns the fee rate, in satoshis per byte, needed
+	// for a transaction to begin confirming within numBlocks blocks.  An
+	// error is returned if no estimate is available.
+	EstimateFeeRate(numBlocks uint32) (coinutil.Amount, error)
+}
+
+// txFeeRate returns the fee rate, in satoshis per byte, of a created
+// transaction.  A positive override is always used.  Otherwise, the wallet's
+// FeeRate is used if set, followed by the estimate of the FeeEstimator (or the
+// chain server when no estimator is set), and finally the FallbackFeeRate when
+// no estimate is available.
+func (w *Wallet) txFeeRate(override coinutil.Amount) coinutil.Amount {
+	if override > 0 {
+		return override
+	}
+	if w.FeeRate > 0 {
+		return w.FeeRate
+	}
+
+	estimator := w.FeeEstimator
+	if estimator == nil && w.chainSvr != nil {
+		estimator = w.chainSvr
+	}
+	if estimator != nil {
+		feeRate, err := estimator.EstimateFeeRate(feeEstimateBlocks)
+		if err == nil && feeRate > 0 {
+			return feeRate
+		}
+		log.Debugf("No fee rate estimate available (%v), using "+
+			"fallback fee rate of %v per byte", err,
+			w.FallbackFeeRate)
+	}
+	return w.FallbackFeeRate
 }
 
 // InsufficientFundsError represents an error where there are not enough
@@ -91,8 +135,9 @@ func (e InsufficientFundsError) Error() string {
 }
 
 // ErrUnsupportedTransactionType represents an error where a transaction
-// cannot be signed as the API only supports spending P2PKH outputs.
-var ErrUnsupportedTransactionType = errors.New("Only P2PKH transactions are supported")
+// cannot be signed as the API only supports spending P2PKH, P2PK and P2SH
+// multisig outputs.
+var ErrUnsupportedTransactionType = errors.New("Only P2PKH, P2PK and P2SH multisig transactions are supported")
 
 // ErrNonPositiveAmount represents an error where a bitcoin amount is
 // not positive (either negative, or zero).
@@ -102,18 +147,30 @@ var ErrNonPositiveAmount = errors.New("amount is not positive")
 // negative.
 var ErrNegativeFee = errors.New("fee is negative")
 
-// defaultFeeIncrement is the default minimum transation fee (0.00001 BTC,
-// measured in satoshis) added to transactions requiring a fee.
-const defaultFeeIncrement = 1e3
+// defaultFeeRate is the default fee rate, in satoshis per byte, of
+// transactions when no fee rate estimate is available.
+const defaultFeeRate = 1
 
 // CreatedTx holds the state of a newly-created transaction and the change
-// output (if one was added).
+// output (if one was added).  Transactions spending outputs of watching-only
+// accounts are left unsigned, and those spending outputs of HD multisig
+// accounts only hold the signatures of the wallet.  Such incomplete
+// transactions can not be published by the wallet.  PrevOutputs holds the
+// output spent by each input of MsgTx, in order.
 type CreatedTx struct {
 	MsgTx       *wire.MsgTx
 	ChangeAddr  coinutil.Address
 	ChangeIndex int // negative if no change
+	Incomplete  bool
+	PrevOutputs []wtxmgr.Credit
 }
 
+// ErrIncompleteTx describes an error where a transaction spending outputs of a
+// watching-only or HD multisig account would need to be fully signed by the
+// wallet.
+var ErrIncompleteTx = errors.New("transactions of watching-only and " +
+	"multisig accounts can not be fully signed by the wallet")
+
 // ByAmount defines the methods needed to satisify sort.Interface to
 // sort a slice of Utxos by their amount.
 type ByAmount []wtxmgr.Credit
@@ -125,20 +182,31 @@ func (u ByAmount) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
 // txToPairs creates a raw transaction sending the amounts for each
 // address/amount pair and fee to each address and the miner.  minconf
 // specifies the minimum number of confirmations required before an
-// unspent output is eligible for spending. Leftover input funds not sent
-// to addr or as a fee for the miner are sent to a newly generated
-// address. InsufficientFundsError is returned if there are not enough
-// eligible unspent outputs to create the transaction.
-func (w *Wallet) txToPairs(pairs map[string]coinutil.Amount, account uint32, minconf int32) (*CreatedTx, error) {
+// unspent output is eligible for spending, and selector chooses which
+// eligible outputs are spent.  feeRate overrides the fee rate of the wallet
+// when positive. Leftover input funds not sent to addr or as a
+// fee for the miner are sent to a newly generated address.  The transaction is
+// signed by the wallet's Signer, or left unsigned when unsigned is set.
+// InsufficientFundsError is returned if there are not enough eligible unspent
+// outputs to create the transaction.
+func (w *Wallet) txToPairs(pairs map[string]coinutil.Amount, account uint32, minconf int32,
+	selector CoinSelector, feeRate coinutil.Amount, unsigned bool) (*CreatedTx, error) {
 
 	// Address manager must be unlocked to compose transaction.  Grab
 	// the unlock if possible (to prevent future unlocks), or return the
-	// error if already locked.
-	heldUnlock, err := w.HoldUnlock()
+	// error if already locked.  Unsigned transactions, including those
+	// of watching-only accounts, do not need the unlock.
+	watchingOnly, err := w.Manager.IsWatchingOnlyAccount(account)
 	if err != nil {
 		return nil, err
 	}
-	defer heldUnlock.Release()
+	if !watchingOnly && !unsigned {
+		heldUnlock, err := w.HoldUnlock()
+		if err != nil {
+			return nil, err
+		}
+		defer heldUnlock.Release()
+	}
 
 	// Get current block's height and hash.
 	bs, err := w.chainSvr.BlockStamp()
@@ -151,49 +219,249 @@ func (w *Wallet) txToPairs(pairs map[string]coinutil.Amount, account uint32, min
 		return nil, err
 	}
 
-	return createTx(eligible, pairs, bs, w.FeeIncrement, w.Manager, account, w.NewChangeAddress, w.chainParams, w.DisallowFree)
+	s := w.Signer
+	if unsigned {
+		s = nil
+	}
+	return createTx(&txParams{
+		eligible:      eligible,
+		selector:      selector,
+		outputs:       pairs,
+		bs:            bs,
+		feeRate:       w.txFeeRate(feeRate),
+		disallowFree:  w.DisallowFree,
+		mgr:           w.Manager,
+		account:       account,
+		changeAddress: w.NewChangeAddress,
+		chainParams:   w.chainParams,
+		replaceable:   w.SignalReplaceable,
+		signer:        s,
+	})
+}
+
+// txFromOutpoints creates a raw transaction spending exactly the passed
+// outpoints to each address/amount pair.  Leftover input funds not sent to
+// the addresses or as a fee for the miner are sent to a newly generated
+// address of the account.  Every outpoint must be an unspent and unlocked
+// P2PKH output controlled by the account, else an OutpointError is returned.
+// InsufficientFundsError is returned if the outpoints do not pay for the
+// outputs and fee.  The fee is never waived for high priority transactions
+// when disallowFree is set.
+func (w *Wallet) txFromOutpoints(outpoints []wire.OutPoint, pairs map[string]coinutil.Amount,
+	account uint32, feeRate coinutil.Amount, disallowFree bool) (*CreatedTx, error) {
+
+	watchingOnly, err := w.Manager.IsWatchingOnlyAccount(account)
+	if err != nil {
+		return nil, err
+	}
+	if !watchingOnly {
+		heldUnlock, err := w.HoldUnlock()
+		if err != nil {
+			return nil, err
+		}
+		defer heldUnlock.Release()
+	}
+
+	bs, err := w.chainSvr.BlockStamp()
+	if err != nil {
+		return nil, err
+	}
+
+	required, err := w.outpointCredits(outpoints, account, bs)
+	if err != nil {
+		return nil, err
+	}
+
+	return createTx(&txParams{
+		required:      required,
+		selector:      w.CoinSelector,
+		outputs:       pairs,
+		bs:            bs,
+		feeRate:       w.txFeeRate(feeRate),
+		disallowFree:  w.DisallowFree || disallowFree,
+		mgr:           w.Manager,
+		account:       account,
+		changeAddress: w.NewChangeAddress,
+		chainParams:   w.chainParams,
+		replaceable:   w.SignalReplaceable,
+		signer:        w.Signer,
+	})
+}
+
+// OutpointError describes an outpoint which can not be spent by a transaction
+// created from explicitly chosen outpoints.
+type OutpointError struct {
+	OutPoint wire.OutPoint
+	Reason   string
+}
+
+// Error satisifies the builtin error interface.
+func (e OutpointError) Error() string {
+	return fmt.Sprintf("cannot spend outpoint %v: %s", e.OutPoint, e.Reason)
+}
+
+// spendableClass returns the class of the output scripts which may be spent by
+// transactions of the account.  These are P2SH outputs for HD multisig
+// accounts and P2PKH outputs for all other accounts.
+func (w *Wallet) spendableClass(account uint32) (txscript.ScriptClass, error) {
+	reqSigs, _, err := w.Manager.AccountMultisig(account)
+	if err != nil {
+		return txscript.NonStandardTy, err
+	}
+	if reqSigs != 0 {
+		return txscript.ScriptHashTy, nil
+	}
+	return txscript.PubKeyHashTy, nil
+}
+
+// outpointCredits returns the credits of the passed outpoints, in order,
+// checking that each may be spent by a transaction of the account.
+func (w *Wallet) outpointCredits(outpoints []wire.OutPoint, account uint32,
+	bs *waddrmgr.BlockStamp) ([]wtxmgr.Credit, error) {
+
+	spendable, err := w.spendableClass(account)
+	if err != nil {
+		return nil, err
+	}
+	unspent, err := w.TxStore.UnspentOutputs()
+	if err != nil {
+		return nil, err
+	}
+	credits := make(map[wire.OutPoint]*wtxmgr.Credit, len(unspent))
+	for i := range unspent {
+		credits[unspent[i].OutPoint] = &unspent[i]
+	}
+
+	selected := make([]wtxmgr.Credit, 0, len(outpoints))
+	seen := make(map[wire.OutPoint]struct{}, len(outpoints))
+	for _, op := range outpoints {
+		if _, ok := seen[op]; ok {
+			return nil, OutpointError{op, "outpoint is repeated"}
+		}
+		seen[op] = struct{}{}
+
+		credit, ok := credits[op]
+		if !ok {
+			return nil, OutpointError{op, "not an unspent output of the wallet"}
+		}
+		if w.LockedOutpoint(op) {
+			return nil, OutpointError{op, "output is locked"}
+		}
+		if credit.FromCoinBase {
+			const target = blockchain.CoinbaseMaturity
+			if !confirmed(target, credit.Height, bs.Height) {
+				return nil, OutpointError{op, "coinbase output is immature"}
+			}
+		}
+
+		class, addrs, _, err := txscript.ExtractPkScriptAddrs(
+			credit.PkScript, w.chainParams)
+		if err != nil || class != spendable {
+			if spendable == txscript.ScriptHashTy {
+				return nil, OutpointError{op, "only P2SH outputs can be spent by multisig accounts"}
+			}
+			return nil, OutpointError{op, "only P2PKH outputs can be spent"}
+		}
+		addrAcct, err := w.Manager.AddrAccount(addrs[0])
+		if err != nil || addrAcct != account {
+			return nil, OutpointError{op, "output is not controlled by the account"}
+		}
+
+		selected = append(selected, *credit)
+	}
+	return selected, nil
+}
+
+// txParams describes a transaction created by createTx.
+type txParams struct {
+	// Every required credit is spent, followed by eligible credits in the
+	// order chosen by selector until they pay for the outputs and fee.
+	eligible []wtxmgr.Credit
+	required []wtxmgr.Credit
+	selector CoinSelector
+	outputs  map[string]coinutil.Amount
+
+	// The fee is paid at feeRate satoshis per byte, and is only waived
+	// for high priority transactions at the height of bs when
+	// disallowFree is unset.
+	bs           *waddrmgr.BlockStamp
+	feeRate      coinutil.Amount
+	disallowFree bool
+
+	// Change is paid to an address of the account returned by
+	// changeAddress.
+	mgr           *waddrmgr.Manager
+	account       uint32
+	changeAddress func(account uint32) (coinutil.Address, error)
+	chainParams   *chaincfg.Params
+
+	// replaceable is whether the transaction signals that it may be
+	// replaced (BIP0125), and signer signs the inputs, or is nil to leave
+	// the transaction unsigned.
+	replaceable bool
+	signer      signer.Signer
 }
 
-// createTx selects inputs (from the given slice of eligible utxos)
-// whose amount are sufficient to fulfil all the desired outputs plus
-// the mining fee. It then creates and returns a CreatedTx containing
-// the selected inputs and the given outputs, validating it (using
-// validateMsgTx) as well.
-func createTx(eligible []wtxmgr.Credit,
-	outputs map[string]coinutil.Amount, bs *waddrmgr.BlockStamp,
-	feeIncrement coinutil.Amount, mgr *waddrmgr.Manager, account uint32,
-	changeAddress func(account uint32) (coinutil.Address, error),
-	chainParams *chaincfg.Params, disallowFree bool) (*CreatedTx, error) {
+// createTx spends every required utxo and selects further inputs (from the
+// given slice of eligible utxos), in the order chosen by the coin selector,
+// until their amount is sufficient to fulfil all the desired outputs plus the
+// mining fee at feeRate satoshis per byte.  It then creates and returns a
+// CreatedTx containing the selected inputs and the given outputs, validating
+// it (using validateMsgTx) as well.  Unless the coin selector picks the
+// largest credits first, change worth less than the cost of creating and
+// later spending a change output is added to the fee instead.  Inputs are
+// signed by the signer, and the transaction is left unsigned when it is nil.
+// Inputs of keys the signer does not hold, such as those of watching-only
+// accounts or of the other cosigners of HD multisig accounts, are left
+// incomplete.  The fee of incomplete transactions is based on the estimated
+// size of the complete signature scripts.
+func createTx(p *txParams) (*CreatedTx, error) {
+	reqSigs, numKeys, err := p.mgr.AccountMultisig(p.account)
+	if err != nil {
+		return nil, err
+	}
+	incomplete := true
+	sigScriptSize := sigScriptEstimate
+	if reqSigs != 0 {
+		sigScriptSize = multisigScriptEstimate(reqSigs, numKeys)
+	}
+	inEstimate := txInEstimate - sigScriptEstimate + sigScriptSize
 
 	msgtx := wire.NewMsgTx()
-	minAmount, err := addOutputs(msgtx, outputs, chainParams)
+	minAmount, err := addOutputs(msgtx, p.outputs, p.chainParams)
 	if err != nil {
 		return nil, err
 	}
 
-	// Sort eligible inputs so that we first pick the ones with highest
-	// amount, thus reducing number of inputs.
-	sort.Sort(sort.Reverse(ByAmount(eligible)))
+	// Order eligible inputs by the preference of the coin selector.
+	eligible := p.selector.SelectCoins(p.eligible, minAmount, len(msgtx.TxOut),
+		p.feeRate)
 
-	// Start by adding enough inputs to cover for the total amount of all
-	// desired outputs.
+	// Start by adding the required inputs, followed by enough inputs to
+	// cover for the total amount of all desired outputs.
 	var input wtxmgr.Credit
 	var inputs []wtxmgr.Credit
 	totalAdded := coinutil.Amount(0)
+	for i := range p.required {
+		inputs = append(inputs, p.required[i])
+		addInput(msgtx, &p.required[i].OutPoint, p.replaceable)
+		totalAdded += p.required[i].Amount
+	}
 	for totalAdded < minAmount {
 		if len(eligible) == 0 {
 			return nil, InsufficientFundsError{totalAdded, minAmount, 0}
 		}
 		input, eligible = eligible[0], eligible[1:]
 		inputs = append(inputs, input)
-		msgtx.AddTxIn(wire.NewTxIn(&input.OutPoint, nil))
+		addInput(msgtx, &input.OutPoint, p.replaceable)
 		totalAdded += input.Amount
 	}
 
 	// Get an initial fee estimate based on the number of selected inputs
 	// and added outputs, with no change.
-	szEst := estimateTxSize(len(inputs), len(msgtx.TxOut))
-	feeEst := minimumFee(feeIncrement, szEst, msgtx.TxOut, inputs, bs.Height, disallowFree)
+	szEst := estimateTxSize(len(inputs), len(msgtx.TxOut)) +
+		(inEstimate-txInEstimate)*len(inputs)
+	feeEst := minimumFee(p.feeRate, szEst, msgtx.TxOut, inputs, p.bs.Height, p.disallowFree)
 
 	// Now make sure the sum amount of all our inputs is enough for the
 	// sum amount of all outputs plus the fee. If necessary we add more,
@@ -204,10 +472,10 @@ func createTx(eligible []wtxmgr.Credit,
 		}
 		input, eligible = eligible[0], eligible[1:]
 		inputs = append(inputs, input)
-		msgtx.AddTxIn(wire.NewTxIn(&input.OutPoint, nil))
-		szEst += txInEstimate
+		addInput(msgtx, &input.OutPoint, p.replaceable)
+		szEst += inEstimate
 		totalAdded += input.Amount
-		feeEst = minimumFee(feeIncrement, szEst, msgtx.TxOut, inputs, bs.Height, disallowFree)
+		feeEst = minimumFee(p.feeRate, szEst, msgtx.TxOut, inputs, p.bs.Height, p.disallowFree)
 	}
 
 	var changeAddr coinutil.Address
@@ -216,9 +484,12 @@ func createTx(eligible []wtxmgr.Credit,
 
 	for {
 		change := totalAdded - minAmount - feeEst
+		if dropsSmallChange(p.selector) && change < changeCost(p.feeRate) {
+			change = 0
+		}
 		if change > 0 {
 			if changeAddr == nil {
-				changeAddr, err = changeAddress(account)
+				changeAddr, err = p.changeAddress(p.account)
 				if err != nil {
 					return nil, err
 				}
@@ -230,50 +501,93 @@ func createTx(eligible []wtxmgr.Credit,
 			}
 		}
 
-		if err = signMsgTx(msgtx, inputs, mgr, chainParams); err != nil {
-			return nil, err
+		if p.signer != nil {
+			complete, err := signMsgTx(msgtx, inputs, p.mgr, p.signer, p.chainParams)
+			if err != nil {
+				return nil, err
+			}
+			incomplete = !complete
 		}
-
-		if feeForSize(feeIncrement, msgtx.SerializeSize()) <= feeEst {
+		szActual := msgtx.SerializeSize()
+		if incomplete {
+			szActual = estimateSignedSize(msgtx, sigScriptSize)
+		}
+		if feeForSize(p.feeRate, szActual) <= feeEst {
 			// The required fee for this size is less than or equal to what
 			// we guessed, so we're done.
 			break
 		}
 
+		// Base the new estimate on the size of the signed transaction,
+		// allowing an extra byte for each signature since signing again
+		// may produce a longer encoding.
+		szEst = szActual + len(msgtx.TxIn)
 		if change > 0 {
 			// Remove the change output since the next iteration will add
 			// it again (with a new amount) if necessary.
 			tmp := msgtx.TxOut[:changeIdx]
 			tmp = append(tmp, msgtx.TxOut[changeIdx+1:]...)
 			msgtx.TxOut = tmp
+			changeIdx = -1
 		}
 
-		feeEst += feeIncrement
+		feeEst = feeForSize(p.feeRate, szEst)
 		for totalAdded < minAmount+feeEst {
 			if len(eligible) == 0 {
 				return nil, InsufficientFundsError{totalAdded, minAmount, feeEst}
 			}
 			input, eligible = eligible[0], eligible[1:]
 			inputs = append(inputs, input)
-			msgtx.AddTxIn(wire.NewTxIn(&input.OutPoint, nil))
-			szEst += txInEstimate
+			addInput(msgtx, &input.OutPoint, p.replaceable)
+			szEst += inEstimate
 			totalAdded += input.Amount
-			feeEst = minimumFee(feeIncrement, szEst, msgtx.TxOut, inputs, bs.Height, disallowFree)
+			feeEst = minimumFee(p.feeRate, szEst, msgtx.TxOut, inputs, p.bs.Height, p.disallowFree)
 		}
 	}
 
-	if err := validateMsgTx(msgtx, inputs); err != nil {
-		return nil, err
+	if !incomplete {
+		if err := validateMsgTx(msgtx, inputs); err != nil {
+			return nil, err
+		}
 	}
 
 	info := &CreatedTx{
 		MsgTx:       msgtx,
 		ChangeAddr:  changeAddr,
 		ChangeIndex: changeIdx,
+		Incomplete:  incomplete,
+		PrevOutputs: inputs,
 	}
 	return info, nil
 }
 
+// replaceableSequence is the sequence number of each input of a created
+// transaction signaling replaceability.  Sequence numbers below the maximum
+// minus one signal that the transaction may be replaced by one paying a higher
+// fee (BIP0125), so stuck transactions can later be bumped with BumpFee.
+const replaceableSequence = wire.MaxTxInSequenceNum - 2
+
+// signalsReplaceable returns whether a transaction signals that it may be
+// replaced by one paying a higher fee (BIP0125).
+func signalsReplaceable(msgtx *wire.MsgTx) bool {
+	for _, txIn := range msgtx.TxIn {
+		if txIn.Sequence < wire.MaxTxInSequenceNum-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// addInput adds an input spending op to msgtx, which signals replaceability
+// when replaceable is set.
+func addInput(msgtx *wire.MsgTx, op *wire.OutPoint, replaceable bool) {
+	txIn := wire.NewTxIn(op, nil)
+	if replaceable {
+		txIn.Sequence = replaceableSequence
+	}
+	msgtx.AddTxIn(txIn)
+}
+
 // addChange adds a new output with the given amount and address, and
 // randomizes the index (and returns it) of the newly added output.
 func addChange(msgtx *wire.MsgTx, change coinutil.Amount, changeAddr coinutil.Address) (int, error) {
@@ -317,6 +631,10 @@ func addOutputs(msgtx *wire.MsgTx, pairs map[string]coinutil.Amount, chainParams
 }
 
 func (w *Wallet) findEligibleOutputs(account uint32, minconf int32, bs *waddrmgr.BlockStamp) ([]wtxmgr.Credit, error) {
+	spendable, err := w.spendableClass(account)
+	if err != nil {
+		return nil, err
+	}
 	unspent, err := w.TxStore.UnspentOutputs()
 	if err != nil {
 		return nil, err
@@ -350,19 +668,20 @@ func (w *Wallet) findEligibleOutputs(account uint32, minconf int32, bs *waddrmgr
 		}
 
 		// Filter out unspendable outputs, that is, remove those that
-		// (at this time) are not P2PKH outputs.  Other inputs must be
-		// manually included in transactions and sent (for example,
-		// using createrawtransaction, signrawtransaction, and
+		// (at this time) are not P2PKH outputs, or P2SH outputs for HD
+		// multisig accounts.  Other inputs must be manually included
+		// in transactions and sent (for example, using
+		// createrawtransaction, signrawtransaction, and
 		// sendrawtransaction).
 		class, addrs, _, err := txscript.ExtractPkScriptAddrs(
 			output.PkScript, w.chainParams)
-		if err != nil || class != txscript.PubKeyHashTy {
+		if err != nil || class != spendable {
 			continue
 		}
 
 		// Only include the output if it is associated with the passed
 		// account.  There should only be one address since this is a
-		// P2PKH script.
+		// P2PKH or P2SH script.
 		addrAcct, err := w.Manager.AddrAccount(addrs[0])
 		if err != nil || addrAcct != account {
 			continue
@@ -373,49 +692,42 @@ func (w *Wallet) findEligibleOutputs(account uint32, minconf int32, bs *waddrmgr
 	return eligible, nil
 }
 
-// signMsgTx sets the SignatureScript for every item in msgtx.TxIn.
+// signMsgTx sets the SignatureScript for every item in msgtx.TxIn to one
+// holding the signatures s makes with the keys of mgr's addresses, and returns
+// whether every input is fully signed.  Inputs of keys s does not hold are
+// left without (or with only some of) their signatures.
 // It must be called every time a msgtx is changed.
-// Only P2PKH outputs are supported at this point.
-func signMsgTx(msgtx *wire.MsgTx, prevOutputs []wtxmgr.Credit, mgr *waddrmgr.Manager, chainParams *chaincfg.Params) error {
+func signMsgTx(msgtx *wire.MsgTx, prevOutputs []wtxmgr.Credit, mgr *waddrmgr.Manager,
+	s signer.Signer, chainParams *chaincfg.Params) (bool, error) {
+
 	if len(prevOutputs) != len(msgtx.TxIn) {
-		return fmt.Errorf(
+		return false, fmt.Errorf(
 			"Number of prevOutputs (%d) does not match number of tx inputs (%d)",
 			len(prevOutputs), len(msgtx.TxIn))
 	}
 	for i, output := range prevOutputs {
-		// Errors don't matter here, as we only consider the
-		// case where len(addrs) == 1.
-		_, addrs, _, _ := txscript.ExtractPkScriptAddrs(output.PkScript,
-			chainParams)
-		if len(addrs) != 1 {
-			continue
-		}
-		apkh, ok := addrs[0].(*coinutil.AddressPubKeyHash)
-		if !ok {
-			return ErrUnsupportedTransactionType
-		}
-
-		ai, err := mgr.Address(apkh)
+		// Signatures of a previous version of the transaction are no
+		// longer valid.
+		msgtx.TxIn[i].SignatureScript = nil
+		sigscript, err := signTxInput(s, mgr, chainParams, msgtx, i,
+			output.PkScript, txscript.SigHashAll)
 		if err != nil {
-			return fmt.Errorf("cannot get address info: %v", err)
+			return false, fmt.Errorf("cannot create sigscript: %s", err)
 		}
+		msgtx.TxIn[i].SignatureScript = sigscript
+	}
 
-		pka := ai.(waddrmgr.ManagedPubKeyAddress)
-		privkey, err := pka.PrivKey()
-		if err != nil {
-			return fmt.Errorf("cannot get private key: %v", err)
+	for i, output := range prevOutputs {
+		vm, err := txscript.NewEngine(output.PkScript, msgtx, i,
+			txscript.StandardVerifyFlags, nil)
+		if err == nil {
+			err = vm.Execute()
 		}
-
-		sigscript, err := txscript.SignatureScript(msgtx, i,
-			output.PkScript, txscript.SigHashAll, privkey,
-			ai.Compressed())
 		if err != nil {
-			return fmt.Errorf("cannot create sigscript: %s", err)
+			return false, nil
 		}
-		msgtx.TxIn[i].SignatureScript = sigscript
 	}
-
-	return nil
+	return true, nil
 }
 
 func validateMsgTx(msgtx *wire.MsgTx, prevOutputs []wtxmgr.Credit) error {
@@ -435,23 +747,24 @@ func validateMsgTx(msgtx *wire.MsgTx, prevOutputs []wtxmgr.Credit) error {
 // minimumFee estimates the minimum fee required for a transaction.
 // If cfg.DisallowFree is false, a fee may be zero so long as txLen
 // s less than 1 kilobyte and none of the outputs contain a value
-// less than 1 bitcent. Otherwise, the fee will be calculated using
-// incr, incrementing the fee for each kilobyte of transaction.
-func minimumFee(incr coinutil.Amount, txLen int, outputs []*wire.TxOut, prevOutputs []wtxmgr.Credit, height int32, disallowFree bool) coinutil.Amount {
+// less than 1 bitcent. Otherwise, the fee will be calculated by
+// charging feeRate for each byte of the transaction, and is at least
+// the fee of a kilobyte when any output is less than 1 bitcent.
+func minimumFee(feeRate coinutil.Amount, txLen int, outputs []*wire.TxOut, prevOutputs []wtxmgr.Credit, height int32, disallowFree bool) coinutil.Amount {
 	allowFree := false
 	if !disallowFree {
 		allowFree = allowNoFeeTx(height, prevOutputs, txLen)
 	}
-	fee := feeForSize(incr, txLen)
+	fee := feeForSize(feeRate, txLen)
 
 	if allowFree && txLen < 1000 {
 		fee = 0
 	}
 
-	if fee < incr {
+	if minFee := feeForSize(feeRate, 1000); fee < minFee {
 		for _, txOut := range outputs {
 			if txOut.Value < coinutil.SatoshiPerBitcent {
-				return incr
+				return minFee
 			}
 		}
 	}
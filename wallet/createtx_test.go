@@ -2,6 +2,7 @@ package wallet
 
 import (
 	"encoding/hex"
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -73,26 +74,20 @@ func Test_addOutputs(t *testing.T) {
 }
 
 func TestCreateTx(t *testing.T) {
-	bs := &waddrmgr.BlockStamp{Height: 11111}
-	mgr := newManager(t, txInfo.privKeys, bs)
-	account := uint32(0)
-	changeAddr, _ := coinutil.DecodeAddress("muqW4gcixv58tVbSKRC5q6CRKy8RmyLgZ5", &chaincfg.TestNet3Params)
-	var tstChangeAddress = func(account uint32) (coinutil.Address, error) {
-		return changeAddr, nil
-	}
+	tt := newCreateTxTest(t)
 
 	// Pick all utxos from txInfo as eligible input.
 	eligible := mockCredits(t, txInfo.hex, []uint32{1, 2, 3, 4, 5})
 	// Now create a new TX sending 25e6 satoshis to the following addresses:
 	outputs := map[string]coinutil.Amount{outAddr1: 15e6, outAddr2: 10e6}
-	tx, err := createTx(eligible, outputs, bs, defaultFeeIncrement, mgr, account, tstChangeAddress, &chaincfg.TestNet3Params, false)
+	tx, err := createTx(tt.params(eligible, nil, outputs))
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if tx.ChangeAddr.String() != changeAddr.String() {
+	if tx.ChangeAddr.String() != tt.changeAddr.String() {
 		t.Fatalf("Unexpected change address; got %v, want %v",
-			tx.ChangeAddr.String(), changeAddr.String())
+			tx.ChangeAddr.String(), tt.changeAddr.String())
 	}
 
 	msgTx := tx.MsgTx
@@ -107,32 +102,93 @@ func TestCreateTx(t *testing.T) {
 		t.Fatalf("Unexpected number of inputs; got %d, want 3", len(msgTx.TxIn))
 	}
 
-	// Given the input (15e6 + 10e6 + 1e7) and requested output (15e6 + 10e6)
-	// amounts in the new TX, we should have a change output with 8.99e6, which
-	// implies a fee of 1e3 satoshis.
-	expectedChange := coinutil.Amount(8.999e6)
+	// Given the input (15e6 + 10e6 + 9e6) and requested output (15e6 + 10e6)
+	// amounts in the new TX, the change output holds what is left after
+	// paying the fee.  The fee must pay the fee rate for every byte of the
+	// transaction, overpaying by no more than a byte per input.
+	if tx.ChangeIndex < 0 {
+		t.Fatal("Expected a change output")
+	}
+	change := coinutil.Amount(msgTx.TxOut[tx.ChangeIndex].Value)
+	fee := 9e6 - change
+	size := msgTx.SerializeSize()
+	minFee := feeForSize(defaultFeeRate, size)
+	maxFee := feeForSize(defaultFeeRate, size+len(msgTx.TxIn))
+	if fee < minFee || fee > maxFee {
+		t.Fatalf("Unexpected fee %v for tx size %d; want between %v and %v",
+			fee, size, minFee, maxFee)
+	}
 
-	outputs[changeAddr.String()] = expectedChange
+	outputs[tt.changeAddr.String()] = change
 	checkOutputsMatch(t, msgTx, outputs)
+}
 
-	minFee := feeForSize(defaultFeeIncrement, msgTx.SerializeSize())
-	actualFee := coinutil.Amount(1e3)
-	if minFee > actualFee {
-		t.Fatalf("Requested fee (%v) for tx size higher than actual fee (%v)", minFee, actualFee)
+// mockFeeEstimator is a FeeEstimator returning a fixed estimate.
+type mockFeeEstimator struct {
+	feeRate coinutil.Amount
+	err     error
+}
+
+func (e mockFeeEstimator) EstimateFeeRate(numBlocks uint32) (coinutil.Amount, error) {
+	return e.feeRate, e.err
+}
+
+func TestTxFeeRate(t *testing.T) {
+	errNoEstimate := errors.New("no estimate")
+	tests := []struct {
+		name      string
+		feeRate   coinutil.Amount
+		estimator FeeEstimator
+		override  coinutil.Amount
+		want      coinutil.Amount
+	}{
+		{
+			name:      "estimate",
+			estimator: mockFeeEstimator{feeRate: 20},
+			want:      20,
+		},
+		{
+			name:      "no estimate",
+			estimator: mockFeeEstimator{err: errNoEstimate},
+			want:      5,
+		},
+		{
+			name: "no estimator",
+			want: 5,
+		},
+		{
+			name:      "wallet fee rate",
+			feeRate:   10,
+			estimator: mockFeeEstimator{feeRate: 20},
+			want:      10,
+		},
+		{
+			name:      "override",
+			feeRate:   10,
+			estimator: mockFeeEstimator{feeRate: 20},
+			override:  30,
+			want:      30,
+		},
+	}
+	for _, test := range tests {
+		w := &Wallet{
+			FeeRate:         test.feeRate,
+			FeeEstimator:    test.estimator,
+			FallbackFeeRate: 5,
+		}
+		if got := w.txFeeRate(test.override); got != test.want {
+			t.Errorf("%s: got fee rate %v, want %v", test.name, got,
+				test.want)
+		}
 	}
 }
 
 func TestCreateTxInsufficientFundsError(t *testing.T) {
+	tt := newCreateTxTest(t)
+
 	outputs := map[string]coinutil.Amount{outAddr1: 10, outAddr2: 1e9}
 	eligible := mockCredits(t, txInfo.hex, []uint32{1})
-	bs := &waddrmgr.BlockStamp{Height: 11111}
-	account := uint32(0)
-	changeAddr, _ := coinutil.DecodeAddress("muqW4gcixv58tVbSKRC5q6CRKy8RmyLgZ5", &chaincfg.TestNet3Params)
-	var tstChangeAddress = func(account uint32) (coinutil.Address, error) {
-		return changeAddr, nil
-	}
-
-	_, err := createTx(eligible, outputs, bs, defaultFeeIncrement, nil, account, tstChangeAddress, &chaincfg.TestNet3Params, false)
+	_, err := createTx(tt.params(eligible, nil, outputs))
 
 	if err == nil {
 		t.Error("Expected InsufficientFundsError, got no error")
@@ -141,6 +197,159 @@ func TestCreateTxInsufficientFundsError(t *testing.T) {
 	}
 }
 
+func TestCreateTxRequiredInputs(t *testing.T) {
+	tt := newCreateTxTest(t)
+
+	// Spend the 1e5 and 3e6 outputs, even though the larger outputs
+	// would be chosen by the coin selector.
+	required := mockCredits(t, txInfo.hex, []uint32{5, 1})
+	outputs := map[string]coinutil.Amount{outAddr1: 2e6}
+	tx, err := createTx(tt.params(nil, required, outputs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgTx := tx.MsgTx
+	if len(msgTx.TxIn) != len(required) {
+		t.Fatalf("Unexpected number of inputs; got %d, want %d",
+			len(msgTx.TxIn), len(required))
+	}
+	for i, txIn := range msgTx.TxIn {
+		if txIn.PreviousOutPoint != required[i].OutPoint {
+			t.Errorf("Unexpected input %d; got %v, want %v", i,
+				txIn.PreviousOutPoint, required[i].OutPoint)
+		}
+	}
+	if tx.ChangeIndex < 0 {
+		t.Fatal("Expected a change output")
+	}
+	checkOutputsMatch(t, msgTx, outputs)
+
+	// Other eligible outputs are only added when the required outputs do
+	// not pay for the transaction.
+	eligible := mockCredits(t, txInfo.hex, []uint32{2, 3, 4})
+	outputs = map[string]coinutil.Amount{outAddr1: 5e6}
+	tx, err = createTx(tt.params(eligible, required, outputs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.MsgTx.TxIn) != 3 {
+		t.Fatalf("Unexpected number of inputs; got %d, want 3",
+			len(tx.MsgTx.TxIn))
+	}
+
+	_, err = createTx(tt.params(nil, required, outputs))
+	if _, ok := err.(InsufficientFundsError); !ok {
+		t.Errorf("Unexpected error, got %v, want InsufficientFundsError", err)
+	}
+}
+
+func TestCreateTxMergeOutputs(t *testing.T) {
+	tt := newCreateTxTest(t)
+
+	// Without any outputs, the required inputs are merged into a single
+	// change output paying everything but the fee.
+	required := mockCredits(t, txInfo.hex, []uint32{1, 2, 5})
+	tx, err := createTx(tt.params(nil, required, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgTx := tx.MsgTx
+	if len(msgTx.TxIn) != 3 || len(msgTx.TxOut) != 1 || tx.ChangeIndex != 0 {
+		t.Fatalf("Unexpected transaction shape; got %d inputs, %d "+
+			"outputs and change index %d", len(msgTx.TxIn),
+			len(msgTx.TxOut), tx.ChangeIndex)
+	}
+	fee := coinutil.Amount(3e6+9e6+1e5) - coinutil.Amount(msgTx.TxOut[0].Value)
+	if minFee := feeForSize(defaultFeeRate, msgTx.SerializeSize()); fee < minFee {
+		t.Errorf("Fee %v is less than the required fee %v", fee, minFee)
+	}
+}
+
+func TestCreateTxUnsigned(t *testing.T) {
+	tt := newCreateTxTest(t)
+
+	eligible := mockCredits(t, txInfo.hex, []uint32{1, 2, 3, 4, 5})
+	outputs := map[string]coinutil.Amount{outAddr1: 15e6, outAddr2: 10e6}
+	params := tt.params(eligible, nil, outputs)
+	params.signer = nil
+	tx, err := createTx(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tx.Incomplete {
+		t.Error("Unsigned transaction is not marked incomplete")
+	}
+	msgTx := tx.MsgTx
+	if len(tx.PrevOutputs) != len(msgTx.TxIn) {
+		t.Fatalf("Got %d previous outputs for %d inputs",
+			len(tx.PrevOutputs), len(msgTx.TxIn))
+	}
+	for i, txIn := range msgTx.TxIn {
+		if len(txIn.SignatureScript) != 0 {
+			t.Errorf("Input %d is signed", i)
+		}
+		if txIn.PreviousOutPoint != tx.PrevOutputs[i].OutPoint {
+			t.Errorf("Input %d spends %v, but previous output is %v", i,
+				txIn.PreviousOutPoint, tx.PrevOutputs[i].OutPoint)
+		}
+	}
+
+	// The fee must pay for the signature scripts to be added.
+	change := coinutil.Amount(msgTx.TxOut[tx.ChangeIndex].Value)
+	fee := 9e6 - change
+	size := estimateSignedSize(msgTx, sigScriptEstimate)
+	if minFee := feeForSize(defaultFeeRate, size); fee < minFee {
+		t.Errorf("Fee %v is less than the fee %v for the signed size", fee,
+			minFee)
+	}
+}
+
+// createTxTest is the setup shared by the createTx tests: a block stamp, an
+// address manager holding the keys of txInfo, and the change address of the
+// created transactions.
+type createTxTest struct {
+	bs         *waddrmgr.BlockStamp
+	mgr        *waddrmgr.Manager
+	changeAddr coinutil.Address
+}
+
+func newCreateTxTest(t *testing.T) *createTxTest {
+	bs := &waddrmgr.BlockStamp{Height: 11111}
+	changeAddr, err := coinutil.DecodeAddress("muqW4gcixv58tVbSKRC5q6CRKy8RmyLgZ5", &chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &createTxTest{
+		bs:         bs,
+		mgr:        newManager(t, txInfo.privKeys, bs),
+		changeAddr: changeAddr,
+	}
+}
+
+// changeAddress is the change address source passed to createTx.
+func (tt *createTxTest) changeAddress(account uint32) (coinutil.Address, error) {
+	return tt.changeAddr, nil
+}
+
+// params returns the parameters of a transaction of account 0 spending the
+// credits to the outputs, with the defaults of the createTx tests.
+func (tt *createTxTest) params(eligible, required []wtxmgr.Credit,
+	outputs map[string]coinutil.Amount) *txParams {
+
+	return &txParams{
+		eligible:      eligible,
+		required:      required,
+		selector:      LargestFirstSelector{},
+		outputs:       outputs,
+		bs:            tt.bs,
+		feeRate:       defaultFeeRate,
+		mgr:           tt.mgr,
+		changeAddress: tt.changeAddress,
+		chainParams:   &chaincfg.TestNet3Params,
+		signer:        newManagerSigner(tt.mgr),
+	}
+}
+
 // checkOutputsMatch checks that the outputs in the tx match the expected ones.
 func checkOutputsMatch(t *testing.T, msgtx *wire.MsgTx, expected map[string]coinutil.Amount) {
 	// This is a bit convoluted because the index of the change output is randomized.
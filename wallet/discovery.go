@@ -0,0 +1,304 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcwallet/waddrmgr"
+)
+
+// ErrDiscoveryRunning describes an error where account discovery is requested
+// while a previous discovery is still running.
+var ErrDiscoveryRunning = errors.New("account discovery is already running")
+
+// DiscoveryStatus describes the progress of the account discovery started by
+// DiscoverAccounts.
+type DiscoveryStatus struct {
+	// Running is whether account discovery is in progress.
+	Running bool
+
+	// Account is the account being discovered, or the next account to use
+	// once discovery finished.
+	Account uint32
+
+	// Rescanned is the number of addresses rescanned so far.
+	Rescanned int
+
+	// Err is the error which stopped the last discovery, if any.
+	Err error
+}
+
+// DefaultGapLimit is the number of consecutive unused addresses after which no
+// further addresses of a branch are looked for during account discovery, as
+// recommended by BIP0044.
+const DefaultGapLimit = 20
+
+// branchUsage describes how far a branch of an account has been derived and
+// used.
+type branchUsage struct {
+	// next is the index of the next address to derive.  lastUsed is the
+	// index of the last used address, or -1 when no address was used.
+	next     uint32
+	lastUsed int64
+}
+
+// extension returns the number of addresses which must be derived for the
+// branch to end with gapLimit unused addresses.
+func (b *branchUsage) extension(gapLimit uint32) uint32 {
+	end := b.lastUsed + 1 + int64(gapLimit)
+	if end <= int64(b.next) {
+		return 0
+	}
+	return uint32(end - int64(b.next))
+}
+
+// accountUsage returns the usage of the external and internal branches of an
+// account, along with every address derived for it.
+func (w *Wallet) accountUsage(account uint32) (external, internal branchUsage,
+	addrs []coinutil.Address, err error) {
+
+	// Addresses are collected before querying them since the manager is
+	// locked while iterating.
+	var managed []waddrmgr.ManagedAddress
+	err = w.Manager.ForEachAccountAddress(account,
+		func(ma waddrmgr.ManagedAddress) error {
+			managed = append(managed, ma)
+			return nil
+		})
+	if err != nil {
+		return
+	}
+
+	external.lastUsed, internal.lastUsed = -1, -1
+	for _, ma := range managed {
+		var details *waddrmgr.AddressDetails
+		details, err = w.Manager.AddrDetails(ma.Address())
+		if err != nil {
+			return
+		}
+		if !details.Chained {
+			continue
+		}
+		addrs = append(addrs, ma.Address())
+
+		b := &external
+		if ma.Internal() {
+			b = &internal
+		}
+		if details.Index >= b.next {
+			b.next = details.Index + 1
+		}
+		var used bool
+		used, err = ma.Used()
+		if err != nil {
+			return
+		}
+		if used && int64(details.Index) > b.lastUsed {
+			b.lastUsed = int64(details.Index)
+		}
+	}
+	return
+}
+
+// rescanAndWait requests transaction notifications for addrs and rescans the
+// chain for them beginning with the block bs, returning after every
+// transaction found by the rescan has been recorded.
+func (w *Wallet) rescanAndWait(addrs []coinutil.Address, bs waddrmgr.BlockStamp) error {
+	if err := w.chainSvr.NotifyReceived(addrs); err != nil {
+		return err
+	}
+	job := &RescanJob{
+		Addrs:      addrs,
+		BlockStamp: bs,
+		finished:   make(chan struct{}),
+	}
+	if err := <-w.SubmitRescan(job); err != nil {
+		return err
+	}
+	select {
+	case <-job.finished:
+		return nil
+	case <-w.quitChan():
+		return ErrShuttingDown
+	}
+}
+
+// rescanFunc rescans the chain for addresses beginning with the block bs,
+// returning after every transaction found has been recorded.
+type rescanFunc func(addrs []coinutil.Address, bs waddrmgr.BlockStamp) error
+
+// discoverAccount derives and rescans addresses of an account with rescan until
+// both of its branches end with gapLimit unused addresses.  It returns whether
+// any address of the account was used.
+func (w *Wallet) discoverAccount(account, gapLimit uint32, bs waddrmgr.BlockStamp,
+	rescan rescanFunc) (bool, error) {
+	// The addresses already derived are rescanned as well so discovery
+	// does not depend on the initial sync having finished.
+	external, internal, addrs, err := w.accountUsage(account)
+	if err != nil {
+		return false, err
+	}
+	for {
+		if n := external.extension(gapLimit); n > 0 {
+			mas, err := w.Manager.NextExternalAddresses(account, n)
+			if err != nil {
+				return false, err
+			}
+			for _, ma := range mas {
+				addrs = append(addrs, ma.Address())
+			}
+		}
+		if n := internal.extension(gapLimit); n > 0 {
+			mas, err := w.Manager.NextInternalAddresses(account, n)
+			if err != nil {
+				return false, err
+			}
+			for _, ma := range mas {
+				addrs = append(addrs, ma.Address())
+			}
+		}
+		if len(addrs) == 0 {
+			break
+		}
+
+		log.Infof("Rescanning %d %s of account %d", len(addrs),
+			pickNoun(len(addrs), "address", "addresses"), account)
+		if err := rescan(addrs, bs); err != nil {
+			return false, err
+		}
+		w.discoveryMtx.Lock()
+		w.discovery.Rescanned += len(addrs)
+		w.discoveryMtx.Unlock()
+		external, internal, _, err = w.accountUsage(account)
+		if err != nil {
+			return false, err
+		}
+		addrs = nil
+	}
+	return external.lastUsed != -1 || internal.lastUsed != -1, nil
+}
+
+// DiscoverAccounts recovers the accounts and addresses of a wallet restored
+// from an existing seed using the account discovery procedure of BIP0044.
+// Beginning with the default account, addresses of both branches of each
+// account are derived and the chain is rescanned for them from the start
+// block of the wallet, until each branch ends with gapLimit unused addresses.
+// Accounts are created as required until an account without any used address
+// is found, which is kept as the next account to use.  The gap limit of the
+// address manager is raised to gapLimit if it is lower, since addresses would
+// otherwise be refused.
+//
+// Discovery runs in the background, and its progress is reported by
+// DiscoveryStatus.  The wallet must be unlocked to start discovery, and is
+// kept unlocked until every account has been created.  ErrDiscoveryRunning is
+// returned if a previous discovery has not finished.
+func (w *Wallet) DiscoverAccounts(gapLimit uint32) error {
+	if gapLimit == 0 {
+		return errors.New("gap limit must be positive")
+	}
+
+	w.discoveryMtx.Lock()
+	defer w.discoveryMtx.Unlock()
+	if w.discovery.Running {
+		return ErrDiscoveryRunning
+	}
+	heldUnlock, err := w.HoldUnlock()
+	if err != nil {
+		return err
+	}
+	w.discovery = DiscoveryStatus{Running: true}
+
+	if limit := w.Manager.GapLimit(); limit != 0 && limit < gapLimit {
+		log.Infof("Raising the gap limit of address generation from %d "+
+			"to %d", limit, gapLimit)
+		w.Manager.SetGapLimit(gapLimit)
+	}
+
+	w.wg.Add(1)
+	go func() {
+		err := w.discoverAccounts(gapLimit, w.rescanAndWait)
+		heldUnlock.Release()
+		if err != nil {
+			log.Errorf("Account discovery failed: %v", err)
+		}
+		w.discoveryMtx.Lock()
+		w.discovery.Running = false
+		w.discovery.Err = err
+		w.discoveryMtx.Unlock()
+		w.wg.Done()
+	}()
+	return nil
+}
+
+// DiscoveryStatus returns the progress of the account discovery started by
+// DiscoverAccounts, or of the last discovery once it finished.
+func (w *Wallet) DiscoveryStatus() DiscoveryStatus {
+	w.discoveryMtx.Lock()
+	status := w.discovery
+	w.discoveryMtx.Unlock()
+	return status
+}
+
+// discoverAccounts discovers every account, as described by DiscoverAccounts,
+// rescanning addresses with rescan.  The address manager must be unlocked.
+func (w *Wallet) discoverAccounts(gapLimit uint32, rescan rescanFunc) error {
+	bs := w.Manager.StartBlock()
+	for account := uint32(0); ; account++ {
+		last, err := w.Manager.LastAccount()
+		if err != nil {
+			return err
+		}
+		if account > last {
+			name := fmt.Sprintf("account-%d", account)
+			account, err = w.Manager.NewAccount(name)
+			if err != nil {
+				return err
+			}
+			log.Infof("Created account %d (%s) for discovery", account,
+				name)
+		}
+		w.discoveryMtx.Lock()
+		w.discovery.Account = account
+		w.discoveryMtx.Unlock()
+
+		// Accounts imported from an extended public key are not
+		// derived from the seed, and the addresses of HD multisig
+		// accounts also depend on the keys of the other cosigners.
+		watchingOnly, err := w.Manager.IsWatchingOnlyAccount(account)
+		if err != nil {
+			return err
+		}
+		reqSigs, _, err := w.Manager.AccountMultisig(account)
+		if err != nil {
+			return err
+		}
+		if watchingOnly || reqSigs != 0 {
+			continue
+		}
+
+		used, err := w.discoverAccount(account, gapLimit, bs, rescan)
+		if err != nil {
+			return err
+		}
+		if !used {
+			log.Infof("Account discovery finished with %d used %s",
+				account, pickNoun(int(account), "account", "accounts"))
+			return nil
+		}
+	}
+}
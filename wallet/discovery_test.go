@@ -0,0 +1,107 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcwallet/waddrmgr"
+)
+
+func TestBranchUsageExtension(t *testing.T) {
+	tests := []struct {
+		name     string
+		usage    branchUsage
+		gapLimit uint32
+		want     uint32
+	}{
+		{"empty branch", branchUsage{0, -1}, 20, 20},
+		{"unused branch", branchUsage{20, -1}, 20, 0},
+		{"first address used", branchUsage{20, 0}, 20, 1},
+		{"last address used", branchUsage{20, 19}, 20, 20},
+		{"gap already derived", branchUsage{40, 5}, 20, 0},
+		{"small gap limit", branchUsage{3, 4}, 2, 4},
+	}
+	for _, test := range tests {
+		got := test.usage.extension(test.gapLimit)
+		if got != test.want {
+			t.Errorf("%s: got %d, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDiscoverAccounts(t *testing.T) {
+	bs := &waddrmgr.BlockStamp{Height: 11111}
+	mgr := newManager(t, nil, bs)
+	if err := mgr.Unlock([]byte("priv")); err != nil {
+		t.Fatal(err)
+	}
+	w := &Wallet{Manager: mgr}
+
+	// The rescan marks the addresses at these positions used, as if
+	// transactions paying them were found.
+	type position struct{ account, branch, index uint32 }
+	used := map[position]bool{
+		{0, 0, 2}: true,
+		{0, 0, 4}: true,
+		{1, 1, 0}: true,
+	}
+	rescanned := make(map[position]bool)
+	rescan := func(addrs []coinutil.Address, bs waddrmgr.BlockStamp) error {
+		for _, addr := range addrs {
+			details, err := mgr.AddrDetails(addr)
+			if err != nil {
+				return err
+			}
+			pos := position{details.Account, details.Branch, details.Index}
+			rescanned[pos] = true
+			if used[pos] {
+				if err := mgr.MarkUsed(addr); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	const gapLimit = 3
+	if err := w.discoverAccounts(gapLimit, rescan); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each branch is extended until it ends with gapLimit unused
+	// addresses, and discovery stops at the first unused account.
+	last, err := mgr.LastAccount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != 2 {
+		t.Fatalf("Got last account %d, want 2", last)
+	}
+	wantNext := []struct{ external, internal uint32 }{{8, 3}, {3, 4}, {3, 3}}
+	for account, want := range wantNext {
+		external, internal, _, err := w.accountUsage(uint32(account))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if external.next != want.external || internal.next != want.internal {
+			t.Errorf("Account %d: derived %d external and %d internal "+
+				"addresses, want %d and %d", account, external.next,
+				internal.next, want.external, want.internal)
+		}
+		for branch, n := range []uint32{want.external, want.internal} {
+			for index := uint32(0); index < n; index++ {
+				pos := position{uint32(account), uint32(branch), index}
+				if !rescanned[pos] {
+					t.Errorf("Address %v was not rescanned", pos)
+				}
+			}
+		}
+	}
+
+	status := w.DiscoveryStatus()
+	if status.Account != 2 || status.Rescanned != len(rescanned) {
+		t.Errorf("Got status account %d and %d rescanned addresses, "+
+			"want 2 and %d", status.Account, status.Rescanned,
+			len(rescanned))
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package wallet
+
+import (
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+)
+
+// multisigScriptEstimate returns the estimated size of a signature script
+// redeeming a P2SH output of an HD multisig account requiring reqSigs of
+// numKeys signatures.  The script holds the extra item popped by
+// OP_CHECKMULTISIG, a data push of the largest possible DER signature plus the
+// hash type flag for each required signature, and a data push of the redeem
+// script with numKeys compressed public keys.  Scripts too long for a single
+// byte varint include the two extra bytes of their length.
+func multisigScriptEstimate(reqSigs, numKeys int) int {
+	redeemScriptSize := 1 + numKeys*(1+33) + 1 + 1
+	pushSize := 1
+	switch {
+	case redeemScriptSize > 0xff:
+		pushSize = 3
+	case redeemScriptSize > txscript.OP_DATA_75:
+		pushSize = 2
+	}
+	sz := 1 + reqSigs*(1+72+1) + pushSize + redeemScriptSize
+	if sz > 0xfc {
+		sz += 2
+	}
+	return sz
+}
+
+// estimateSignedSize returns the estimated size of msgtx once the signature
+// script of every input is complete, where sigScriptSize is the estimated size
+// of each complete signature script.
+func estimateSignedSize(msgtx *wire.MsgTx, sigScriptSize int) int {
+	sz := msgtx.SerializeSize()
+	for _, txIn := range msgtx.TxIn {
+		sz += sigScriptSize - len(txIn.SignatureScript)
+	}
+	return sz
+}
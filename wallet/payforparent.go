@@ -0,0 +1,285 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package wallet
+
+import (
+	"fmt"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+	"github.com/conseweb/stcwallet/wtxmgr"
+)
+
+// PayForParentError describes an unmined transaction which can not be
+// accelerated by PayForParent.
+type PayForParentError struct {
+	Hash   wire.ShaHash
+	Reason string
+}
+
+// Error satisifies the builtin error interface.
+func (e PayForParentError) Error() string {
+	return fmt.Sprintf("cannot pay for transaction %v: %s", e.Hash, e.Reason)
+}
+
+// childFee returns the fee a child transaction of childSize bytes must pay so
+// the child and its parent of parentSize bytes paying parentFee together pay
+// feeRate satoshis per byte.  The child always pays at least feeRate for its
+// own size.
+func childFee(feeRate coinutil.Amount, parentSize int, parentFee coinutil.Amount,
+	childSize int) coinutil.Amount {
+
+	fee := feeForSize(feeRate, parentSize+childSize) - parentFee
+	if minFee := feeForSize(feeRate, childSize); fee < minFee {
+		fee = minFee
+	}
+	return fee
+}
+
+// PayForParent accelerates the mining of an unmined transaction paying the
+// wallet, such as a payment sent with too low a fee, by creating and
+// publishing a child transaction spending its outputs (child-pays-for-parent).
+// Every unspent output of the parent paying a P2PKH address of the account
+// controlling its first such output is spent to a new internal address of the
+// account.  The child's fee is chosen so the parent and child together pay
+// feeRate satoshis per byte, or the fee rate of the wallet if feeRate is not
+// positive.  The inputs, which may be nil, are further outpoints spent by the
+// child.  Each must be an unspent and unlocked P2PKH output of the account, or
+// an OutpointError is returned.  If these outputs do not cover the fee,
+// outputs of the account with at least one confirmation are spent as well.
+// The hash of the child transaction is returned.
+//
+// The fee of the parent is only known when all of its previous outputs can be
+// looked up in the wallet or from the chain server.  Otherwise, the parent is
+// assumed to pay no fee.
+func (w *Wallet) PayForParent(parentHash *wire.ShaHash, feeRate coinutil.Amount,
+	inputs []wire.OutPoint) (*wire.ShaHash, error) {
+
+	req := createTxRequest{
+		feeRate:   feeRate,
+		parent:    parentHash,
+		outpoints: inputs,
+	}
+	createdTx, err := w.requestTx(req)
+	if err != nil {
+		return nil, err
+	}
+	return w.publishCreatedTx(createdTx, nil)
+}
+
+// txPayForParent creates a child transaction accelerating the unmined parent
+// transaction with hash parentHash, also spending the extra outpoints, as
+// described by PayForParent.
+func (w *Wallet) txPayForParent(parentHash *wire.ShaHash, feeRate coinutil.Amount,
+	extra []wire.OutPoint) (*CreatedTx, error) {
+	heldUnlock, err := w.HoldUnlock()
+	if err != nil {
+		return nil, err
+	}
+	defer heldUnlock.Release()
+
+	bs, err := w.chainSvr.BlockStamp()
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := w.TxStore.TxDetails(parentHash)
+	if err != nil {
+		return nil, err
+	}
+	if details == nil {
+		return nil, PayForParentError{*parentHash, "transaction is not " +
+			"recorded by the wallet"}
+	}
+	if details.Block.Height != -1 {
+		return nil, PayForParentError{*parentHash, "transaction is mined"}
+	}
+
+	// Spend the unmined credits of the parent controlled by the account
+	// of the first spendable credit.
+	unmined, err := w.TxStore.UnminedCredits(parentHash)
+	if err != nil {
+		return nil, err
+	}
+	var account uint32
+	var inputs []wtxmgr.Credit
+	for _, credit := range unmined {
+		if w.LockedOutpoint(credit.OutPoint) {
+			continue
+		}
+		class, addrs, _, err := txscript.ExtractPkScriptAddrs(
+			credit.PkScript, w.chainParams)
+		if err != nil || class != txscript.PubKeyHashTy {
+			continue
+		}
+		addrAcct, err := w.Manager.AddrAccount(addrs[0])
+		if err != nil {
+			return nil, err
+		}
+		if len(inputs) == 0 {
+			account = addrAcct
+		} else if addrAcct != account {
+			continue
+		}
+		inputs = append(inputs, credit)
+	}
+	if len(inputs) == 0 {
+		return nil, PayForParentError{*parentHash, "transaction has no " +
+			"unspent P2PKH outputs controlled by the wallet"}
+	}
+
+	// The extra inputs are spent after the outputs of the parent, and are
+	// never selected again from the eligible outputs.
+	spent := make(map[wire.OutPoint]struct{}, len(inputs)+len(extra))
+	for i := range inputs {
+		spent[inputs[i].OutPoint] = struct{}{}
+	}
+	extraCredits, err := w.outpointCredits(extra, account, bs)
+	if err != nil {
+		return nil, err
+	}
+	for _, credit := range extraCredits {
+		if _, ok := spent[credit.OutPoint]; ok {
+			return nil, OutpointError{credit.OutPoint, "outpoint is " +
+				"an output of the parent transaction"}
+		}
+		spent[credit.OutPoint] = struct{}{}
+		inputs = append(inputs, credit)
+	}
+
+	feeRate = w.txFeeRate(feeRate)
+	parentSize := details.MsgTx.SerializeSize()
+	parentFee := w.parentFee(details)
+	if parentFee >= feeForSize(feeRate, parentSize) {
+		return nil, PayForParentError{*parentHash, fmt.Sprintf("transaction "+
+			"already pays a fee rate of %v per byte", feeRate)}
+	}
+
+	eligible, err := w.findEligibleOutputs(account, 1, bs)
+	if err != nil {
+		return nil, err
+	}
+	unspent := eligible[:0]
+	for _, credit := range eligible {
+		if _, ok := spent[credit.OutPoint]; !ok {
+			unspent = append(unspent, credit)
+		}
+	}
+	eligible = w.CoinSelector.SelectCoins(unspent, 0, 1, feeRate)
+
+	var total coinutil.Amount
+	for i := range inputs {
+		total += inputs[i].Amount
+	}
+	fee := childFee(feeRate, parentSize, parentFee,
+		estimateTxSize(len(inputs), 1))
+
+	changeAddr, err := w.NewChangeAddress(account)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(changeAddr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create txout script: %s", err)
+	}
+
+	var msgtx *wire.MsgTx
+	for {
+		// Add further inputs until the output left after the fee is
+		// worth creating.
+		for total-fee < changeCost(feeRate) {
+			if len(eligible) == 0 {
+				return nil, InsufficientFundsError{total, 0, fee}
+			}
+			inputs = append(inputs, eligible[0])
+			total += eligible[0].Amount
+			eligible = eligible[1:]
+			fee = childFee(feeRate, parentSize, parentFee,
+				estimateTxSize(len(inputs), 1))
+		}
+
+		msgtx = wire.NewMsgTx()
+		for i := range inputs {
+			addInput(msgtx, &inputs[i].OutPoint, w.SignalReplaceable)
+		}
+		msgtx.AddTxOut(wire.NewTxOut(int64(total-fee), pkScript))
+		complete, err := signMsgTx(msgtx, inputs, w.Manager, w.Signer,
+			w.chainParams)
+		if err != nil {
+			return nil, err
+		}
+		if !complete {
+			return nil, ErrIncompleteTx
+		}
+
+		// Increase the fee if the signed transaction is larger than
+		// estimated, allowing an extra byte for each signature since
+		// signing again may produce a longer encoding.
+		sz := msgtx.SerializeSize()
+		if childFee(feeRate, parentSize, parentFee, sz) <= fee {
+			break
+		}
+		fee = childFee(feeRate, parentSize, parentFee, sz+len(inputs))
+	}
+	if err := validateMsgTx(msgtx, inputs); err != nil {
+		return nil, err
+	}
+
+	log.Infof("Paying %v for transaction %v and its child %v", fee,
+		parentHash, msgtx.TxSha())
+	info := &CreatedTx{
+		MsgTx:       msgtx,
+		ChangeAddr:  changeAddr,
+		ChangeIndex: 0,
+	}
+	return info, nil
+}
+
+// parentFee returns the fee paid by an unmined transaction, looking up the
+// previous outputs it spends in the wallet, or from the chain server when the
+// previous transaction is not recorded by the wallet.  Zero is returned if any
+// previous output can not be found.
+func (w *Wallet) parentFee(details *wtxmgr.TxDetails) coinutil.Amount {
+	var fee coinutil.Amount
+	for _, txIn := range details.MsgTx.TxIn {
+		prevOut := &txIn.PreviousOutPoint
+		var prevTx *wire.MsgTx
+		prev, err := w.TxStore.TxDetails(&prevOut.Hash)
+		if err == nil && prev != nil {
+			prevTx = &prev.MsgTx
+		} else {
+			tx, err := w.chainSvr.GetRawTransaction(&prevOut.Hash)
+			if err != nil {
+				log.Debugf("Cannot look up previous transaction "+
+					"%v (%v), assuming transaction %v pays no fee",
+					&prevOut.Hash, err, &details.Hash)
+				return 0
+			}
+			prevTx = tx.MsgTx()
+		}
+		if int(prevOut.Index) >= len(prevTx.TxOut) {
+			return 0
+		}
+		fee += coinutil.Amount(prevTx.TxOut[prevOut.Index].Value)
+	}
+	for _, txOut := range details.MsgTx.TxOut {
+		fee -= coinutil.Amount(txOut.Value)
+	}
+	if fee < 0 {
+		return 0
+	}
+	return fee
+}
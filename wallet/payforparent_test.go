@@ -0,0 +1,52 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/conseweb/coinutil"
+)
+
+func TestChildFee(t *testing.T) {
+	tests := []struct {
+		name       string
+		feeRate    coinutil.Amount
+		parentSize int
+		parentFee  coinutil.Amount
+		childSize  int
+		want       coinutil.Amount
+	}{
+		{
+			name:       "parent pays no fee",
+			feeRate:    10,
+			parentSize: 226,
+			parentFee:  0,
+			childSize:  191,
+			want:       4170,
+		},
+		{
+			name:       "parent pays part of the fee",
+			feeRate:    10,
+			parentSize: 226,
+			parentFee:  226,
+			childSize:  191,
+			want:       3944,
+		},
+		{
+			// The child still pays for its own size when the
+			// parent overpays.
+			name:       "parent overpays",
+			feeRate:    10,
+			parentSize: 226,
+			parentFee:  10000,
+			childSize:  191,
+			want:       1910,
+		},
+	}
+	for _, test := range tests {
+		got := childFee(test.feeRate, test.parentSize, test.parentFee,
+			test.childSize)
+		if got != test.want {
+			t.Errorf("%s: got fee %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package wallet
+
+import (
+	"bytes"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/btcec"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+	"github.com/conseweb/stcwallet/psbt"
+	"github.com/conseweb/stcwallet/signer"
+	"github.com/conseweb/stcwallet/waddrmgr"
+)
+
+// CreatePSBT creates a partially signed transaction spending unspent outputs
+// of any account with at least minconf confirmations to any number of
+// address/amount pairs.  Change and an appropiate transaction fee are
+// automatically included, if necessary.  The transaction is not signed, so the
+// wallet does not need to be unlocked, and the fee is based on the estimated
+// size of the signed transaction.  The packet carries the output spent by each
+// input and the redeem scripts and key paths of the wallet's addresses.  A
+// positive feeRate, in satoshis per byte, overrides the fee rate the wallet
+// would otherwise use.  Creation is serialized with all other transactions
+// created by CreateSimpleTx.
+func (w *Wallet) CreatePSBT(account uint32, pairs map[string]coinutil.Amount,
+	minconf int32, feeRate coinutil.Amount) (*psbt.Packet, error) {
+
+	req := createTxRequest{
+		account:  account,
+		pairs:    pairs,
+		minconf:  minconf,
+		selector: w.CoinSelector,
+		feeRate:  feeRate,
+		unsigned: true,
+	}
+	createdTx, err := w.requestTx(req)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := psbt.New(createdTx.MsgTx)
+	if err != nil {
+		return nil, err
+	}
+	for i, credit := range createdTx.PrevOutputs {
+		p.Inputs[i].PrevOut = wire.NewTxOut(int64(credit.Amount),
+			credit.PkScript)
+	}
+	if err := w.UpdatePSBT(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// psbtScriptInfo returns the redeem script and key path known by the wallet
+// for an output script paying the wallet.  The redeem script is nil for
+// outputs not paying a script hash and the key path is nil unless the address
+// was derived from an account key.  Both are nil for output scripts not paying
+// the wallet.
+func (w *Wallet) psbtScriptInfo(pkScript []byte) ([]byte, *psbt.KeyPath, error) {
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, w.chainParams)
+	if err != nil || len(addrs) != 1 {
+		return nil, nil, nil
+	}
+	ma, err := w.Manager.Address(addrs[0])
+	if err != nil {
+		if waddrmgr.IsError(err, waddrmgr.ErrAddressNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	var redeemScript []byte
+	var pubKey *btcec.PublicKey
+	switch a := ma.(type) {
+	case waddrmgr.ManagedMultisigAddress:
+		redeemScript, err = a.Script()
+		if err != nil {
+			return nil, nil, err
+		}
+		pubKey = a.OwnPubKey()
+	case waddrmgr.ManagedScriptAddress:
+		// Scripts of imported P2SH addresses are encrypted and only
+		// available when the wallet is unlocked.
+		redeemScript, err = a.Script()
+		if waddrmgr.IsError(err, waddrmgr.ErrLocked) {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	case waddrmgr.ManagedPubKeyAddress:
+		pubKey = a.PubKey()
+	}
+	if pubKey == nil || ma.Imported() {
+		return redeemScript, nil, nil
+	}
+
+	details, err := w.Manager.AddrDetails(ma.Address())
+	if err != nil {
+		return nil, nil, err
+	}
+	fingerprint, err := accountFingerprint(w.Manager, ma.Account())
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPath := &psbt.KeyPath{
+		PubKey:      pubKey.SerializeCompressed(),
+		Fingerprint: fingerprint,
+		Path:        []uint32{details.Branch, details.Index},
+	}
+	return redeemScript, keyPath, nil
+}
+
+// UpdatePSBT adds what the wallet knows about the inputs and outputs of a
+// partially signed transaction.  The output spent by each input is added if it
+// is recorded by the wallet, as are the redeem scripts and key paths of every
+// address of the wallet paid by a spent output or an output of the
+// transaction.  Finalized inputs are left as is.
+func (w *Wallet) UpdatePSBT(p *psbt.Packet) error {
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		if in.FinalScriptSig != nil {
+			continue
+		}
+		if in.PrevOut == nil {
+			op := &p.UnsignedTx.TxIn[i].PreviousOutPoint
+			details, err := w.TxStore.TxDetails(&op.Hash)
+			if err != nil {
+				return err
+			}
+			if details == nil || op.Index >= uint32(len(details.MsgTx.TxOut)) {
+				continue
+			}
+			prevOut := details.MsgTx.TxOut[op.Index]
+			in.PrevOut = wire.NewTxOut(prevOut.Value, prevOut.PkScript)
+		}
+
+		redeemScript, keyPath, err := w.psbtScriptInfo(in.PrevOut.PkScript)
+		if err != nil {
+			return err
+		}
+		if in.RedeemScript == nil {
+			in.RedeemScript = redeemScript
+		}
+		if keyPath != nil {
+			in.AddKeyPath(*keyPath)
+		}
+	}
+
+	for i, txOut := range p.UnsignedTx.TxOut {
+		out := &p.Outputs[i]
+		redeemScript, keyPath, err := w.psbtScriptInfo(txOut.PkScript)
+		if err != nil {
+			return err
+		}
+		if out.RedeemScript == nil {
+			out.RedeemScript = redeemScript
+		}
+		if keyPath != nil {
+			out.AddKeyPath(*keyPath)
+		}
+	}
+	return nil
+}
+
+// psbtKey returns the signer key of the public key or pubkey hash address
+// addr for an input of a partially signed transaction, or nil if the key is
+// not known.  A key path of the input matching addr is preferred, so keys of
+// addresses the wallet has not derived itself, such as those handed out by a
+// watching-only copy of the wallet, are signed for too.  Otherwise, the key is
+// that of the wallet's own address, or of the HD multisig address ma if not
+// nil.
+func (w *Wallet) psbtKey(in *psbt.Input, addr coinutil.Address,
+	ma waddrmgr.ManagedMultisigAddress) (*signer.Key, error) {
+
+	for i := range in.KeyPaths {
+		kp := &in.KeyPaths[i]
+		var match bool
+		switch addr.(type) {
+		case *coinutil.AddressPubKeyHash:
+			match = bytes.Equal(coinutil.Hash160(kp.PubKey),
+				addr.ScriptAddress())
+		case *coinutil.AddressPubKey:
+			match = bytes.Equal(kp.PubKey, addr.ScriptAddress())
+		}
+		if match {
+			return &signer.Key{
+				PubKey:      kp.PubKey,
+				Fingerprint: kp.Fingerprint,
+				Path:        kp.Path,
+			}, nil
+		}
+	}
+	return addressKey(w.Manager, addr, ma)
+}
+
+// SignPSBT updates a partially signed transaction with UpdatePSBT and adds a
+// signature (SIGHASH_ALL) made by the wallet's Signer for every key which can
+// redeem an output spent by the transaction.  P2PKH, P2PK and P2SH multisig
+// outputs, including those of HD multisig accounts, are signed.  Keys of
+// addresses the wallet has not derived are found by the key paths of the
+// input, so an offline wallet signs for the addresses of its watching-only
+// copy.  Inputs missing the spent output, or the redeem script of a P2SH
+// output, are not signed.  The wallet must be unlocked unless every key is
+// held by an external signer.
+func (w *Wallet) SignPSBT(p *psbt.Packet) error {
+	heldUnlock, err := w.HoldUnlock()
+	if err == nil {
+		defer heldUnlock.Release()
+	}
+
+	if err := w.UpdatePSBT(p); err != nil {
+		return err
+	}
+
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		if in.FinalScriptSig != nil || in.PrevOut == nil {
+			continue
+		}
+
+		// Outputs paying a script hash are signed for the redeem
+		// script rather than the output script.
+		script := in.PrevOut.PkScript
+		var ma waddrmgr.ManagedMultisigAddress
+		class, addrs, _, err := txscript.ExtractPkScriptAddrs(script,
+			w.chainParams)
+		if err != nil {
+			continue
+		}
+		if class == txscript.ScriptHashTy {
+			if in.RedeemScript == nil {
+				continue
+			}
+			if addr, err := w.Manager.Address(addrs[0]); err == nil {
+				ma, _ = addr.(waddrmgr.ManagedMultisigAddress)
+			}
+			script = in.RedeemScript
+			_, addrs, _, err = txscript.ExtractPkScriptAddrs(script,
+				w.chainParams)
+			if err != nil {
+				continue
+			}
+		}
+
+		for _, addr := range addrs {
+			key, err := w.psbtKey(in, addr, ma)
+			if err != nil {
+				return err
+			}
+			if key == nil {
+				continue
+			}
+			sig, err := w.Signer.SignTxInput(p.UnsignedTx, i, script,
+				txscript.SigHashAll, key)
+			if err == signer.ErrUnknownKey {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			in.AddPartialSig(key.PubKey, sig)
+		}
+	}
+	return nil
+}
@@ -49,6 +49,11 @@ type RescanJob struct {
 	OutPoints   []*wire.OutPoint
 	BlockStamp  waddrmgr.BlockStamp
 	err         chan error
+
+	// finished, if non-nil, is closed after the rescanfinished
+	// notification of the job is handled, at which point every
+	// transaction notified by the rescan has been handled as well.
+	finished chan struct{}
 }
 
 // rescanBatch is a collection of one or more RescanJobs that were merged
@@ -59,6 +64,7 @@ type rescanBatch struct {
 	outpoints   []*wire.OutPoint
 	bs          waddrmgr.BlockStamp
 	errChans    []chan error
+	finished    []chan struct{}
 }
 
 // SubmitRescan submits a RescanJob to the RescanManager.  A channel is
@@ -73,13 +79,17 @@ func (w *Wallet) SubmitRescan(job *RescanJob) <-chan error {
 
 // batch creates the rescanBatch for a single rescan job.
 func (job *RescanJob) batch() *rescanBatch {
-	return &rescanBatch{
+	b := &rescanBatch{
 		initialSync: job.InitialSync,
 		addrs:       job.Addrs,
 		outpoints:   job.OutPoints,
 		bs:          job.BlockStamp,
 		errChans:    []chan error{job.err},
 	}
+	if job.finished != nil {
+		b.finished = []chan struct{}{job.finished}
+	}
+	return b
 }
 
 // merge merges the work from k into j, setting the starting height to
@@ -95,6 +105,9 @@ func (b *rescanBatch) merge(job *RescanJob) {
 		b.bs = job.BlockStamp
 	}
 	b.errChans = append(b.errChans, job.err)
+	if job.finished != nil {
+		b.finished = append(b.finished, job.finished)
+	}
 }
 
 // done iterates through all error channels, duplicating sending the error
@@ -106,6 +119,14 @@ func (b *rescanBatch) done(err error) {
 	}
 }
 
+// finish closes the finished channel of every job of the batch after the
+// rescanfinished notification for the batch has been handled.
+func (b *rescanBatch) finish() {
+	for _, c := range b.finished {
+		close(c)
+	}
+}
+
 // rescanBatchHandler handles incoming rescan request, serializing rescan
 // submissions, and possibly batching many waiting requests together so they
 // can be handled by a single rescan after the current one completes.
@@ -121,6 +142,7 @@ out:
 				// Set current batch as this job and send
 				// request.
 				curBatch = job.batch()
+				w.setRescanning(true)
 				w.rescanBatch <- curBatch
 			} else {
 				// Create next batch if it doesn't exist, or
@@ -151,8 +173,10 @@ out:
 					Addresses:    curBatch.addrs,
 					Notification: n,
 				}
+				curBatch.finish()
 
 				curBatch, nextBatch = nextBatch, nil
+				w.setRescanning(curBatch != nil)
 
 				if curBatch != nil {
 					w.rescanBatch <- curBatch
@@ -0,0 +1,415 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package wallet
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/btcec"
+	"github.com/conseweb/stcd/chaincfg"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+	"github.com/conseweb/stcwallet/internal/zero"
+	"github.com/conseweb/stcwallet/signer"
+	"github.com/conseweb/stcwallet/waddrmgr"
+)
+
+// managerSigner is the Signer of the private keys held by an address manager.
+// Keys of addresses recorded by the manager are found by their public key.
+// Keys of the manager's accounts which were never recorded as addresses, such
+// as the own keys of HD multisig addresses and the addresses handed out by a
+// watching-only copy of the wallet, are derived for their key path.
+type managerSigner struct {
+	mgr *waddrmgr.Manager
+}
+
+// newManagerSigner returns the Signer of the private keys held by mgr.
+func newManagerSigner(mgr *waddrmgr.Manager) *managerSigner {
+	return &managerSigner{mgr: mgr}
+}
+
+// accountFingerprint returns the fingerprint of the account extended public
+// key of an account, which identifies the key the key paths of the account's
+// addresses are relative to.
+func accountFingerprint(mgr *waddrmgr.Manager, account uint32) (uint32, error) {
+	acctKey, err := mgr.AccountPubKey(account)
+	if err != nil {
+		return 0, err
+	}
+	return signer.Fingerprint(acctKey)
+}
+
+// fingerprintAccount returns the account of mgr whose account extended key
+// has the passed fingerprint.  ok is false if there is no such account.
+func fingerprintAccount(mgr *waddrmgr.Manager, fingerprint uint32) (account uint32, ok bool, err error) {
+	var accounts []uint32
+	err = mgr.ForEachAccount(func(account uint32) error {
+		if account != waddrmgr.ImportedAddrAccount {
+			accounts = append(accounts, account)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	for _, account := range accounts {
+		fp, err := accountFingerprint(mgr, account)
+		if err != nil {
+			return 0, false, err
+		}
+		if fp == fingerprint {
+			return account, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// privKey returns the private key of key, which must be cleared with
+// zero.BigInt once used.  signer.ErrUnknownKey is returned if the key is not
+// held by the manager, including the keys of watching-only accounts.
+func (s *managerSigner) privKey(key *signer.Key) (*btcec.PrivateKey, error) {
+	addr, err := coinutil.NewAddressPubKeyHash(coinutil.Hash160(key.PubKey),
+		s.mgr.ChainParams())
+	if err != nil {
+		return nil, err
+	}
+	ma, err := s.mgr.Address(addr)
+	switch {
+	case err == nil:
+		pka, ok := ma.(waddrmgr.ManagedPubKeyAddress)
+		if !ok {
+			return nil, signer.ErrUnknownKey
+		}
+		watchingOnly, err := s.mgr.IsWatchingOnlyAccount(ma.Account())
+		if err != nil {
+			return nil, err
+		}
+		if watchingOnly {
+			return nil, signer.ErrUnknownKey
+		}
+		privKey, err := pka.PrivKey()
+		if waddrmgr.IsError(err, waddrmgr.ErrWatchingOnly) {
+			return nil, signer.ErrUnknownKey
+		}
+		return privKey, err
+
+	case !waddrmgr.IsError(err, waddrmgr.ErrAddressNotFound):
+		return nil, err
+	}
+
+	// Keys which were never recorded as addresses are derived from the
+	// account extended key along the branch and index of the key path.
+	if len(key.Path) != 2 {
+		return nil, signer.ErrUnknownKey
+	}
+	account, ok, err := fingerprintAccount(s.mgr, key.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, signer.ErrUnknownKey
+	}
+	watchingOnly, err := s.mgr.IsWatchingOnlyAccount(account)
+	if err != nil {
+		return nil, err
+	}
+	if watchingOnly {
+		return nil, signer.ErrUnknownKey
+	}
+	acctKey, err := s.mgr.AccountPrivKey(account)
+	if waddrmgr.IsError(err, waddrmgr.ErrWatchingOnly) {
+		return nil, signer.ErrUnknownKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer acctKey.Zero()
+	branchKey, err := acctKey.Child(key.Path[0])
+	if err != nil {
+		return nil, err
+	}
+	defer branchKey.Zero()
+	addrKey, err := branchKey.Child(key.Path[1])
+	if err != nil {
+		return nil, err
+	}
+	defer addrKey.Zero()
+	privKey, err := addrKey.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+
+	// Never trust the key path alone, since signing with a key other than
+	// the requested one would only produce invalid signatures.
+	if !bytes.Equal(privKey.PubKey().SerializeCompressed(), key.PubKey) {
+		zero.BigInt(privKey.D)
+		return nil, signer.ErrUnknownKey
+	}
+	return privKey, nil
+}
+
+// SignTxInput signs a transaction input with a private key of the manager.
+func (s *managerSigner) SignTxInput(tx *wire.MsgTx, idx int, subScript []byte,
+	hashType txscript.SigHashType, key *signer.Key) ([]byte, error) {
+
+	privKey, err := s.privKey(key)
+	if err != nil {
+		return nil, err
+	}
+	defer zero.BigInt(privKey.D)
+	return txscript.RawTxInSignature(tx, idx, subScript, hashType, privKey)
+}
+
+// SignMessage signs a message with a private key of the manager.
+func (s *managerSigner) SignMessage(message string, key *signer.Key) ([]byte, error) {
+	privKey, err := s.privKey(key)
+	if err != nil {
+		return nil, err
+	}
+	defer zero.BigInt(privKey.D)
+	compressed := len(key.PubKey) == btcec.PubKeyBytesLenCompressed
+	return btcec.SignCompact(btcec.S256(), privKey,
+		signer.MessageHash(message), compressed)
+}
+
+// derivedKey returns the signer key of the serialized public key pubKey,
+// derived for the chained address ma.
+func derivedKey(mgr *waddrmgr.Manager, ma waddrmgr.ManagedAddress,
+	pubKey []byte) (*signer.Key, error) {
+
+	details, err := mgr.AddrDetails(ma.Address())
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := accountFingerprint(mgr, ma.Account())
+	if err != nil {
+		return nil, err
+	}
+	return &signer.Key{
+		PubKey:      pubKey,
+		Fingerprint: fingerprint,
+		Path:        []uint32{details.Branch, details.Index},
+	}, nil
+}
+
+// addressKey returns the signer key of the public key or pubkey hash address
+// addr, or nil if the address is not of the wallet.  The own key of the HD
+// multisig address ma, if not nil, is derived for the multisig address rather
+// than recorded with an address of its own.
+func addressKey(mgr *waddrmgr.Manager, addr coinutil.Address,
+	ma waddrmgr.ManagedMultisigAddress) (*signer.Key, error) {
+
+	if ma != nil {
+		ownPubKey := ma.OwnPubKey().SerializeCompressed()
+		if bytes.Equal(addr.ScriptAddress(), ownPubKey) {
+			return derivedKey(mgr, ma, ownPubKey)
+		}
+	}
+
+	if pk, ok := addr.(*coinutil.AddressPubKey); ok {
+		addr = pk.AddressPubKeyHash()
+	}
+	address, err := mgr.Address(addr)
+	if waddrmgr.IsError(err, waddrmgr.ErrAddressNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	pka, ok := address.(waddrmgr.ManagedPubKeyAddress)
+	if !ok {
+		return nil, nil
+	}
+
+	var pubKey []byte
+	if pka.Compressed() {
+		pubKey = pka.PubKey().SerializeCompressed()
+	} else {
+		pubKey = pka.PubKey().SerializeUncompressed()
+	}
+	if pka.Imported() {
+		return &signer.Key{PubKey: pubKey}, nil
+	}
+	return derivedKey(mgr, pka, pubKey)
+}
+
+// noKeys is a txscript.KeyDB holding no keys, used to merge signature scripts
+// without signing.
+var noKeys = txscript.KeyClosure(func(coinutil.Address) (*btcec.PrivateKey,
+	bool, error) {
+	return nil, false, errors.New("no key for address")
+})
+
+// mergeMultisig returns the signature script of input idx of tx spending the
+// P2SH output pkScript with the multisig redeemScript, holding both the
+// signatures of the script prevScript and sigs.  The signatures are matched to
+// the public keys of the redeem script and ordered as OP_CHECKMULTISIG
+// expects, and signatures matching none of the keys are dropped.
+func mergeMultisig(chainParams *chaincfg.Params, tx *wire.MsgTx, idx int,
+	pkScript, redeemScript, prevScript []byte, sigs [][]byte) ([]byte, error) {
+
+	b := txscript.NewScriptBuilder().AddOp(txscript.OP_FALSE)
+	if len(prevScript) != 0 {
+		pushes, err := txscript.PushedData(prevScript)
+		if err != nil {
+			return nil, err
+		}
+		for _, data := range pushes {
+			if len(data) != 0 && !bytes.Equal(data, redeemScript) {
+				b.AddData(data)
+			}
+		}
+	}
+	for _, sig := range sigs {
+		b.AddData(sig)
+	}
+	unordered, err := b.AddData(redeemScript).Script()
+	if err != nil {
+		return nil, err
+	}
+
+	// Signing without any keys merges the previous script into an empty
+	// one, which orders the signatures by verifying each of them.
+	getScript := txscript.ScriptClosure(func(coinutil.Address) ([]byte, error) {
+		return redeemScript, nil
+	})
+	return txscript.SignTxOutput(chainParams, tx, idx, pkScript,
+		txscript.SigHashAll, noKeys, getScript, unordered)
+}
+
+// signTxInput returns the signature script of input idx of tx spending an
+// output with the passed pkScript, holding the signatures s makes with the
+// keys of mgr's addresses.  P2PKH, P2PK and P2SH multisig outputs are signed,
+// and the signatures already in the script of a multisig input are kept.  The
+// current script of the input is returned unchanged if s holds none of the
+// keys.
+func signTxInput(s signer.Signer, mgr *waddrmgr.Manager,
+	chainParams *chaincfg.Params, tx *wire.MsgTx, idx int, pkScript []byte,
+	hashType txscript.SigHashType) ([]byte, error) {
+
+	prevScript := tx.TxIn[idx].SignatureScript
+	class, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript,
+		chainParams)
+	if err != nil {
+		return nil, err
+	}
+
+	switch class {
+	case txscript.PubKeyHashTy, txscript.PubKeyTy:
+		key, err := addressKey(mgr, addrs[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			return prevScript, nil
+		}
+		sig, err := s.SignTxInput(tx, idx, pkScript, hashType, key)
+		if err == signer.ErrUnknownKey {
+			return prevScript, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		b := txscript.NewScriptBuilder().AddData(sig)
+		if class == txscript.PubKeyHashTy {
+			b.AddData(key.PubKey)
+		}
+		return b.Script()
+
+	case txscript.ScriptHashTy:
+		address, err := mgr.Address(addrs[0])
+		if waddrmgr.IsError(err, waddrmgr.ErrAddressNotFound) {
+			return prevScript, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		sa, ok := address.(waddrmgr.ManagedScriptAddress)
+		if !ok {
+			return prevScript, nil
+		}
+		redeemScript, err := sa.Script()
+		if err != nil {
+			return nil, err
+		}
+		class, addrs, _, err = txscript.ExtractPkScriptAddrs(redeemScript,
+			chainParams)
+		if err != nil {
+			return nil, err
+		}
+		if class != txscript.MultiSigTy {
+			return nil, ErrUnsupportedTransactionType
+		}
+
+		ma, _ := address.(waddrmgr.ManagedMultisigAddress)
+		var sigs [][]byte
+		for _, addr := range addrs {
+			key, err := addressKey(mgr, addr, ma)
+			if err != nil {
+				return nil, err
+			}
+			if key == nil {
+				continue
+			}
+			sig, err := s.SignTxInput(tx, idx, redeemScript, hashType,
+				key)
+			if err == signer.ErrUnknownKey {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			sigs = append(sigs, sig)
+		}
+		if len(sigs) == 0 {
+			return prevScript, nil
+		}
+		return mergeMultisig(chainParams, tx, idx, pkScript, redeemScript,
+			prevScript, sigs)
+	}
+
+	return nil, ErrUnsupportedTransactionType
+}
+
+// SignTxInput returns the signature script of input idx of tx spending an
+// output with the passed pkScript, signed (with hashType) by the wallet's
+// Signer.  P2PKH, P2PK and P2SH multisig outputs paying the wallet are signed,
+// and the signatures already in the script of a multisig input are kept.  The
+// current script of the input is returned unchanged if the Signer holds none
+// of the keys.
+func (w *Wallet) SignTxInput(tx *wire.MsgTx, idx int, pkScript []byte,
+	hashType txscript.SigHashType) ([]byte, error) {
+
+	return signTxInput(w.Signer, w.Manager, w.chainParams, tx, idx,
+		pkScript, hashType)
+}
+
+// SignMessage returns the compact signature of message made by the wallet's
+// Signer with the key of the public key or pubkey hash address addr.
+// signer.ErrUnknownKey is returned if the address is not of the wallet or its
+// key is not held by the Signer.
+func (w *Wallet) SignMessage(addr coinutil.Address, message string) ([]byte, error) {
+	key, err := addressKey(w.Manager, addr, nil)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, signer.ErrUnknownKey
+	}
+	return w.Signer.SignMessage(message, key)
+}
@@ -0,0 +1,304 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/blockchain"
+	"github.com/conseweb/stcd/txscript"
+	"github.com/conseweb/stcd/wire"
+	"github.com/conseweb/stcwallet/waddrmgr"
+	"github.com/conseweb/stcwallet/wtxmgr"
+)
+
+// ErrNothingToSweep describes an error where a swept key does not control any
+// spendable outputs.
+var ErrNothingToSweep = errors.New("no spendable outputs to sweep")
+
+// sweeper collects the unspent outputs paying to the address of a swept key
+// from the transactions notified by the chain server.
+type sweeper struct {
+	pkScript []byte
+
+	mu      sync.Mutex
+	credits map[wire.OutPoint]wtxmgr.Credit
+}
+
+// addTx records the outputs of a notified transaction paying to the swept
+// address and removes the outputs spent by it.  It returns whether the
+// transaction was relevant to the sweep.
+func (s *sweeper) addTx(rec *wtxmgr.TxRecord, block *wtxmgr.BlockMeta) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	relevant := false
+	for _, input := range rec.MsgTx.TxIn {
+		if _, ok := s.credits[input.PreviousOutPoint]; ok {
+			delete(s.credits, input.PreviousOutPoint)
+			relevant = true
+		}
+	}
+	for i, output := range rec.MsgTx.TxOut {
+		if !bytes.Equal(output.PkScript, s.pkScript) {
+			continue
+		}
+		credit := wtxmgr.Credit{
+			OutPoint: wire.OutPoint{
+				Hash:  rec.Hash,
+				Index: uint32(i),
+			},
+			BlockMeta: wtxmgr.BlockMeta{
+				Block: wtxmgr.Block{Height: -1},
+			},
+			Amount:       coinutil.Amount(output.Value),
+			PkScript:     output.PkScript,
+			Received:     rec.Received,
+			FromCoinBase: blockchain.IsCoinBaseTx(&rec.MsgTx),
+		}
+		if block != nil {
+			credit.BlockMeta = *block
+		}
+		s.credits[credit.OutPoint] = credit
+		relevant = true
+	}
+	return relevant
+}
+
+// sweepRelevantTx passes a transaction notified by the chain server to the
+// sweeper of every key swept so far.  It returns true when the transaction
+// only concerns swept keys and must not be recorded by the wallet.
+func (w *Wallet) sweepRelevantTx(rec *wtxmgr.TxRecord, block *wtxmgr.BlockMeta) (bool, error) {
+	w.sweeperMtx.Lock()
+	sweepers := w.sweepers
+	w.sweeperMtx.Unlock()
+
+	swept := false
+	for _, s := range sweepers {
+		if s.addTx(rec, block) {
+			swept = true
+		}
+	}
+	if !swept {
+		return false, nil
+	}
+	relevant, err := w.walletRelevantTx(rec)
+	return !relevant, err
+}
+
+// keySweeper returns the sweeper of the key with the passed pay-to-pubkey-hash
+// script, adding a sweeper if the key was never swept before.  Sweepers are
+// never removed since the chain server keeps notifying the transactions of
+// the swept address.
+func (w *Wallet) keySweeper(pkScript []byte) *sweeper {
+	w.sweeperMtx.Lock()
+	defer w.sweeperMtx.Unlock()
+
+	for _, s := range w.sweepers {
+		if bytes.Equal(s.pkScript, pkScript) {
+			return s
+		}
+	}
+	s := &sweeper{
+		pkScript: pkScript,
+		credits:  make(map[wire.OutPoint]wtxmgr.Credit),
+	}
+	// A new slice is created so the handler can range over the previous
+	// one without holding the mutex.
+	sweepers := make([]*sweeper, len(w.sweepers), len(w.sweepers)+1)
+	copy(sweepers, w.sweepers)
+	w.sweepers = append(sweepers, s)
+	return s
+}
+
+// walletRelevantTx returns whether a transaction pays to an address of the
+// wallet or spends an output of a wallet transaction.
+func (w *Wallet) walletRelevantTx(rec *wtxmgr.TxRecord) (bool, error) {
+	for _, output := range rec.MsgTx.TxOut {
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(output.PkScript,
+			w.chainParams)
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			_, err := w.Manager.Address(addr)
+			if err == nil {
+				return true, nil
+			}
+			if !waddrmgr.IsError(err, waddrmgr.ErrAddressNotFound) {
+				return false, err
+			}
+		}
+	}
+	for _, input := range rec.MsgTx.TxIn {
+		details, err := w.TxStore.TxDetails(&input.PreviousOutPoint.Hash)
+		if err != nil {
+			return false, err
+		}
+		if details != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SweepPrivKey moves every spendable output controlled by a private key into
+// the wallet without importing the key.  The chain is rescanned from the
+// genesis block for outputs paying to the key's pay-to-pubkey-hash address,
+// and a transaction spending all unspent outputs found to a new address of
+// account, less the fee, is signed with the key and published.  The
+// transaction signals replaceability when SignalReplaceable is set.  A positive
+// feeRate, in satoshis per byte, overrides the fee rate of the wallet.  The
+// key is never written to the address manager.  ErrNothingToSweep is returned
+// if no spendable outputs are found.
+func (w *Wallet) SweepPrivKey(wif *coinutil.WIF, account uint32,
+	feeRate coinutil.Amount) (*wire.ShaHash, error) {
+
+	// Only a single key is swept at a time, so the credits of a sweeper
+	// are collected for one sweep only.
+	w.sweepMtx.Lock()
+	defer w.sweepMtx.Unlock()
+
+	pubKey, err := coinutil.NewAddressPubKey(wif.SerializePubKey(),
+		w.chainParams)
+	if err != nil {
+		return nil, err
+	}
+	addr := pubKey.AddressPubKeyHash()
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := w.keySweeper(pkScript)
+
+	// Rescan for the address and wait until every notification of the
+	// rescan has been handled, not just for the rescan RPC to return.
+	log.Infof("Sweeping outputs of address %v", addr)
+	job := &RescanJob{
+		Addrs:      []coinutil.Address{addr},
+		BlockStamp: waddrmgr.BlockStamp{Hash: *w.chainParams.GenesisHash},
+		finished:   make(chan struct{}),
+	}
+	if err := <-w.SubmitRescan(job); err != nil {
+		return nil, err
+	}
+	select {
+	case <-job.finished:
+	case <-w.quitChan():
+		return nil, ErrShuttingDown
+	}
+
+	bs, err := w.chainSvr.BlockStamp()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	var inputs []wtxmgr.Credit
+	var total coinutil.Amount
+	for _, credit := range s.credits {
+		if credit.FromCoinBase {
+			const target = blockchain.CoinbaseMaturity
+			if !confirmed(target, credit.Height, bs.Height) {
+				continue
+			}
+		}
+		inputs = append(inputs, credit)
+		total += credit.Amount
+	}
+	s.mu.Unlock()
+	if len(inputs) == 0 {
+		return nil, ErrNothingToSweep
+	}
+
+	feeRate = w.txFeeRate(feeRate)
+	fee := feeForSize(feeRate, estimateTxSize(len(inputs), 1))
+	if total <= fee {
+		return nil, InsufficientFundsError{total, 0, fee}
+	}
+
+	toAddr, err := w.NewAddress(account)
+	if err != nil {
+		return nil, err
+	}
+	toPkScript, err := txscript.PayToAddrScript(toAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	msgtx := wire.NewMsgTx()
+	for i := range inputs {
+		addInput(msgtx, &inputs[i].OutPoint, w.SignalReplaceable)
+	}
+	msgtx.AddTxOut(wire.NewTxOut(int64(total-fee), toPkScript))
+	for {
+		for i := range inputs {
+			sigScript, err := txscript.SignatureScript(msgtx, i,
+				inputs[i].PkScript, txscript.SigHashAll,
+				wif.PrivKey, wif.CompressPubKey)
+			if err != nil {
+				return nil, err
+			}
+			msgtx.TxIn[i].SignatureScript = sigScript
+		}
+
+		// Increase the fee if the signed transaction is larger than
+		// estimated, allowing an extra byte for each signature since
+		// signing again may produce a longer encoding.
+		sz := msgtx.SerializeSize()
+		if feeForSize(feeRate, sz) <= fee {
+			break
+		}
+		fee = feeForSize(feeRate, sz+len(inputs))
+		if total <= fee {
+			return nil, InsufficientFundsError{total, 0, fee}
+		}
+		msgtx.TxOut[0].Value = int64(total - fee)
+	}
+	if err := validateMsgTx(msgtx, inputs); err != nil {
+		return nil, err
+	}
+
+	// Publish the transaction before recording it and its output to the
+	// wallet, so nothing is recorded if the chain server rejects it.
+	log.Infof("Sweeping %v from %d %s of address %v to %v", total-fee,
+		len(inputs), pickNoun(len(inputs), "output", "outputs"), addr,
+		toAddr)
+	hash, err := w.chainSvr.SendRawTransaction(msgtx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transaction was published, so failing to record it is only
+	// logged.  It is recorded again when the chain server notifies it.
+	rec, err := wtxmgr.NewTxRecordFromMsgTx(msgtx, time.Now())
+	if err != nil {
+		log.Errorf("Cannot create record for sweep transaction: %v", err)
+		return hash, nil
+	}
+	if err := w.TxStore.InsertTx(rec, nil); err != nil {
+		log.Errorf("Error adding sweep tx history: %v", err)
+		return hash, nil
+	}
+	if err := w.TxStore.AddCredit(rec, nil, 0, false); err != nil {
+		log.Errorf("Error adding sweep tx output: %v", err)
+	}
+	return hash, nil
+}
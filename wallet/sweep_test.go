@@ -0,0 +1,109 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/wire"
+	"github.com/conseweb/stcwallet/wtxmgr"
+)
+
+func TestSweeperAddTx(t *testing.T) {
+	serialized, err := hex.DecodeString(txInfo.hex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fund, err := wtxmgr.NewTxRecord(serialized, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sweep the key controlling output 2 (9e6 satoshis).
+	s := &sweeper{
+		pkScript: fund.MsgTx.TxOut[2].PkScript,
+		credits:  make(map[wire.OutPoint]wtxmgr.Credit),
+	}
+	block := &wtxmgr.BlockMeta{Block: wtxmgr.Block{Height: 100}}
+	if !s.addTx(fund, block) {
+		t.Fatal("Funding transaction not relevant to sweep")
+	}
+	op := wire.OutPoint{Hash: fund.Hash, Index: 2}
+	credit, ok := s.credits[op]
+	if !ok || len(s.credits) != 1 {
+		t.Fatalf("Unexpected swept credits %v", s.credits)
+	}
+	if credit.Amount != coinutil.Amount(9e6) || credit.Height != 100 {
+		t.Errorf("Unexpected credit amount %v at height %d", credit.Amount,
+			credit.Height)
+	}
+
+	// Transactions not paying or spending the swept outputs are ignored.
+	unrelated := wire.NewMsgTx()
+	unrelated.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: fund.Hash, Index: 1}, nil))
+	unrelated.AddTxOut(wire.NewTxOut(1e6, fund.MsgTx.TxOut[1].PkScript))
+	rec, err := wtxmgr.NewTxRecordFromMsgTx(unrelated, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.addTx(rec, nil) {
+		t.Error("Unrelated transaction relevant to sweep")
+	}
+
+	// Spending the output removes it from the sweep.
+	spend := wire.NewMsgTx()
+	spend.AddTxIn(wire.NewTxIn(&op, nil))
+	spend.AddTxOut(wire.NewTxOut(8e6, fund.MsgTx.TxOut[1].PkScript))
+	rec, err = wtxmgr.NewTxRecordFromMsgTx(spend, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.addTx(rec, nil) {
+		t.Fatal("Spending transaction not relevant to sweep")
+	}
+	if len(s.credits) != 0 {
+		t.Errorf("Spent output was not removed from sweep: %v", s.credits)
+	}
+}
+
+func TestKeySweeper(t *testing.T) {
+	serialized, err := hex.DecodeString(txInfo.hex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fund, err := wtxmgr.NewTxRecord(serialized, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sweeping a key again reuses its sweeper, which is kept after the
+	// sweep.
+	w := &Wallet{}
+	s := w.keySweeper(fund.MsgTx.TxOut[2].PkScript)
+	if again := w.keySweeper(fund.MsgTx.TxOut[2].PkScript); again != s {
+		t.Error("Sweeping a key again created another sweeper")
+	}
+	if other := w.keySweeper(fund.MsgTx.TxOut[1].PkScript); other == s {
+		t.Error("Sweeping another key reused the sweeper")
+	}
+	if len(w.sweepers) != 2 {
+		t.Errorf("Got %d sweepers, want 2", len(w.sweepers))
+	}
+
+	// Transactions of keys never swept are left to the wallet.
+	unrelated := wire.NewMsgTx()
+	unrelated.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: fund.Hash}, nil))
+	unrelated.AddTxOut(wire.NewTxOut(1e6, fund.MsgTx.TxOut[0].PkScript))
+	rec, err := wtxmgr.NewTxRecordFromMsgTx(unrelated, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	swept, err := w.sweepRelevantTx(rec, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if swept {
+		t.Error("Transaction of a key never swept was filtered")
+	}
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/conseweb/stcd/txscript"
 	"github.com/conseweb/stcd/wire"
 	"github.com/conseweb/stcwallet/chain"
+	"github.com/conseweb/stcwallet/signer"
 	"github.com/conseweb/stcwallet/waddrmgr"
 	"github.com/conseweb/stcwallet/walletdb"
 	"github.com/conseweb/stcwallet/wtxmgr"
@@ -65,15 +66,46 @@ type Wallet struct {
 	Manager *waddrmgr.Manager
 	TxStore *wtxmgr.Store
 
+	// pubPass is the public passphrase the wallet was opened with.
+	pubPass []byte
+
 	chainSvr        *chain.Client
 	chainSvrLock    sync.Mutex
 	chainSvrSynced  bool
 	chainSvrSyncMtx sync.Mutex
 
 	lockedOutpoints map[wire.OutPoint]struct{}
-	FeeIncrement    coinutil.Amount
 	DisallowFree    bool
 
+	// FeeRate is the fee rate, in satoshis per byte, of created
+	// transactions.  When zero, the fee rate is estimated by FeeEstimator,
+	// or by the chain server if FeeEstimator is nil, and FallbackFeeRate
+	// is used when no estimate is available.
+	FeeRate         coinutil.Amount
+	FeeEstimator    FeeEstimator
+	FallbackFeeRate coinutil.Amount
+
+	// SignalReplaceable marks the inputs of created transactions as
+	// replaceable (BIP0125), so a stuck transaction can later be replaced
+	// by BumpFee with one paying a higher fee.
+	SignalReplaceable bool
+
+	// CoinSelector is the default input selection strategy of created
+	// transactions.  It is used when no selector is passed to
+	// CreateSimpleTx or SendPairs.
+	CoinSelector CoinSelector
+
+	// Signer signs the inputs of created transactions and messages.  It
+	// defaults to a signer of the private keys held by Manager, and may
+	// be replaced to ask an external signer holding further keys.
+	Signer signer.Signer
+
+	// UnminedExpiry and UnminedExpiryBlocks, when positive, are the age
+	// and the number of connected blocks after which unmined transactions
+	// are abandoned.
+	UnminedExpiry       time.Duration
+	UnminedExpiryBlocks int32
+
 	// Channels for rescan processing.  Requests are added and merged with
 	// any waiting requests, before being sent to another goroutine to
 	// call the rescan RPC.
@@ -82,15 +114,32 @@ type Wallet struct {
 	rescanNotifications chan interface{} // From chain server
 	rescanProgress      chan *RescanProgressMsg
 	rescanFinished      chan *RescanFinishedMsg
+	rescanning          bool
+	rescanningMtx       sync.Mutex
 
 	// Channel for transaction creation requests.
 	createTxRequests chan createTxRequest
 
+	// The progress of the running or last account discovery.
+	discovery    DiscoveryStatus
+	discoveryMtx sync.Mutex
+
+	// The sweepers of every private key swept since the wallet was
+	// opened.  The address of a swept key remains in the notification
+	// filter of the chain server, so its sweeper keeps filtering the
+	// transactions of the key out of the wallet.  sweepMtx serializes
+	// sweeps, while sweeperMtx protects the slice which is read by the
+	// chain notification handler.
+	sweepMtx   sync.Mutex
+	sweepers   []*sweeper
+	sweeperMtx sync.Mutex
+
 	// Channels for the manager locker.
 	unlockRequests     chan unlockRequest
 	lockRequests       chan struct{}
 	holdUnlockRequests chan chan HeldUnlock
 	lockState          chan bool
+	lockExpiry         chan time.Time
 	changePassphrase   chan changePassphraseRequest
 
 	// Notification channels so other components can listen in on wallet
@@ -102,6 +151,7 @@ type Wallet struct {
 	lockStateChanges   chan bool // true when locked
 	confirmedBalance   chan coinutil.Amount
 	unconfirmedBalance chan coinutil.Amount
+	conflictedTxs      chan ConflictedTx
 	notificationMu     sync.Mutex
 
 	chainParams *chaincfg.Params
@@ -214,6 +264,30 @@ func (w *Wallet) ListenRelevantTxs() (<-chan chain.RelevantTx, error) {
 	return w.relevantTxs, nil
 }
 
+// ConflictedTx describes an unmined wallet transaction which was removed as a
+// double spend.  ConflictedBy is the hash of the transaction double spending
+// it (or a transaction it spends from), or nil if unknown.
+type ConflictedTx struct {
+	Hash         wire.ShaHash
+	ConflictedBy *wire.ShaHash
+}
+
+// ListenConflictedTxs returns a channel that passes every unmined transaction
+// which is removed from the wallet because it was double spent.  This channel
+// must be read, or other wallet methods will block.
+//
+// If this is called twice, ErrDuplicateListen is returned.
+func (w *Wallet) ListenConflictedTxs() (<-chan ConflictedTx, error) {
+	defer w.notificationMu.Unlock()
+	w.notificationMu.Lock()
+
+	if w.conflictedTxs != nil {
+		return nil, ErrDuplicateListen
+	}
+	w.conflictedTxs = make(chan ConflictedTx)
+	return w.conflictedTxs, nil
+}
+
 func (w *Wallet) notifyConnectedBlock(block wtxmgr.BlockMeta) {
 	w.notificationMu.Lock()
 	if w.connectedBlocks != nil {
@@ -262,6 +336,16 @@ func (w *Wallet) notifyRelevantTx(relevantTx chain.RelevantTx) {
 	w.notificationMu.Unlock()
 }
 
+func (w *Wallet) notifyConflictedTx(txHash, conflictedBy *wire.ShaHash) {
+	log.Infof("Transaction %v was double spent and is now conflicted",
+		txHash)
+	w.notificationMu.Lock()
+	if w.conflictedTxs != nil {
+		w.conflictedTxs <- ConflictedTx{*txHash, conflictedBy}
+	}
+	w.notificationMu.Unlock()
+}
+
 // Start starts the goroutines necessary to manage a wallet.
 func (w *Wallet) Start(chainServer *chain.Client) {
 	w.quitMu.Lock()
@@ -293,6 +377,36 @@ func (w *Wallet) Start(chainServer *chain.Client) {
 	go w.rescanRPCHandler()
 }
 
+// StartOffline starts the goroutines necessary to manage a wallet which is
+// never attached to a chain server, such as an air-gapped wallet only used to
+// sign transactions.  The wallet can be unlocked and sign, but never syncs,
+// creates or publishes transactions.
+func (w *Wallet) StartOffline() {
+	w.quitMu.Lock()
+	if w.started {
+		w.quitMu.Unlock()
+		return
+	}
+	w.started = true
+	w.quitMu.Unlock()
+
+	w.wg.Add(1)
+	go w.walletLocker()
+}
+
+// ErrNotConnected describes an error where a request needs the chain server
+// but the wallet is not attached to one.
+var ErrNotConnected = errors.New("wallet is not attached to a chain server")
+
+// ChainClient returns the chain server RPC client the wallet was started with,
+// or nil if the wallet is not attached to a chain server.
+func (w *Wallet) ChainClient() *chain.Client {
+	w.chainSvrLock.Lock()
+	chainSvr := w.chainSvr
+	w.chainSvrLock.Unlock()
+	return chainSvr
+}
+
 // quitChan atomically reads the quit channel.
 func (w *Wallet) quitChan() <-chan struct{} {
 	w.quitMu.Lock()
@@ -330,6 +444,10 @@ func (w *Wallet) ShuttingDown() bool {
 	}
 }
 
+// ErrShuttingDown describes an error where a request can not be completed
+// since the wallet is shutting down.
+var ErrShuttingDown = errors.New("wallet shutting down")
+
 // WaitForShutdown blocks until all wallet goroutines have finished executing.
 func (w *Wallet) WaitForShutdown() {
 	w.chainSvrLock.Lock()
@@ -362,6 +480,21 @@ func (w *Wallet) SetChainSynced(synced bool) {
 	w.chainSvrSyncMtx.Unlock()
 }
 
+// Rescanning returns whether a rescan is currently being performed.
+func (w *Wallet) Rescanning() bool {
+	w.rescanningMtx.Lock()
+	rescanning := w.rescanning
+	w.rescanningMtx.Unlock()
+	return rescanning
+}
+
+// setRescanning marks whether a rescan is currently being performed.
+func (w *Wallet) setRescanning(rescanning bool) {
+	w.rescanningMtx.Lock()
+	w.rescanning = rescanning
+	w.rescanningMtx.Unlock()
+}
+
 // activeData returns the currently-active receiving addresses and all unspent
 // outputs.  This is primarely intended to provide the parameters for a
 // rescan request.
@@ -448,10 +581,17 @@ func (w *Wallet) syncWithChain() error {
 
 type (
 	createTxRequest struct {
-		account uint32
-		pairs   map[string]coinutil.Amount
-		minconf int32
-		resp    chan createTxResponse
+		account      uint32
+		pairs        map[string]coinutil.Amount
+		minconf      int32
+		selector     CoinSelector
+		feeRate      coinutil.Amount
+		outpoints    []wire.OutPoint // spent exactly, or by a child too, when non-nil
+		replaces     *wire.ShaHash   // unmined tx to bump the fee of when non-nil
+		parent       *wire.ShaHash   // unmined tx to pay for when non-nil
+		unsigned     bool            // leave the tx unsigned
+		disallowFree bool            // always pay feeRate
+		resp         chan createTxResponse
 	}
 	createTxResponse struct {
 		tx  *CreatedTx
@@ -475,7 +615,22 @@ out:
 	for {
 		select {
 		case txr := <-w.createTxRequests:
-			tx, err := w.txToPairs(txr.pairs, txr.account, txr.minconf)
+			var tx *CreatedTx
+			var err error
+			switch {
+			case txr.replaces != nil:
+				tx, err = w.txBumpFee(txr.replaces, txr.feeRate)
+			case txr.parent != nil:
+				tx, err = w.txPayForParent(txr.parent, txr.feeRate,
+					txr.outpoints)
+			case txr.outpoints != nil:
+				tx, err = w.txFromOutpoints(txr.outpoints, txr.pairs,
+					txr.account, txr.feeRate, txr.disallowFree)
+			default:
+				tx, err = w.txToPairs(txr.pairs, txr.account,
+					txr.minconf, txr.selector, txr.feeRate,
+					txr.unsigned)
+			}
 			txr.resp <- createTxResponse{tx, err}
 
 		case <-quit:
@@ -485,24 +640,76 @@ out:
 	w.wg.Done()
 }
 
+// requestTx passes a transaction creation request to the txCreator goroutine
+// and waits for its response.  ErrShuttingDown is returned if the wallet is
+// shutting down, since requests are no longer handled.
+func (w *Wallet) requestTx(req createTxRequest) (*CreatedTx, error) {
+	req.resp = make(chan createTxResponse)
+	select {
+	case w.createTxRequests <- req:
+	case <-w.quitChan():
+		return nil, ErrShuttingDown
+	}
+	resp := <-req.resp
+	return resp.tx, resp.err
+}
+
 // CreateSimpleTx creates a new signed transaction spending unspent P2PKH
 // outputs with at laest minconf confirmations spending to any number of
 // address/amount pairs.  Change and an appropiate transaction fee are
-// automatically included, if necessary.  All transaction creation through
-// this function is serialized to prevent the creation of many transactions
-// which spend the same outputs.
+// automatically included, if necessary.  Inputs are chosen by selector, or by
+// the wallet's default CoinSelector if selector is nil.  A positive feeRate,
+// in satoshis per byte, overrides the fee rate the wallet would otherwise use.
+// All transaction creation through this function is serialized to prevent the
+// creation of many transactions which spend the same outputs.
 func (w *Wallet) CreateSimpleTx(account uint32, pairs map[string]coinutil.Amount,
-	minconf int32) (*CreatedTx, error) {
+	minconf int32, selector CoinSelector, feeRate coinutil.Amount) (*CreatedTx, error) {
 
+	if selector == nil {
+		selector = w.CoinSelector
+	}
 	req := createTxRequest{
-		account: account,
-		pairs:   pairs,
-		minconf: minconf,
-		resp:    make(chan createTxResponse),
+		account:  account,
+		pairs:    pairs,
+		minconf:  minconf,
+		selector: selector,
+		feeRate:  feeRate,
 	}
-	w.createTxRequests <- req
-	resp := <-req.resp
-	return resp.tx, resp.err
+	return w.requestTx(req)
+}
+
+// CreateTxFromOutpoints creates a new signed transaction spending exactly the
+// passed outpoints to any number of address/amount pairs.  Change and an
+// appropiate transaction fee are automatically included, if necessary, and
+// no other inputs are added.  Every outpoint must be an unspent and unlocked
+// P2PKH output controlled by the account, or an OutpointError is returned.  A
+// positive feeRate, in satoshis per byte, overrides the fee rate the wallet
+// would otherwise use.  Creation is serialized with all other transactions
+// created by CreateSimpleTx.
+func (w *Wallet) CreateTxFromOutpoints(account uint32, outpoints []wire.OutPoint,
+	pairs map[string]coinutil.Amount, feeRate coinutil.Amount) (*CreatedTx, error) {
+
+	return w.createTxFromOutpoints(account, outpoints, pairs, feeRate, false)
+}
+
+// createTxFromOutpoints creates a transaction as described by
+// CreateTxFromOutpoints.  When disallowFree is set, the transaction pays feeRate
+// even if it could be relayed without a fee.
+func (w *Wallet) createTxFromOutpoints(account uint32, outpoints []wire.OutPoint,
+	pairs map[string]coinutil.Amount, feeRate coinutil.Amount,
+	disallowFree bool) (*CreatedTx, error) {
+
+	if len(outpoints) == 0 {
+		return nil, errors.New("no outpoints to spend")
+	}
+	req := createTxRequest{
+		account:      account,
+		pairs:        pairs,
+		feeRate:      feeRate,
+		outpoints:    outpoints,
+		disallowFree: disallowFree,
+	}
+	return w.requestTx(req)
 }
 
 type (
@@ -528,6 +735,7 @@ type (
 // walletLocker manages the locked/unlocked state of a wallet.
 func (w *Wallet) walletLocker() {
 	var timeout <-chan time.Time
+	var expiry time.Time
 	holdChan := make(HeldUnlock)
 	quit := w.quitChan()
 out:
@@ -542,8 +750,10 @@ out:
 			w.notifyLockStateChange(false)
 			if req.timeout == 0 {
 				timeout = nil
+				expiry = time.Time{}
 			} else {
 				timeout = time.After(req.timeout)
+				expiry = time.Now().Add(req.timeout)
 			}
 			req.err <- nil
 			continue
@@ -578,6 +788,9 @@ out:
 		case w.lockState <- w.Manager.IsLocked():
 			continue
 
+		case w.lockExpiry <- expiry:
+			continue
+
 		case <-quit:
 			break out
 
@@ -589,6 +802,7 @@ out:
 		// timer expiring.  Lock the manager here.
 		if timeout != nil {
 			timeout = nil
+			expiry = time.Time{}
 			err := w.Manager.Lock()
 			if err != nil {
 				log.Errorf("Could not lock wallet: %v", err)
@@ -625,6 +839,13 @@ func (w *Wallet) Locked() bool {
 	return <-w.lockState
 }
 
+// UnlockedUntil returns the time at which the wallet will be automatically
+// locked again.  The zero time is returned if the wallet is locked or was
+// unlocked without a timeout.
+func (w *Wallet) UnlockedUntil() time.Time {
+	return <-w.lockExpiry
+}
+
 // HoldUnlock prevents the wallet from being locked.  The HeldUnlock object
 // *must* be released, or the wallet will forever remain unlocked.
 //
@@ -830,6 +1051,9 @@ func ListTransactions(details *wtxmgr.TxDetails, syncHeight int32, net *chaincfg
 		blockTime = details.Block.Time.Unix()
 		confirmations = int64(confirms(details.Block.Height, syncHeight))
 	}
+	if details.Conflicted {
+		confirmations = -1
+	}
 
 	results := []btcjson.ListTransactionsResult{}
 	txHashStr := details.Hash.String()
@@ -899,9 +1123,10 @@ outputs:
 			BlockHash:       blockHashStr,
 			BlockTime:       blockTime,
 			TxID:            txHashStr,
-			WalletConflicts: []string{},
+			WalletConflicts: WalletConflicts(details),
 			Time:            received,
 			TimeReceived:    received,
+			Comment:         details.Metadata.Comment,
 		}
 
 		// Add a received/generated/immature result if this is a credit.
@@ -935,14 +1160,26 @@ outputs:
 // This is intended to be used for listsinceblock RPC replies.
 func (w *Wallet) ListSinceBlock(start, end, syncHeight int32) ([]btcjson.ListTransactionsResult, error) {
 	txList := []btcjson.ListTransactionsResult{}
-	err := w.TxStore.RangeTransactions(start, end, func(details []wtxmgr.TxDetails) (bool, error) {
+	f := func(details []wtxmgr.TxDetails) (bool, error) {
 		for _, detail := range details {
 			jsonResults := ListTransactions(&detail, syncHeight,
 				w.chainParams)
 			txList = append(txList, jsonResults...)
 		}
 		return false, nil
-	})
+	}
+	err := w.TxStore.RangeTransactions(start, end, f)
+	if err != nil || end != -1 {
+		return txList, err
+	}
+
+	// Conflicted transactions were never mined, so they are included
+	// with the unmined transactions.
+	conflicted, err := w.TxStore.ConflictedTxs()
+	if err != nil {
+		return nil, err
+	}
+	_, err = f(conflicted)
 	return txList, err
 }
 
@@ -961,9 +1198,7 @@ func (w *Wallet) ListTransactions(from, count int) ([]btcjson.ListTransactionsRe
 	skipped := 0
 	n := 0
 
-	// Return newer results first by starting at mempool height and working
-	// down to the genesis block.
-	err := w.TxStore.RangeTransactions(-1, 0, func(details []wtxmgr.TxDetails) (bool, error) {
+	f := func(details []wtxmgr.TxDetails) (bool, error) {
 		// Iterate over transactions at this height in reverse order.
 		// This does nothing for unmined transactions, which are
 		// unsorted, but it will process mined transactions in the
@@ -985,8 +1220,21 @@ func (w *Wallet) ListTransactions(from, count int) ([]btcjson.ListTransactionsRe
 		}
 
 		return false, nil
-	})
+	}
 
+	// Conflicted transactions were never mined, so they are returned
+	// first along with the unmined transactions.
+	conflicted, err := w.TxStore.ConflictedTxs()
+	if err != nil {
+		return nil, err
+	}
+	if done, err := f(conflicted); done || err != nil {
+		return txList, err
+	}
+
+	// Return newer results first by starting at mempool height and working
+	// down to the genesis block.
+	err = w.TxStore.RangeTransactions(-1, 0, f)
 	return txList, err
 }
 
@@ -1048,9 +1296,7 @@ func (w *Wallet) ListAllTransactions() ([]btcjson.ListTransactionsResult, error)
 	// the number of tx confirmations.
 	syncBlock := w.Manager.SyncedTo()
 
-	// Return newer results first by starting at mempool height and working
-	// down to the genesis block.
-	err := w.TxStore.RangeTransactions(-1, 0, func(details []wtxmgr.TxDetails) (bool, error) {
+	f := func(details []wtxmgr.TxDetails) (bool, error) {
 		// Iterate over transactions at this height in reverse order.
 		// This does nothing for unmined transactions, which are
 		// unsorted, but it will process mined transactions in the
@@ -1061,11 +1307,186 @@ func (w *Wallet) ListAllTransactions() ([]btcjson.ListTransactionsResult, error)
 			txList = append(txList, jsonResults...)
 		}
 		return false, nil
-	})
+	}
 
+	// Conflicted transactions were never mined, so they are returned
+	// first along with the unmined transactions.
+	conflicted, err := w.TxStore.ConflictedTxs()
+	if err != nil {
+		return nil, err
+	}
+	f(conflicted)
+
+	// Return newer results first by starting at mempool height and working
+	// down to the genesis block.
+	err = w.TxStore.RangeTransactions(-1, 0, f)
 	return txList, err
 }
 
+// GroupedAddress describes a wallet address of an address grouping returned
+// by AddressGroupings.
+type GroupedAddress struct {
+	Address coinutil.Address
+	Account uint32
+	Balance coinutil.Amount
+}
+
+// AddressGroupings returns every wallet address with transaction history,
+// grouped by the common ownership an observer of the block chain could infer
+// from the wallet's transactions.  Two addresses are placed in the same group
+// when outputs paid to both are spent as inputs of a single transaction, or
+// when one address receives change from a transaction spending an output of
+// the other.  Groups are merged transitively.  The balance of each address is
+// the total value of its unspent outputs, including outputs of unmined
+// transactions.
+//
+// Addresses within a group are sorted by their encoding, and groups are
+// sorted by their first address.
+func (w *Wallet) AddressGroupings() ([][]GroupedAddress, error) {
+	// Addresses are keyed by their string encoding.  The inputs and change
+	// outputs of each transaction are recorded while ranging and only
+	// linked afterwards, as unmined transactions are not returned in the
+	// order they spend each other.
+	var (
+		addrs       = make(map[string]coinutil.Address)
+		balances    = make(map[string]coinutil.Amount)
+		creditAddrs = make(map[wire.OutPoint]string)
+		txs         []addrTxLinks
+	)
+	err := w.TxStore.RangeTransactions(0, -1, func(details []wtxmgr.TxDetails) (bool, error) {
+		for i := range details {
+			detail := &details[i]
+			var links addrTxLinks
+			for _, cred := range detail.Credits {
+				pkScript := detail.MsgTx.TxOut[cred.Index].PkScript
+				_, outAddrs, _, err := txscript.ExtractPkScriptAddrs(
+					pkScript, w.chainParams)
+				if err != nil || len(outAddrs) != 1 {
+					continue
+				}
+				addr := outAddrs[0].EncodeAddress()
+				addrs[addr] = outAddrs[0]
+				op := wire.OutPoint{Hash: detail.Hash, Index: cred.Index}
+				creditAddrs[op] = addr
+				if !cred.Spent {
+					balances[addr] += cred.Amount
+				}
+				if cred.Change {
+					links.change = append(links.change, addr)
+				}
+			}
+			for _, debit := range detail.Debits {
+				prevOut := detail.MsgTx.TxIn[debit.Index].PreviousOutPoint
+				links.inputs = append(links.inputs, prevOut)
+			}
+			txs = append(txs, links)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := make([]string, 0, len(addrs))
+	for addr := range addrs {
+		encoded = append(encoded, addr)
+	}
+	groupAddrs := clusterAddresses(encoded, creditAddrs, txs)
+
+	groups := make([][]GroupedAddress, 0, len(groupAddrs))
+	for _, set := range groupAddrs {
+		group := make([]GroupedAddress, 0, len(set))
+		for _, addr := range set {
+			account, err := w.Manager.AddrAccount(addrs[addr])
+			if err != nil {
+				return nil, err
+			}
+			group = append(group, GroupedAddress{
+				Address: addrs[addr],
+				Account: account,
+				Balance: balances[addr],
+			})
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// addrTxLinks records the outpoints spent by a wallet transaction and the
+// encoded addresses of its change outputs.
+type addrTxLinks struct {
+	inputs []wire.OutPoint
+	change []string
+}
+
+// clusterAddresses groups the encoded addresses by common ownership.  The
+// addresses of the credits spent by a transaction are placed in the group of
+// its change addresses, and groups are merged transitively.  Spent outpoints
+// missing from creditAddrs, such as those of other wallets, are ignored.
+// Addresses within a group are sorted, and groups are sorted by their first
+// address.
+func clusterAddresses(addrs []string, creditAddrs map[wire.OutPoint]string,
+	txs []addrTxLinks) [][]string {
+
+	// Link the addresses of each transaction using a disjoint-set forest
+	// keyed by address.
+	parents := make(map[string]string, len(addrs))
+	for _, addr := range addrs {
+		parents[addr] = addr
+	}
+	find := func(addr string) string {
+		for parents[addr] != addr {
+			parents[addr] = parents[parents[addr]]
+			addr = parents[addr]
+		}
+		return addr
+	}
+	for _, links := range txs {
+		members := links.change
+		for _, prevOut := range links.inputs {
+			if addr, ok := creditAddrs[prevOut]; ok {
+				members = append(members, addr)
+			}
+		}
+		for _, addr := range members {
+			root, first := find(addr), find(members[0])
+			if root != first {
+				parents[root] = first
+			}
+		}
+	}
+
+	sets := make(map[string][]string)
+	for _, addr := range addrs {
+		root := find(addr)
+		sets[root] = append(sets[root], addr)
+	}
+	groups := make([][]string, 0, len(sets))
+	for _, set := range sets {
+		sort.Strings(set)
+		groups = append(groups, set)
+	}
+	sort.Sort(addrGroupSlice(groups))
+	return groups
+}
+
+// addrGroupSlice satisfies the sort.Interface interface to sort groups of
+// encoded addresses by their first address.  Each group must already be
+// sorted and must not be empty.
+type addrGroupSlice [][]string
+
+func (s addrGroupSlice) Len() int {
+	return len(s)
+}
+
+func (s addrGroupSlice) Less(i, j int) bool {
+	return s[i][0] < s[j][0]
+}
+
+func (s addrGroupSlice) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
 // creditSlice satisifies the sort.Interface interface to provide sorting
 // transaction credits from oldest to newest.  Credits with the same receive
 // time and mined in the same block are not guaranteed to be sorted by the order
@@ -1257,6 +1678,13 @@ func (w *Wallet) DumpPrivKeys() ([]string, error) {
 		if !ok {
 			return nil
 		}
+		watchingOnly, err := w.Manager.IsWatchingOnlyAccount(pka.Account())
+		if err != nil {
+			return err
+		}
+		if watchingOnly {
+			return nil
+		}
 
 		wif, err := pka.ExportPrivKey()
 		if err != nil {
@@ -1336,8 +1764,9 @@ func (w *Wallet) ImportPrivateKey(wif *coinutil.WIF, bs *waddrmgr.BlockStamp,
 }
 
 // ExportWatchingWallet returns a watching-only version of the wallet serialized
-// database as a base64-encoded string.
-func (w *Wallet) ExportWatchingWallet(pubPass string) (string, error) {
+// database as a base64-encoded string.  The exported wallet is opened with the
+// same public passphrase as the wallet.
+func (w *Wallet) ExportWatchingWallet() (string, error) {
 	tmpDir, err := ioutil.TempDir("", "btcwallet")
 	if err != nil {
 		return "", err
@@ -1370,8 +1799,7 @@ func (w *Wallet) ExportWatchingWallet(pubPass string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	woMgr, err := waddrmgr.Open(namespace, []byte(pubPass),
-		w.chainParams, nil)
+	woMgr, err := waddrmgr.Open(namespace, w.pubPass, w.chainParams, nil)
 	if err != nil {
 		return "", err
 	}
@@ -1394,6 +1822,18 @@ func (w *Wallet) ExportWatchingWallet(pubPass string) (string, error) {
 	return woWallet.exportBase64()
 }
 
+// BackupWallet writes a consistent copy of the wallet database, including both
+// the address manager and transaction store namespaces, to a new file at the
+// destination path.  The wallet does not need to be stopped or locked while
+// the backup is being written.
+func (w *Wallet) BackupWallet(destination string) error {
+	if err := w.db.CopyFile(destination); err != nil {
+		return err
+	}
+	log.Infof("Wrote wallet backup to %s", destination)
+	return nil
+}
+
 // exportBase64 exports a wallet's serialized database as a base64-encoded
 // string.
 func (w *Wallet) exportBase64() (string, error) {
@@ -1454,19 +1894,85 @@ func (w *Wallet) ResendUnminedTxs() {
 		log.Errorf("Cannot load unmined transactions for resending: %v", err)
 		return
 	}
+	unmined := make(map[wire.ShaHash]struct{}, len(txs))
+	for _, tx := range txs {
+		unmined[tx.TxSha()] = struct{}{}
+	}
 	for _, tx := range txs {
 		resp, err := w.chainSvr.SendRawTransaction(tx, false)
+		if err == nil {
+			log.Debugf("Resent unmined transaction %v", resp)
+			continue
+		}
+		txHash := tx.TxSha()
+		log.Debugf("Could not resend transaction %v: %v", txHash, err)
+
+		// The transaction may have been removed already as part of the
+		// spend chain of another conflicted transaction.
+		if _, ok := unmined[txHash]; !ok {
+			continue
+		}
+		doubleSpent, err := w.doubleSpent(tx, unmined)
+		if err != nil {
+			log.Errorf("Cannot check transaction %v for double "+
+				"spends: %v", txHash, err)
+			continue
+		}
+		if !doubleSpent {
+			continue
+		}
+		err = w.TxStore.MarkConflicted(&txHash)
 		if err != nil {
-			// TODO(jrick): Check error for if this tx is a double spend,
-			// remove it if so.
-			log.Debugf("Could not resend transaction %v: %v",
-				tx.TxSha(), err)
+			log.Errorf("Cannot mark transaction %v conflicted: %v",
+				txHash, err)
 			continue
 		}
-		log.Debugf("Resent unmined transaction %v", resp)
+		delete(unmined, txHash)
+
+		// Transactions spending the conflicted transaction were
+		// removed with it.
+		remaining, err := w.TxStore.UnminedTxs()
+		if err != nil {
+			log.Errorf("Cannot load unmined transactions: %v", err)
+			return
+		}
+		unmined = make(map[wire.ShaHash]struct{}, len(remaining))
+		for _, remainingTx := range remaining {
+			unmined[remainingTx.TxSha()] = struct{}{}
+		}
 	}
 }
 
+// doubleSpent checks whether an unmined wallet transaction rejected by the
+// chain server spends an output which is already spent by another transaction
+// in the block chain or the chain server's memory pool.  Inputs spending the
+// wallet's unmined transactions, which is the set unmined, are not checked
+// since those transactions are resent themselves.
+func (w *Wallet) doubleSpent(tx *wire.MsgTx, unmined map[wire.ShaHash]struct{}) (bool, error) {
+	// A transaction already known to the chain server spends its own
+	// inputs.
+	txHash := tx.TxSha()
+	if _, err := w.chainSvr.GetRawTransaction(&txHash); err == nil {
+		return false, nil
+	}
+
+	for _, input := range tx.TxIn {
+		prevOut := &input.PreviousOutPoint
+		if _, ok := unmined[prevOut.Hash]; ok {
+			continue
+		}
+		txOut, err := w.chainSvr.GetTxOut(&prevOut.Hash, prevOut.Index,
+			true)
+		if err != nil {
+			return false, err
+		}
+		if txOut == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // SortedActivePaymentAddresses returns a slice of all active payment
 // addresses in a wallet.
 func (w *Wallet) SortedActivePaymentAddresses() ([]string, error) {
@@ -1625,41 +2131,216 @@ func (w *Wallet) TotalReceivedForAddr(addr coinutil.Address, minConf int32) (coi
 }
 
 // SendPairs creates and sends payment transactions. It returns the transaction
-// hash upon success
+// hash upon success.  Inputs are chosen by selector, or by the wallet's default
+// CoinSelector if selector is nil, and a positive feeRate overrides the fee
+// rate of the wallet.  If md is non-nil, it is recorded as the metadata of the
+// created transaction once the transaction is published.
 func (w *Wallet) SendPairs(amounts map[string]coinutil.Amount, account uint32,
-	minconf int32) (*wire.ShaHash, error) {
+	minconf int32, selector CoinSelector, feeRate coinutil.Amount,
+	md *wtxmgr.TxMetadata) (*wire.ShaHash, error) {
 
 	// Create transaction, replying with an error if the creation
 	// was not successful.
-	createdTx, err := w.CreateSimpleTx(account, amounts, minconf)
+	createdTx, err := w.CreateSimpleTx(account, amounts, minconf, selector,
+		feeRate)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create transaction record and insert into the db.
+	return w.publishCreatedTx(createdTx, md)
+}
+
+// SendOutpoints creates and sends a payment transaction spending exactly the
+// passed outpoints, as described by CreateTxFromOutpoints.  It returns the
+// transaction hash upon success.  If md is non-nil, it is recorded as the
+// metadata of the created transaction once the transaction is published.
+func (w *Wallet) SendOutpoints(outpoints []wire.OutPoint, amounts map[string]coinutil.Amount,
+	account uint32, feeRate coinutil.Amount, md *wtxmgr.TxMetadata) (*wire.ShaHash, error) {
+
+	createdTx, err := w.CreateTxFromOutpoints(account, outpoints, amounts,
+		feeRate)
+	if err != nil {
+		return nil, err
+	}
+	return w.publishCreatedTx(createdTx, md)
+}
+
+// ConsolidateUnspent merges the spendable outputs of an account worth less
+// than threshold into outputs paying new internal addresses of the account.
+// Only outputs with at least one confirmation which are worth more than the
+// fee of spending them are merged, and locked outputs are skipped.  As many
+// transactions as are needed to keep each at or below maxTxSize bytes are
+// created, each spending at least two outputs and paying a fee of feeRate
+// satoshis per byte, or the fee rate of the wallet if feeRate is not positive.
+// The fee is paid even when the transactions could be relayed for free.  The
+// hashes of the published transactions are returned, along with the hashes of
+// the transactions published before any error.
+func (w *Wallet) ConsolidateUnspent(account uint32, threshold coinutil.Amount,
+	maxTxSize int, feeRate coinutil.Amount) ([]*wire.ShaHash, error) {
+
+	bs, err := w.chainSvr.BlockStamp()
+	if err != nil {
+		return nil, err
+	}
+	eligible, err := w.findEligibleOutputs(account, 1, bs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fix the fee rate so every transaction is created with the rate used
+	// to choose the merged outputs.
+	feeRate = w.txFeeRate(feeRate)
+	sets, err := consolidationSets(eligible, threshold, feeRate, maxTxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes []*wire.ShaHash
+	for _, outpoints := range sets {
+		createdTx, err := w.createTxFromOutpoints(account, outpoints,
+			nil, feeRate, true)
+		if err != nil {
+			return hashes, err
+		}
+		if createdTx.ChangeIndex < 0 {
+			return hashes, errors.New("merged outputs do not pay " +
+				"for the transaction fee")
+		}
+		hash, err := w.publishCreatedTx(createdTx, nil)
+		if err != nil {
+			return hashes, err
+		}
+		log.Infof("Merged %d outputs of account %d in transaction %v",
+			len(outpoints), account, hash)
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// consolidationSets returns the outpoints spent by each transaction created by
+// ConsolidateUnspent.  The credits worth less than threshold and more than the
+// fee of spending them at feeRate are merged smallest first, in sets of at
+// least two outpoints and as many as fit a transaction of maxTxSize bytes.
+// Sets which would not leave an output worth at least the cost of change after
+// paying the fee of their transaction are dropped, so every set is checked
+// before any transaction is published.
+func consolidationSets(eligible []wtxmgr.Credit, threshold, feeRate coinutil.Amount,
+	maxTxSize int) ([][]wire.OutPoint, error) {
+
+	// Signatures may be up to two bytes longer than estimated, and the
+	// varint for the number of inputs up to two bytes longer as well.
+	const sigExtra, varIntExtra = 2, 2
+	maxInputs := (maxTxSize - txOverheadEstimate - varIntExtra -
+		txOutEstimate) / (txInEstimate + sigExtra)
+	if maxInputs < 2 {
+		return nil, fmt.Errorf("maximum transaction size %d is too "+
+			"small to merge outputs", maxTxSize)
+	}
+
+	inputFee := feeForSize(feeRate, txInEstimate)
+	var small []wtxmgr.Credit
+	for _, credit := range eligible {
+		if credit.Amount < threshold && credit.Amount > inputFee {
+			small = append(small, credit)
+		}
+	}
+	sort.Sort(ByAmount(small))
+
+	var sets [][]wire.OutPoint
+	for len(small) >= 2 {
+		n := len(small)
+		if n > maxInputs {
+			n = maxInputs
+		}
+		outpoints := make([]wire.OutPoint, n)
+		var total coinutil.Amount
+		for i := range outpoints {
+			outpoints[i] = small[i].OutPoint
+			total += small[i].Amount
+		}
+		small = small[n:]
+
+		fee := feeForSize(feeRate, estimateTxSize(n, 1))
+		if total-fee < changeCost(feeRate) {
+			continue
+		}
+		sets = append(sets, outpoints)
+	}
+	return sets, nil
+}
+
+// publishCreatedTx sends a created transaction to the chain server before
+// recording it, its change output and the optional metadata md in the
+// transaction store, so nothing is recorded if the chain server rejects it.
+// It returns the transaction hash upon success.  Incomplete transactions of
+// watching-only and HD multisig accounts are refused with ErrIncompleteTx.
+func (w *Wallet) publishCreatedTx(createdTx *CreatedTx, md *wtxmgr.TxMetadata) (*wire.ShaHash, error) {
+	if createdTx.Incomplete {
+		return nil, ErrIncompleteTx
+	}
+
 	rec, err := wtxmgr.NewTxRecordFromMsgTx(createdTx.MsgTx, time.Now())
 	if err != nil {
 		log.Errorf("Cannot create record for created transaction: %v", err)
 		return nil, err
 	}
-	err = w.TxStore.InsertTx(rec, nil)
+	// TODO: The record already has the serialized tx, so no need to
+	// serialize it again.
+	hash, err := w.chainSvr.SendRawTransaction(&rec.MsgTx, false)
 	if err != nil {
-		log.Errorf("Error adding sent tx history: %v", err)
 		return nil, err
 	}
 
+	// The transaction was published, so failing to record it is only
+	// logged.  It is recorded again when the chain server notifies it.
+	err = w.TxStore.InsertTx(rec, nil)
+	if err != nil {
+		log.Errorf("Error adding sent tx history: %v", err)
+		return hash, nil
+	}
 	if createdTx.ChangeIndex >= 0 {
 		err = w.TxStore.AddCredit(rec, nil, uint32(createdTx.ChangeIndex), true)
 		if err != nil {
 			log.Errorf("Error adding change address for sent "+
 				"tx: %v", err)
-			return nil, err
 		}
 	}
+	if md != nil {
+		err = w.TxStore.SetTxMetadata(&rec.Hash, md)
+		if err != nil {
+			log.Errorf("Error adding comments for sent tx: %v", err)
+		}
+	}
+	return hash, nil
+}
 
-	// TODO: The record already has the serialized tx, so no need to
-	// serialize it again.
-	return w.chainSvr.SendRawTransaction(&rec.MsgTx, false)
+// PublishTransaction sends a transaction signed outside of the wallet, such as
+// one created by a watching-only wallet and signed by an offline copy, to the
+// chain server, and records it in the transaction store along with every
+// output paying the wallet.  Transactions rejected by the chain server are
+// never recorded.  It returns the transaction hash upon success.
+func (w *Wallet) PublishTransaction(tx *wire.MsgTx) (*wire.ShaHash, error) {
+	chainSvr := w.ChainClient()
+	if chainSvr == nil {
+		return nil, ErrNotConnected
+	}
+	rec, err := wtxmgr.NewTxRecordFromMsgTx(tx, time.Now())
+	if err != nil {
+		log.Errorf("Cannot create record for signed transaction: %v", err)
+		return nil, err
+	}
+	hash, err := chainSvr.SendRawTransaction(&rec.MsgTx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transaction was published, so failing to record it is only
+	// logged.  It is recorded again when the chain server notifies it.
+	err = w.addRelevantTx(rec, nil)
+	if err != nil {
+		log.Errorf("Error adding signed tx history: %v", err)
+	}
+	return hash, nil
 }
 
 // Open loads an already-created wallet from the passed database and namespaces.
@@ -1689,8 +2370,11 @@ func Open(pubPass []byte, params *chaincfg.Params, db walletdb.DB, waddrmgrNS, w
 		db:                  db,
 		Manager:             addrMgr,
 		TxStore:             txMgr,
+		pubPass:             pubPass,
 		lockedOutpoints:     map[wire.OutPoint]struct{}{},
-		FeeIncrement:        defaultFeeIncrement,
+		FallbackFeeRate:     defaultFeeRate,
+		CoinSelector:        LargestFirstSelector{},
+		Signer:              newManagerSigner(addrMgr),
 		rescanAddJob:        make(chan *RescanJob),
 		rescanBatch:         make(chan *rescanBatch),
 		rescanNotifications: make(chan interface{}),
@@ -1701,9 +2385,11 @@ func Open(pubPass []byte, params *chaincfg.Params, db walletdb.DB, waddrmgrNS, w
 		lockRequests:        make(chan struct{}),
 		holdUnlockRequests:  make(chan chan HeldUnlock),
 		lockState:           make(chan bool),
+		lockExpiry:          make(chan time.Time),
 		changePassphrase:    make(chan changePassphraseRequest),
 		chainParams:         params,
 		quit:                make(chan struct{}),
 	}
+	txMgr.NotifyConflicted = w.notifyConflictedTx
 	return w, nil
 }
@@ -0,0 +1,206 @@
+package wallet
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcd/wire"
+	"github.com/conseweb/stcwallet/wtxmgr"
+)
+
+func TestClusterAddresses(t *testing.T) {
+	// op returns a distinct outpoint for each index.
+	op := func(i uint32) wire.OutPoint {
+		return wire.OutPoint{Hash: wire.ShaHash{1}, Index: i}
+	}
+
+	tests := []struct {
+		name        string
+		addrs       []string
+		creditAddrs map[wire.OutPoint]string
+		txs         []addrTxLinks
+		want        [][]string
+	}{
+		{
+			name:  "no transactions spending credits",
+			addrs: []string{"c", "a", "b"},
+			creditAddrs: map[wire.OutPoint]string{
+				op(0): "a", op(1): "b", op(2): "c",
+			},
+			txs:  []addrTxLinks{{}, {}},
+			want: [][]string{{"a"}, {"b"}, {"c"}},
+		},
+		{
+			name:  "inputs sharing a transaction are merged",
+			addrs: []string{"a", "b", "c"},
+			creditAddrs: map[wire.OutPoint]string{
+				op(0): "a", op(1): "b", op(2): "c",
+			},
+			txs: []addrTxLinks{
+				{inputs: []wire.OutPoint{op(0), op(1)}},
+			},
+			want: [][]string{{"a", "b"}, {"c"}},
+		},
+		{
+			name:  "change joins its input group",
+			addrs: []string{"a", "b", "change"},
+			creditAddrs: map[wire.OutPoint]string{
+				op(0): "a", op(1): "b", op(2): "change",
+			},
+			txs: []addrTxLinks{
+				{inputs: []wire.OutPoint{op(0)}, change: []string{"change"}},
+			},
+			want: [][]string{{"a", "change"}, {"b"}},
+		},
+		{
+			name:  "groups are merged transitively",
+			addrs: []string{"a", "b", "c", "d", "e"},
+			creditAddrs: map[wire.OutPoint]string{
+				op(0): "a", op(1): "b", op(2): "c", op(3): "d",
+				op(4): "e",
+			},
+			txs: []addrTxLinks{
+				{inputs: []wire.OutPoint{op(0), op(1)}},
+				{inputs: []wire.OutPoint{op(2)}, change: []string{"d"}},
+				{inputs: []wire.OutPoint{op(3), op(1)}},
+			},
+			want: [][]string{{"a", "b", "c", "d"}, {"e"}},
+		},
+		{
+			name:  "foreign inputs are ignored",
+			addrs: []string{"a", "b"},
+			creditAddrs: map[wire.OutPoint]string{
+				op(0): "a", op(1): "b",
+			},
+			txs: []addrTxLinks{
+				{inputs: []wire.OutPoint{op(0), op(7)}},
+				{inputs: []wire.OutPoint{op(8)}, change: []string{"b"}},
+			},
+			want: [][]string{{"a"}, {"b"}},
+		},
+	}
+	for _, test := range tests {
+		got := clusterAddresses(test.addrs, test.creditAddrs, test.txs)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestConsolidationSets(t *testing.T) {
+	// credits returns a credit of each amount, with the amount as the
+	// index of its outpoint.
+	credits := func(amounts ...coinutil.Amount) []wtxmgr.Credit {
+		c := make([]wtxmgr.Credit, len(amounts))
+		for i, amount := range amounts {
+			c[i].OutPoint = wire.OutPoint{Index: uint32(amount)}
+			c[i].Amount = amount
+		}
+		return c
+	}
+	// sets returns the outpoints of the credits of each set of amounts.
+	sets := func(amounts ...[]coinutil.Amount) [][]wire.OutPoint {
+		s := make([][]wire.OutPoint, len(amounts))
+		for i := range amounts {
+			for _, c := range credits(amounts[i]...) {
+				s[i] = append(s[i], c.OutPoint)
+			}
+		}
+		return s
+	}
+	// txSize returns the maximum transaction size fitting n inputs.
+	txSize := func(n int) int {
+		return txOverheadEstimate + 2 + txOutEstimate + n*(txInEstimate+2)
+	}
+
+	const feeRate = 1
+	inputFee := feeForSize(feeRate, txInEstimate)
+	tests := []struct {
+		name      string
+		eligible  []wtxmgr.Credit
+		threshold coinutil.Amount
+		maxTxSize int
+		want      [][]wire.OutPoint
+	}{
+		{
+			name:      "smallest first",
+			eligible:  credits(5000, 1000, 3000, 2000),
+			threshold: 10000,
+			maxTxSize: txSize(10),
+			want:      sets([]coinutil.Amount{1000, 2000, 3000, 5000}),
+		},
+		{
+			name: "outputs at the threshold or not paying for " +
+				"their input are skipped",
+			eligible: credits(20000, 10000, 3000, inputFee,
+				inputFee+1, 9999),
+			threshold: 10000,
+			maxTxSize: txSize(10),
+			want: sets([]coinutil.Amount{inputFee + 1, 3000,
+				9999}),
+		},
+		{
+			name:      "transactions are kept within the maximum size",
+			eligible:  credits(7000, 6000, 5000, 4000, 3000, 2000, 1000),
+			threshold: 10000,
+			maxTxSize: txSize(3),
+			want: sets([]coinutil.Amount{1000, 2000, 3000},
+				[]coinutil.Amount{4000, 5000, 6000}),
+		},
+		{
+			name:      "the remaining pair of outputs is merged",
+			eligible:  credits(5000, 4000, 3000, 2000, 1000),
+			threshold: 10000,
+			maxTxSize: txSize(3),
+			want: sets([]coinutil.Amount{1000, 2000, 3000},
+				[]coinutil.Amount{4000, 5000}),
+		},
+		{
+			name:      "a remaining output is not merged",
+			eligible:  credits(4000, 3000, 2000, 1000),
+			threshold: 10000,
+			maxTxSize: txSize(3),
+			want:      sets([]coinutil.Amount{1000, 2000, 3000}),
+		},
+		{
+			name: "sets not paying for their transaction and " +
+				"change are dropped",
+			eligible: credits(6000, inputFee+2, 5000,
+				inputFee+1),
+			threshold: 10000,
+			maxTxSize: txSize(2),
+			want:      sets([]coinutil.Amount{5000, 6000}),
+		},
+		{
+			name:      "a single output is not merged",
+			eligible:  credits(1000, 20000),
+			threshold: 10000,
+			maxTxSize: txSize(10),
+			want:      nil,
+		},
+	}
+	for _, test := range tests {
+		got, err := consolidationSets(test.eligible, test.threshold,
+			feeRate, test.maxTxSize)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	// Transactions must fit at least two inputs.
+	_, err := consolidationSets(credits(1000, 2000), 10000, feeRate,
+		txSize(2)-1)
+	if err == nil {
+		t.Error("Expected an error for a maximum size fitting one input")
+	}
+	if _, err := consolidationSets(credits(1000, 2000), 10000, feeRate,
+		txSize(2)); err != nil {
+		t.Errorf("Unexpected error for a maximum size fitting two "+
+			"inputs: %v", err)
+	}
+}
@@ -0,0 +1,636 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package wallet
+
+// This file implements the wallet dump format written by DumpWallet and read
+// by ImportWallet.  The format is a line based text format which is compatible
+// with the dumpwallet format of the reference implementation, so dumps can be
+// moved between the two in either direction.
+//
+// Blank lines and lines beginning with '#' are ignored when importing, except
+// for the format version header.  A dump written by this package begins with
+// a header such as:
+//
+//   # Wallet dump created by btcwallet
+//   # * Format version: 1
+//   # * Created on 2015-11-23T12:00:00Z
+//   # * Network: mainnet
+//   # * Best block at time of backup was 384000 (000000...)
+//   # * Wallet birthday is block 350000 (000000...)
+//
+// followed by one comment line per account recording the account number and
+// name:
+//
+//   # account=0 name=default
+//
+// Every private key and script known to the wallet is written on its own
+// line made up of whitespace separated fields:
+//
+//   <key> <time> <attr>=<value>... # addr=<address> <detail>=<value>...
+//
+// The key is a WIF-encoded private key, or a hex-encoded script when the
+// script=1 attribute is present.  The time is the RFC3339 (UTC) time the key
+// was added to the wallet.  The attributes are one of label=<account name> for
+// receiving addresses and imports, or change=1 for change addresses.  After
+// the '#', the details record the encoded address, the BIP0044 derivation
+// path as hdkeypath=m/44'/<coin type>'/<account>'/<branch>/<index> for keys
+// derived from the wallet seed, and birthday=<height>, a lower bound of the
+// height of the earliest block that may contain transactions for the key.
+// DumpWallet does not track when each key was first used and writes the
+// birthday of the wallet for every key.  Account names and labels are encoded
+// by percent-escaping spaces, control characters, non-ASCII bytes and '%'.
+//
+// The dump ends with the line "# End of dump".  Readers must reject dumps with
+// a format version newer than dumpFormatVersion.
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conseweb/coinutil"
+	"github.com/conseweb/stcwallet/waddrmgr"
+)
+
+// dumpFormatVersion is the latest version of the wallet dump format.
+const dumpFormatVersion = 1
+
+// dumpVersionPrefix is the prefix of the header line which records the format
+// version of a dump.
+const dumpVersionPrefix = "# * Format version:"
+
+// dumpTimeFormat is the format used for key times in a wallet dump.
+const dumpTimeFormat = "2006-01-02T15:04:05Z"
+
+// encodeDumpString percent-escapes every byte of s which is a space, control
+// character, non-ASCII or '%' so the result can be written as a single field
+// of a dump line.
+func encodeDumpString(s string) string {
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || c == '%' {
+			buf = append(buf, fmt.Sprintf("%%%02x", c)...)
+			continue
+		}
+		buf = append(buf, c)
+	}
+	return string(buf)
+}
+
+// decodeDumpString reverses the percent-escaping of encodeDumpString.
+func decodeDumpString(s string) (string, error) {
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			buf = append(buf, s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", fmt.Errorf("truncated escape in %q", s)
+		}
+		b, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		buf = append(buf, b[0])
+		i += 2
+	}
+	return string(buf), nil
+}
+
+// dumpEntry describes a single key or script line of a wallet dump.  The
+// account, branch and index are only set for chained keys, which record the
+// BIP0044 derivation path of the key.
+type dumpEntry struct {
+	key      string
+	script   bool
+	birthday int32 // -1 when unknown
+
+	chained                bool
+	account, branch, index uint32
+}
+
+// parseHDKeyPath parses a BIP0044 derivation path of the form
+// m/44'/<coin type>'/<account>'/<branch>/<index>.  False is returned for paths
+// of any other form, such as those written by the reference implementation.
+func parseHDKeyPath(path string) (account, branch, index uint32, ok bool) {
+	parts := strings.Split(path, "/")
+	if len(parts) != 6 || parts[0] != "m" || parts[1] != "44'" {
+		return 0, 0, 0, false
+	}
+	var values [4]uint32
+	for i, part := range parts[2:] {
+		hardened := strings.HasSuffix(part, "'")
+		if hardened != (i < 2) {
+			return 0, 0, 0, false
+		}
+		v, err := strconv.ParseUint(strings.TrimSuffix(part, "'"), 10, 31)
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		values[i] = uint32(v)
+	}
+	return values[1], values[2], values[3], true
+}
+
+// parseDumpLine parses a non-comment line of a wallet dump.
+func parseDumpLine(line string) (*dumpEntry, error) {
+	var details string
+	if i := strings.Index(line, "#"); i != -1 {
+		line, details = line[:i], line[i+1:]
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("malformed wallet dump line %q", line)
+	}
+
+	entry := &dumpEntry{key: fields[0], birthday: -1}
+	for _, attr := range fields[2:] {
+		if attr == "script=1" {
+			entry.script = true
+		}
+	}
+	for _, detail := range strings.Fields(details) {
+		switch {
+		case strings.HasPrefix(detail, "birthday="):
+			height, err := strconv.ParseInt(detail[len("birthday="):],
+				10, 32)
+			if err != nil || height < 0 {
+				return nil, fmt.Errorf("invalid birthday in wallet "+
+					"dump line for key %q", entry.key)
+			}
+			entry.birthday = int32(height)
+		case strings.HasPrefix(detail, "hdkeypath="):
+			account, branch, index, ok := parseHDKeyPath(
+				detail[len("hdkeypath="):])
+			if ok {
+				entry.chained = true
+				entry.account = account
+				entry.branch = branch
+				entry.index = index
+			}
+		}
+	}
+	return entry, nil
+}
+
+// parseDumpAccount parses a "# account=<number> name=<name>" comment line of a
+// wallet dump.  False is returned for any other comment.
+func parseDumpAccount(line string) (account uint32, name string, ok bool) {
+	fields := strings.Fields(strings.TrimPrefix(line, "#"))
+	if len(fields) != 2 || !strings.HasPrefix(fields[0], "account=") ||
+		!strings.HasPrefix(fields[1], "name=") {
+		return 0, "", false
+	}
+	v, err := strconv.ParseUint(fields[0][len("account="):], 10, 32)
+	if err != nil {
+		return 0, "", false
+	}
+	name, err = decodeDumpString(fields[1][len("name="):])
+	if err != nil {
+		return 0, "", false
+	}
+	return uint32(v), name, true
+}
+
+// DumpWallet writes every private key and script of the wallet, along with
+// the account names, derivation paths and birthdays needed to restore them,
+// to out in the wallet dump format described at the top of this file.  The
+// addresses of watching-only accounts are omitted since the wallet does not
+// hold their keys.  The wallet must be unlocked.
+func (w *Wallet) DumpWallet(out io.Writer) error {
+	accountNames := make(map[uint32]string)
+	var accounts []uint32
+	err := w.Manager.ForEachAccount(func(account uint32) error {
+		accounts = append(accounts, account)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	watchingOnly := make(map[uint32]bool)
+	for _, account := range accounts {
+		name, err := w.Manager.AccountName(account)
+		if err != nil {
+			return err
+		}
+		accountNames[account] = name
+		watchingOnly[account], err = w.Manager.IsWatchingOnlyAccount(account)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Collect the addresses first since the manager can not be queried
+	// while it is being iterated.
+	var addrs []coinutil.Address
+	err = w.Manager.ForEachActiveAddress(func(addr coinutil.Address) error {
+		addrs = append(addrs, addr)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	syncedTo := w.Manager.SyncedTo()
+	birthday := w.Manager.StartBlock()
+
+	bw := bufio.NewWriter(out)
+	fmt.Fprintf(bw, "# Wallet dump created by btcwallet\n")
+	fmt.Fprintf(bw, "%s %d\n", dumpVersionPrefix, dumpFormatVersion)
+	fmt.Fprintf(bw, "# * Created on %s\n",
+		time.Now().UTC().Format(dumpTimeFormat))
+	fmt.Fprintf(bw, "# * Network: %s\n", w.chainParams.Name)
+	fmt.Fprintf(bw, "# * Best block at time of backup was %d (%v)\n",
+		syncedTo.Height, syncedTo.Hash)
+	fmt.Fprintf(bw, "# * Wallet birthday is block %d (%v)\n",
+		birthday.Height, birthday.Hash)
+	fmt.Fprintf(bw, "\n")
+	for _, account := range accounts {
+		fmt.Fprintf(bw, "# account=%d name=%s\n", account,
+			encodeDumpString(accountNames[account]))
+	}
+	fmt.Fprintf(bw, "\n")
+
+	for _, addr := range addrs {
+		ma, err := w.Manager.Address(addr)
+		if err != nil {
+			return err
+		}
+		details, err := w.Manager.AddrDetails(addr)
+		if err != nil {
+			return err
+		}
+
+		var key, attrs string
+		switch ma := ma.(type) {
+		case waddrmgr.ManagedPubKeyAddress:
+			if watchingOnly[ma.Account()] {
+				continue
+			}
+			wif, err := ma.ExportPrivKey()
+			if err != nil {
+				return err
+			}
+			key = wif.String()
+		case waddrmgr.ManagedScriptAddress:
+			script, err := ma.Script()
+			if err != nil {
+				return err
+			}
+			key = hex.EncodeToString(script)
+			attrs = "script=1 "
+		default:
+			continue
+		}
+		if ma.Internal() {
+			attrs += "change=1"
+		} else {
+			attrs += "label=" + encodeDumpString(accountNames[ma.Account()])
+		}
+
+		fmt.Fprintf(bw, "%s %s %s # addr=%s", key,
+			details.AddTime.UTC().Format(dumpTimeFormat), attrs,
+			addr.EncodeAddress())
+		if details.Chained {
+			fmt.Fprintf(bw, " hdkeypath=m/44'/%d'/%d'/%d/%d",
+				w.chainParams.HDCoinType, details.Account,
+				details.Branch, details.Index)
+		}
+		fmt.Fprintf(bw, " birthday=%d\n", birthday.Height)
+	}
+
+	fmt.Fprintf(bw, "\n# End of dump\n")
+	return bw.Flush()
+}
+
+// chainedPubKeyAddress returns the P2PKH address of the key at the passed
+// branch and index of an account of the wallet.
+func (w *Wallet) chainedPubKeyAddress(account, branch, index uint32) (coinutil.Address, error) {
+	acctKey, err := w.Manager.AccountPubKey(account)
+	if err != nil {
+		return nil, err
+	}
+	defer acctKey.Zero()
+	branchKey, err := acctKey.Child(branch)
+	if err != nil {
+		return nil, err
+	}
+	defer branchKey.Zero()
+	key, err := branchKey.Child(index)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Zero()
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	return coinutil.NewAddressPubKeyHash(
+		coinutil.Hash160(pubKey.SerializeCompressed()), w.chainParams)
+}
+
+// restorable returns whether a chained key of a wallet dump is derived at its
+// dumped path of an existing HD account of the wallet, which is the case when
+// the wallet was created from the seed of the dumped wallet.
+func (w *Wallet) restorable(entry *dumpEntry, wif *coinutil.WIF) (bool, error) {
+	last, err := w.Manager.LastAccount()
+	if err != nil {
+		return false, err
+	}
+	if !entry.chained || entry.account > last || entry.branch > 1 {
+		return false, nil
+	}
+	watchingOnly, err := w.Manager.IsWatchingOnlyAccount(entry.account)
+	if err != nil {
+		return false, err
+	}
+	reqSigs, _, err := w.Manager.AccountMultisig(entry.account)
+	if err != nil {
+		return false, err
+	}
+	if watchingOnly || reqSigs != 0 {
+		return false, nil
+	}
+
+	addr, err := w.chainedPubKeyAddress(entry.account, entry.branch,
+		entry.index)
+	if err != nil {
+		return false, nil
+	}
+	wifAddr, err := coinutil.NewAddressPubKeyHash(
+		coinutil.Hash160(wif.SerializePubKey()), w.chainParams)
+	if err != nil {
+		return false, err
+	}
+	return addr.EncodeAddress() == wifAddr.EncodeAddress(), nil
+}
+
+// createDumpedAccounts creates every account of a wallet dump which the
+// wallet does not have yet, up to the last account of a chained key, using the
+// dumped account names when they are not already taken.
+func (w *Wallet) createDumpedAccounts(entries []*dumpEntry, names map[uint32]string) error {
+	last, err := w.Manager.LastAccount()
+	if err != nil {
+		return err
+	}
+	var end uint32
+	for _, entry := range entries {
+		if entry.chained && entry.account > last && entry.account >= end {
+			end = entry.account + 1
+		}
+	}
+	for account := last + 1; account < end; account++ {
+		name, ok := names[account]
+		if ok {
+			_, err := w.Manager.LookupAccount(name)
+			ok = waddrmgr.IsError(err, waddrmgr.ErrAccountNotFound)
+		}
+		if !ok {
+			name = fmt.Sprintf("account-%d", account)
+		}
+		if _, err := w.Manager.NewAccount(name); err != nil {
+			return err
+		}
+		log.Infof("Created account %d (%s) from wallet dump", account,
+			name)
+	}
+	return nil
+}
+
+// ImportWallet reads a wallet dump in the format described at the top of this
+// file and imports every private key and script in it.  Keys derived from the
+// seed of the wallet are restored to their accounts by deriving the addresses
+// of each account branch up to the last dumped index, so they remain part of
+// their HD account rather than becoming imported keys.  When the keys of the
+// existing accounts show the wallet shares the seed of the dumped wallet, the
+// accounts it is missing are created first with their dumped names.  All other
+// keys and scripts, including those of dumps written by the reference
+// implementation, are imported into the imported account.  Keys and scripts
+// which are already known to the wallet are skipped.  After all keys have been
+// imported, a single rescan for the newly imported and derived addresses is
+// started from the earliest birthday of the imported keys, or from the
+// genesis block when any key does not record a birthday.  The wallet must be
+// unlocked.
+func (w *Wallet) ImportWallet(in io.Reader) error {
+	var entries []*dumpEntry
+	names := make(map[uint32]string)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if account, name, ok := parseDumpAccount(line); ok {
+				names[account] = name
+				continue
+			}
+			if !strings.HasPrefix(line, dumpVersionPrefix) {
+				continue
+			}
+			v := strings.TrimSpace(line[len(dumpVersionPrefix):])
+			version, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid wallet dump version %q", v)
+			}
+			if version > dumpFormatVersion {
+				return fmt.Errorf("unsupported wallet dump "+
+					"version %d", version)
+			}
+			continue
+		}
+
+		entry, err := parseDumpLine(line)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// Decode every key before importing anything so a malformed dump does
+	// not result in a partial import.  Keys without a birthday may have
+	// been used at any point in the chain, so the rescan must begin at the
+	// genesis block when any are found.
+	wifs := make([]*coinutil.WIF, len(entries))
+	scripts := make([][]byte, len(entries))
+	earliest := entries[0].birthday
+	for i, entry := range entries {
+		if entry.script {
+			script, err := hex.DecodeString(entry.key)
+			if err != nil {
+				return fmt.Errorf("invalid script in wallet "+
+					"dump: %v", err)
+			}
+			scripts[i] = script
+		} else {
+			wif, err := coinutil.DecodeWIF(entry.key)
+			if err != nil {
+				return fmt.Errorf("invalid private key in "+
+					"wallet dump: %v", err)
+			}
+			wifs[i] = wif
+		}
+		if entry.birthday < earliest {
+			earliest = entry.birthday
+		}
+	}
+	bs := waddrmgr.BlockStamp{Hash: *w.chainParams.GenesisHash}
+	if earliest > 0 {
+		hash, err := w.chainSvr.GetBlockHash(int64(earliest))
+		if err != nil {
+			return err
+		}
+		bs = waddrmgr.BlockStamp{Hash: *hash, Height: earliest}
+	}
+
+	// Keys of existing accounts derived at their dumped paths show the
+	// wallet was created from the seed of the dumped wallet, so the
+	// accounts it is missing are created and restored as well.
+	for i, entry := range entries {
+		if wifs[i] == nil {
+			continue
+		}
+		ok, err := w.restorable(entry, wifs[i])
+		if err != nil {
+			return err
+		}
+		if ok {
+			err := w.createDumpedAccounts(entries, names)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	restore := make([]bool, len(entries))
+	ends := make(map[uint32]*[2]uint32)
+	for i, entry := range entries {
+		if wifs[i] == nil {
+			continue
+		}
+		ok, err := w.restorable(entry, wifs[i])
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		restore[i] = true
+		if ends[entry.account] == nil {
+			ends[entry.account] = new([2]uint32)
+		}
+		if end := &ends[entry.account][entry.branch]; entry.index >= *end {
+			*end = entry.index + 1
+		}
+	}
+
+	// Derive the addresses of each account branch up to the last restored
+	// key.  The gap limit does not apply since the addresses were handed
+	// out by the dumped wallet and are rescanned below.
+	var addrs []coinutil.Address
+	var restored int
+	for account, end := range ends {
+		external, internal, _, err := w.accountUsage(account)
+		if err != nil {
+			return err
+		}
+		for i, entry := range entries {
+			if !restore[i] || entry.account != account {
+				continue
+			}
+			// Branch 1 is the internal branch of change addresses.
+			next := external.next
+			if entry.branch == 1 {
+				next = internal.next
+			}
+			if entry.index >= next {
+				restored++
+			}
+		}
+		if end[0] > external.next {
+			mas, err := w.Manager.RestoreExternalAddresses(account,
+				end[0]-external.next)
+			if err != nil {
+				return err
+			}
+			for _, ma := range mas {
+				addrs = append(addrs, ma.Address())
+			}
+		}
+		if end[1] > internal.next {
+			mas, err := w.Manager.NextInternalAddresses(account,
+				end[1]-internal.next)
+			if err != nil {
+				return err
+			}
+			for _, ma := range mas {
+				addrs = append(addrs, ma.Address())
+			}
+		}
+	}
+
+	var imported int
+	for i := range entries {
+		if restore[i] {
+			continue
+		}
+		var ma waddrmgr.ManagedAddress
+		var err error
+		if scripts[i] != nil {
+			ma, err = w.Manager.ImportScript(scripts[i], &bs)
+		} else {
+			ma, err = w.Manager.ImportPrivateKey(wifs[i], &bs)
+		}
+		if waddrmgr.IsError(err, waddrmgr.ErrDuplicateAddress) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		addrs = append(addrs, ma.Address())
+		imported++
+	}
+
+	log.Infof("Restored %d %s to their accounts and imported %d from "+
+		"wallet dump (%d already present)", restored,
+		pickNoun(restored, "key", "keys"), imported,
+		len(entries)-restored-imported)
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	// Submit a single rescan for all imported addresses.  Do not block on
+	// the rescan finishing; its result is logged by the rescan handlers.
+	job := &RescanJob{
+		Addrs:      addrs,
+		OutPoints:  nil,
+		BlockStamp: bs,
+	}
+	_ = w.SubmitRescan(job)
+	return nil
+}
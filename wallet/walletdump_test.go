@@ -0,0 +1,161 @@
+package wallet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDumpString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"default", "default"},
+		{"savings account", "savings%20account"},
+		{"100%", "100%25"},
+		{"tab\there", "tab%09here"},
+		{"caf\xc3\xa9", "caf%c3%a9"},
+	}
+	for i, test := range tests {
+		if got := encodeDumpString(test.in); got != test.want {
+			t.Errorf("Test %d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestDecodeDumpString(t *testing.T) {
+	for _, s := range []string{"default", "savings account", "100%",
+		"tab\there", "caf\xc3\xa9"} {
+		got, err := decodeDumpString(encodeDumpString(s))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", s, err)
+			continue
+		}
+		if got != s {
+			t.Errorf("%q: decoded as %q", s, got)
+		}
+	}
+	for _, s := range []string{"100%", "100%2", "100%zz"} {
+		if _, err := decodeDumpString(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestParseDumpAccount(t *testing.T) {
+	tests := []struct {
+		line    string
+		account uint32
+		name    string
+		ok      bool
+	}{
+		{"# account=0 name=default", 0, "default", true},
+		{"# account=3 name=savings%20account", 3, "savings account", true},
+		{"# * Format version: 1", 0, "", false},
+		{"# account=x name=default", 0, "", false},
+		{"# account=1", 0, "", false},
+	}
+	for _, test := range tests {
+		account, name, ok := parseDumpAccount(test.line)
+		if ok != test.ok || account != test.account || name != test.name {
+			t.Errorf("%q: got (%d, %q, %v), want (%d, %q, %v)",
+				test.line, account, name, ok, test.account,
+				test.name, test.ok)
+		}
+	}
+}
+
+func TestParseDumpLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    *dumpEntry
+		wantErr bool
+	}{
+		{
+			name: "chained key",
+			line: "cVDJUtDjdaM25yNVVDLLX3hcHUfth4c7tY3rSc4hy9e8ibtCuj6G " +
+				"2015-11-23T12:00:00Z label=default # " +
+				"addr=mjqnv9JoxdYyQK7NMZGCKLxNWHfA6XFVC7 " +
+				"hdkeypath=m/44'/1'/0'/0/3 birthday=350000",
+			want: &dumpEntry{
+				key:      "cVDJUtDjdaM25yNVVDLLX3hcHUfth4c7tY3rSc4hy9e8ibtCuj6G",
+				birthday: 350000,
+				chained:  true,
+				index:    3,
+			},
+		},
+		{
+			name: "chained change key",
+			line: "cVDJUtDjdaM25yNVVDLLX3hcHUfth4c7tY3rSc4hy9e8ibtCuj6G " +
+				"2015-11-23T12:00:00Z change=1 # " +
+				"addr=mjqnv9JoxdYyQK7NMZGCKLxNWHfA6XFVC7 " +
+				"hdkeypath=m/44'/1'/2'/1/7 birthday=350000",
+			want: &dumpEntry{
+				key:      "cVDJUtDjdaM25yNVVDLLX3hcHUfth4c7tY3rSc4hy9e8ibtCuj6G",
+				birthday: 350000,
+				chained:  true,
+				account:  2,
+				branch:   1,
+				index:    7,
+			},
+		},
+		{
+			name: "reference implementation key path",
+			line: "cVDJUtDjdaM25yNVVDLLX3hcHUfth4c7tY3rSc4hy9e8ibtCuj6G " +
+				"2015-11-23T12:00:00Z label= # " +
+				"addr=mjqnv9JoxdYyQK7NMZGCKLxNWHfA6XFVC7 " +
+				"hdkeypath=m/0'/0'/5'",
+			want: &dumpEntry{
+				key:      "cVDJUtDjdaM25yNVVDLLX3hcHUfth4c7tY3rSc4hy9e8ibtCuj6G",
+				birthday: -1,
+			},
+		},
+		{
+			name: "script",
+			line: "5221abcd 2015-11-23T12:00:00Z script=1 label=imported " +
+				"# addr=2N3oefVeg6stiTb5Kh3ozCSkaqmx91FDbsm birthday=0",
+			want: &dumpEntry{
+				key:      "5221abcd",
+				script:   true,
+				birthday: 0,
+			},
+		},
+		{
+			name: "reference implementation key without birthday",
+			line: "cVDJUtDjdaM25yNVVDLLX3hcHUfth4c7tY3rSc4hy9e8ibtCuj6G " +
+				"2015-11-23T12:00:00Z reserve=1 # " +
+				"addr=mjqnv9JoxdYyQK7NMZGCKLxNWHfA6XFVC7",
+			want: &dumpEntry{
+				key:      "cVDJUtDjdaM25yNVVDLLX3hcHUfth4c7tY3rSc4hy9e8ibtCuj6G",
+				birthday: -1,
+			},
+		},
+		{
+			name:    "missing time",
+			line:    "cVDJUtDjdaM25yNVVDLLX3hcHUfth4c7tY3rSc4hy9e8ibtCuj6G",
+			wantErr: true,
+		},
+		{
+			name: "invalid birthday",
+			line: "cVDJUtDjdaM25yNVVDLLX3hcHUfth4c7tY3rSc4hy9e8ibtCuj6G " +
+				"2015-11-23T12:00:00Z # birthday=-5",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		got, err := parseDumpLine(test.line)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package wallet
+
+import (
+	"github.com/conseweb/coinutil/hdkeychain"
+	"github.com/conseweb/stcwallet/waddrmgr"
+)
+
+// ImportAccount creates a watching-only account named name from the extended
+// public key of an account kept elsewhere, such as in cold storage, and
+// returns its account number.  The wallet tracks the outputs of the account's
+// addresses and creates unsigned transactions spending them, but never signs
+// for the account.  When rescan is true, addresses of both branches are
+// derived up to the gap limit past the last used address, and the chain is
+// rescanned for them from the genesis block, since the birthday of the
+// account is not known.
+func (w *Wallet) ImportAccount(name string, acctKeyPub *hdkeychain.ExtendedKey,
+	rescan bool) (uint32, error) {
+
+	account, err := w.Manager.ImportAccount(name, acctKeyPub)
+	if err != nil {
+		return 0, err
+	}
+	log.Infof("Imported watching-only account %d (%s)", account, name)
+	if !rescan {
+		return account, nil
+	}
+
+	gapLimit := w.Manager.GapLimit()
+	if gapLimit == 0 {
+		gapLimit = DefaultGapLimit
+	}
+	bs := waddrmgr.BlockStamp{Hash: *w.chainParams.GenesisHash}
+	_, err = w.discoverAccount(account, gapLimit, bs, w.rescanAndWait)
+	if err != nil {
+		return account, err
+	}
+	return account, nil
+}
@@ -18,7 +18,9 @@ package bdb
 
 import (
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/conseweb/bolt"
 	"github.com/conseweb/stcwallet/walletdb"
@@ -413,6 +415,43 @@ func (db *db) Copy(w io.Writer) error {
 	}))
 }
 
+// CopyFile writes a copy of the database to a new file at the provided path.
+// The copy is taken from a single read-only transaction, so it is consistent
+// even while other goroutines continue to update the database.  The copy is
+// first written and synced to a temporary file in the destination directory
+// and then renamed to path, so a partial copy is never left behind on error.
+//
+// This function is part of the walletdb.Db interface implementation.
+func (db *db) CopyFile(path string) error {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	fi, err := ioutil.TempFile(dir, name+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := fi.Name()
+
+	err = db.Copy(fi)
+	if err == nil {
+		err = fi.Sync()
+	}
+	if closeErr := fi.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
 // Close cleanly shuts down the database and syncs all data.
 //
 // This function is part of the walletdb.Db interface implementation.
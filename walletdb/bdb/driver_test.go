@@ -199,3 +199,78 @@ func TestInterface(t *testing.T) {
 	// Run all of the interface tests against the database.
 	testInterface(t, db)
 }
+
+// TestCopyFile ensures that a copy of an open database written with CopyFile
+// can be opened and contains the values stored before the copy was made, and
+// that later writes to the original database are not reflected in the copy.
+func TestCopyFile(t *testing.T) {
+	// Create a new database to run tests against.
+	dbPath := "copyfiletest.db"
+	db, err := walletdb.Create(dbType, dbPath)
+	if err != nil {
+		t.Errorf("Failed to create test database (%s) %v", dbType, err)
+		return
+	}
+	defer os.Remove(dbPath)
+	defer db.Close()
+
+	ns1Key := []byte("ns1")
+	ns1, err := db.Namespace(ns1Key)
+	if err != nil {
+		t.Errorf("Namespace: unexpected error: %v", err)
+		return
+	}
+	err = ns1.Update(func(tx walletdb.Tx) error {
+		return tx.RootBucket().Put([]byte("ns1key1"), []byte("foo1"))
+	})
+	if err != nil {
+		t.Errorf("ns1 Update: unexpected error: %v", err)
+		return
+	}
+
+	// Copy the database while it is still open and then modify the
+	// original.
+	copyPath := "copyfiletest-copy.db"
+	if err := db.CopyFile(copyPath); err != nil {
+		t.Errorf("CopyFile: unexpected error: %v", err)
+		return
+	}
+	defer os.Remove(copyPath)
+	err = ns1.Update(func(tx walletdb.Tx) error {
+		return tx.RootBucket().Put([]byte("ns1key2"), []byte("foo2"))
+	})
+	if err != nil {
+		t.Errorf("ns1 Update: unexpected error: %v", err)
+		return
+	}
+
+	// Open the copy and ensure it contains only the values written before
+	// the copy was made.
+	copyDB, err := walletdb.Open(dbType, copyPath)
+	if err != nil {
+		t.Errorf("Failed to open copied database (%s) %v", dbType, err)
+		return
+	}
+	defer copyDB.Close()
+	copyNS1, err := copyDB.Namespace(ns1Key)
+	if err != nil {
+		t.Errorf("Namespace: unexpected error: %v", err)
+		return
+	}
+	err = copyNS1.View(func(tx walletdb.Tx) error {
+		rootBucket := tx.RootBucket()
+		if gotVal := rootBucket.Get([]byte("ns1key1")); !reflect.DeepEqual(gotVal, []byte("foo1")) {
+			return fmt.Errorf("Get: key 'ns1key1' does not match "+
+				"expected value - got %s, want foo1", gotVal)
+		}
+		if gotVal := rootBucket.Get([]byte("ns1key2")); gotVal != nil {
+			return fmt.Errorf("Get: key 'ns1key2' written after the "+
+				"copy was found in the copy with value %s", gotVal)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("copy ns1 View: unexpected error: %v", err)
+		return
+	}
+}
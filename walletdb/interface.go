@@ -202,6 +202,13 @@ type DB interface {
 	// call will start a read-only transaction to perform all operations.
 	Copy(w io.Writer) error
 
+	// CopyFile writes a consistent copy of the database to a new file at
+	// the provided path.  Like Copy, all operations are performed in a
+	// single read-only transaction so the database may continue to be used
+	// while the copy is in progress.  Implementations must not leave a
+	// partially written file at path when an error is returned.
+	CopyFile(path string) error
+
 	// Close cleanly shuts down the database and syncs all data.
 	Close() error
 }
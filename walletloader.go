@@ -0,0 +1,356 @@
+// Copyright (c) 2015 The btcsuite developers
+//
+// Permission to use, copy, modify, and distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/conseweb/stcwallet/chain"
+	"github.com/conseweb/stcwallet/internal/cfgutil"
+	"github.com/conseweb/stcwallet/signer"
+	"github.com/conseweb/stcwallet/wallet"
+	"github.com/conseweb/stcwallet/walletdb"
+)
+
+// walletsDirName is the name of the directory of the network directory which
+// holds the named wallets, each in a directory of its own.
+const walletsDirName = "wallets"
+
+// maxWalletNameLen is the maximum length of a wallet name.
+const maxWalletNameLen = 64
+
+// Errors returned when loading and unloading named wallets.
+var (
+	errWalletLoaded    = errors.New("wallet is already loaded")
+	errWalletNotLoaded = errors.New("wallet is not loaded")
+	errWalletNotExist  = errors.New("wallet does not exist")
+)
+
+// checkWalletName returns an error if name can not be the name of a wallet.
+// Names are used as directory names and in the URL path of requests, so only
+// letters, digits, '-', '_' and '.' are allowed, and names may not begin with
+// a '.'.
+func checkWalletName(name string) error {
+	if name == "" || len(name) > maxWalletNameLen {
+		return fmt.Errorf("wallet names must be between 1 and %d "+
+			"characters long", maxWalletNameLen)
+	}
+	if name[0] == '.' {
+		return errors.New("wallet names may not begin with '.'")
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z',
+			c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return fmt.Errorf("invalid character %q in wallet name", c)
+		}
+	}
+	return nil
+}
+
+// namedWalletDir returns the directory of the database of a named wallet.
+func namedWalletDir(dataDir, name string) string {
+	netDir := networkDir(dataDir, activeNet.Params)
+	return filepath.Join(netDir, walletsDirName, name)
+}
+
+// walletDbPaths returns the paths of the databases of the default wallet and
+// every named wallet, whether loaded or not.
+func walletDbPaths() []string {
+	netDir := networkDir(cfg.DataDir, activeNet.Params)
+	paths := []string{filepath.Join(netDir, walletDbName)}
+	named, _ := filepath.Glob(filepath.Join(netDir, walletsDirName, "*",
+		walletDbName))
+	return append(paths, named...)
+}
+
+// walletNameFromPath returns the wallet name of the URL path of a request,
+// which is "/wallet/<name>" for requests of a named wallet.  The name is empty
+// for requests of the default wallet.
+func walletNameFromPath(path string) string {
+	const prefix = "/wallet/"
+	if !strings.HasPrefix(path, prefix) {
+		return ""
+	}
+	return strings.TrimSuffix(path[len(prefix):], "/")
+}
+
+// loadedWallet is a named wallet loaded by the walletLoader, with its own
+// database and connection to the chain server.
+type loadedWallet struct {
+	name   string
+	wallet *wallet.Wallet
+	db     walletdb.DB
+
+	chainSvr    *chain.Client
+	chainSvrMtx sync.Mutex
+
+	// quit is closed to stop syncing the wallet with the chain server,
+	// and done is closed once syncing stopped.
+	quit chan struct{}
+	done chan struct{}
+
+	// requestsMtx is held for reading by every request of the wallet for
+	// its duration, and for writing when the wallet is stopped, so the
+	// wallet and its database are never closed under a running request.
+	// stopped is set once no further requests are served.
+	requestsMtx sync.RWMutex
+	stopped     bool
+}
+
+// acquire marks the start of a request of the wallet.  It returns false if the
+// wallet was stopped, in which case the request must not be served.  Otherwise
+// release must be called once the request finished.
+func (lw *loadedWallet) acquire() bool {
+	lw.requestsMtx.RLock()
+	if lw.stopped {
+		lw.requestsMtx.RUnlock()
+		return false
+	}
+	return true
+}
+
+// release marks the end of a request started by acquire.
+func (lw *loadedWallet) release() {
+	lw.requestsMtx.RUnlock()
+}
+
+// stopRequests waits for the running requests of the wallet to finish and
+// refuses every later request.
+func (lw *loadedWallet) stopRequests() {
+	lw.requestsMtx.Lock()
+	lw.stopped = true
+	lw.requestsMtx.Unlock()
+}
+
+// ChainServer returns the current chain server RPC client of the wallet, or
+// nil if the wallet was never connected.
+func (lw *loadedWallet) ChainServer() *chain.Client {
+	lw.chainSvrMtx.Lock()
+	chainSvr := lw.chainSvr
+	lw.chainSvrMtx.Unlock()
+	return chainSvr
+}
+
+// setChainServer sets the chain server RPC client the wallet is synced with.
+func (lw *loadedWallet) setChainServer(chainSvr *chain.Client) {
+	lw.chainSvrMtx.Lock()
+	lw.chainSvr = chainSvr
+	lw.chainSvrMtx.Unlock()
+}
+
+// stop waits for the running requests of the wallet, stops the wallet and its
+// connection to the chain server, waits for them to finish, and closes the
+// wallet database.
+func (lw *loadedWallet) stop() {
+	lw.stopRequests()
+	close(lw.quit)
+	lw.wallet.Stop()
+	if chainSvr := lw.ChainServer(); chainSvr != nil {
+		chainSvr.Stop()
+	}
+	<-lw.done
+	lw.wallet.WaitForShutdown()
+	if err := lw.db.Close(); err != nil {
+		log.Errorf("Cannot close database of wallet %q: %v", lw.name,
+			err)
+	}
+}
+
+// walletLoader loads and unloads the named wallets served by the RPC server in
+// addition to its default wallet.  Each named wallet has its own database in a
+// directory of the network directory, and each loaded wallet is synced with
+// the chain server over a connection of its own.
+type walletLoader struct {
+	mtx     sync.Mutex
+	wallets map[string]*loadedWallet
+
+	// offline is set when wallets are never attached to the chain server.
+	offline bool
+
+	// signer, when set, is asked for the signatures of keys a loaded
+	// wallet does not hold itself.
+	signer signer.Signer
+}
+
+// newWalletLoader returns a walletLoader with no loaded wallets.
+func newWalletLoader() *walletLoader {
+	return &walletLoader{wallets: make(map[string]*loadedWallet)}
+}
+
+// SetOffline marks that wallets loaded from now on are never attached to the
+// chain server, and only serve the requests of an offline wallet.
+func (l *walletLoader) SetOffline() {
+	l.mtx.Lock()
+	l.offline = true
+	l.mtx.Unlock()
+}
+
+// Offline returns whether wallets are never attached to the chain server.
+func (l *walletLoader) Offline() bool {
+	l.mtx.Lock()
+	offline := l.offline
+	l.mtx.Unlock()
+	return offline
+}
+
+// SetSigner sets the external signer asked for the signatures of keys the
+// wallets loaded from now on do not hold themselves.
+func (l *walletLoader) SetSigner(remote signer.Signer) {
+	l.mtx.Lock()
+	l.signer = remote
+	l.mtx.Unlock()
+}
+
+// Signer returns the external signer set by SetSigner, or nil if none is set.
+func (l *walletLoader) Signer() signer.Signer {
+	l.mtx.Lock()
+	remote := l.signer
+	l.mtx.Unlock()
+	return remote
+}
+
+// Wallet returns the loaded wallet with the passed name, or nil if no such
+// wallet is loaded.
+func (l *walletLoader) Wallet(name string) *loadedWallet {
+	l.mtx.Lock()
+	lw := l.wallets[name]
+	l.mtx.Unlock()
+	return lw
+}
+
+// Names returns the names of the loaded wallets, sorted.
+func (l *walletLoader) Names() []string {
+	l.mtx.Lock()
+	names := make([]string, 0, len(l.wallets))
+	for name := range l.wallets {
+		names = append(names, name)
+	}
+	l.mtx.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+// Load opens the existing named wallet with the public passphrase and starts
+// syncing it with the chain server, or starts it offline.  The public
+// passphrase of the config is used if pubPass is nil.
+func (l *walletLoader) Load(name string, pubPass []byte) (*loadedWallet, error) {
+	if err := checkWalletName(name); err != nil {
+		return nil, err
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if _, ok := l.wallets[name]; ok {
+		return nil, errWalletLoaded
+	}
+	dir := namedWalletDir(cfg.DataDir, name)
+	exists, err := cfgutil.FileExists(filepath.Join(dir, walletDbName))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errWalletNotExist
+	}
+
+	if pubPass == nil {
+		pubPass = []byte(cfg.WalletPass)
+	}
+	w, db, err := openWalletDir(dir, pubPass)
+	if err != nil {
+		if db != nil {
+			db.Close()
+		}
+		return nil, err
+	}
+	if l.signer != nil {
+		w.Signer = signer.Fallback(w.Signer, l.signer)
+	}
+
+	lw := &loadedWallet{
+		name:   name,
+		wallet: w,
+		db:     db,
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if l.offline {
+		w.StartOffline()
+		close(lw.done)
+	} else {
+		go func() {
+			syncWithChainServer(w, lw.setChainServer, lw.quit)
+			close(lw.done)
+		}()
+	}
+	l.wallets[name] = lw
+
+	log.Infof("Loaded wallet %q", name)
+	return lw, nil
+}
+
+// Unload stops the named wallet and closes its database.
+func (l *walletLoader) Unload(name string) error {
+	l.mtx.Lock()
+	lw, ok := l.wallets[name]
+	delete(l.wallets, name)
+	l.mtx.Unlock()
+	if !ok {
+		return errWalletNotLoaded
+	}
+
+	lw.stop()
+	log.Infof("Unloaded wallet %q", name)
+	return nil
+}
+
+// UnloadAll stops every loaded wallet and closes their databases.
+func (l *walletLoader) UnloadAll() {
+	l.mtx.Lock()
+	wallets := l.wallets
+	l.wallets = make(map[string]*loadedWallet)
+	l.mtx.Unlock()
+
+	for _, lw := range wallets {
+		lw.stop()
+	}
+}
+
+// createNamedWallet creates a named wallet with the console prompts used to
+// create the default wallet.
+func createNamedWallet(cfg *config, name string) error {
+	if err := checkWalletName(name); err != nil {
+		return err
+	}
+	dir := namedWalletDir(cfg.DataDir, name)
+	exists, err := cfgutil.FileExists(filepath.Join(dir, walletDbName))
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the wallet %q already exists", name)
+	}
+	if err := checkCreateDir(dir); err != nil {
+		return err
+	}
+	return createWallet(cfg, dir)
+}
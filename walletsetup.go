@@ -31,6 +31,7 @@ import (
 	"github.com/conseweb/stcd/btcec"
 	"github.com/conseweb/stcd/chaincfg"
 	"github.com/conseweb/stcd/wire"
+	"github.com/conseweb/stcwallet/internal/bip39"
 	"github.com/conseweb/stcwallet/internal/legacy/keystore"
 	"github.com/conseweb/stcwallet/waddrmgr"
 	"github.com/conseweb/stcwallet/wallet"
@@ -64,28 +65,7 @@ func networkDir(dataDir string, chainParams *chaincfg.Params) string {
 // promptSeed is used to prompt for the wallet seed which maybe required during
 // upgrades.
 func promptSeed() ([]byte, error) {
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		fmt.Print("Enter existing wallet seed: ")
-		seedStr, err := reader.ReadString('\n')
-		if err != nil {
-			return nil, err
-		}
-		seedStr = strings.TrimSpace(strings.ToLower(seedStr))
-
-		seed, err := hex.DecodeString(seedStr)
-		if err != nil || len(seed) < hdkeychain.MinSeedBytes ||
-			len(seed) > hdkeychain.MaxSeedBytes {
-
-			fmt.Printf("Invalid seed specified.  Must be a "+
-				"hexadecimal value that is at least %d bits and "+
-				"at most %d bits\n", hdkeychain.MinSeedBytes*8,
-				hdkeychain.MaxSeedBytes*8)
-			continue
-		}
-
-		return seed, nil
-	}
+	return promptExistingSeed(bufio.NewReader(os.Stdin))
 }
 
 // promptPrivPassPhrase is used to prompt for the private passphrase which maybe
@@ -300,10 +280,11 @@ func promptConsolePublicPass(reader *bufio.Reader, privPass []byte, cfg *config)
 }
 
 // promptConsoleSeed prompts the user whether they want to use an existing
-// wallet generation seed.  When the user answers no, a seed will be generated
-// and displayed to the user along with prompting them for confirmation.  When
-// the user answers yes, a the user is prompted for it.  All prompts are
-// repeated until the user enters a valid response.
+// wallet generation seed.  When the user answers no, a BIP0039 mnemonic, or a
+// hex seed if the user prefers, is generated and displayed to the user along
+// with prompting them for confirmation.  When the user answers yes, the user
+// is prompted for either a mnemonic or a hex seed.  All prompts are repeated
+// until the user enters a valid response.
 func promptConsoleSeed(reader *bufio.Reader) ([]byte, error) {
 	// Ascertain the wallet generation seed.
 	useUserSeed, err := promptConsoleListBool(reader, "Do you have an "+
@@ -311,51 +292,127 @@ func promptConsoleSeed(reader *bufio.Reader) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	if !useUserSeed {
-		seed, err := hdkeychain.GenerateSeed(hdkeychain.RecommendedSeedLen)
+	if useUserSeed {
+		seed, err := promptExistingSeed(reader)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Println("NOTE: Only the first account of a restored wallet " +
+			"is created.  Once the wallet\nis running, unlock it and " +
+			"use the discoveraccounts RPC to recover every\naccount " +
+			"and address used with the seed.")
+		return seed, nil
+	}
+
+	format, err := promptConsoleList(reader, "Generate the seed as a "+
+		"mnemonic or a hexadecimal value?", []string{"mnemonic", "hex"},
+		"mnemonic")
+	if err != nil {
+		return nil, err
+	}
+	var seed []byte
+	if format == "hex" {
+		seed, err = hdkeychain.GenerateSeed(hdkeychain.RecommendedSeedLen)
 		if err != nil {
 			return nil, err
 		}
 
 		fmt.Println("Your wallet generation seed is:")
 		fmt.Printf("%x\n", seed)
-		fmt.Println("IMPORTANT: Keep the seed in a safe place as you\n" +
-			"will NOT be able to restore your wallet without it.")
-		fmt.Println("Please keep in mind that anyone who has access\n" +
-			"to the seed can also restore your wallet thereby\n" +
-			"giving them access to all your funds, so it is\n" +
-			"imperative that you keep it in a secure location.")
-
-		for {
-			fmt.Print(`Once you have stored the seed in a safe ` +
-				`and secure location, enter "OK" to continue: `)
-			confirmSeed, err := reader.ReadString('\n')
-			if err != nil {
-				return nil, err
-			}
-			confirmSeed = strings.TrimSpace(confirmSeed)
-			confirmSeed = strings.Trim(confirmSeed, `"`)
-			if confirmSeed == "OK" {
-				break
-			}
+	} else {
+		numWords, err := promptConsoleList(reader, "Number of mnemonic "+
+			"words", []string{"12", "24"}, "24")
+		if err != nil {
+			return nil, err
+		}
+		bits := bip39.RecommendedEntropyBits
+		if numWords == "12" {
+			bits = bip39.MinEntropyBits
+		}
+		entropy, err := bip39.NewEntropy(bits)
+		if err != nil {
+			return nil, err
+		}
+		mnemonic, err := bip39.NewMnemonic(entropy)
+		if err != nil {
+			return nil, err
+		}
+		passphrase, err := promptMnemonicPassphrase(reader, "Do you "+
+			"want to protect the mnemonic with a passphrase?")
+		if err != nil {
+			return nil, err
+		}
+		seed, err = bip39.Seed(mnemonic, string(passphrase))
+		if err != nil {
+			return nil, err
 		}
 
-		return seed, nil
+		fmt.Println("Your wallet generation mnemonic is:")
+		fmt.Println(mnemonic)
+		if len(passphrase) != 0 {
+			fmt.Println("IMPORTANT: The mnemonic passphrase is " +
+				"also required to restore your wallet.")
+		}
 	}
 
+	fmt.Println("IMPORTANT: Keep the seed in a safe place as you\n" +
+		"will NOT be able to restore your wallet without it.")
+	fmt.Println("Please keep in mind that anyone who has access\n" +
+		"to the seed can also restore your wallet thereby\n" +
+		"giving them access to all your funds, so it is\n" +
+		"imperative that you keep it in a secure location.")
+
+	for {
+		fmt.Print(`Once you have stored the seed in a safe ` +
+			`and secure location, enter "OK" to continue: `)
+		confirmSeed, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		confirmSeed = strings.TrimSpace(confirmSeed)
+		confirmSeed = strings.Trim(confirmSeed, `"`)
+		if confirmSeed == "OK" {
+			break
+		}
+	}
+
+	return seed, nil
+}
+
+// promptExistingSeed prompts the user for an existing wallet generation seed,
+// entered either as a hexadecimal value or as a BIP0039 mnemonic, in which
+// case the mnemonic checksum is validated and the user is prompted for the
+// mnemonic passphrase, if any.  All prompts are repeated until the user enters
+// a valid response.
+func promptExistingSeed(reader *bufio.Reader) ([]byte, error) {
 	for {
-		fmt.Print("Enter existing wallet seed: ")
+		fmt.Print("Enter existing wallet seed or mnemonic: ")
 		seedStr, err := reader.ReadString('\n')
 		if err != nil {
 			return nil, err
 		}
 		seedStr = strings.TrimSpace(strings.ToLower(seedStr))
 
+		// Mnemonics are the only seeds containing spaces.
+		if strings.ContainsAny(seedStr, " \t") {
+			if _, err := bip39.MnemonicEntropy(seedStr); err != nil {
+				fmt.Printf("Invalid mnemonic specified: %v\n", err)
+				continue
+			}
+			passphrase, err := promptMnemonicPassphrase(reader,
+				"Is the mnemonic protected by a passphrase?")
+			if err != nil {
+				return nil, err
+			}
+			return bip39.Seed(seedStr, string(passphrase))
+		}
+
 		seed, err := hex.DecodeString(seedStr)
 		if err != nil || len(seed) < hdkeychain.MinSeedBytes ||
 			len(seed) > hdkeychain.MaxSeedBytes {
 
 			fmt.Printf("Invalid seed specified.  Must be a "+
+				"mnemonic of 12 to 24 words, or a "+
 				"hexadecimal value that is at least %d bits and "+
 				"at most %d bits\n", hdkeychain.MinSeedBytes*8,
 				hdkeychain.MaxSeedBytes*8)
@@ -366,6 +423,30 @@ func promptConsoleSeed(reader *bufio.Reader) ([]byte, error) {
 	}
 }
 
+// promptMnemonicPassphrase asks the user the passed yes/no question of whether
+// a mnemonic is protected by a passphrase, and when the user answers yes,
+// prompts them for the passphrase and its confirmation.  A nil passphrase is
+// returned when the user answers no.  Only ASCII passphrases are accepted.
+func promptMnemonicPassphrase(reader *bufio.Reader, question string) ([]byte, error) {
+	usePassphrase, err := promptConsoleListBool(reader, question, "no")
+	if err != nil || !usePassphrase {
+		return nil, err
+	}
+
+	for {
+		passphrase, err := promptConsolePass(reader, "Enter the "+
+			"mnemonic passphrase", true)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.IndexFunc(passphrase, func(r rune) bool { return r > 0x7f }) != -1 {
+			fmt.Println(bip39.ErrPassphraseNotASCII)
+			continue
+		}
+		return passphrase, nil
+	}
+}
+
 // convertLegacyKeystore converts all of the addresses in the passed legacy
 // key store to the new waddrmgr.Manager format.  Both the legacy keystore and
 // the new manager must be unlocked.
@@ -423,14 +504,13 @@ func convertLegacyKeystore(legacyKeyStore *keystore.Store, manager *waddrmgr.Man
 }
 
 // createWallet prompts the user for information needed to generate a new wallet
-// and generates the wallet accordingly.  The new wallet will reside at the
-// provided path.
-func createWallet(cfg *config) error {
+// and generates the wallet accordingly.  The new wallet will reside in the
+// provided directory.
+func createWallet(cfg *config, dir string) error {
 	// When there is a legacy keystore, open it now to ensure any errors
 	// don't end up exiting the process after the user has spent time
 	// entering a bunch of information.
-	netDir := networkDir(cfg.DataDir, activeNet.Params)
-	keystorePath := filepath.Join(netDir, keystore.Filename)
+	keystorePath := filepath.Join(dir, keystore.Filename)
 	var legacyKeyStore *keystore.Store
 	_, err := os.Stat(keystorePath)
 	if err != nil && !os.IsNotExist(err) {
@@ -439,7 +519,7 @@ func createWallet(cfg *config) error {
 		return err
 	} else if err == nil {
 		// Keystore file exists.
-		legacyKeyStore, err = keystore.OpenDir(netDir)
+		legacyKeyStore, err = keystore.OpenDir(dir)
 		if err != nil {
 			return err
 		}
@@ -471,7 +551,7 @@ func createWallet(cfg *config) error {
 	}
 
 	// Create the wallet.
-	dbPath := filepath.Join(netDir, walletDbName)
+	dbPath := filepath.Join(dir, walletDbName)
 	fmt.Println("Creating the wallet...")
 
 	// Create the wallet database backed by bolt db.
@@ -517,6 +597,43 @@ func createWallet(cfg *config) error {
 	return nil
 }
 
+// createWalletDir creates a wallet without prompting the user.  The new wallet
+// will reside in the provided directory.  The passphrase keys are derived
+// with the passed scrypt options, or the default options if nil.
+func createWalletDir(dir string, seed, pubPass, privPass []byte,
+	scryptOpts *waddrmgr.ScryptOptions) error {
+
+	if err := checkCreateDir(dir); err != nil {
+		return err
+	}
+
+	// Create the wallet database backed by bolt db.
+	dbPath := filepath.Join(dir, walletDbName)
+	db, err := walletdb.Create("bdb", dbPath)
+	if err != nil {
+		return err
+	}
+
+	// Create the address manager.  The database is removed if that fails
+	// so creating the wallet may be retried.
+	namespace, err := db.Namespace(waddrmgrNamespaceKey)
+	if err == nil {
+		var manager *waddrmgr.Manager
+		manager, err = waddrmgr.Create(namespace, seed, pubPass,
+			privPass, activeNet.Params, scryptOpts)
+		if err == nil {
+			manager.Close()
+		}
+	}
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dbPath)
+	}
+	return err
+}
+
 // createSimulationWallet is intended to be called from the rpcclient
 // and used to create a wallet for actors involved in simulations.
 func createSimulationWallet(cfg *config) error {
@@ -602,9 +719,14 @@ func openDb(directory string, dbname string) (walletdb.DB, error) {
 // database, the address manager and the transaction store and uses the values
 // to open a wallet.Wallet
 func openWallet() (*wallet.Wallet, walletdb.DB, error) {
-	netdir := networkDir(cfg.DataDir, activeNet.Params)
+	return openWalletDir(networkDir(cfg.DataDir, activeNet.Params),
+		[]byte(cfg.WalletPass))
+}
 
-	db, err := openDb(netdir, walletDbName)
+// openWalletDir opens the wallet with the database in the passed directory
+// using the public passphrase.
+func openWalletDir(dir string, pubPass []byte) (*wallet.Wallet, walletdb.DB, error) {
+	db, err := openDb(dir, walletDbName)
 	if err != nil {
 		log.Errorf("Failed to open database: %v", err)
 		return nil, nil, err
@@ -622,7 +744,18 @@ func openWallet() (*wallet.Wallet, walletdb.DB, error) {
 		ObtainSeed:        promptSeed,
 		ObtainPrivatePass: promptPrivPassPhrase,
 	}
-	w, err := wallet.Open([]byte(cfg.WalletPass), activeNet.Params, db,
+	w, err := wallet.Open(pubPass, activeNet.Params, db,
 		addrMgrNS, txMgrNS, cbs)
-	return w, db, err
+	if err != nil {
+		return nil, db, err
+	}
+
+	// The coin selection strategy was validated when loading the config.
+	w.CoinSelector, _ = wallet.CoinSelectorByName(cfg.CoinSelection)
+	w.FallbackFeeRate = coinutil.Amount(cfg.FallbackFeeRate)
+	w.SignalReplaceable = cfg.SignalRBF
+	w.UnminedExpiry = cfg.UnminedExpiry
+	w.UnminedExpiryBlocks = cfg.UnminedExpiryBlocks
+	w.Manager.SetGapLimit(cfg.GapLimit)
+	return w, db, nil
 }
@@ -66,7 +66,7 @@ var byteOrder = binary.BigEndian
 // change.
 const (
 	// LatestVersion is the most recent store version.
-	LatestVersion = 1
+	LatestVersion = 2
 )
 
 // This package makes assumptions that the width of a wire.ShaHash is always 32
@@ -85,6 +85,8 @@ var (
 	bucketUnmined        = []byte("m")
 	bucketUnminedCredits = []byte("mc")
 	bucketUnminedInputs  = []byte("mi")
+	bucketTxMetadata     = []byte("tm")
+	bucketConflicted     = []byte("cf")
 )
 
 // Root (namespace) bucket keys
@@ -1194,6 +1196,212 @@ func deleteRawUnminedInput(ns walletdb.Bucket, k []byte) error {
 	return nil
 }
 
+// Transaction metadata, such as user comments, is saved in the tx metadata
+// bucket keyed by the transaction hash.  Since the key does not include the
+// block, the metadata is kept as a transaction is mined, rolled back, or mined
+// again.  The value is serialized as such:
+//
+//   [0:4]          Comment length (4 bytes)
+//   [4:4+n]        Comment (n bytes)
+//   [4+n:8+n]      Comment to length (4 bytes)
+//   [8+n:8+n+m]    Comment to (m bytes)
+//   [8+n+m:12+n+m] Number of replaced transactions (4 bytes)
+//   [12+n+m:]      Replaced transaction hashes (32 bytes each)
+
+func valueTxMetadata(md *TxMetadata) []byte {
+	n := len(md.Comment)
+	m := len(md.CommentTo)
+	v := make([]byte, 12+n+m+32*len(md.Replaces))
+	byteOrder.PutUint32(v, uint32(n))
+	copy(v[4:], md.Comment)
+	byteOrder.PutUint32(v[4+n:], uint32(m))
+	copy(v[8+n:], md.CommentTo)
+	byteOrder.PutUint32(v[8+n+m:], uint32(len(md.Replaces)))
+	off := 12 + n + m
+	for i := range md.Replaces {
+		copy(v[off:], md.Replaces[i][:])
+		off += 32
+	}
+	return v
+}
+
+func putTxMetadata(ns walletdb.Bucket, txHash *wire.ShaHash, md *TxMetadata) error {
+	v := valueTxMetadata(md)
+	err := ns.Bucket(bucketTxMetadata).Put(txHash[:], v)
+	if err != nil {
+		str := "failed to put tx metadata"
+		return storeError(ErrDatabase, str, err)
+	}
+	return nil
+}
+
+func readRawTxMetadata(v []byte, md *TxMetadata) error {
+	if len(v) < 4 {
+		str := "short tx metadata value"
+		return storeError(ErrData, str, nil)
+	}
+	n := int(byteOrder.Uint32(v))
+	if len(v) < 8+n {
+		str := "short tx metadata value"
+		return storeError(ErrData, str, nil)
+	}
+	m := int(byteOrder.Uint32(v[4+n:]))
+	if len(v) < 12+n+m {
+		str := "short tx metadata value"
+		return storeError(ErrData, str, nil)
+	}
+	r := int(byteOrder.Uint32(v[8+n+m:]))
+	if len(v) != 12+n+m+32*r {
+		str := fmt.Sprintf("tx metadata value: expected %d bytes, "+
+			"read %d", 12+n+m+32*r, len(v))
+		return storeError(ErrData, str, nil)
+	}
+	md.Comment = string(v[4 : 4+n])
+	md.CommentTo = string(v[8+n : 8+n+m])
+	md.Replaces = nil
+	if r != 0 {
+		md.Replaces = make([]wire.ShaHash, r)
+		off := 12 + n + m
+		for i := range md.Replaces {
+			copy(md.Replaces[i][:], v[off:off+32])
+			off += 32
+		}
+	}
+	return nil
+}
+
+func existsRawTxMetadata(ns walletdb.Bucket, k []byte) (v []byte) {
+	return ns.Bucket(bucketTxMetadata).Get(k)
+}
+
+func deleteTxMetadata(ns walletdb.Bucket, txHash *wire.ShaHash) error {
+	err := ns.Bucket(bucketTxMetadata).Delete(txHash[:])
+	if err != nil {
+		str := "failed to delete tx metadata"
+		return storeError(ErrDatabase, str, err)
+	}
+	return nil
+}
+
+// Conflicted transactions are unmined transactions which were removed from the
+// store because they, or a transaction they spend from, were double spent.
+// They are saved in the conflicted bucket keyed by the transaction hash, along
+// with the credits and debits recorded for the transaction when it was
+// removed, so its history is kept.  The value is serialized as such:
+//
+//   [0:32]      Hash of the double spending transaction, zero if unknown
+//   [32:36]     Number of credits c (4 bytes)
+//   [36:36+13c] Credits, each the output index (4 bytes), amount (8 bytes)
+//               and change flag (1 byte)
+//   [a:a+4]     Number of debits d (4 bytes), where a = 36+13c
+//   [a+4:b]     Debits, each the input index (4 bytes) and amount (8 bytes),
+//               where b = a+4+12d
+//   [b:]        Transaction record (matches the tx records bucket value)
+
+func valueConflicted(conflictedBy *wire.ShaHash, credits []CreditRecord,
+	debits []DebitRecord, recVal []byte) []byte {
+
+	a := 36 + 13*len(credits)
+	b := a + 4 + 12*len(debits)
+	v := make([]byte, b+len(recVal))
+	if conflictedBy != nil {
+		copy(v, conflictedBy[:])
+	}
+	byteOrder.PutUint32(v[32:], uint32(len(credits)))
+	off := 36
+	for _, cred := range credits {
+		byteOrder.PutUint32(v[off:], cred.Index)
+		byteOrder.PutUint64(v[off+4:], uint64(cred.Amount))
+		if cred.Change {
+			v[off+12] = 1
+		}
+		off += 13
+	}
+	byteOrder.PutUint32(v[a:], uint32(len(debits)))
+	off = a + 4
+	for _, deb := range debits {
+		byteOrder.PutUint32(v[off:], deb.Index)
+		byteOrder.PutUint64(v[off+4:], uint64(deb.Amount))
+		off += 12
+	}
+	copy(v[b:], recVal)
+	return v
+}
+
+func putRawConflicted(ns walletdb.Bucket, k, v []byte) error {
+	err := ns.Bucket(bucketConflicted).Put(k, v)
+	if err != nil {
+		str := "failed to put conflicted record"
+		return storeError(ErrDatabase, str, err)
+	}
+	return nil
+}
+
+// readRawConflicted reads the details of a conflicted transaction.  The
+// Metadata field is not set.
+func readRawConflicted(txHash *wire.ShaHash, v []byte, details *TxDetails) error {
+	short := func() error {
+		str := fmt.Sprintf("%s: short read for conflicted transaction %v",
+			bucketConflicted, txHash)
+		return storeError(ErrData, str, nil)
+	}
+	if len(v) < 36 {
+		return short()
+	}
+	details.Block = BlockMeta{Block: Block{Height: -1}}
+	details.Conflicted = true
+	details.ConflictedBy = nil
+	var conflictedBy wire.ShaHash
+	copy(conflictedBy[:], v)
+	if conflictedBy != (wire.ShaHash{}) {
+		details.ConflictedBy = &conflictedBy
+	}
+
+	c := int(byteOrder.Uint32(v[32:]))
+	a := 36 + 13*c
+	if len(v) < a+4 {
+		return short()
+	}
+	details.Credits = make([]CreditRecord, c)
+	off := 36
+	for i := range details.Credits {
+		details.Credits[i] = CreditRecord{
+			Index:  byteOrder.Uint32(v[off:]),
+			Amount: coinutil.Amount(byteOrder.Uint64(v[off+4:])),
+			Change: v[off+12] != 0,
+		}
+		off += 13
+	}
+	d := int(byteOrder.Uint32(v[a:]))
+	b := a + 4 + 12*d
+	if len(v) < b {
+		return short()
+	}
+	details.Debits = make([]DebitRecord, d)
+	off = a + 4
+	for i := range details.Debits {
+		details.Debits[i] = DebitRecord{
+			Index:  byteOrder.Uint32(v[off:]),
+			Amount: coinutil.Amount(byteOrder.Uint64(v[off+4:])),
+		}
+		off += 12
+	}
+	return readRawTxRecord(txHash, v[b:], &details.TxRecord)
+}
+
+func existsRawConflicted(ns walletdb.Bucket, k []byte) (v []byte) {
+	return ns.Bucket(bucketConflicted).Get(k)
+}
+
+func deleteRawConflicted(ns walletdb.Bucket, k []byte) error {
+	err := ns.Bucket(bucketConflicted).Delete(k)
+	if err != nil {
+		str := "failed to delete conflicted record"
+		return storeError(ErrDatabase, str, err)
+	}
+	return nil
+}
+
 // openStore opens an existing transaction store from the passed namespace.  If
 // necessary, an already existing store is upgraded to newer db format.
 func openStore(namespace walletdb.Namespace) error {
@@ -1234,19 +1442,45 @@ func openStore(namespace walletdb.Namespace) error {
 	// Upgrade the tx store as needed, one version at a time, until
 	// LatestVersion is reached.  Versions are not skipped when performing
 	// database upgrades, and each upgrade is done in its own transaction.
-	//
-	// No upgrades yet.
-	//if version < LatestVersion {
-	//	err := scopedUpdate(namespace, func(ns walletdb.Bucket) error {
-	//	})
-	//	if err != nil {
-	//		// Handle err
-	//	}
-	//}
+	if version < 2 {
+		err := scopedUpdate(namespace, upgradeToVersion2)
+		if err != nil {
+			const desc = "failed to upgrade store to version 2"
+			if serr, ok := err.(Error); ok {
+				serr.Desc = desc + ": " + serr.Desc
+				return serr
+			}
+			return storeError(ErrDatabase, desc, err)
+		}
+	}
 
 	return nil
 }
 
+// upgradeToVersion2 upgrades a version 1 store by creating the tx metadata and
+// conflicted transactions buckets.
+func upgradeToVersion2(ns walletdb.Bucket) error {
+	_, err := ns.CreateBucket(bucketTxMetadata)
+	if err != nil {
+		str := "failed to create tx metadata bucket"
+		return storeError(ErrDatabase, str, err)
+	}
+	_, err = ns.CreateBucket(bucketConflicted)
+	if err != nil {
+		str := "failed to create conflicted bucket"
+		return storeError(ErrDatabase, str, err)
+	}
+
+	v := make([]byte, 4)
+	byteOrder.PutUint32(v, 2)
+	err = ns.Put(rootVersion, v)
+	if err != nil {
+		str := "failed to store database version"
+		return storeError(ErrDatabase, str, err)
+	}
+	return nil
+}
+
 // createStore creates the tx store (with the latest db version) in the passed
 // namespace.  If a store already exists, ErrAlreadyExists is returned.
 func createStore(namespace walletdb.Namespace) error {
@@ -1333,6 +1567,18 @@ func createStore(namespace walletdb.Namespace) error {
 			return storeError(ErrDatabase, str, err)
 		}
 
+		_, err = ns.CreateBucket(bucketTxMetadata)
+		if err != nil {
+			str := "failed to create tx metadata bucket"
+			return storeError(ErrDatabase, str, err)
+		}
+
+		_, err = ns.CreateBucket(bucketConflicted)
+		if err != nil {
+			str := "failed to create conflicted bucket"
+			return storeError(ErrDatabase, str, err)
+		}
+
 		return nil
 	})
 	if err != nil {
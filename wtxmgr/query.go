@@ -47,9 +47,17 @@ type DebitRecord struct {
 // debits.
 type TxDetails struct {
 	TxRecord
-	Block   BlockMeta
-	Credits []CreditRecord
-	Debits  []DebitRecord
+	Block    BlockMeta
+	Credits  []CreditRecord
+	Debits   []DebitRecord
+	Metadata TxMetadata
+
+	// Conflicted is set for transactions which were removed from the store
+	// as double spends, which are only returned by ConflictedTxDetails and
+	// ConflictedTxs.  ConflictedBy is the hash of the transaction double
+	// spending it (or a transaction it spends from), or nil if unknown.
+	Conflicted   bool
+	ConflictedBy *wire.ShaHash
 }
 
 // minedTxDetails fetches the TxDetails for the mined transaction with hash
@@ -67,6 +75,12 @@ func (s *Store) minedTxDetails(ns walletdb.Bucket, txHash *wire.ShaHash, recKey,
 	if err != nil {
 		return nil, err
 	}
+	if v := existsRawTxMetadata(ns, txHash[:]); v != nil {
+		err = readRawTxMetadata(v, &details.Metadata)
+		if err != nil {
+			return nil, err
+		}
+	}
 	details.Block.Time, err = fetchBlockTime(ns, details.Block.Height)
 	if err != nil {
 		return nil, err
@@ -114,6 +128,12 @@ func (s *Store) unminedTxDetails(ns walletdb.Bucket, txHash *wire.ShaHash, v []b
 	if err != nil {
 		return nil, err
 	}
+	if v := existsRawTxMetadata(ns, txHash[:]); v != nil {
+		err = readRawTxMetadata(v, &details.Metadata)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	it := makeUnminedCreditIterator(ns, txHash)
 	for it.next() {
@@ -232,6 +252,69 @@ func (s *Store) UniqueTxDetails(txHash *wire.ShaHash, block *Block) (*TxDetails,
 	return details, err
 }
 
+// conflictedTxDetails fetches the TxDetails for the conflicted transaction
+// with hash txHash and the passed conflicted record value.
+func (s *Store) conflictedTxDetails(ns walletdb.Bucket, txHash *wire.ShaHash, v []byte) (*TxDetails, error) {
+	var details TxDetails
+	err := readRawConflicted(txHash, v, &details)
+	if err != nil {
+		return nil, err
+	}
+	if v := existsRawTxMetadata(ns, txHash[:]); v != nil {
+		err = readRawTxMetadata(v, &details.Metadata)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &details, nil
+}
+
+// ConflictedTxDetails looks up the recorded details of a transaction which was
+// removed from the store as a double spend.  The returned details have the
+// Conflicted field set, and the credits and debits recorded when the
+// transaction was removed.
+//
+// Not finding a conflicted transaction with this hash is not an error.  In
+// this case, a nil TxDetails is returned.
+func (s *Store) ConflictedTxDetails(txHash *wire.ShaHash) (*TxDetails, error) {
+	var details *TxDetails
+	err := scopedView(s.namespace, func(ns walletdb.Bucket) error {
+		v := existsRawConflicted(ns, txHash[:])
+		if v == nil {
+			return nil
+		}
+		var err error
+		details, err = s.conflictedTxDetails(ns, txHash, v)
+		return err
+	})
+	return details, err
+}
+
+// ConflictedTxs returns the details of every transaction removed from the
+// store as a double spend.
+func (s *Store) ConflictedTxs() ([]TxDetails, error) {
+	var details []TxDetails
+	err := scopedView(s.namespace, func(ns walletdb.Bucket) error {
+		return ns.Bucket(bucketConflicted).ForEach(func(k, v []byte) error {
+			if len(k) < 32 {
+				str := fmt.Sprintf("%s: short key (expected %d "+
+					"bytes, read %d)", bucketConflicted, 32, len(k))
+				return storeError(ErrData, str, nil)
+			}
+
+			var txHash wire.ShaHash
+			copy(txHash[:], k)
+			detail, err := s.conflictedTxDetails(ns, &txHash, v)
+			if err != nil {
+				return err
+			}
+			details = append(details, *detail)
+			return nil
+		})
+	})
+	return details, err
+}
+
 // rangeUnminedTransactions executes the function f with TxDetails for every
 // unmined transaction.  f is not executed if no unmined transactions exist.
 // Error returns from f (if any) are propigated to the caller.  Returns true
@@ -408,6 +491,49 @@ func (s *Store) RangeTransactions(begin, end int32, f func([]TxDetails) (bool, e
 	})
 }
 
+// TxCount returns the number of transactions recorded by the store, including
+// both mined and unmined transactions.  A transaction mined in more than one
+// block due to a reorganize which was not yet rolled back is counted once per
+// block.
+func (s *Store) TxCount() (int, error) {
+	var n int
+	err := scopedView(s.namespace, func(ns walletdb.Bucket) error {
+		count := func(k, v []byte) error {
+			n++
+			return nil
+		}
+		err := ns.Bucket(bucketTxRecords).ForEach(count)
+		if err != nil {
+			return err
+		}
+		return ns.Bucket(bucketUnmined).ForEach(count)
+	})
+	return n, err
+}
+
+// SetTxMetadata records the metadata of the transaction with hash txHash,
+// replacing any metadata previously recorded for it.  Recording empty metadata
+// removes it.  The metadata is returned in the Metadata field of the
+// transaction's TxDetails.
+//
+// If no transaction with this hash has been recorded, an error with code
+// ErrNoExists is returned.
+func (s *Store) SetTxMetadata(txHash *wire.ShaHash, md *TxMetadata) error {
+	return scopedUpdate(s.namespace, func(ns walletdb.Bucket) error {
+		if existsRawUnmined(ns, txHash[:]) == nil {
+			if k, _ := latestTxRecord(ns, txHash); k == nil {
+				str := fmt.Sprintf("transaction %v is not recorded",
+					txHash)
+				return storeError(ErrNoExists, str, nil)
+			}
+		}
+		if md.Comment == "" && md.CommentTo == "" && len(md.Replaces) == 0 {
+			return deleteTxMetadata(ns, txHash)
+		}
+		return putTxMetadata(ns, txHash, md)
+	})
+}
+
 // PreviousPkScripts returns a slice of previous output scripts for each credit
 // output this transaction record debits from.
 func (s *Store) PreviousPkScripts(rec *TxRecord, block *Block) ([][]byte, error) {
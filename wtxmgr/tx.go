@@ -126,6 +126,22 @@ func NewTxRecordFromMsgTx(msgTx *wire.MsgTx, received time.Time) (*TxRecord, err
 	return rec, nil
 }
 
+// TxMetadata describes user-provided details about a transaction which are not
+// part of the transaction itself.
+type TxMetadata struct {
+	// Comment is a description of the transaction, such as its purpose.
+	Comment string
+
+	// CommentTo describes the person or organization the transaction
+	// pays.
+	CommentTo string
+
+	// Replaces holds the hashes of the unmined transactions this
+	// transaction replaced, such as by paying a higher fee, oldest first.
+	// It is set by ReplaceTx.
+	Replaces []wire.ShaHash
+}
+
 // Credit is the type representing a transaction output which was spent or
 // is still spendable by wallet.  A UTXO is an unspent Credit, but not all
 // Credits are UTXOs.
@@ -142,6 +158,23 @@ type Credit struct {
 // transactions.
 type Store struct {
 	namespace walletdb.Namespace
+
+	// conflicts collects the transactions removed as conflicted by the
+	// running update, which are notified once the update is committed.
+	// Updates are serialized by the database, so only the running update
+	// accesses it.
+	conflicts []conflict
+
+	// Event callbacks.  These execute in the same goroutine as the wtxmgr
+	// caller, after the update of the store is committed.
+	NotifyConflicted func(txHash, conflictedBy *wire.ShaHash)
+}
+
+// conflict describes a transaction removed as conflicted, and the transaction
+// double spending it if known.
+type conflict struct {
+	txHash       wire.ShaHash
+	conflictedBy *wire.ShaHash
 }
 
 // Open opens the wallet transaction store from a walletdb namespace.  If the
@@ -153,7 +186,7 @@ func Open(namespace walletdb.Namespace) (*Store, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Store{namespace}, nil
+	return &Store{namespace: namespace}, nil
 }
 
 // Create creates and opens a new persistent transaction store in the walletdb
@@ -164,7 +197,28 @@ func Create(namespace walletdb.Namespace) (*Store, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Store{namespace}, nil
+	return &Store{namespace: namespace}, nil
+}
+
+// update runs f in a database update like scopedUpdate.  NotifyConflicted is
+// called for each transaction removed as conflicted by f only after the update
+// is committed, so the callback observes the updated store.
+func (s *Store) update(f func(walletdb.Bucket) error) error {
+	var conflicts []conflict
+	err := scopedUpdate(s.namespace, func(ns walletdb.Bucket) error {
+		s.conflicts = nil
+		err := f(ns)
+		conflicts = s.conflicts
+		s.conflicts = nil
+		return err
+	})
+	if err != nil || s.NotifyConflicted == nil {
+		return err
+	}
+	for i := range conflicts {
+		s.NotifyConflicted(&conflicts[i].txHash, conflicts[i].conflictedBy)
+	}
+	return nil
 }
 
 // moveMinedTx moves a transaction record from the unmined buckets to block
@@ -291,7 +345,7 @@ func (s *Store) moveMinedTx(ns walletdb.Bucket, rec *TxRecord, recKey, recVal []
 // history.  If block is nil, the transaction is considered unspent, and the
 // transaction's index must be unset.
 func (s *Store) InsertTx(rec *TxRecord, block *BlockMeta) error {
-	return scopedUpdate(s.namespace, func(ns walletdb.Bucket) error {
+	return s.update(func(ns walletdb.Bucket) error {
 		if block == nil {
 			return s.insertMemPoolTx(ns, rec)
 		}
@@ -318,12 +372,19 @@ func (s *Store) insertMinedTx(ns walletdb.Bucket, rec *TxRecord, block *BlockMet
 		return s.moveMinedTx(ns, rec, k, v, block)
 	}
 
+	// A transaction previously removed as a conflict is no longer
+	// conflicted once it is mined.
+	err := deleteRawConflicted(ns, rec.Hash[:])
+	if err != nil {
+		return err
+	}
+
 	// As there may be unconfirmed transactions that are invalidated by this
 	// transaction (either being duplicates, or double spends), remove them
 	// from the unconfirmed set.  This also handles removing unconfirmed
 	// transaction spend chains if any other unconfirmed transactions spend
 	// outputs of the removed double spend.
-	err := s.removeDoubleSpends(ns, rec)
+	err = s.removeDoubleSpends(ns, rec)
 	if err != nil {
 		return err
 	}
@@ -471,7 +532,7 @@ func (s *Store) addCredit(ns walletdb.Bucket, rec *TxRecord, block *BlockMeta, i
 // Rollback removes all blocks at height onwards, moving any transactions within
 // each block to the unconfirmed pool.
 func (s *Store) Rollback(height int32) error {
-	return scopedUpdate(s.namespace, func(ns walletdb.Bucket) error {
+	return s.update(func(ns walletdb.Bucket) error {
 		return s.rollback(ns, height)
 	})
 }
@@ -676,7 +737,7 @@ func (s *Store) rollback(ns walletdb.Bucket, height int32) error {
 
 			log.Debugf("Transaction %v spends a removed coinbase "+
 				"output -- removing as well", unminedRec.Hash)
-			err = s.removeConflict(ns, &unminedRec)
+			err = s.removeConflict(ns, &unminedRec, nil)
 			if err != nil {
 				return err
 			}
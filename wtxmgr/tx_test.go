@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -1156,6 +1157,93 @@ func TestMoveMultipleToSameBlock(t *testing.T) {
 // Test the optional-ness of the serialized transaction in a TxRecord.
 // NewTxRecord and NewTxRecordFromMsgTx both save the serialized transaction, so
 // manually strip it out to test this code path.
+// Test that TxCount counts both mined and unmined transactions as they move
+// between the two.
+func TestTxCount(t *testing.T) {
+	t.Parallel()
+
+	s, teardown, err := testStore()
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkCount := func(desc string, want int) {
+		n, err := s.TxCount()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Fatalf("Unexpected transaction count %s: got %d, want %d",
+				desc, n, want)
+		}
+	}
+	checkCount("of empty store", 0)
+
+	b100 := makeBlockMeta(100)
+	cb := newCoinBase(20e8, 30e8)
+	cbRec, err := NewTxRecordFromMsgTx(cb, b100.Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.InsertTx(cbRec, &b100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.AddCredit(cbRec, &b100, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.AddCredit(cbRec, &b100, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spenderRecs []*TxRecord
+	for i := uint32(0); i < 2; i++ {
+		spender := spendOutput(&cbRec.Hash, i, 1e8)
+		rec, err := NewTxRecordFromMsgTx(spender, timeNow())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.InsertTx(rec, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		spenderRecs = append(spenderRecs, rec)
+	}
+	checkCount("with unmined spenders", 3)
+
+	// Inserting a recorded transaction again must not count it twice.
+	err = s.InsertTx(spenderRecs[0], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCount("after inserting a spender again", 3)
+
+	bMaturity := makeBlockMeta(b100.Height + blockchain.CoinbaseMaturity)
+	for _, rec := range spenderRecs {
+		err = s.InsertTx(rec, &bMaturity)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkCount("after mining spenders", 3)
+
+	// Rolling back the spenders' block moves them back to unmined, and
+	// rolling back the coinbase removes every transaction.
+	err = s.Rollback(bMaturity.Height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCount("after spender block rollback", 3)
+	err = s.Rollback(b100.Height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCount("after coinbase rollback", 0)
+}
+
 func TestInsertUnserializedTx(t *testing.T) {
 	t.Parallel()
 
@@ -1211,3 +1299,640 @@ func TestInsertUnserializedTx(t *testing.T) {
 		t.Fatal("Serialized txs for coinbase spender do not match")
 	}
 }
+
+func TestTxMetadata(t *testing.T) {
+	t.Parallel()
+
+	s, teardown, err := testStore()
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b100 := BlockMeta{
+		Block: Block{Height: 100},
+		Time:  time.Now(),
+	}
+
+	cb := newCoinBase(20e8)
+	cbRec, err := NewTxRecordFromMsgTx(cb, b100.Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spender := spendOutput(&cbRec.Hash, 0, 19e8)
+	spenderRec, err := NewTxRecordFromMsgTx(spender, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Metadata can not be recorded for unknown transactions.
+	md := &TxMetadata{Comment: "rent", CommentTo: "landlord"}
+	err = s.SetTxMetadata(&spenderRec.Hash, md)
+	if !IsNoExists(err) {
+		t.Fatalf("Expected ErrNoExists setting metadata of unknown tx, got %v", err)
+	}
+
+	err = s.InsertTx(cbRec, &b100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.AddCredit(cbRec, &b100, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.InsertTx(spenderRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkMetadata := func(desc string, want TxMetadata) {
+		details, err := s.TxDetails(&spenderRec.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if details == nil {
+			t.Fatalf("%s: no details for spender", desc)
+		}
+		if !reflect.DeepEqual(details.Metadata, want) {
+			t.Fatalf("%s: got metadata %+v, want %+v", desc,
+				details.Metadata, want)
+		}
+	}
+
+	err = s.SetTxMetadata(&spenderRec.Hash, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetadata("unmined", *md)
+
+	// Metadata must be kept when the transaction is mined.
+	b101 := BlockMeta{
+		Block: Block{Height: 101},
+		Time:  time.Now(),
+	}
+	err = s.InsertTx(spenderRec, &b101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetadata("mined", *md)
+
+	// Comments may be replaced and removed.
+	md = &TxMetadata{Comment: "rent for May"}
+	err = s.SetTxMetadata(&spenderRec.Hash, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetadata("replaced", *md)
+	err = s.SetTxMetadata(&spenderRec.Hash, &TxMetadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMetadata("removed", TxMetadata{})
+}
+
+func TestReplaceTx(t *testing.T) {
+	t.Parallel()
+
+	s, teardown, err := testStore()
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b100 := BlockMeta{
+		Block: Block{Height: 100},
+		Time:  time.Now(),
+	}
+
+	cb := newCoinBase(20e8)
+	cbRec, err := NewTxRecordFromMsgTx(cb, b100.Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.InsertTx(cbRec, &b100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.AddCredit(cbRec, &b100, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Record an unmined spend of the coinbase with a change output, a
+	// comment, and a further unmined spend of the change.
+	spend := func(txHash *wire.ShaHash, amount int64) *TxRecord {
+		rec, err := NewTxRecordFromMsgTx(spendOutput(txHash, 0, amount),
+			time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+	origRec := spend(&cbRec.Hash, 19e8)
+	err = s.InsertTx(origRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.AddCredit(origRec, nil, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := &TxMetadata{Comment: "rent"}
+	err = s.SetTxMetadata(&origRec.Hash, md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	childRec := spend(&origRec.Hash, 18e8)
+	err = s.InsertTx(childRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A transaction which does not double spend the replaced transaction
+	// can not replace it.
+	err = s.ReplaceTx(&origRec.Hash, childRec)
+	if serr, ok := err.(Error); !ok || serr.Code != ErrInput {
+		t.Fatalf("Expected ErrInput replacing with unrelated tx, got %v", err)
+	}
+
+	checkReplaced := func(replaced, replacement *TxRecord, want TxMetadata) {
+		details, err := s.TxDetails(&replaced.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if details != nil {
+			t.Fatalf("Replaced transaction %v still recorded",
+				replaced.Hash)
+		}
+		details, err = s.TxDetails(&replacement.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if details == nil {
+			t.Fatalf("Replacement %v not recorded", replacement.Hash)
+		}
+		if !reflect.DeepEqual(details.Metadata, want) {
+			t.Fatalf("Got replacement metadata %+v, want %+v",
+				details.Metadata, want)
+		}
+	}
+
+	// Replacing the transaction removes it and its spender, keeps the
+	// comment, and links the replacement to it.
+	bumpRec := spend(&cbRec.Hash, 18.9e8)
+	err = s.ReplaceTx(&origRec.Hash, bumpRec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReplaced(origRec, bumpRec, TxMetadata{
+		Comment:  "rent",
+		Replaces: []wire.ShaHash{origRec.Hash},
+	})
+	details, err := s.TxDetails(&childRec.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details != nil {
+		t.Fatal("Spender of replaced transaction still recorded")
+	}
+
+	// Replacements may be replaced again, accumulating the links.
+	bump2Rec := spend(&cbRec.Hash, 18.8e8)
+	err = s.ReplaceTx(&bumpRec.Hash, bump2Rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReplaced(bumpRec, bump2Rec, TxMetadata{
+		Comment:  "rent",
+		Replaces: []wire.ShaHash{origRec.Hash, bumpRec.Hash},
+	})
+
+	// Removed transactions can not be replaced.
+	err = s.ReplaceTx(&bumpRec.Hash, spend(&cbRec.Hash, 18.7e8))
+	if serr, ok := err.(Error); !ok || serr.Code != ErrInput {
+		t.Fatalf("Expected ErrInput replacing removed tx, got %v", err)
+	}
+}
+
+func TestUnminedCredits(t *testing.T) {
+	t.Parallel()
+
+	s, teardown, err := testStore()
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b100 := BlockMeta{
+		Block: Block{Height: 100},
+		Time:  time.Now(),
+	}
+
+	cb := newCoinBase(20e8)
+	cbRec, err := NewTxRecordFromMsgTx(cb, b100.Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.InsertTx(cbRec, &b100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Mined transactions have no unmined credits.
+	_, err = s.UnminedCredits(&cbRec.Hash)
+	if serr, ok := err.(Error); !ok || serr.Code != ErrInput {
+		t.Fatalf("Expected ErrInput for mined tx, got %v", err)
+	}
+
+	// Record an unmined transaction with credits for outputs 0 and 2.
+	parent := spendOutput(&cbRec.Hash, 0, 5e8, 6e8, 7e8)
+	parentRec, err := NewTxRecordFromMsgTx(parent, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.InsertTx(parentRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []uint32{2, 0} {
+		err = s.AddCredit(parentRec, nil, index, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkCredits := func(desc string, want []uint32) {
+		credits, err := s.UnminedCredits(&parentRec.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(credits) != len(want) {
+			t.Fatalf("%s: got %d credits, want %d", desc,
+				len(credits), len(want))
+		}
+		for i, credit := range credits {
+			index := want[i]
+			wantOp := wire.OutPoint{Hash: parentRec.Hash, Index: index}
+			if credit.OutPoint != wantOp {
+				t.Errorf("%s: credit %d has outpoint %v, want %v",
+					desc, i, credit.OutPoint, wantOp)
+			}
+			if credit.Amount != coinutil.Amount(parent.TxOut[index].Value) {
+				t.Errorf("%s: credit %d has amount %v", desc, i,
+					credit.Amount)
+			}
+			if credit.Height != -1 {
+				t.Errorf("%s: credit %d has height %d", desc, i,
+					credit.Height)
+			}
+		}
+	}
+	checkCredits("unspent", []uint32{0, 2})
+
+	// Credits spent by other unmined transactions are not returned.
+	child := spendOutput(&parentRec.Hash, 0, 4e8)
+	childRec, err := NewTxRecordFromMsgTx(child, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.InsertTx(childRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCredits("spent", []uint32{2})
+}
+
+func TestConflictedTxs(t *testing.T) {
+	t.Parallel()
+
+	s, teardown, err := testStore()
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Notifications are only sent once the conflicted transaction is
+	// committed, so the callback can read it from the store.
+	conflicted := make(map[wire.ShaHash]*wire.ShaHash)
+	s.NotifyConflicted = func(txHash, conflictedBy *wire.ShaHash) {
+		conflicted[*txHash] = conflictedBy
+		details, err := s.ConflictedTxDetails(txHash)
+		if err != nil {
+			t.Errorf("Cannot read notified conflicted transaction "+
+				"%v: %v", txHash, err)
+		} else if details == nil {
+			t.Errorf("Notified conflicted transaction %v is not "+
+				"committed", txHash)
+		}
+	}
+
+	b100 := BlockMeta{
+		Block: Block{Height: 100},
+		Time:  time.Now(),
+	}
+	b101 := BlockMeta{
+		Block: Block{Height: 101},
+		Time:  time.Now(),
+	}
+
+	cb := newCoinBase(20e8)
+	cbRec, err := NewTxRecordFromMsgTx(cb, b100.Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.InsertTx(cbRec, &b100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.AddCredit(cbRec, &b100, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Record an unmined spend of the coinbase with a change output and a
+	// comment, and a further unmined spend of the change.
+	newRec := func(tx *wire.MsgTx) *TxRecord {
+		rec, err := NewTxRecordFromMsgTx(tx, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+	spendRec := newRec(spendOutput(&cbRec.Hash, 0, 19e8))
+	err = s.InsertTx(spendRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.AddCredit(spendRec, nil, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetTxMetadata(&spendRec.Hash, &TxMetadata{Comment: "rent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	childRec := newRec(spendOutput(&spendRec.Hash, 0, 18e8))
+	err = s.InsertTx(childRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A double spend of the coinbase output removes both transactions as
+	// conflicted by it, keeping their history.
+	doubleRec := newRec(spendOutput(&cbRec.Hash, 0, 18.5e8))
+	err = s.InsertTx(doubleRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range []*TxRecord{spendRec, childRec} {
+		details, err := s.TxDetails(&rec.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if details != nil {
+			t.Fatalf("Conflicted transaction %v still recorded",
+				rec.Hash)
+		}
+		by, ok := conflicted[rec.Hash]
+		if !ok || by == nil || *by != doubleRec.Hash {
+			t.Fatalf("Transaction %v notified conflicted by %v, "+
+				"want %v", rec.Hash, by, doubleRec.Hash)
+		}
+	}
+	if len(conflicted) != 2 {
+		t.Fatalf("Got %d conflicted notifications, want 2",
+			len(conflicted))
+	}
+	details, err := s.ConflictedTxDetails(&spendRec.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details == nil || !details.Conflicted {
+		t.Fatal("Conflicted transaction details not recorded")
+	}
+	if details.ConflictedBy == nil || *details.ConflictedBy != doubleRec.Hash {
+		t.Errorf("Transaction conflicted by %v, want %v",
+			details.ConflictedBy, doubleRec.Hash)
+	}
+	if details.Block.Height != -1 {
+		t.Errorf("Conflicted transaction has height %d",
+			details.Block.Height)
+	}
+	wantCredits := []CreditRecord{{Amount: 19e8, Index: 0, Change: true}}
+	if !reflect.DeepEqual(details.Credits, wantCredits) {
+		t.Errorf("Got credits %+v, want %+v", details.Credits,
+			wantCredits)
+	}
+	wantDebits := []DebitRecord{{Amount: 20e8, Index: 0}}
+	if !reflect.DeepEqual(details.Debits, wantDebits) {
+		t.Errorf("Got debits %+v, want %+v", details.Debits, wantDebits)
+	}
+	if details.Metadata.Comment != "rent" {
+		t.Errorf("Conflicted transaction lost comment %q",
+			details.Metadata.Comment)
+	}
+	all, err := s.ConflictedTxs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("Got %d conflicted transactions, want 2", len(all))
+	}
+
+	// Mining a conflicted transaction restores it, and the unmined double
+	// spend becomes conflicted instead.
+	err = s.InsertTx(spendRec, &b101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	details, err = s.ConflictedTxDetails(&spendRec.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details != nil {
+		t.Fatal("Mined transaction still recorded as conflicted")
+	}
+	details, err = s.TxDetails(&spendRec.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details == nil || details.Block.Height != 101 {
+		t.Fatal("Mined transaction not recorded")
+	}
+	details, err = s.ConflictedTxDetails(&doubleRec.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details == nil || details.ConflictedBy == nil ||
+		*details.ConflictedBy != spendRec.Hash {
+		t.Fatal("Double spend not recorded as conflicted by mined tx")
+	}
+
+	// Transactions double spent by an unknown transaction can be marked
+	// conflicted explicitly, but only while unmined.
+	err = s.MarkConflicted(&spendRec.Hash)
+	if serr, ok := err.(Error); !ok || serr.Code != ErrInput {
+		t.Fatalf("Expected ErrInput marking mined tx, got %v", err)
+	}
+	otherRec := newRec(spendOutput(&wire.ShaHash{}, 0, 1e8))
+	err = s.InsertTx(otherRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.MarkConflicted(&otherRec.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if by, ok := conflicted[otherRec.Hash]; !ok || by != nil {
+		t.Fatalf("Marked transaction notified conflicted by %v", by)
+	}
+	details, err = s.ConflictedTxDetails(&otherRec.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details == nil || details.ConflictedBy != nil {
+		t.Fatal("Marked transaction not recorded as conflicted")
+	}
+
+	// Recording the transaction as unmined again removes the conflict.
+	err = s.InsertTx(otherRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	details, err = s.ConflictedTxDetails(&otherRec.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details != nil {
+		t.Fatal("Reinserted transaction still recorded as conflicted")
+	}
+}
+
+func TestAbandonTx(t *testing.T) {
+	t.Parallel()
+
+	s, teardown, err := testStore()
+	defer teardown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var conflicted int
+	s.NotifyConflicted = func(txHash, conflictedBy *wire.ShaHash) {
+		conflicted++
+	}
+
+	b100 := BlockMeta{
+		Block: Block{Height: 100},
+		Time:  time.Now(),
+	}
+
+	cb := newCoinBase(20e8)
+	cbRec, err := NewTxRecordFromMsgTx(cb, b100.Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.InsertTx(cbRec, &b100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.AddCredit(cbRec, &b100, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Mined transactions can not be abandoned.
+	_, err = s.AbandonTx(&cbRec.Hash)
+	if serr, ok := err.(Error); !ok || serr.Code != ErrInput {
+		t.Fatalf("Expected ErrInput abandoning mined tx, got %v", err)
+	}
+
+	// Record an unmined spend of the coinbase with a comment and a change
+	// credit, and a further unmined spend of the change.
+	newRec := func(tx *wire.MsgTx) *TxRecord {
+		rec, err := NewTxRecordFromMsgTx(tx, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+	spendRec := newRec(spendOutput(&cbRec.Hash, 0, 19e8))
+	err = s.InsertTx(spendRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.AddCredit(spendRec, nil, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetTxMetadata(&spendRec.Hash, &TxMetadata{Comment: "rent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	childRec := newRec(spendOutput(&spendRec.Hash, 0, 18e8))
+	err = s.InsertTx(childRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unspent, err := s.UnspentOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unspent) != 0 {
+		t.Fatalf("Got %d unspent outputs before abandoning, want 0",
+			len(unspent))
+	}
+
+	// Abandoning the spend removes it and its spender without keeping
+	// any history, and the coinbase output becomes spendable again.
+	abandoned, err := s.AbandonTx(&spendRec.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []wire.ShaHash{spendRec.Hash, childRec.Hash}
+	if !reflect.DeepEqual(abandoned, want) {
+		t.Fatalf("Abandoned %v, want %v", abandoned, want)
+	}
+	for _, rec := range []*TxRecord{spendRec, childRec} {
+		details, err := s.TxDetails(&rec.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if details != nil {
+			t.Fatalf("Abandoned transaction %v still recorded",
+				rec.Hash)
+		}
+		details, err = s.ConflictedTxDetails(&rec.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if details != nil {
+			t.Fatalf("Abandoned transaction %v recorded as "+
+				"conflicted", rec.Hash)
+		}
+	}
+	if conflicted != 0 {
+		t.Fatalf("Abandoned transactions notified as conflicted")
+	}
+	unspent, err = s.UnspentOutputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unspent) != 1 || unspent[0].OutPoint.Hash != cbRec.Hash {
+		t.Fatalf("Got unspent outputs %v, want the coinbase output",
+			unspent)
+	}
+
+	// The metadata of abandoned transactions is removed as well.
+	err = s.InsertTx(spendRec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	details, err := s.TxDetails(&spendRec.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details == nil || details.Metadata.Comment != "" {
+		t.Fatal("Reinserted abandoned transaction kept its metadata")
+	}
+}
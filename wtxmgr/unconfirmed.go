@@ -17,6 +17,9 @@
 package wtxmgr
 
 import (
+	"bytes"
+	"fmt"
+
 	"github.com/conseweb/stcd/wire"
 	"github.com/conseweb/stcwallet/walletdb"
 )
@@ -35,6 +38,10 @@ func (s *Store) insertMemPoolTx(ns walletdb.Bucket, rec *TxRecord) error {
 	if err != nil {
 		return err
 	}
+	err = deleteRawConflicted(ns, rec.Hash[:])
+	if err != nil {
+		return err
+	}
 	err = putRawUnmined(ns, rec.Hash[:], v)
 	if err != nil {
 		return err
@@ -76,7 +83,7 @@ func (s *Store) removeDoubleSpends(ns walletdb.Bucket, rec *TxRecord) error {
 
 			log.Debugf("Removing double spending transaction %v",
 				doubleSpend.Hash)
-			err = s.removeConflict(ns, &doubleSpend)
+			err = s.removeConflict(ns, &doubleSpend, &rec.Hash)
 			if err != nil {
 				return err
 			}
@@ -88,8 +95,41 @@ func (s *Store) removeDoubleSpends(ns walletdb.Bucket, rec *TxRecord) error {
 // removeConflict removes an unmined transaction record and all spend chains
 // deriving from it from the store.  This is designed to remove transactions
 // that would otherwise result in double spend conflicts if left in the store,
-// and to remove transactions that spend coinbase transactions on reorgs.
-func (s *Store) removeConflict(ns walletdb.Bucket, rec *TxRecord) error {
+// and to remove transactions that spend coinbase transactions on reorgs.  Each
+// removed transaction is saved as a conflicted transaction, double spent by
+// the transaction with hash conflictedBy (nil if unknown), and the
+// NotifyConflicted callback is called for it once the update is committed.
+func (s *Store) removeConflict(ns walletdb.Bucket, rec *TxRecord, conflictedBy *wire.ShaHash) error {
+	return s.removeUnmined(ns, rec, func(rec *TxRecord) error {
+		// Keep the history of the transaction.  The metadata is kept
+		// with the conflicted transaction as well.
+		v := existsRawUnmined(ns, rec.Hash[:])
+		details, err := s.unminedTxDetails(ns, &rec.Hash, v)
+		if err != nil {
+			return err
+		}
+		v = valueConflicted(conflictedBy, details.Credits, details.Debits, v)
+		err = putRawConflicted(ns, rec.Hash[:], v)
+		if err != nil {
+			return err
+		}
+
+		s.conflicts = append(s.conflicts, conflict{rec.Hash, conflictedBy})
+		return nil
+	})
+}
+
+// removeUnmined removes an unmined transaction record and all spend chains
+// deriving from it from the store, marking the outputs spent by each removed
+// transaction unspent again.  The function removed is called with each removed
+// transaction before any of its credits, or the credits it spends, are
+// deleted.
+func (s *Store) removeUnmined(ns walletdb.Bucket, rec *TxRecord, removed func(*TxRecord) error) error {
+	err := removed(rec)
+	if err != nil {
+		return err
+	}
+
 	// For each potential credit for this record, each spender (if any) must
 	// be recursively removed as well.  Once the spenders are removed, the
 	// credit is deleted.
@@ -106,9 +146,9 @@ func (s *Store) removeConflict(ns walletdb.Bucket, rec *TxRecord) error {
 				return err
 			}
 
-			log.Debugf("Transaction %v is part of a removed conflict "+
+			log.Debugf("Transaction %v is part of a removed spend "+
 				"chain -- removing as well", spender.Hash)
-			err = s.removeConflict(ns, &spender)
+			err = s.removeUnmined(ns, &spender, removed)
 			if err != nil {
 				return err
 			}
@@ -134,6 +174,181 @@ func (s *Store) removeConflict(ns walletdb.Bucket, rec *TxRecord) error {
 	return deleteRawUnmined(ns, rec.Hash[:])
 }
 
+// AbandonTx removes the unmined transaction with hash txHash, and all
+// transactions which spend it, from the store.  This is used for transactions
+// which are never expected to be mined.  The outputs spent by the removed
+// transactions become unspent again, and unlike double spent transactions,
+// no history of the removed transactions is kept.  The hashes of the removed
+// transactions are returned, beginning with txHash.
+//
+// If txHash is not an unmined transaction, an error with code ErrInput is
+// returned.
+func (s *Store) AbandonTx(txHash *wire.ShaHash) ([]wire.ShaHash, error) {
+	var abandoned []wire.ShaHash
+	err := scopedUpdate(s.namespace, func(ns walletdb.Bucket) error {
+		v := existsRawUnmined(ns, txHash[:])
+		if v == nil {
+			str := fmt.Sprintf("transaction %v is not a recorded "+
+				"unmined transaction", txHash)
+			return storeError(ErrInput, str, nil)
+		}
+		var rec TxRecord
+		err := readRawTxRecord(txHash, v, &rec)
+		if err != nil {
+			return err
+		}
+
+		return s.removeUnmined(ns, &rec, func(rec *TxRecord) error {
+			log.Infof("Abandoning unmined transaction %v", rec.Hash)
+			abandoned = append(abandoned, rec.Hash)
+			return deleteTxMetadata(ns, &rec.Hash)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return abandoned, nil
+}
+
+// MarkConflicted removes the unmined transaction with hash txHash, and all
+// transactions which spend it, from the store as conflicted transactions.
+// This is used for transactions which are known to be double spent by a
+// transaction not recorded by the store, such as when the chain server
+// rejects the transaction because its inputs are already spent.
+//
+// If txHash is not an unmined transaction, an error with code ErrInput is
+// returned.
+func (s *Store) MarkConflicted(txHash *wire.ShaHash) error {
+	return s.update(func(ns walletdb.Bucket) error {
+		v := existsRawUnmined(ns, txHash[:])
+		if v == nil {
+			str := fmt.Sprintf("transaction %v is not a recorded "+
+				"unmined transaction", txHash)
+			return storeError(ErrInput, str, nil)
+		}
+		var rec TxRecord
+		err := readRawTxRecord(txHash, v, &rec)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("Removing conflicted transaction %v", txHash)
+		return s.removeConflict(ns, &rec, nil)
+	})
+}
+
+// ReplaceTx records rec as an unmined transaction replacing the unmined
+// transaction with hash replaced, such as a transaction paying a higher fee
+// which spends at least one of the same outputs.  The replaced transaction,
+// and all transactions which spend it, are removed as double spends and kept
+// as transactions conflicted by rec.  The metadata of the replaced transaction
+// is kept for the replacement, with the replaced hash appended to its Replaces
+// field so both transactions remain linked.
+//
+// If replaced is not an unmined transaction double spent by rec, an error with
+// code ErrInput is returned.
+func (s *Store) ReplaceTx(replaced *wire.ShaHash, rec *TxRecord) error {
+	return s.update(func(ns walletdb.Bucket) error {
+		if existsRawUnmined(ns, replaced[:]) == nil {
+			str := fmt.Sprintf("transaction %v is not a recorded "+
+				"unmined transaction", replaced)
+			return storeError(ErrInput, str, nil)
+		}
+		doubleSpent := false
+		for _, input := range rec.MsgTx.TxIn {
+			prevOut := &input.PreviousOutPoint
+			k := canonicalOutPoint(&prevOut.Hash, prevOut.Index)
+			if bytes.Equal(existsRawUnminedInput(ns, k), replaced[:]) {
+				doubleSpent = true
+				break
+			}
+		}
+		if !doubleSpent {
+			str := fmt.Sprintf("transaction %v does not double spend "+
+				"transaction %v", rec.Hash, replaced)
+			return storeError(ErrInput, str, nil)
+		}
+
+		var md TxMetadata
+		if v := existsRawTxMetadata(ns, replaced[:]); v != nil {
+			err := readRawTxMetadata(v, &md)
+			if err != nil {
+				return err
+			}
+		}
+		md.Replaces = append(md.Replaces, *replaced)
+
+		err := s.removeDoubleSpends(ns, rec)
+		if err != nil {
+			return err
+		}
+		err = s.insertMemPoolTx(ns, rec)
+		if err != nil {
+			return err
+		}
+		return putTxMetadata(ns, &rec.Hash, &md)
+	})
+}
+
+// UnminedCredits returns the unspent credits of the unmined transaction with
+// hash txHash, ordered by output index.  These outputs may be spent by a child
+// transaction paying a fee for both itself and the unmined transaction.
+//
+// If txHash is not an unmined transaction, an error with code ErrInput is
+// returned.
+func (s *Store) UnminedCredits(txHash *wire.ShaHash) ([]Credit, error) {
+	var credits []Credit
+	err := scopedView(s.namespace, func(ns walletdb.Bucket) error {
+		var err error
+		credits, err = s.unminedCredits(ns, txHash)
+		return err
+	})
+	return credits, err
+}
+
+func (s *Store) unminedCredits(ns walletdb.Bucket, txHash *wire.ShaHash) ([]Credit, error) {
+	recVal := existsRawUnmined(ns, txHash[:])
+	if recVal == nil {
+		str := fmt.Sprintf("transaction %v is not a recorded unmined "+
+			"transaction", txHash)
+		return nil, storeError(ErrInput, str, nil)
+	}
+	var rec TxRecord
+	err := readRawTxRecord(txHash, recVal, &rec)
+	if err != nil {
+		return nil, err
+	}
+
+	var credits []Credit
+	it := makeUnminedCreditIterator(ns, txHash)
+	for it.next() {
+		if existsRawUnminedInput(ns, it.ck) != nil {
+			// Output is spent by an unmined transaction.
+			continue
+		}
+		if int(it.elem.Index) >= len(rec.MsgTx.TxOut) {
+			str := "saved credit index exceeds number of outputs"
+			return nil, storeError(ErrData, str, nil)
+		}
+
+		// Unmined transactions are never coinbases, so FromCoinBase is
+		// left unset.
+		credits = append(credits, Credit{
+			OutPoint: wire.OutPoint{
+				Hash:  *txHash,
+				Index: it.elem.Index,
+			},
+			BlockMeta: BlockMeta{
+				Block: Block{Height: -1},
+			},
+			Amount:   it.elem.Amount,
+			PkScript: rec.MsgTx.TxOut[it.elem.Index].PkScript,
+			Received: rec.Received,
+		})
+	}
+	return credits, it.err
+}
+
 // UnminedTxs returns the underlying transactions for all unmined transactions
 // which are not known to have been mined in a block.
 func (s *Store) UnminedTxs() ([]*wire.MsgTx, error) {